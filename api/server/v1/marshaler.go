@@ -22,6 +22,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // CustomMarshaler is a marshaler to customize the response. Currently, it is only used to marshal custom error message
@@ -118,9 +119,23 @@ func (x *SearchRequest) UnmarshalJSON(data []byte) error {
 		case "sort":
 			// delaying the sort deserialization
 			x.Sort = value
+		case "vector":
+			// delaying the vector query deserialization
+			x.Vector = value
 		case "fields":
 			// not decoding it here and let it decode during fields parsing
 			x.Fields = value
+		case "boost":
+			// delaying the boost deserialization, the weights are checked against the search fields
+			x.Boost = value
+		case "prefix":
+			if err := jsoniter.Unmarshal(value, &x.Prefix); err != nil {
+				return err
+			}
+		case "typo_tolerance":
+			if err := jsoniter.Unmarshal(value, &x.TypoTolerance); err != nil {
+				return err
+			}
 		case "page_size":
 			if err := jsoniter.Unmarshal(value, &x.PageSize); err != nil {
 				return err
@@ -129,6 +144,14 @@ func (x *SearchRequest) UnmarshalJSON(data []byte) error {
 			if err := jsoniter.Unmarshal(value, &x.Page); err != nil {
 				return err
 			}
+		case "cursor":
+			if err := jsoniter.Unmarshal(value, &x.Cursor); err != nil {
+				return err
+			}
+		case "export":
+			if err := jsoniter.Unmarshal(value, &x.Export); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -271,6 +294,137 @@ func (x *DeleteRequest) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UnmarshalJSON on EventsRequest avoids unmarshalling filter, it is parsed against the collection schema when the
+// stream is started.
+func (x *EventsRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "db":
+			if err := jsoniter.Unmarshal(value, &x.Db); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "resume_token":
+			if err := jsoniter.Unmarshal(value, &x.ResumeToken); err != nil {
+				return err
+			}
+		case "from_beginning":
+			if err := jsoniter.Unmarshal(value, &x.FromBeginning); err != nil {
+				return err
+			}
+		case "start_time":
+			var tm time.Time
+			if err := jsoniter.Unmarshal(value, &tm); err != nil {
+				return err
+			}
+			x.StartTime = timestamppb.New(tm)
+		case "ops":
+			if err := jsoniter.Unmarshal(value, &x.Ops); err != nil {
+				return err
+			}
+		case "filter":
+			// not decoding it here and let it decode during filter parsing
+			x.Filter = value
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON on CreateWebhookRequest avoids unmarshalling filter, it is parsed against the collection schema when
+// the webhook is created.
+func (x *CreateWebhookRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "db":
+			if err := jsoniter.Unmarshal(value, &x.Db); err != nil {
+				return err
+			}
+		case "name":
+			if err := jsoniter.Unmarshal(value, &x.Name); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "url":
+			if err := jsoniter.Unmarshal(value, &x.Url); err != nil {
+				return err
+			}
+		case "secret":
+			if err := jsoniter.Unmarshal(value, &x.Secret); err != nil {
+				return err
+			}
+		case "ops":
+			if err := jsoniter.Unmarshal(value, &x.Ops); err != nil {
+				return err
+			}
+		case "filter":
+			x.Filter = value
+		case "dead_letter_collection":
+			if err := jsoniter.Unmarshal(value, &x.DeadLetterCollection); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON on CreateTriggerRequest avoids unmarshalling the filter, the key and the update, they are parsed when
+// the trigger fires.
+func (x *CreateTriggerRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "db":
+			if err := jsoniter.Unmarshal(value, &x.Db); err != nil {
+				return err
+			}
+		case "name":
+			if err := jsoniter.Unmarshal(value, &x.Name); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "ops":
+			if err := jsoniter.Unmarshal(value, &x.Ops); err != nil {
+				return err
+			}
+		case "filter":
+			x.Filter = value
+		case "target":
+			if err := jsoniter.Unmarshal(value, &x.Target); err != nil {
+				return err
+			}
+		case "key":
+			x.Key = value
+		case "update":
+			x.Update = value
+		case "upsert":
+			if err := jsoniter.Unmarshal(value, &x.Upsert); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // UnmarshalJSON on CreateCollectionRequest avoids unmarshalling schema. The req handler deserializes the schema.
 func (x *CreateOrUpdateCollectionRequest) UnmarshalJSON(data []byte) error {
 	var mp map[string]jsoniter.RawMessage
@@ -291,6 +445,14 @@ func (x *CreateOrUpdateCollectionRequest) UnmarshalJSON(data []byte) error {
 			if err := jsoniter.Unmarshal(value, &x.OnlyCreate); err != nil {
 				return err
 			}
+		case "drop_fields":
+			if err := jsoniter.Unmarshal(value, &x.DropFields); err != nil {
+				return err
+			}
+		case "dry_run":
+			if err := jsoniter.Unmarshal(value, &x.DryRun); err != nil {
+				return err
+			}
 		case "schema":
 			x.Schema = value
 		case "options":
@@ -306,6 +468,29 @@ type collDesc struct {
 	Collection string              `json:"collection"`
 	Metadata   *CollectionMetadata `json:"metadata"`
 	Schema     json.RawMessage     `json:"schema"`
+	Synonyms   []*SynonymSet       `json:"synonyms,omitempty"`
+	StopWords  []string            `json:"stop_words,omitempty"`
+}
+
+type schemaRevision struct {
+	Revision  int32           `json:"revision"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	CreatedBy string          `json:"created_by,omitempty"`
+}
+
+// MarshalJSON on SchemaRevision avoids base64 encoding of the schema.
+func (x *SchemaRevision) MarshalJSON() ([]byte, error) {
+	resp := &schemaRevision{
+		Revision:  x.Revision,
+		Schema:    x.Schema,
+		CreatedBy: x.CreatedBy,
+	}
+	if x.CreatedAt != nil {
+		tm := x.CreatedAt.AsTime()
+		resp.CreatedAt = &tm
+	}
+	return json.Marshal(resp)
 }
 
 func (x *DescribeCollectionResponse) MarshalJSON() ([]byte, error) {
@@ -313,6 +498,8 @@ func (x *DescribeCollectionResponse) MarshalJSON() ([]byte, error) {
 		Collection: x.Collection,
 		Metadata:   x.Metadata,
 		Schema:     x.Schema,
+		Synonyms:   x.Synonyms,
+		StopWords:  x.StopWords,
 	})
 }
 
@@ -337,35 +524,104 @@ func (x *DescribeDatabaseResponse) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&resp)
 }
 
-func (x *EventsResponse) MarshalJSON() ([]byte, error) {
+// MarshalJSON on StreamEvent avoids base64 encoding of the documents and the primary key.
+func (x *StreamEvent) MarshalJSON() ([]byte, error) {
 	type event struct {
-		TxId       []byte          `json:"tx_id"`
-		Collection string          `json:"collection"`
-		Op         string          `json:"op"`
-		Key        []byte          `json:"key,omitempty"`
-		LKey       []byte          `json:"lkey,omitempty"`
-		RKey       []byte          `json:"rkey,omitempty"`
-		Data       json.RawMessage `json:"data,omitempty"`
-		Last       bool            `json:"last"`
-	}
+		TxId        []byte          `json:"tx_id"`
+		Collection  string          `json:"collection"`
+		Op          string          `json:"op"`
+		Key         []byte          `json:"key,omitempty"`
+		LKey        []byte          `json:"lkey,omitempty"`
+		RKey        []byte          `json:"rkey,omitempty"`
+		Data        json.RawMessage `json:"data,omitempty"`
+		Last        bool            `json:"last"`
+		ResumeToken []byte          `json:"resume_token,omitempty"`
+		PrimaryKey  json.RawMessage `json:"primary_key,omitempty"`
+		OldData     json.RawMessage `json:"old_data,omitempty"`
+		Schema      json.RawMessage `json:"schema,omitempty"`
+		Revision    int32           `json:"schema_revision,omitempty"`
+	}
+
+	return json.Marshal(&event{
+		TxId:        x.TxId,
+		Collection:  x.Collection,
+		Op:          x.Op,
+		Key:         x.Key,
+		LKey:        x.Lkey,
+		RKey:        x.Rkey,
+		Data:        x.Data,
+		Last:        x.Last,
+		ResumeToken: x.ResumeToken,
+		PrimaryKey:  x.PrimaryKey,
+		OldData:     x.OldData,
+		Schema:      x.Schema,
+		Revision:    x.SchemaRevision,
+	})
+}
 
+func (x *EventsResponse) MarshalJSON() ([]byte, error) {
 	resp := struct {
-		Event event `json:"event,omitempty"`
+		Event *StreamEvent `json:"event,omitempty"`
 	}{
-		Event: event{
-			TxId:       x.Event.TxId,
-			Collection: x.Event.Collection,
-			Op:         x.Event.Op,
-			Key:        x.Event.Key,
-			LKey:       x.Event.Lkey,
-			RKey:       x.Event.Rkey,
-			Data:       x.Event.Data,
-			Last:       x.Event.Last,
-		},
+		Event: x.Event,
 	}
 	return json.Marshal(resp)
 }
 
+// MarshalJSON on Webhook avoids base64 encoding of the filter.
+func (x *Webhook) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Name                 string          `json:"name"`
+		Collection           string          `json:"collection,omitempty"`
+		Url                  string          `json:"url"`
+		Ops                  []string        `json:"ops,omitempty"`
+		Filter               json.RawMessage `json:"filter,omitempty"`
+		DeadLetterCollection string          `json:"dead_letter_collection,omitempty"`
+		CreatedAt            *time.Time      `json:"created_at,omitempty"`
+	}{
+		Name:                 x.Name,
+		Collection:           x.Collection,
+		Url:                  x.Url,
+		Ops:                  x.Ops,
+		Filter:               x.Filter,
+		DeadLetterCollection: x.DeadLetterCollection,
+	}
+	if x.CreatedAt != nil {
+		tm := x.CreatedAt.AsTime()
+		resp.CreatedAt = &tm
+	}
+	return json.Marshal(&resp)
+}
+
+// MarshalJSON on Trigger avoids base64 encoding of the filter, the key and the update.
+func (x *Trigger) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Name       string          `json:"name"`
+		Collection string          `json:"collection"`
+		Ops        []string        `json:"ops,omitempty"`
+		Filter     json.RawMessage `json:"filter,omitempty"`
+		Target     string          `json:"target"`
+		Key        json.RawMessage `json:"key,omitempty"`
+		Update     json.RawMessage `json:"update,omitempty"`
+		Upsert     bool            `json:"upsert,omitempty"`
+		CreatedAt  *time.Time      `json:"created_at,omitempty"`
+	}{
+		Name:       x.Name,
+		Collection: x.Collection,
+		Ops:        x.Ops,
+		Filter:     x.Filter,
+		Target:     x.Target,
+		Key:        x.Key,
+		Update:     x.Update,
+		Upsert:     x.Upsert,
+	}
+	if x.CreatedAt != nil {
+		tm := x.CreatedAt.AsTime()
+		resp.CreatedAt = &tm
+	}
+	return json.Marshal(&resp)
+}
+
 // Proper marshal timestamp in metadata
 type dmlResponse struct {
 	Metadata      Metadata          `json:"metadata,omitempty"`
@@ -450,13 +706,15 @@ func (x *SearchHit) MarshalJSON() ([]byte, error) {
 
 func (x *SearchMetadata) MarshalJSON() ([]byte, error) {
 	resp := struct {
-		Found      int64 `json:"found"`
-		TotalPages int32 `json:"totalPages"`
-		Page       *Page `json:"page"`
+		Found      int64  `json:"found"`
+		TotalPages int32  `json:"totalPages"`
+		Page       *Page  `json:"page"`
+		NextCursor string `json:"next_cursor,omitempty"`
 	}{
 		Found:      x.Found,
 		TotalPages: x.TotalPages,
 		Page:       x.Page,
+		NextCursor: x.NextCursor,
 	}
 	return json.Marshal(resp)
 }
@@ -493,9 +751,11 @@ func (x *FacetStats) MarshalJSON() ([]byte, error) {
 }
 
 type SearchHitMetadata struct {
-	CreatedAt *time.Time `json:"created_at,omitempty"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt  *time.Time         `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time         `json:"updated_at,omitempty"`
+	DeletedAt  *time.Time         `json:"deleted_at,omitempty"`
+	Highlights []*SearchHighlight `json:"highlights,omitempty"`
+	Score      int64              `json:"score,omitempty"`
 }
 
 type Metadata struct {
@@ -538,6 +798,8 @@ func CreateMDFromSearchMD(x *SearchHitMeta) SearchHitMetadata {
 		tm := x.UpdatedAt.AsTime()
 		md.UpdatedAt = &tm
 	}
+	md.Highlights = x.Highlights
+	md.Score = x.Score
 
 	return md
 }
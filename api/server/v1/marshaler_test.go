@@ -33,7 +33,9 @@ func TestJSONEncoding(t *testing.T) {
 	t.Run("unmarshal SearchRequest", func(t *testing.T) {
 		inputDoc := []byte(`{"q":"my search text","search_fields":["first_name","last_name"],
 							"filter":{"last_name":"Steve"},"facet":{"facet stat":0},
-							"sort":[{"salary":"$asc"}],"fields":["employment","history"]}`)
+							"sort":[{"salary":"$asc"}],"fields":["employment","history"],
+							"vector":{"embedding":{"$vectorNear":{"vector":[0.1,0.2],"k":5}}},
+							"boost":{"first_name":3},"prefix":false,"typo_tolerance":{"num_typos":1}}`)
 
 		req := &SearchRequest{}
 		err := json.Unmarshal(inputDoc, req)
@@ -44,6 +46,11 @@ func TestJSONEncoding(t *testing.T) {
 		require.Equal(t, []byte(`{"facet stat":0}`), req.GetFacet())
 		require.Equal(t, []byte(`[{"salary":"$asc"}]`), req.GetSort())
 		require.Equal(t, []byte(`["employment","history"]`), req.GetFields())
+		require.Equal(t, []byte(`{"embedding":{"$vectorNear":{"vector":[0.1,0.2],"k":5}}}`), req.GetVector())
+		require.Equal(t, []byte(`{"first_name":3}`), req.GetBoost())
+		require.NotNil(t, req.Prefix)
+		require.False(t, req.GetPrefix())
+		require.Equal(t, int32(1), req.GetTypoTolerance().GetNumTypos())
 	})
 
 	t.Run("marshal SearchResponse", func(t *testing.T) {
@@ -75,4 +82,187 @@ func TestJSONEncoding(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, []byte(`{"hits":[{"metadata":{}}],"facets":{"myField":{"counts":[{"count":32,"value":"adidas"}],"stats":{"avg":40,"count":50}}},"meta":{"found":1234,"totalPages":0,"page":{"current":2,"size":10}}}`), r)
 	})
+
+	t.Run("search cursor", func(t *testing.T) {
+		req := &SearchRequest{}
+		require.NoError(t, json.Unmarshal([]byte(`{"db":"db1","collection":"c1","cursor":"","export":true}`), req))
+		require.NotNil(t, req.Cursor)
+		require.Equal(t, "", req.GetCursor())
+		require.True(t, req.GetExport())
+		require.NoError(t, req.Validate())
+
+		req.Page = 2
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "page can't be combined with a cursor or an export"), req.Validate())
+
+		multi := &MultiSearchRequest{Searches: []*SearchRequest{{Db: "db1", Collection: "c1", Export: true}}}
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "multi search doesn't support cursors and exports"), multi.Validate())
+
+		r, err := json.Marshal(&SearchMetadata{Found: 3, Page: &Page{Size: 2}, NextCursor: "abc"})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"found":3,"totalPages":0,"page":{"size":2},"next_cursor":"abc"}`), r)
+	})
+
+	t.Run("marshal SearchHit with highlights", func(t *testing.T) {
+		hit := &SearchHit{
+			Data: []byte(`{"name":"running shoes"}`),
+			Metadata: &SearchHitMeta{
+				Highlights: []*SearchHighlight{{
+					Field:         "name",
+					Snippet:       "<mark>running</mark> shoes",
+					MatchedTokens: []string{"running"},
+				}},
+			},
+		}
+		r, err := json.Marshal(hit)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"data":{"name":"running shoes"},"metadata":{"highlights":[{"field":"name","snippet":"\u003cmark\u003erunning\u003c/mark\u003e shoes","matched_tokens":["running"]}]}}`), r)
+	})
+
+	t.Run("unmarshal CreateOrUpdateCollectionRequest", func(t *testing.T) {
+		inputDoc := []byte(`{"db":"db1","collection":"c1","drop_fields":true,"schema":{"title":"c1"}}`)
+
+		req := &CreateOrUpdateCollectionRequest{}
+		require.NoError(t, json.Unmarshal(inputDoc, req))
+		require.True(t, req.GetDropFields())
+		require.Equal(t, []byte(`{"title":"c1"}`), req.GetSchema())
+	})
+
+	t.Run("marshal SchemaRevision", func(t *testing.T) {
+		rev := &SchemaRevision{
+			Revision:  2,
+			Schema:    []byte(`{"title":"c1"}`),
+			CreatedBy: "test@tigrisdata.com",
+		}
+		r, err := json.Marshal(rev)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"revision":2,"schema":{"title":"c1"},"created_by":"test@tigrisdata.com"}`), r)
+	})
+	t.Run("marshal EventsResponse", func(t *testing.T) {
+		resp := &EventsResponse{
+			Event: &StreamEvent{
+				TxId:        []byte{0x01},
+				Collection:  "c1",
+				Op:          "insert",
+				Data:        []byte(`{"a":1}`),
+				ResumeToken: []byte{0x00, 0x01},
+			},
+		}
+		r, err := json.Marshal(resp)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"event":{"tx_id":"AQ==","collection":"c1","op":"insert","data":{"a":1},"last":false,"resume_token":"AAE="}}`), r)
+
+		resp.Event = &StreamEvent{
+			TxId:           []byte{0x01},
+			Collection:     "c1",
+			Op:             "updateCollection",
+			Schema:         []byte(`{"title":"c1"}`),
+			SchemaRevision: 2,
+		}
+		r, err = json.Marshal(resp)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"event":{"tx_id":"AQ==","collection":"c1","op":"updateCollection","last":false,"schema":{"title":"c1"},"schema_revision":2}}`), r)
+	})
+
+	t.Run("validate EventsRequest", func(t *testing.T) {
+		req := &EventsRequest{Db: "db1", ResumeToken: []byte{0x01}}
+		require.NoError(t, req.Validate())
+
+		req.FromBeginning = true
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "only one of resume_token, from_beginning or start_time can be set"), req.Validate())
+	})
+	t.Run("unmarshal EventsRequest", func(t *testing.T) {
+		inputDoc := []byte(`{"db":"db1","collection":"orders","ops":["insert","update"],"filter":{"status":"shipped"},"start_time":"2022-06-01T10:00:00Z"}`)
+
+		req := &EventsRequest{}
+		require.NoError(t, json.Unmarshal(inputDoc, req))
+		require.Equal(t, []string{"insert", "update"}, req.GetOps())
+		require.Equal(t, []byte(`{"status":"shipped"}`), req.GetFilter())
+		require.Equal(t, int64(1654077600), req.GetStartTime().GetSeconds())
+		require.NoError(t, req.Validate())
+
+		req.Collection = ""
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "filter requires the collection to be set"), req.Validate())
+	})
+	t.Run("unmarshal CreateWebhookRequest", func(t *testing.T) {
+		inputDoc := []byte(`{"db":"db1","name":"orders_hook","collection":"orders","url":"https://example.com/hook","secret":"s1","ops":["insert"],"filter":{"status":"shipped"},"dead_letter_collection":"orders_dlq"}`)
+
+		req := &CreateWebhookRequest{}
+		require.NoError(t, json.Unmarshal(inputDoc, req))
+		require.Equal(t, "https://example.com/hook", req.GetUrl())
+		require.Equal(t, "s1", req.GetSecret())
+		require.Equal(t, []byte(`{"status":"shipped"}`), req.GetFilter())
+		require.Equal(t, "orders_dlq", req.GetDeadLetterCollection())
+		require.NoError(t, req.Validate())
+
+		req.Url = "ftp://example.com"
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "invalid webhook url '%s'", "ftp://example.com"), req.Validate())
+
+		req.Url = "https://example.com/hook"
+		req.DeadLetterCollection = "orders"
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "dead letter collection can't be the collection of the webhook"), req.Validate())
+	})
+	t.Run("marshal Webhook", func(t *testing.T) {
+		hook := &Webhook{
+			Name:       "orders_hook",
+			Collection: "orders",
+			Url:        "https://example.com/hook",
+			Filter:     []byte(`{"status":"shipped"}`),
+		}
+		r, err := json.Marshal(hook)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"name":"orders_hook","collection":"orders","url":"https://example.com/hook","filter":{"status":"shipped"}}`), r)
+	})
+	t.Run("unmarshal CreateTriggerRequest", func(t *testing.T) {
+		inputDoc := []byte(`{"db":"db1","name":"order_stats","collection":"orders","ops":["insert"],"filter":{"status":"paid"},"target":"order_stats","key":{"customer":"$customer"},"update":{"$increment":{"orders":1}},"upsert":true}`)
+
+		req := &CreateTriggerRequest{}
+		require.NoError(t, json.Unmarshal(inputDoc, req))
+		require.Equal(t, "order_stats", req.GetTarget())
+		require.Equal(t, []byte(`{"status":"paid"}`), req.GetFilter())
+		require.Equal(t, []byte(`{"customer":"$customer"}`), req.GetKey())
+		require.Equal(t, []byte(`{"$increment":{"orders":1}}`), req.GetUpdate())
+		require.True(t, req.GetUpsert())
+		require.NoError(t, req.Validate())
+
+		req.Key = nil
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "trigger requires the key of the target document"), req.Validate())
+	})
+	t.Run("marshal Trigger", func(t *testing.T) {
+		trigger := &Trigger{
+			Name:       "order_stats",
+			Collection: "orders",
+			Target:     "order_stats",
+			Key:        []byte(`{"customer":"$customer"}`),
+			Update:     []byte(`{"$increment":{"orders":1}}`),
+			Upsert:     true,
+		}
+		r, err := json.Marshal(trigger)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"name":"order_stats","collection":"orders","target":"order_stats","key":{"customer":"$customer"},"update":{"$increment":{"orders":1}},"upsert":true}`), r)
+	})
+	t.Run("marshal DescribeCollectionResponse with synonyms", func(t *testing.T) {
+		resp := &DescribeCollectionResponse{
+			Collection: "products",
+			Metadata:   &CollectionMetadata{},
+			Schema:     []byte(`{"title":"products"}`),
+			Synonyms:   []*SynonymSet{{Id: "tee", Synonyms: []string{"tee", "t-shirt"}}},
+			StopWords:  []string{"the"},
+		}
+		r, err := json.Marshal(resp)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"collection":"products","metadata":{},"schema":{"title":"products"},"synonyms":[{"id":"tee","synonyms":["tee","t-shirt"]}],"stop_words":["the"]}`), r)
+	})
+	t.Run("validate CreateOrUpdateSynonymSetRequest", func(t *testing.T) {
+		req := &CreateOrUpdateSynonymSetRequest{Db: "db1", Collection: "products", Id: "tee", Synonyms: []string{"tee", "t-shirt"}}
+		require.NoError(t, req.Validate())
+
+		req.Synonyms = []string{"t-shirt"}
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "synonym set requires at least two synonyms, or a root and a synonym"), req.Validate())
+
+		req.Root = "tee"
+		require.NoError(t, req.Validate())
+
+		stopWords := &SetStopWordsRequest{Db: "db1", Collection: "products", StopWords: []string{"the", "a b"}}
+		require.Equal(t, Errorf(Code_INVALID_ARGUMENT, "stop word must be a single word 'a b'"), stopWords.Validate())
+	})
 }
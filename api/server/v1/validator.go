@@ -14,6 +14,11 @@
 
 package api
 
+import (
+	"net/url"
+	"strings"
+)
+
 type Validator interface {
 	Validate() error
 }
@@ -23,6 +28,13 @@ func (x *BeginTransactionRequest) Validate() error {
 		return err
 	}
 
+	if x.GetOptions().GetReadVersion() < 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "read version can't be negative")
+	}
+	if x.GetOptions().GetReadVersion() > 0 && !x.GetOptions().GetReadOnly() {
+		return Errorf(Code_INVALID_ARGUMENT, "read version is only supported by read only transactions")
+	}
+
 	return nil
 }
 
@@ -111,6 +123,45 @@ func (x *SearchRequest) Validate() error {
 		return err
 	}
 
+	if err := isValidTypoTolerance(x.TypoTolerance); err != nil {
+		return err
+	}
+
+	if (x.Cursor != nil || x.Export) && x.Page != 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "page can't be combined with a cursor or an export")
+	}
+
+	return nil
+}
+
+// MaxMultiSearches is the maximum number of searches in a multi search.
+const MaxMultiSearches = 10
+
+func (x *MultiSearchRequest) Validate() error {
+	if len(x.Searches) == 0 || len(x.Searches) > MaxMultiSearches {
+		return Errorf(Code_INVALID_ARGUMENT, "multi search needs between 1 and %d searches", MaxMultiSearches)
+	}
+
+	for _, search := range x.Searches {
+		if search == nil {
+			return Errorf(Code_INVALID_ARGUMENT, "multi search can't have an empty search")
+		}
+		if search.Cursor != nil || search.Export {
+			return Errorf(Code_INVALID_ARGUMENT, "multi search doesn't support cursors and exports")
+		}
+		if err := search.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := isValidPaginationParam("page", int(x.Page)); err != nil {
+		return err
+	}
+
+	if err := isValidPaginationParam("page_size", int(x.PageSize)); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -142,6 +193,42 @@ func (x *DescribeCollectionRequest) Validate() error {
 	return nil
 }
 
+func (x *ListSchemaRevisionsRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (x *GetSchemaRevisionRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+
+	if x.Revision <= 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "revision is required")
+	}
+
+	if x.CompareTo < 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid compare_to revision %d", x.CompareTo)
+	}
+
+	return nil
+}
+
+func (x *RollbackSchemaRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+
+	if x.Revision <= 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "revision is required")
+	}
+
+	return nil
+}
+
 func (x *DescribeDatabaseRequest) Validate() error {
 	return nil
 }
@@ -171,9 +258,160 @@ func (x *EventsRequest) Validate() error {
 		return err
 	}
 
+	var positions int
+	if len(x.ResumeToken) > 0 {
+		positions++
+	}
+	if x.FromBeginning {
+		positions++
+	}
+	if x.StartTime != nil {
+		positions++
+	}
+	if positions > 1 {
+		return Errorf(Code_INVALID_ARGUMENT, "only one of resume_token, from_beginning or start_time can be set")
+	}
+	if len(x.Filter) > 0 {
+		if err := isValidCollection(x.Collection); err != nil {
+			return Errorf(Code_INVALID_ARGUMENT, "filter requires the collection to be set")
+		}
+	}
+
 	return nil
 }
 
+func (x *CreateWebhookRequest) Validate() error {
+	if err := isValidDatabase(x.Db); err != nil {
+		return err
+	}
+	if len(x.Name) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid webhook name")
+	}
+	if u, err := url.Parse(x.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid webhook url '%s'", x.Url)
+	}
+	if len(x.Filter) > 0 {
+		if err := isValidCollection(x.Collection); err != nil {
+			return Errorf(Code_INVALID_ARGUMENT, "filter requires the collection to be set")
+		}
+	}
+	if len(x.DeadLetterCollection) > 0 && x.DeadLetterCollection == x.Collection {
+		return Errorf(Code_INVALID_ARGUMENT, "dead letter collection can't be the collection of the webhook")
+	}
+
+	return nil
+}
+
+func (x *ListWebhooksRequest) Validate() error {
+	return isValidDatabase(x.Db)
+}
+
+func (x *DeleteWebhookRequest) Validate() error {
+	if err := isValidDatabase(x.Db); err != nil {
+		return err
+	}
+	if len(x.Name) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid webhook name")
+	}
+
+	return nil
+}
+
+func (x *CreateOrUpdateSynonymSetRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+	if len(x.Id) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid synonym set id")
+	}
+	// without a root all the synonyms are replaced by each other, so at least two are needed
+	if len(x.Synonyms) == 0 || (len(x.Root) == 0 && len(x.Synonyms) < 2) {
+		return Errorf(Code_INVALID_ARGUMENT, "synonym set requires at least two synonyms, or a root and a synonym")
+	}
+	for _, s := range x.Synonyms {
+		if len(strings.TrimSpace(s)) == 0 {
+			return Errorf(Code_INVALID_ARGUMENT, "synonym set can't have an empty synonym")
+		}
+	}
+
+	return nil
+}
+
+func (x *DeleteSynonymSetRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+	if len(x.Id) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid synonym set id")
+	}
+
+	return nil
+}
+
+func (x *SetStopWordsRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+	for _, w := range x.StopWords {
+		if len(w) == 0 || len(strings.Fields(w)) != 1 {
+			return Errorf(Code_INVALID_ARGUMENT, "stop word must be a single word '%s'", w)
+		}
+	}
+
+	return nil
+}
+
+func (x *CreateTriggerRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+		return err
+	}
+	if len(x.Name) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid trigger name")
+	}
+	if len(x.Target) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid target collection name")
+	}
+	if len(x.Key) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "trigger requires the key of the target document")
+	}
+	if len(x.Update) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "trigger requires the update of the target document")
+	}
+
+	return nil
+}
+
+func (x *ListTriggersRequest) Validate() error {
+	return isValidDatabase(x.Db)
+}
+
+func (x *DropTriggerRequest) Validate() error {
+	if err := isValidDatabase(x.Db); err != nil {
+		return err
+	}
+	if len(x.Name) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid trigger name")
+	}
+
+	return nil
+}
+
+func (x *ReindexCollectionRequest) Validate() error {
+	if len(x.Namespace) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid namespace name")
+	}
+
+	return isValidCollectionAndDatabase(x.Collection, x.Db)
+}
+
+func (x *VerifyCollectionRequest) Validate() error {
+	if len(x.Namespace) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid namespace name")
+	}
+
+	return isValidCollectionAndDatabase(x.Collection, x.Db)
+}
+
 func isValidCollection(name string) error {
 	if len(name) == 0 {
 		return Errorf(Code_INVALID_ARGUMENT, "invalid collection name")
@@ -202,6 +440,22 @@ func isValidCollectionAndDatabase(c string, db string) error {
 	return nil
 }
 
+// maxNumTypos is the maximum number of typos the search backend tolerates in a query token.
+const maxNumTypos = 2
+
+func isValidTypoTolerance(t *TypoTolerance) error {
+	if t == nil {
+		return nil
+	}
+	if t.GetNumTypos() < 0 || t.GetNumTypos() > maxNumTypos {
+		return Errorf(Code_INVALID_ARGUMENT, "`num_typos` should be between 0 and %d", maxNumTypos)
+	}
+	if t.MinLengthOneTypo < 0 || t.MinLengthTwoTypos < 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "invalid value for the minimum length of the tokens with typos")
+	}
+	return nil
+}
+
 func isValidPaginationParam(param string, value int) error {
 	if value < 0 {
 		return Errorf(Code_INVALID_ARGUMENT, "invalid value for `%s`", param)
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+// verify compares a collection with its search index through the admin API of a running server and prints the
+// report. It exits with a non-zero status when the search index isn't consistent and it wasn't asked to repair it.
+func main() {
+	server := flag.String("url", "http://localhost:8081", "url of the server")
+	namespace := flag.String("namespace", "default_namespace", "namespace of the collection")
+	db := flag.String("db", "", "database of the collection")
+	collection := flag.String("collection", "", "collection to verify")
+	repair := flag.Bool("repair", false, "index the missing and stale documents again and delete the orphaned documents")
+	timeout := flag.Duration("timeout", time.Hour, "how long to wait for the verification")
+	flag.Parse()
+
+	req := &api.VerifyCollectionRequest{
+		Namespace:  *namespace,
+		Db:         *db,
+		Collection: *collection,
+		Repair:     *repair,
+	}
+	if err := req.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("invalid arguments")
+	}
+
+	resp, err := verify(*server, req, *timeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("verification failed")
+	}
+
+	out, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		log.Fatal().Err(err).Msg("verification failed")
+	}
+	fmt.Println(string(out))
+
+	if !*repair && resp.Missing+resp.Stale+resp.Orphaned > 0 {
+		os.Exit(1)
+	}
+}
+
+func verify(server string, req *api.VerifyCollectionRequest, timeout time.Duration) (*api.VerifyCollectionResponse, error) {
+	body, err := json.Marshal(map[string]bool{"repair": req.Repair})
+	if err != nil {
+		return nil, err
+	}
+
+	u := fmt.Sprintf("%s/admin/v1/namespaces/%s/databases/%s/collections/%s/verify", server,
+		url.PathEscape(req.Namespace), url.PathEscape(req.Db), url.PathEscape(req.Collection))
+
+	client := &http.Client{Timeout: timeout}
+	httpResp, err := client.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s: %s", httpResp.Status, string(respBody))
+	}
+
+	var resp api.VerifyCollectionResponse
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -217,6 +217,19 @@ func (factory *Factory) ParseSelector(k []byte, v []byte, dataType jsonparser.Va
 		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "querying on non schema field '%s'", string(k))
 	}
 
+	if field.DataType == schema.GeoPointType {
+		if dataType != jsonparser.Object {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "geopoint field '%s' only supports '%s' and '%s'", field.Name(), NEAR, GEOWITHIN)
+		}
+
+		geoMatcher, err := buildGeoOperator(v, field)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewGeoSelector(field, geoMatcher), nil
+	}
+
 	switch dataType {
 	case jsonparser.Boolean, jsonparser.Number, jsonparser.String:
 		val, err := value.NewValue(field.DataType, v)
@@ -0,0 +1,305 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+const (
+	NEAR      = "$near"
+	GEOWITHIN = "$geoWithin"
+)
+
+const (
+	GeoUnitKm = "km"
+	GeoUnitMi = "mi"
+
+	earthRadiusKm  = 6371.0088
+	kmPerMile      = 1.609344
+	minPolygonSize = 3
+)
+
+// GeoSelector is a condition on a geopoint field. Similar to Selector, it pairs the field with a GeoMatcher which is
+// formed from the user condition. It can have the following form inside the JSON
+//    {f: {"$near": {"lat": 48.85, "lon": 2.29, "radius": 5, "unit": "km"}}}
+//    {f: {"$geoWithin": {"polygon": [{"lat": 48.8, "lon": 2.2}, {"lat": 48.9, "lon": 2.2}, {"lat": 48.9, "lon": 2.4}]}}}
+//    {f: {"$geoWithin": {"box": {"bottom_left": {"lat": 48.8, "lon": 2.2}, "top_right": {"lat": 48.9, "lon": 2.4}}}}}
+type GeoSelector struct {
+	Field   *schema.QueryableField
+	Matcher GeoMatcher
+}
+
+// NewGeoSelector returns GeoSelector object
+func NewGeoSelector(field *schema.QueryableField, matcher GeoMatcher) *GeoSelector {
+	return &GeoSelector{
+		Field:   field,
+		Matcher: matcher,
+	}
+}
+
+// MatchesDoc returns true if the geopoint field of the selector in the document passes the condition, a document
+// without the field doesn't pass it.
+func (s *GeoSelector) MatchesDoc(doc map[string]interface{}) bool {
+	v, ok := docValue(doc, s.Field.Name())
+	if !ok {
+		return false
+	}
+
+	point, err := schema.NewGeoPoint(v)
+	if err != nil {
+		return false
+	}
+
+	return s.Matcher.Matches(point)
+}
+
+// Matches returns true if the input doc matches this filter.
+func (s *GeoSelector) Matches(doc []byte) bool {
+	//ToDo: not implemented
+	return false
+}
+
+func (s *GeoSelector) ToSearchFilter() []string {
+	return []string{fmt.Sprintf("%s:(%s)", s.Field.Name(), s.Matcher.ToSearchFilter())}
+}
+
+// String a helpful method for logging.
+func (s *GeoSelector) String() string {
+	return fmt.Sprintf("{%v:%v}", s.Field.Name(), s.Matcher)
+}
+
+// GeoMatcher is an interface similar to ValueMatcher but for geopoint values.
+type GeoMatcher interface {
+	// Matches returns true if the input point satisfies the condition
+	Matches(point schema.GeoPoint) bool
+
+	// Type return the type of the geo matcher, syntactic sugar for logging, etc
+	Type() string
+
+	// ToSearchFilter returns the condition in the format understood by the search backend
+	ToSearchFilter() string
+}
+
+// NearMatcher implements "$near" operand. It matches points that are within the radius of the center.
+type NearMatcher struct {
+	Center schema.GeoPoint
+	Radius float64
+	Unit   string
+}
+
+func NewNearMatcher(input jsoniter.RawMessage) (*NearMatcher, error) {
+	type near struct {
+		Lat    *float64 `json:"lat"`
+		Lon    *float64 `json:"lon"`
+		Radius float64  `json:"radius"`
+		Unit   string   `json:"unit"`
+	}
+
+	var n near
+	if err := jsoniter.Unmarshal(input, &n); err != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid '%s' condition %s", NEAR, err.Error())
+	}
+	if n.Lat == nil || n.Lon == nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "'%s' needs both 'lat' and 'lon'", NEAR)
+	}
+	if n.Radius <= 0 {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "'%s' needs a positive 'radius'", NEAR)
+	}
+
+	switch n.Unit {
+	case "":
+		n.Unit = GeoUnitKm
+	case GeoUnitKm, GeoUnitMi:
+	default:
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported unit '%s', supported units are '%s' and '%s'", n.Unit, GeoUnitKm, GeoUnitMi)
+	}
+
+	center := schema.GeoPoint{Lat: *n.Lat, Lon: *n.Lon}
+	if err := center.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &NearMatcher{
+		Center: center,
+		Radius: n.Radius,
+		Unit:   n.Unit,
+	}, nil
+}
+
+func (n *NearMatcher) Matches(point schema.GeoPoint) bool {
+	radiusKm := n.Radius
+	if n.Unit == GeoUnitMi {
+		radiusKm *= kmPerMile
+	}
+
+	return HaversineKm(n.Center, point) <= radiusKm
+}
+
+func (n *NearMatcher) Type() string {
+	return NEAR
+}
+
+func (n *NearMatcher) ToSearchFilter() string {
+	return fmt.Sprintf("%s, %s, %s %s", formatCoordinate(n.Center.Lat), formatCoordinate(n.Center.Lon), formatCoordinate(n.Radius), n.Unit)
+}
+
+func (n *NearMatcher) String() string {
+	return fmt.Sprintf("{$near:%v,%v%s}", n.Center, n.Radius, n.Unit)
+}
+
+// WithinMatcher implements "$geoWithin" operand. It matches points that are inside the polygon, a box is converted to
+// a polygon with four vertices.
+type WithinMatcher struct {
+	Polygon []schema.GeoPoint
+}
+
+func NewWithinMatcher(input jsoniter.RawMessage) (*WithinMatcher, error) {
+	type box struct {
+		BottomLeft *schema.GeoPoint `json:"bottom_left"`
+		TopRight   *schema.GeoPoint `json:"top_right"`
+	}
+	type within struct {
+		Polygon []schema.GeoPoint `json:"polygon"`
+		Box     *box              `json:"box"`
+	}
+
+	var w within
+	if err := jsoniter.Unmarshal(input, &w); err != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid '%s' condition %s", GEOWITHIN, err.Error())
+	}
+
+	var polygon []schema.GeoPoint
+	switch {
+	case len(w.Polygon) > 0 && w.Box != nil:
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "'%s' accepts either 'polygon' or 'box'", GEOWITHIN)
+	case w.Box != nil:
+		if w.Box.BottomLeft == nil || w.Box.TopRight == nil {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "box needs both 'bottom_left' and 'top_right'")
+		}
+		bl, tr := *w.Box.BottomLeft, *w.Box.TopRight
+		polygon = []schema.GeoPoint{
+			bl,
+			{Lat: tr.Lat, Lon: bl.Lon},
+			tr,
+			{Lat: bl.Lat, Lon: tr.Lon},
+		}
+	case len(w.Polygon) >= minPolygonSize:
+		polygon = w.Polygon
+	default:
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "polygon needs minimum %d points", minPolygonSize)
+	}
+
+	for _, p := range polygon {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &WithinMatcher{
+		Polygon: polygon,
+	}, nil
+}
+
+// Matches uses ray casting to check whether the point is inside the polygon. The edges are treated as straight lines
+// in the lat/lon plane which is good enough for the areas this is meant for.
+func (w *WithinMatcher) Matches(point schema.GeoPoint) bool {
+	inside := false
+	for i, j := 0, len(w.Polygon)-1; i < len(w.Polygon); j, i = i, i+1 {
+		pi, pj := w.Polygon[i], w.Polygon[j]
+		if (pi.Lat > point.Lat) != (pj.Lat > point.Lat) &&
+			point.Lon < (pj.Lon-pi.Lon)*(point.Lat-pi.Lat)/(pj.Lat-pi.Lat)+pi.Lon {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+func (w *WithinMatcher) Type() string {
+	return GEOWITHIN
+}
+
+func (w *WithinMatcher) ToSearchFilter() string {
+	var coordinates []string
+	for _, p := range w.Polygon {
+		coordinates = append(coordinates, formatCoordinate(p.Lat), formatCoordinate(p.Lon))
+	}
+
+	return strings.Join(coordinates, ", ")
+}
+
+func (w *WithinMatcher) String() string {
+	return fmt.Sprintf("{$geoWithin:%v}", w.Polygon)
+}
+
+// HaversineKm returns the great-circle distance between two points in kilometers.
+func HaversineKm(p1 schema.GeoPoint, p2 schema.GeoPoint) float64 {
+	toRadians := func(d float64) float64 { return d * math.Pi / 180 }
+
+	dLat := toRadians(p2.Lat - p1.Lat)
+	dLon := toRadians(p2.Lon - p1.Lon)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(p1.Lat))*math.Cos(toRadians(p2.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func buildGeoOperator(input jsoniter.RawMessage, field *schema.QueryableField) (GeoMatcher, error) {
+	if len(input) == 0 {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "empty object")
+	}
+
+	var geoMatcher GeoMatcher
+	var err error
+	err = jsonparser.ObjectEach(input, func(key []byte, v []byte, dataType jsonparser.ValueType, offset int) error {
+		if err != nil {
+			return err
+		}
+		if geoMatcher != nil {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "only one geo condition is allowed on field '%s'", field.Name())
+		}
+		if dataType != jsonparser.Object {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "'%s' expects an object", string(key))
+		}
+
+		switch string(key) {
+		case NEAR:
+			geoMatcher, err = NewNearMatcher(v)
+		case GEOWITHIN:
+			geoMatcher, err = NewWithinMatcher(v)
+		default:
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "only '%s' and '%s' are supported on geopoint field '%s'", NEAR, GEOWITHIN, field.Name())
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return geoMatcher, nil
+}
+
+func formatCoordinate(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestGeoFilter(t *testing.T) {
+	var factory = Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "f1", DataType: schema.Int64Type},
+			{FieldName: "location", DataType: schema.GeoPointType},
+		},
+	}
+
+	t.Run("near", func(t *testing.T) {
+		js := []byte(`{"location": {"$near": {"lat": 48.8584, "lon": 2.2945, "radius": 5}}}`)
+		filters, err := factory.Factorize(js)
+		require.NoError(t, err)
+		require.Len(t, filters, 1)
+		require.Equal(t, []string{"location:(48.8584, 2.2945, 5 km)"}, filters[0].ToSearchFilter())
+
+		// Notre-Dame is ~4.5km away from the Eiffel Tower, Versailles is ~17km away.
+		require.True(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 48.853, "lon": 2.3499}}))
+		require.False(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 48.8049, "lon": 2.1204}}))
+	})
+	t.Run("near_miles", func(t *testing.T) {
+		js := []byte(`{"location": {"$near": {"lat": 48.8584, "lon": 2.2945, "radius": 11, "unit": "mi"}}}`)
+		filters, err := factory.Factorize(js)
+		require.NoError(t, err)
+		require.Equal(t, []string{"location:(48.8584, 2.2945, 11 mi)"}, filters[0].ToSearchFilter())
+		require.True(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 48.8049, "lon": 2.1204}}))
+	})
+	t.Run("within_box", func(t *testing.T) {
+		js := []byte(`{"f1": 10, "location": {"$geoWithin": {"box": {"bottom_left": {"lat": 48.8, "lon": 2.2}, "top_right": {"lat": 48.9, "lon": 2.4}}}}}`)
+		wrapped, err := factory.WrappedFilter(js)
+		require.NoError(t, err)
+		require.Equal(t, []string{"f1:=10&&location:(48.8, 2.2, 48.9, 2.2, 48.9, 2.4, 48.8, 2.4)"}, wrapped.Filter.ToSearchFilter())
+
+		filters, err := factory.Factorize([]byte(`{"location": {"$geoWithin": {"box": {"bottom_left": {"lat": 48.8, "lon": 2.2}, "top_right": {"lat": 48.9, "lon": 2.4}}}}}`))
+		require.NoError(t, err)
+		require.True(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 48.85, "lon": 2.3}}))
+		require.False(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 48.95, "lon": 2.3}}))
+	})
+	t.Run("within_polygon", func(t *testing.T) {
+		js := []byte(`{"location": {"$geoWithin": {"polygon": [{"lat": 0, "lon": 0}, {"lat": 10, "lon": 0}, {"lat": 0, "lon": 10}]}}}`)
+		filters, err := factory.Factorize(js)
+		require.NoError(t, err)
+		require.Equal(t, []string{"location:(0, 0, 10, 0, 0, 10)"}, filters[0].ToSearchFilter())
+		require.True(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 2.0, "lon": 2.0}}))
+		require.False(t, filters[0].MatchesDoc(map[string]interface{}{"location": map[string]interface{}{"lat": 6.0, "lon": 6.0}}))
+	})
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			js     []byte
+			expErr error
+		}{
+			{
+				[]byte(`{"location": 10}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "geopoint field 'location' only supports '$near' and '$geoWithin'"),
+			},
+			{
+				[]byte(`{"location": {"$eq": {"lat": 10, "lon": 10}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "only '$near' and '$geoWithin' are supported on geopoint field 'location'"),
+			},
+			{
+				[]byte(`{"location": {"$near": {"lat": 10, "lon": 10}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "'$near' needs a positive 'radius'"),
+			},
+			{
+				[]byte(`{"location": {"$near": {"lat": 100, "lon": 10, "radius": 1}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "latitude should be between -90 and 90, found '100'"),
+			},
+			{
+				[]byte(`{"location": {"$near": {"lat": 10, "lon": 10, "radius": 1, "unit": "m"}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported unit 'm', supported units are 'km' and 'mi'"),
+			},
+			{
+				[]byte(`{"location": {"$geoWithin": {"polygon": [{"lat": 0, "lon": 0}, {"lat": 10, "lon": 0}]}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "polygon needs minimum 3 points"),
+			},
+			{
+				[]byte(`{"f1": {"$near": {"lat": 10, "lon": 10, "radius": 1}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "expression is not supported inside comparison operator $near"),
+			},
+		}
+		for _, c := range cases {
+			_, err := factory.Factorize(c.js)
+			require.Equal(t, c.expErr, err)
+		}
+	})
+}
@@ -15,7 +15,10 @@
 package filter
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/value"
@@ -43,25 +46,127 @@ func NewSelector(field *schema.QueryableField, matcher ValueMatcher) *Selector {
 	}
 }
 
+// MatchesDoc returns true if the field of the selector in the document passes the condition. A document without the
+// field or with a null value doesn't pass it, and an array passes it if one of its elements does.
 func (s *Selector) MatchesDoc(doc map[string]interface{}) bool {
-	v, ok := doc[s.Field.Name()]
+	v, ok := docValue(doc, s.Field.Name())
 	if !ok {
-		return true
+		return false
 	}
 
-	var val value.Value
-	switch s.Field.DataType {
-	case schema.StringType:
-		val = value.NewStringValue(v.(string))
-	case schema.DoubleType:
-		val = value.NewDoubleUsingFloat(v.(float64))
-	default:
-		return true
+	if arr, ok := v.([]interface{}); ok {
+		for _, item := range arr {
+			if s.matchesValue(item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return s.matchesValue(v)
+}
+
+func (s *Selector) matchesValue(v interface{}) bool {
+	dataType := s.Field.DataType
+	if dataType == schema.ArrayType {
+		dataType = s.Field.ItemType
+	}
+
+	if dataType == schema.DateTimeType {
+		return s.matchesDateTime(v)
 	}
 
+	val := docMatchValue(dataType, v)
+	if val == nil {
+		return false
+	}
 	return s.Matcher.Matches(val)
 }
 
+// matchesDateTime compares the datetime values as instants, so that the values in different time zones compare
+// correctly.
+func (s *Selector) matchesDateTime(v interface{}) bool {
+	str, ok := v.(string)
+	if !ok {
+		return false
+	}
+	actual, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return false
+	}
+	expected, err := time.Parse(time.RFC3339Nano, s.Matcher.GetValue().String())
+	if err != nil {
+		return false
+	}
+
+	matcher, err := NewMatcher(s.Matcher.Type(), value.NewIntValue(expected.UnixNano()))
+	if err != nil {
+		return false
+	}
+	return matcher.Matches(value.NewIntValue(actual.UnixNano()))
+}
+
+// docMatchValue converts the value of a field of a parsed document to the value the conditions on the field are
+// compared with, nil if the value doesn't have the type of the field.
+func docMatchValue(dataType schema.FieldType, v interface{}) value.Value {
+	switch t := v.(type) {
+	case string:
+		switch dataType {
+		case schema.StringType, schema.UUIDType:
+			return value.NewStringValue(t)
+		case schema.ByteType:
+			val, err := value.NewValue(dataType, []byte(t))
+			if err != nil {
+				return nil
+			}
+			return val
+		}
+	case float64:
+		switch dataType {
+		case schema.DoubleType:
+			return value.NewDoubleUsingFloat(t)
+		case schema.Int32Type, schema.Int64Type:
+			return value.NewIntValue(int64(t))
+		}
+	case json.Number:
+		switch dataType {
+		case schema.DoubleType, schema.Int32Type, schema.Int64Type:
+			val, err := value.NewValue(dataType, []byte(t))
+			if err != nil {
+				return nil
+			}
+			return val
+		}
+	case bool:
+		if dataType == schema.BoolType {
+			return value.NewBoolValue(t)
+		}
+	}
+
+	return nil
+}
+
+// docValue returns the value of the field in the parsed document. The name of a nested field is looked up as is first,
+// for the flattened documents, and then through the nested objects.
+func docValue(doc map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := doc[name]; ok {
+		return v, v != nil
+	}
+
+	var v interface{} = doc
+	for _, key := range strings.Split(name, schema.ObjFlattenDelimiter) {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return v, v != nil
+}
+
 // Matches returns true if the input doc matches this filter.
 func (s *Selector) Matches(doc []byte) bool {
 	//ToDo: not implemented
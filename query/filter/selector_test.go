@@ -0,0 +1,87 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestSelectorMatchesDoc(t *testing.T) {
+	factory := NewFactory([]*schema.QueryableField{
+		{FieldName: "name", DataType: schema.StringType},
+		{FieldName: "price", DataType: schema.DoubleType},
+		{FieldName: "qty", DataType: schema.Int64Type},
+		{FieldName: "active", DataType: schema.BoolType},
+		{FieldName: "id", DataType: schema.UUIDType},
+		{FieldName: "raw", DataType: schema.ByteType},
+		{FieldName: "created", DataType: schema.DateTimeType},
+		{FieldName: "a.b", DataType: schema.Int64Type},
+		{FieldName: "location", DataType: schema.GeoPointType},
+	})
+
+	matches := func(t *testing.T, reqFilter string, doc string) bool {
+		wrappedF, err := factory.WrappedFilter([]byte(reqFilter))
+		require.NoError(t, err)
+
+		var decoded map[string]interface{}
+		require.NoError(t, jsoniter.Unmarshal([]byte(doc), &decoded))
+		return wrappedF.Filter.MatchesDoc(decoded)
+	}
+
+	t.Run("types", func(t *testing.T) {
+		require.True(t, matches(t, `{"name": "shoe"}`, `{"name": "shoe"}`))
+		require.False(t, matches(t, `{"name": "shoe"}`, `{"name": "hat"}`))
+		require.True(t, matches(t, `{"price": {"$gt": 9.5}}`, `{"price": 10.25}`))
+		require.False(t, matches(t, `{"qty": {"$lte": 3}}`, `{"qty": 4}`))
+		require.True(t, matches(t, `{"active": true}`, `{"active": true}`))
+		require.True(t, matches(t, `{"id": "1e0d8b5a-2d4c-4b5e-9f4a-3c2f1e0d8b5a"}`, `{"id": "1e0d8b5a-2d4c-4b5e-9f4a-3c2f1e0d8b5a"}`))
+		require.False(t, matches(t, `{"id": "1e0d8b5a-2d4c-4b5e-9f4a-3c2f1e0d8b5a"}`, `{"id": "00000000-2d4c-4b5e-9f4a-3c2f1e0d8b5a"}`))
+		require.True(t, matches(t, `{"raw": "aGVsbG8="}`, `{"raw": "aGVsbG8="}`))
+		require.False(t, matches(t, `{"raw": "aGVsbG8="}`, `{"raw": "d29ybGQ="}`))
+	})
+	t.Run("nested", func(t *testing.T) {
+		require.True(t, matches(t, `{"a.b": 5}`, `{"a": {"b": 5}}`))
+		require.False(t, matches(t, `{"a.b": 5}`, `{"a": {"b": 7}}`))
+		require.True(t, matches(t, `{"a.b": 5}`, `{"a.b": 5}`))
+		require.False(t, matches(t, `{"a.b": 5}`, `{"a": 5}`))
+	})
+	t.Run("missing_and_null", func(t *testing.T) {
+		require.False(t, matches(t, `{"name": "shoe"}`, `{"price": 10}`))
+		require.False(t, matches(t, `{"name": "shoe"}`, `{"name": null}`))
+		require.False(t, matches(t, `{"a.b": 5}`, `{"a": null}`))
+		require.False(t, matches(t, `{"created": {"$gt": "2022-10-03T12:00:00Z"}}`, `{"created": null}`))
+		require.False(t, matches(t, `{"location": {"$near": {"lat": 48.85, "lon": 2.29, "radius": 5}}}`, `{"name": "shoe"}`))
+		require.True(t, matches(t, `{"$or": [{"name": "shoe"}, {"qty": 1}]}`, `{"name": null, "qty": 1}`))
+	})
+	t.Run("datetime", func(t *testing.T) {
+		require.True(t, matches(t, `{"created": {"$gt": "2022-10-03T12:00:00Z"}}`, `{"created": "2022-10-03T12:00:01Z"}`))
+		require.False(t, matches(t, `{"created": {"$gt": "2022-10-03T12:00:00Z"}}`, `{"created": "2022-10-03T11:59:59Z"}`))
+		// the same instant in another time zone
+		require.True(t, matches(t, `{"created": "2022-10-03T12:00:00Z"}`, `{"created": "2022-10-03T14:00:00+02:00"}`))
+		require.True(t, matches(t, `{"created": {"$lt": "2022-10-03T12:00:00Z"}}`, `{"created": "2022-10-03T13:00:00+02:00"}`))
+		require.False(t, matches(t, `{"created": {"$gte": "2022-10-03T12:00:00Z"}}`, `{"created": "not a date"}`))
+	})
+	t.Run("arrays", func(t *testing.T) {
+		tags := &schema.QueryableField{FieldName: "tags", DataType: schema.ArrayType, ItemType: schema.StringType}
+		selector := NewSelector(tags, NewEqualityMatcher(value.NewStringValue("red")))
+		require.True(t, selector.MatchesDoc(map[string]interface{}{"tags": []interface{}{"blue", "red"}}))
+		require.False(t, selector.MatchesDoc(map[string]interface{}{"tags": []interface{}{"blue", nil}}))
+	})
+}
@@ -1,28 +1,59 @@
 package search
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 )
 
 const (
 	defaultFacetSize = 10
+	// MaxFacetIntervals is the most intervals an interval facet counts the hits of when they are counted by a search
+	// per interval in the search backend, only the latest intervals are counted then.
+	MaxFacetIntervals = 1000
+)
+
+const (
+	FacetIntervalDay   = "day"
+	FacetIntervalWeek  = "week"
+	FacetIntervalMonth = "month"
 )
 
 type Facets struct {
 	Fields []FacetField
 }
 
+// FacetField is a field to count the values of in the hits. A facet can have the following form inside the JSON
+//    {"brand": {"size": 10}}
+//    {"price": {"ranges": [{"to": 50}, {"from": 50, "to": 100, "label": "medium"}, {"from": 100}]}}
+//    {"created": {"interval": "week"}}
+// The first form counts the most frequent values. The second form counts the hits in the ranges of a numeric field,
+// "from" is inclusive and "to" is exclusive. The third form counts the hits of a datetime field per day, week or
+// month, the weeks start on Monday and the buckets are in UTC.
 type FacetField struct {
-	Name string
-	Type string
-	Size int
+	Name     string
+	Type     string
+	Size     int
+	Ranges   []FacetRange
+	Interval string
+}
+
+type FacetRange struct {
+	Label string
+	From  *float64
+	To    *float64
 }
 
 func NewFacetField(name string, value jsoniter.RawMessage) (FacetField, error) {
 	type facetValue struct {
-		Type string
-		Size int
+		Type     string
+		Size     int
+		Ranges   []FacetRange
+		Interval string
 	}
 
 	var v facetValue
@@ -30,13 +61,154 @@ func NewFacetField(name string, value jsoniter.RawMessage) (FacetField, error) {
 		return FacetField{}, err
 	}
 
+	if len(v.Ranges) > 0 && len(v.Interval) > 0 {
+		return FacetField{}, api.Errorf(api.Code_INVALID_ARGUMENT, "facet '%s' can have either ranges or an interval", name)
+	}
+	for i := range v.Ranges {
+		r := &v.Ranges[i]
+		if r.From == nil && r.To == nil {
+			return FacetField{}, api.Errorf(api.Code_INVALID_ARGUMENT, "range of facet '%s' needs 'from' or 'to'", name)
+		}
+		if r.From != nil && r.To != nil && *r.From >= *r.To {
+			return FacetField{}, api.Errorf(api.Code_INVALID_ARGUMENT, "range of facet '%s' needs 'from' to be less than 'to'", name)
+		}
+		if len(r.Label) == 0 {
+			r.Label = r.defaultLabel()
+		}
+	}
+	switch v.Interval {
+	case "", FacetIntervalDay, FacetIntervalWeek, FacetIntervalMonth:
+	default:
+		return FacetField{}, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported interval '%s' for facet '%s', supported intervals are '%s', '%s' and '%s'",
+			v.Interval, name, FacetIntervalDay, FacetIntervalWeek, FacetIntervalMonth)
+	}
+
 	return FacetField{
-		Name: name,
-		Type: v.Type,
-		Size: v.Size,
+		Name:     name,
+		Type:     v.Type,
+		Size:     v.Size,
+		Ranges:   v.Ranges,
+		Interval: v.Interval,
 	}, nil
 }
 
+// IsBucketed is true when the hits are counted in ranges or intervals, all the buckets are returned then.
+func (f FacetField) IsBucketed() bool {
+	return len(f.Ranges) > 0 || len(f.Interval) > 0
+}
+
+// IntervalBucket returns the start of the interval the datetime value falls in, formatted as RFC 3339.
+func (f FacetField) IntervalBucket(value string) (string, bool) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return "", false
+	}
+
+	start, ok := f.IntervalStart(t)
+	if !ok {
+		return "", false
+	}
+	return start.Format(time.RFC3339), true
+}
+
+// IntervalStart returns the start of the interval the time falls in, in UTC.
+func (f FacetField) IntervalStart(t time.Time) (time.Time, bool) {
+	t = t.UTC()
+	switch f.Interval {
+	case FacetIntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+	case FacetIntervalWeek:
+		// Monday is the first day of the week
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC), true
+	case FacetIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// Intervals returns the starts of the intervals from the one "first" falls in to the one "last" falls in. The range is
+// clamped to the latest MaxFacetIntervals intervals when it spans more of them.
+func (f FacetField) Intervals(first time.Time, last time.Time) ([]time.Time, error) {
+	from, ok := f.IntervalStart(first)
+	if !ok {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "facet '%s' has no interval", f.Name)
+	}
+
+	var starts []time.Time
+	start, _ := f.IntervalStart(last)
+	for ; !start.Before(from) && len(starts) < MaxFacetIntervals; start = f.previousInterval(start) {
+		starts = append(starts, start)
+	}
+	for i, j := 0, len(starts)-1; i < j; i, j = i+1, j-1 {
+		starts[i], starts[j] = starts[j], starts[i]
+	}
+	return starts, nil
+}
+
+// IntervalFilter returns the filter of the hits whose unix time in milliseconds, stored in field, is in the interval
+// that starts at "start".
+func (f FacetField) IntervalFilter(field string, start time.Time) string {
+	return fmt.Sprintf("%s:>=%d&&%s:<%d", field, start.UnixMilli(), field, f.nextInterval(start).UnixMilli())
+}
+
+func (f FacetField) nextInterval(start time.Time) time.Time {
+	switch f.Interval {
+	case FacetIntervalDay:
+		return start.AddDate(0, 0, 1)
+	case FacetIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+func (f FacetField) previousInterval(start time.Time) time.Time {
+	switch f.Interval {
+	case FacetIntervalDay:
+		return start.AddDate(0, 0, -1)
+	case FacetIntervalWeek:
+		return start.AddDate(0, 0, -7)
+	default:
+		return start.AddDate(0, -1, 0)
+	}
+}
+
+// Contains is true when the value is in the range, "from" is inclusive and "to" is exclusive.
+func (r FacetRange) Contains(v float64) bool {
+	return (r.From == nil || v >= *r.From) && (r.To == nil || v < *r.To)
+}
+
+func (r FacetRange) ToSearchFilter(field string) string {
+	var filter string
+	if r.From != nil {
+		filter = fmt.Sprintf("%s:>=%s", field, formatFloat(*r.From))
+	}
+	if r.To != nil {
+		if len(filter) > 0 {
+			filter += "&&"
+		}
+		filter += fmt.Sprintf("%s:<%s", field, formatFloat(*r.To))
+	}
+	return filter
+}
+
+func (r FacetRange) defaultLabel() string {
+	from, to := "*", "*"
+	if r.From != nil {
+		from = formatFloat(*r.From)
+	}
+	if r.To != nil {
+		to = formatFloat(*r.To)
+	}
+	return from + "-" + to
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 func UnmarshalFacet(input jsoniter.RawMessage) (Facets, error) {
 	var facets = Facets{}
 	var err error
@@ -0,0 +1,110 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+const (
+	// DefaultFieldWeight is the weight of the search fields that are not boosted.
+	DefaultFieldWeight = 1
+)
+
+// Boost is the weight of the search fields, a match in a field with a higher weight ranks higher. The JSON
+// representation looks like below,
+//    {"title": 3, "description": 1}
+// The weights are positive integers and the fields that are not listed have the default weight.
+type Boost map[string]int
+
+func UnmarshalBoost(input jsoniter.RawMessage) (Boost, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+
+	boost := Boost{}
+	var err error
+	parseErr := jsonparser.ObjectEach(input, func(k []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		weight, convErr := jsonparser.ParseInt(v)
+		if dataType != jsonparser.Number || convErr != nil || weight <= 0 {
+			err = api.Errorf(api.Code_INVALID_ARGUMENT, "boost of field '%s' should be a positive integer", string(k))
+			return err
+		}
+
+		boost[string(k)] = int(weight)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid boost, expected an object of field weights")
+	}
+
+	return boost, nil
+}
+
+// TypoTolerance controls how many typos a query token can have and still match. A token needs to have at least
+// MinLengthOneTypo characters to match with one typo and MinLengthTwoTypos characters to match with two typos. The
+// search backend defaults are used for the options that are not set.
+type TypoTolerance struct {
+	NumTypos          *int
+	MinLengthOneTypo  int
+	MinLengthTwoTypos int
+}
+
+func NewTypoTolerance(t *api.TypoTolerance) *TypoTolerance {
+	if t == nil {
+		return nil
+	}
+
+	typos := &TypoTolerance{
+		MinLengthOneTypo:  int(t.GetMinLengthOneTypo()),
+		MinLengthTwoTypos: int(t.GetMinLengthTwoTypos()),
+	}
+	if t.NumTypos != nil {
+		numTypos := int(*t.NumTypos)
+		typos.NumTypos = &numTypos
+	}
+	return typos
+}
+
+func (q *Query) ToSearchFieldWeights() string {
+	if len(q.Boost) == 0 {
+		return ""
+	}
+
+	weights := make([]string, len(q.Fields))
+	for i, f := range q.Fields {
+		weight, ok := q.Boost[f]
+		if !ok {
+			weight = DefaultFieldWeight
+		}
+		weights[i] = strconv.Itoa(weight)
+	}
+	return strings.Join(weights, ",")
+}
+
+func (q *Query) ToSearchPrefix() string {
+	if q.Prefix == nil {
+		return ""
+	}
+	return strconv.FormatBool(*q.Prefix)
+}
@@ -15,6 +15,9 @@
 package search
 
 import (
+	"strings"
+	"unicode"
+
 	"github.com/tigrisdata/tigris/query/filter"
 )
 
@@ -23,11 +26,48 @@ const (
 )
 
 type Query struct {
-	Q        string
-	Fields   []string
-	Facets   Facets
-	PageSize int
-	WrappedF *filter.WrappedFilter
+	Q         string
+	Fields    []string
+	Facets    Facets
+	PageSize  int
+	WrappedF  *filter.WrappedFilter
+	SortOrder Ordering
+	Vector    *VectorQuery
+	Boost     Boost
+	// Prefix is whether the last token of the query also matches the words it is a prefix of, the search backend
+	// decides when it is not set.
+	Prefix *bool
+	Typos  *TypoTolerance
+	// StopWords are removed from the text of the query, they are compared in lower case.
+	StopWords []string
+}
+
+// ToSearchQ returns the text of the query without the stop words. The text is returned unchanged if it only has stop
+// words, as the query would otherwise match all the documents.
+func (q *Query) ToSearchQ() string {
+	if len(q.StopWords) == 0 || q.Q == all {
+		return q.Q
+	}
+
+	stopWords := make(map[string]struct{}, len(q.StopWords))
+	for _, w := range q.StopWords {
+		stopWords[strings.ToLower(w)] = struct{}{}
+	}
+
+	var words []string
+	for _, w := range strings.Fields(q.Q) {
+		trimmed := strings.TrimFunc(w, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if _, ok := stopWords[strings.ToLower(trimmed)]; !ok {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return q.Q
+	}
+
+	return strings.Join(words, " ")
 }
 
 func (q *Query) ToSearchFacetSize() int {
@@ -39,26 +79,23 @@ func (q *Query) ToSearchFacetSize() int {
 	}
 
 	if len(q.Facets.Fields) > 0 && maxSize == 0 {
-		return defaultFacetSize
+		maxSize = defaultFacetSize
 	}
 
 	return maxSize
 }
 
+// ToSearchFacets returns the fields whose values are counted by the search backend, the interval facets are counted
+// separately.
 func (q *Query) ToSearchFacets() string {
-	if len(q.Facets.Fields) == 0 {
-		return ""
-	}
-
-	var facets string
-	for i, f := range q.Facets.Fields {
-		if i != 0 {
-			facets += ","
+	var facets []string
+	for _, f := range q.Facets.Fields {
+		if len(f.Interval) == 0 {
+			facets = append(facets, f.Name)
 		}
-		facets += f.Name
 	}
 
-	return facets
+	return strings.Join(facets, ",")
 }
 
 func (q *Query) ToSearchFields() string {
@@ -72,6 +109,17 @@ func (q *Query) ToSearchFields() string {
 	return fields
 }
 
+func (q *Query) ToSearchSort() string {
+	var sortBy string
+	for i, s := range q.SortOrder {
+		if i != 0 {
+			sortBy += ","
+		}
+		sortBy += s.ToSearchSort()
+	}
+	return sortBy
+}
+
 func (q *Query) ToSearchFilter() []string {
 	return q.WrappedF.Filter.ToSearchFilter()
 }
@@ -108,6 +156,36 @@ func (b *Builder) SearchFields(f []string) *Builder {
 	return b
 }
 
+func (b *Builder) SortOrder(o Ordering) *Builder {
+	b.query.SortOrder = o
+	return b
+}
+
+func (b *Builder) Vector(v *VectorQuery) *Builder {
+	b.query.Vector = v
+	return b
+}
+
+func (b *Builder) Boost(boost Boost) *Builder {
+	b.query.Boost = boost
+	return b
+}
+
+func (b *Builder) Prefix(p *bool) *Builder {
+	b.query.Prefix = p
+	return b
+}
+
+func (b *Builder) TypoTolerance(t *TypoTolerance) *Builder {
+	b.query.Typos = t
+	return b
+}
+
+func (b *Builder) StopWords(w []string) *Builder {
+	b.query.StopWords = w
+	return b
+}
+
 func (b *Builder) PageSize(s int) *Builder {
 	b.query.PageSize = s
 	return b
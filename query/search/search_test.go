@@ -16,8 +16,10 @@ package search
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/query/filter"
 	"github.com/tigrisdata/tigris/schema"
 )
@@ -37,3 +39,247 @@ func TestSearchBuilder(t *testing.T) {
 	require.Equal(t, []string{"a:=4&&int_value:=1&&string_value1:=shoe"}, q.ToSearchFilter())
 	require.Equal(t, "test", q.Q)
 }
+
+func TestSortOrder(t *testing.T) {
+	t.Run("fields", func(t *testing.T) {
+		ordering, err := UnmarshalSort([]byte(`[{"price": "$asc"}, {"location": {"$asc": {"lat": 48.8584, "lon": 2.2945}}}, {"rating": "$desc"}]`))
+		require.NoError(t, err)
+		require.Len(t, ordering, 3)
+		require.Nil(t, ordering[0].GeoOrigin)
+		require.Equal(t, &schema.GeoPoint{Lat: 48.8584, Lon: 2.2945}, ordering[1].GeoOrigin)
+
+		q := NewBuilder().SortOrder(ordering).Build()
+		require.Equal(t, "price:asc,location(48.8584, 2.2945):asc,rating:desc", q.ToSearchSort())
+	})
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			js     []byte
+			expErr error
+		}{
+			{
+				[]byte(`[{"price": "asc"}]`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "sort order can only be '$asc' or '$desc'"),
+			},
+			{
+				[]byte(`["price"]`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "invalid sort order, expected an object per field"),
+			},
+			{
+				[]byte(`[{"location": {"$asc": {"lat": 100, "lon": 2.2945}}}]`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "latitude should be between -90 and 90, found '100'"),
+			},
+			{
+				[]byte(`[{"a": "$asc"}, {"b": "$asc"}, {"c": "$asc"}, {"d": "$asc"}]`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "sorting is supported on maximum 3 fields"),
+			},
+		}
+		for _, c := range cases {
+			_, err := UnmarshalSort(c.js)
+			require.Equal(t, c.expErr, err)
+		}
+	})
+}
+
+func TestVectorQuery(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		q, err := UnmarshalVectorQuery([]byte(`{"emb": {"$vectorNear": {"vector": [1, 0]}}}`))
+		require.NoError(t, err)
+		require.Equal(t, &VectorQuery{Field: "emb", Vector: []float64{1, 0}, K: 10, Metric: MetricCosine}, q)
+
+		require.InDelta(t, 0, q.Distance([]float64{2, 0}), 1e-9)
+		require.InDelta(t, 1, q.Distance([]float64{0, 3}), 1e-9)
+		require.InDelta(t, 2, q.Distance([]float64{-1, 0}), 1e-9)
+	})
+	t.Run("l2", func(t *testing.T) {
+		q, err := UnmarshalVectorQuery([]byte(`{"emb": {"$vectorNear": {"vector": [1, 1], "k": 3, "metric": "l2"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, 3, q.K)
+		require.InDelta(t, 5, q.Distance([]float64{4, 5}), 1e-9)
+	})
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			js     []byte
+			expErr error
+		}{
+			{
+				[]byte(`{"emb": {"$near": {"vector": [1, 1]}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "only '$vectorNear' is supported for vector search, found '$near'"),
+			},
+			{
+				[]byte(`{"emb": {"$vectorNear": {"vector": []}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "'$vectorNear' needs a non-empty 'vector'"),
+			},
+			{
+				[]byte(`{"emb": {"$vectorNear": {"vector": [1], "k": -1}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "'k' should be between 1 and 1000"),
+			},
+			{
+				[]byte(`{"emb": {"$vectorNear": {"vector": [1], "metric": "dot"}}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported metric 'dot', supported metrics are 'cosine' and 'l2'"),
+			},
+		}
+		for _, c := range cases {
+			_, err := UnmarshalVectorQuery(c.js)
+			require.Equal(t, c.expErr, err)
+		}
+	})
+}
+
+func TestRelevance(t *testing.T) {
+	t.Run("boost", func(t *testing.T) {
+		boost, err := UnmarshalBoost([]byte(`{"title": 3, "tags": 2}`))
+		require.NoError(t, err)
+		require.Equal(t, Boost{"title": 3, "tags": 2}, boost)
+
+		q := NewBuilder().SearchFields([]string{"title", "description", "tags"}).Boost(boost).Build()
+		require.Equal(t, "3,1,2", q.ToSearchFieldWeights())
+
+		q = NewBuilder().SearchFields([]string{"title", "description"}).Build()
+		require.Empty(t, q.ToSearchFieldWeights())
+	})
+	t.Run("prefix", func(t *testing.T) {
+		require.Empty(t, NewBuilder().Build().ToSearchPrefix())
+
+		prefix := false
+		require.Equal(t, "false", NewBuilder().Prefix(&prefix).Build().ToSearchPrefix())
+	})
+	t.Run("typo tolerance", func(t *testing.T) {
+		require.Nil(t, NewTypoTolerance(nil))
+		numTypos, apiNumTypos := 1, int32(1)
+		require.Equal(t, &TypoTolerance{NumTypos: &numTypos, MinLengthOneTypo: 5}, NewTypoTolerance(&api.TypoTolerance{NumTypos: &apiNumTypos, MinLengthOneTypo: 5}))
+		require.Equal(t, &TypoTolerance{MinLengthOneTypo: 5}, NewTypoTolerance(&api.TypoTolerance{MinLengthOneTypo: 5}))
+	})
+	t.Run("sort by text match", func(t *testing.T) {
+		ordering, err := UnmarshalSort([]byte(`[{"_text_match": "$desc"}, {"price": "$asc"}]`))
+		require.NoError(t, err)
+		require.Equal(t, "_text_match:desc,price:asc", NewBuilder().SortOrder(ordering).Build().ToSearchSort())
+	})
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			js     []byte
+			expErr error
+		}{
+			{
+				[]byte(`{"title": 0}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "boost of field 'title' should be a positive integer"),
+			},
+			{
+				[]byte(`{"title": "high"}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "boost of field 'title' should be a positive integer"),
+			},
+			{
+				[]byte(`["title"]`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "invalid boost, expected an object of field weights"),
+			},
+		}
+		for _, c := range cases {
+			_, err := UnmarshalBoost(c.js)
+			require.Equal(t, c.expErr, err)
+		}
+	})
+}
+
+func TestFacets(t *testing.T) {
+	t.Run("ranges", func(t *testing.T) {
+		facets, err := UnmarshalFacet([]byte(`{"price": {"ranges": [{"to": 50}, {"from": 50, "to": 99.5, "label": "medium"}, {"from": 99.5}]}}`))
+		require.NoError(t, err)
+		require.Len(t, facets.Fields, 1)
+
+		ff := facets.Fields[0]
+		require.True(t, ff.IsBucketed())
+		require.Equal(t, []string{"*-50", "medium", "99.5-*"}, []string{ff.Ranges[0].Label, ff.Ranges[1].Label, ff.Ranges[2].Label})
+		require.Equal(t, "price:<50", ff.Ranges[0].ToSearchFilter("price"))
+		require.Equal(t, "price:>=50&&price:<99.5", ff.Ranges[1].ToSearchFilter("price"))
+		require.True(t, ff.Ranges[1].Contains(50))
+		require.False(t, ff.Ranges[1].Contains(99.5))
+		require.True(t, ff.Ranges[2].Contains(99.5))
+	})
+	t.Run("interval", func(t *testing.T) {
+		facets, err := UnmarshalFacet([]byte(`{"brand": {"size": 5}, "created": {"interval": "week"}}`))
+		require.NoError(t, err)
+		require.False(t, facets.Fields[0].IsBucketed())
+		// the intervals are not counted from the values
+		require.Equal(t, 5, NewBuilder().Facets(facets).Build().ToSearchFacetSize())
+		require.Equal(t, "brand", NewBuilder().Facets(facets).Build().ToSearchFacets())
+
+		ff := facets.Fields[1]
+		// Sunday belongs to the week that started on the previous Monday
+		bucket, ok := ff.IntervalBucket("2022-10-16T23:00:00Z")
+		require.True(t, ok)
+		require.Equal(t, "2022-10-10T00:00:00Z", bucket)
+
+		ff.Interval = FacetIntervalMonth
+		bucket, ok = ff.IntervalBucket("2022-10-31T23:00:00-05:00")
+		require.True(t, ok)
+		require.Equal(t, "2022-11-01T00:00:00Z", bucket)
+
+		ff.Interval = FacetIntervalDay
+		bucket, ok = ff.IntervalBucket("2022-10-16T10:00:00.123Z")
+		require.True(t, ok)
+		require.Equal(t, "2022-10-16T00:00:00Z", bucket)
+
+		_, ok = ff.IntervalBucket("yesterday")
+		require.False(t, ok)
+
+		ff.Interval = FacetIntervalWeek
+		first, _ := time.Parse(time.RFC3339, "2022-10-16T23:00:00Z")
+		last, _ := time.Parse(time.RFC3339, "2022-10-24T00:00:00+02:00")
+		starts, err := ff.Intervals(first, last)
+		require.NoError(t, err)
+		require.Len(t, starts, 2)
+		require.Equal(t, "2022-10-10T00:00:00Z", starts[0].Format(time.RFC3339))
+		require.Equal(t, "2022-10-17T00:00:00Z", starts[1].Format(time.RFC3339))
+		require.Equal(t, "_tigris_ts_created:>=1665964800000&&_tigris_ts_created:<1666569600000", ff.IntervalFilter("_tigris_ts_created", starts[1]))
+
+		// the range is clamped to the latest intervals
+		ff.Interval = FacetIntervalDay
+		starts, err = ff.Intervals(first, first.AddDate(3, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, starts, MaxFacetIntervals)
+		require.Equal(t, "2025-10-16T00:00:00Z", starts[MaxFacetIntervals-1].Format(time.RFC3339))
+		require.Equal(t, "2023-01-21T00:00:00Z", starts[0].Format(time.RFC3339))
+
+		ff.Interval = FacetIntervalMonth
+		starts, err = ff.Intervals(first, last)
+		require.NoError(t, err)
+		require.Len(t, starts, 1)
+		require.Equal(t, "2022-10-01T00:00:00Z", starts[0].Format(time.RFC3339))
+	})
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			js     []byte
+			expErr error
+		}{
+			{
+				[]byte(`{"price": {"ranges": [{"label": "all"}]}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "range of facet 'price' needs 'from' or 'to'"),
+			},
+			{
+				[]byte(`{"price": {"ranges": [{"from": 10, "to": 10}]}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "range of facet 'price' needs 'from' to be less than 'to'"),
+			},
+			{
+				[]byte(`{"created": {"interval": "year"}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported interval 'year' for facet 'created', supported intervals are 'day', 'week' and 'month'"),
+			},
+			{
+				[]byte(`{"created": {"interval": "day", "ranges": [{"to": 1}]}}`),
+				api.Errorf(api.Code_INVALID_ARGUMENT, "facet 'created' can have either ranges or an interval"),
+			},
+		}
+		for _, c := range cases {
+			_, err := UnmarshalFacet(c.js)
+			require.Equal(t, c.expErr, err)
+		}
+	})
+}
+
+func TestStopWords(t *testing.T) {
+	stopWords := []string{"the", "A", "for"}
+
+	require.Equal(t, "shoes trail", NewBuilder().Query("the shoes for a trail").StopWords(stopWords).Build().ToSearchQ())
+	require.Equal(t, "Shoes, trail", NewBuilder().Query("The Shoes, FOR trail").StopWords(stopWords).Build().ToSearchQ())
+	require.Equal(t, "the a", NewBuilder().Query("the a").StopWords(stopWords).Build().ToSearchQ())
+	require.Equal(t, "*", NewBuilder().StopWords(stopWords).Build().ToSearchQ())
+	require.Equal(t, "the shoes", NewBuilder().Query("the shoes").Build().ToSearchQ())
+}
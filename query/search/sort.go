@@ -0,0 +1,166 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+const (
+	ASC  = "$asc"
+	DESC = "$desc"
+)
+
+const (
+	// MaxSortFields is the maximum number of fields the search backend can sort on.
+	MaxSortFields = 3
+	// TextMatchSortField sorts the hits by their relevance to the text query, it can be combined with the fields.
+	TextMatchSortField = "_text_match"
+)
+
+// SortField is a single field of the sort order. A sort order can have the following form inside the JSON
+//    [{"price": "$asc"}, {"rating": "$desc"}]
+//    [{"location": {"$asc": {"lat": 48.85, "lon": 2.29}}}]
+// The second form is only for geopoint fields where the hits are ordered by their distance from the point.
+type SortField struct {
+	Name      string
+	Ascending bool
+	// GeoOrigin is the point from which the distance is measured when sorting on a geopoint field.
+	GeoOrigin *schema.GeoPoint
+}
+
+type Ordering []SortField
+
+func UnmarshalSort(input jsoniter.RawMessage) (Ordering, error) {
+	var ordering Ordering
+	if len(input) == 0 {
+		return ordering, nil
+	}
+
+	var err error
+	_, parseErr := jsonparser.ArrayEach(input, func(value []byte, dataType jsonparser.ValueType, offset int, _ error) {
+		if err != nil {
+			return
+		}
+		if dataType != jsonparser.Object {
+			err = api.Errorf(api.Code_INVALID_ARGUMENT, "invalid sort order, expected an object per field")
+			return
+		}
+
+		err = jsonparser.ObjectEach(value, func(k []byte, v []byte, vType jsonparser.ValueType, _ int) error {
+			sf, err := newSortField(string(k), v, vType)
+			if err != nil {
+				return err
+			}
+
+			ordering = append(ordering, sf)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid sort order, expected an array")
+	}
+
+	if len(ordering) > MaxSortFields {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "sorting is supported on maximum %d fields", MaxSortFields)
+	}
+
+	return ordering, nil
+}
+
+func newSortField(name string, v []byte, vType jsonparser.ValueType) (SortField, error) {
+	switch vType {
+	case jsonparser.String:
+		ascending, err := isAscending(string(v))
+		if err != nil {
+			return SortField{}, err
+		}
+
+		return SortField{Name: name, Ascending: ascending}, nil
+	case jsonparser.Object:
+		var sf SortField
+		var err error
+		err = jsonparser.ObjectEach(v, func(k []byte, origin []byte, _ jsonparser.ValueType, _ int) error {
+			if err != nil {
+				return err
+			}
+			if sf.GeoOrigin != nil {
+				return api.Errorf(api.Code_INVALID_ARGUMENT, "only one sort order is allowed for field '%s'", name)
+			}
+
+			if sf.Ascending, err = isAscending(string(k)); err != nil {
+				return err
+			}
+
+			var point schema.GeoPoint
+			if err = jsoniter.Unmarshal(origin, &point); err != nil {
+				return api.Errorf(api.Code_INVALID_ARGUMENT, "invalid sort origin for field '%s'", name)
+			}
+			if err = point.Validate(); err != nil {
+				return err
+			}
+
+			sf.Name = name
+			sf.GeoOrigin = &point
+			return nil
+		})
+		if err != nil {
+			return SortField{}, err
+		}
+		if sf.GeoOrigin == nil {
+			return SortField{}, api.Errorf(api.Code_INVALID_ARGUMENT, "missing sort order for field '%s'", name)
+		}
+
+		return sf, nil
+	default:
+		return SortField{}, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid sort order for field '%s'", name)
+	}
+}
+
+func isAscending(order string) (bool, error) {
+	switch order {
+	case ASC:
+		return true, nil
+	case DESC:
+		return false, nil
+	default:
+		return false, api.Errorf(api.Code_INVALID_ARGUMENT, "sort order can only be '%s' or '%s'", ASC, DESC)
+	}
+}
+
+func (s SortField) ToSearchSort() string {
+	var order = "desc"
+	if s.Ascending {
+		order = "asc"
+	}
+
+	if s.GeoOrigin != nil {
+		return fmt.Sprintf("%s(%s, %s):%s", s.Name,
+			strconv.FormatFloat(s.GeoOrigin.Lat, 'f', -1, 64),
+			strconv.FormatFloat(s.GeoOrigin.Lon, 'f', -1, 64),
+			order)
+	}
+
+	return fmt.Sprintf("%s:%s", s.Name, order)
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"math"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+const (
+	VectorNear = "$vectorNear"
+)
+
+const (
+	MetricCosine = "cosine"
+	MetricL2     = "l2"
+
+	defaultVectorK = 10
+	maxVectorK     = 1000
+)
+
+// VectorQuery is a nearest-neighbour query on a vector field. The JSON representation looks like below,
+//    {"embedding": {"$vectorNear": {"vector": [0.12, 0.45, 0.91], "k": 10, "metric": "cosine"}}}
+// where "k" defaults to 10 and "metric" defaults to "cosine".
+type VectorQuery struct {
+	Field  string
+	Vector []float64
+	K      int
+	Metric string
+}
+
+func UnmarshalVectorQuery(input jsoniter.RawMessage) (*VectorQuery, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+
+	var query *VectorQuery
+	var err error
+	err = jsonparser.ObjectEach(input, func(k []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		if err != nil {
+			return err
+		}
+		if query != nil {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "vector search is supported on a single field")
+		}
+		if dataType != jsonparser.Object {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "vector search on '%s' expects an object", string(k))
+		}
+
+		query, err = newVectorQuery(string(k), v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func newVectorQuery(field string, input []byte) (*VectorQuery, error) {
+	type vectorNear struct {
+		Vector []float64 `json:"vector"`
+		K      int       `json:"k"`
+		Metric string    `json:"metric"`
+	}
+
+	var near *vectorNear
+	var err error
+	err = jsonparser.ObjectEach(input, func(k []byte, v []byte, _ jsonparser.ValueType, _ int) error {
+		if err != nil {
+			return err
+		}
+		if string(k) != VectorNear {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "only '%s' is supported for vector search, found '%s'", VectorNear, string(k))
+		}
+
+		near = &vectorNear{}
+		if err = jsoniter.Unmarshal(v, near); err != nil {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "invalid '%s' query %s", VectorNear, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if near == nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "missing '%s' for field '%s'", VectorNear, field)
+	}
+
+	if len(near.Vector) == 0 {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "'%s' needs a non-empty 'vector'", VectorNear)
+	}
+
+	switch {
+	case near.K == 0:
+		near.K = defaultVectorK
+	case near.K < 0 || near.K > maxVectorK:
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "'k' should be between 1 and %d", maxVectorK)
+	}
+
+	switch near.Metric {
+	case "":
+		near.Metric = MetricCosine
+	case MetricCosine, MetricL2:
+	default:
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported metric '%s', supported metrics are '%s' and '%s'", near.Metric, MetricCosine, MetricL2)
+	}
+
+	return &VectorQuery{
+		Field:  field,
+		Vector: near.Vector,
+		K:      near.K,
+		Metric: near.Metric,
+	}, nil
+}
+
+// Distance returns the distance between the query vector and the input vector as per the metric of the query, lower
+// is closer. For cosine, it is 1 - cosine similarity. The input is expected to have the same dimension as the query.
+func (v *VectorQuery) Distance(other []float64) float64 {
+	switch v.Metric {
+	case MetricL2:
+		var sum float64
+		for i := range v.Vector {
+			d := v.Vector[i] - other[i]
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	default:
+		var dot, normA, normB float64
+		for i := range v.Vector {
+			dot += v.Vector[i] * other[i]
+			normA += v.Vector[i] * v.Vector[i]
+			normB += other[i] * other[i]
+		}
+		if normA == 0 || normB == 0 {
+			return 1
+		}
+		return 1 - dot/(math.Sqrt(normA)*math.Sqrt(normB))
+	}
+}
@@ -17,6 +17,7 @@ package update
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
@@ -28,7 +29,8 @@ import (
 type FieldOPType string
 
 const (
-	set FieldOPType = "$set"
+	set       FieldOPType = "$set"
+	increment FieldOPType = "$increment"
 )
 
 // BuildFieldOperators un-marshals request "fields" present in the Update API and returns a FieldOperatorFactory
@@ -45,6 +47,8 @@ func BuildFieldOperators(reqFields []byte) (*FieldOperatorFactory, error) {
 		switch op {
 		case string(set):
 			operators[string(set)] = NewFieldOperator(set, val)
+		case string(increment):
+			operators[string(increment)] = NewFieldOperator(increment, val)
 		}
 	}
 
@@ -59,15 +63,28 @@ type FieldOperatorFactory struct {
 	FieldOperators map[string]*FieldOperator
 }
 
-// MergeAndGet method to converts the input to the output after applying all the operators.
+// MergeAndGet method to converts the input to the output after applying all the operators. The set operator is
+// applied before the increment operator.
 func (factory *FieldOperatorFactory) MergeAndGet(existingDoc jsoniter.RawMessage) (jsoniter.RawMessage, error) {
 	setFieldOp := factory.FieldOperators[string(set)]
-	if setFieldOp == nil {
-		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "set operator not present in the fields parameter")
+	incFieldOp := factory.FieldOperators[string(increment)]
+	if setFieldOp == nil && incFieldOp == nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "set or increment operator not present in the fields parameter")
 	}
-	out, err := factory.apply(existingDoc, setFieldOp.Document)
-	if err != nil {
-		return nil, err
+
+	var (
+		out jsoniter.RawMessage = existingDoc
+		err error
+	)
+	if setFieldOp != nil {
+		if out, err = factory.apply(out, setFieldOp.Document); err != nil {
+			return nil, err
+		}
+	}
+	if incFieldOp != nil {
+		if out, err = factory.increment(out, incFieldOp.Document); err != nil {
+			return nil, err
+		}
 	}
 
 	return out, nil
@@ -97,9 +114,67 @@ func (factory *FieldOperatorFactory) apply(input jsoniter.RawMessage, setDoc jso
 	return output, nil
 }
 
+// increment adds the values of the increment document to the numeric fields of the input. A field missing in the input
+// is set to the value of the increment document. The result is an integer only if both the values are integers.
+func (factory *FieldOperatorFactory) increment(input jsoniter.RawMessage, incDoc jsoniter.RawMessage) (jsoniter.RawMessage, error) {
+	var (
+		output []byte = input
+		err    error
+	)
+	err = jsonparser.ObjectEach(incDoc, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		if dataType != jsonparser.Number {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "increment value of the field '%s' is not a number", key)
+		}
+
+		existing, existingType, _, err := jsonparser.Get(output, string(key))
+		if err != nil && err != jsonparser.KeyPathNotFoundError {
+			return err
+		}
+
+		var sum []byte
+		switch existingType {
+		case jsonparser.NotExist, jsonparser.Null:
+			sum = value
+		case jsonparser.Number:
+			if sum, err = addNumbers(existing, value); err != nil {
+				return err
+			}
+		default:
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "field '%s' is not a number and can't be incremented", key)
+		}
+
+		output, err = jsonparser.Set(output, sum, string(key))
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func addNumbers(a []byte, b []byte) ([]byte, error) {
+	ai, errA := strconv.ParseInt(string(a), 10, 64)
+	bi, errB := strconv.ParseInt(string(b), 10, 64)
+	if errA == nil && errB == nil {
+		return []byte(strconv.FormatInt(ai+bi, 10)), nil
+	}
+
+	af, err := strconv.ParseFloat(string(a), 64)
+	if err != nil {
+		return nil, err
+	}
+	bf, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strconv.FormatFloat(af+bf, 'f', -1, 64)), nil
+}
+
 // A FieldOperator can be of the following type:
 // { "$set": { <field1>: <value1>, ... } }
-// { "$incr": { <field1>: <value> } }
+// { "$increment": { <field1>: <value> } }
 // { "$remove": ["d"] }
 type FieldOperator struct {
 	Op       FieldOPType
@@ -20,6 +20,7 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 )
 
 func TestMergeAndGet(t *testing.T) {
@@ -108,3 +109,47 @@ func TestMergeAndGet_MarshalInput(t *testing.T) {
 		require.JSONEqf(t, string(c.outputDoc), string(actualOut), fmt.Sprintf("exp '%s' actual '%s'", string(c.outputDoc), string(actualOut)))
 	}
 }
+
+func TestMergeAndGet_Increment(t *testing.T) {
+	cases := []struct {
+		fields      jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+		outputDoc   jsoniter.RawMessage
+	}{
+		{
+			[]byte(`{"$increment": {"a": 2}}`),
+			[]byte(`{"a": 1, "b": "foo"}`),
+			[]byte(`{"a": 3, "b": "foo"}`),
+		}, {
+			[]byte(`{"$increment": {"a": 0.5, "c": -1}}`),
+			[]byte(`{"a": 1, "c": 10}`),
+			[]byte(`{"a": 1.5, "c": 9}`),
+		}, {
+			[]byte(`{"$increment": {"count": 1}}`),
+			[]byte(`{"a": 1}`),
+			[]byte(`{"a": 1,"count":1}`),
+		}, {
+			[]byte(`{"$set": {"b": "bar"}, "$increment": {"a": 1}}`),
+			[]byte(`{"a": 1, "b": "foo"}`),
+			[]byte(`{"a": 2, "b": "bar"}`),
+		},
+	}
+	for _, c := range cases {
+		f, err := BuildFieldOperators(c.fields)
+		require.NoError(t, err)
+
+		actualOut, err := f.MergeAndGet(c.existingDoc)
+		require.NoError(t, err)
+		require.Equal(t, c.outputDoc, actualOut, fmt.Sprintf("exp '%s' actual '%s'", string(c.outputDoc), string(actualOut)))
+	}
+
+	f, err := BuildFieldOperators([]byte(`{"$increment": {"b": 1}}`))
+	require.NoError(t, err)
+	_, err = f.MergeAndGet([]byte(`{"b": "foo"}`))
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "field 'b' is not a number and can't be incremented"), err)
+
+	f, err = BuildFieldOperators([]byte(`{"$increment": {"b": "1"}}`))
+	require.NoError(t, err)
+	_, err = f.MergeAndGet([]byte(`{"b": 1}`))
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "increment value of the field 'b' is not a number"), err)
+}
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 
+	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -34,6 +36,19 @@ const (
 	ObjFlattenDelimiter = "."
 )
 
+const (
+	// SearchIndexV0 is the format of the search collections created before the format was recorded. The search
+	// collection has the name of the collection in the search store, there is no alias in front of it.
+	SearchIndexV0 = 0
+	// SearchIndexV1 is the format of the search collections that are searched through an alias. The arrays of strings
+	// are indexed as arrays, and the datetime fields are faceted and their unix time is indexed along with them, see
+	// ToSearchDateTimeKey.
+	SearchIndexV1 = 1
+
+	// SearchIndexVersion is the format of the search collections that are created or reindexed.
+	SearchIndexVersion = SearchIndexV1
+)
+
 // DefaultCollection is used to represent a collection. The tenant in the metadata package is responsible for creating
 // the collection.
 type DefaultCollection struct {
@@ -58,6 +73,13 @@ type DefaultCollection struct {
 	// will be one to one mapped to queryable field but complex fields like object type field there may be more than
 	// one queryableFields. As queryableFields represent a flattened state these can be used as-is to index in memory.
 	QueryableFields []*QueryableField
+	// Options are the collection level options of the schema.
+	Options CollectionOptions
+	// FieldRevisions is the schema revision since which every field, keyed by its flattened name, has its current
+	// type. It is used to upgrade the documents written with an older revision, see NextFieldRevisions.
+	FieldRevisions map[string]int
+	// SearchIndexVersion is the format of the search collection of this collection.
+	SearchIndexVersion int
 }
 
 func NewDefaultCollection(name string, id uint32, schVer int, fields []*Field, indexes *Indexes, schema jsoniter.RawMessage, searchCollectionName string) *DefaultCollection {
@@ -77,21 +99,44 @@ func NewDefaultCollection(name string, id uint32, schVer int, fields []*Field, i
 	// schema validation.
 	validator.AdditionalProperties = false
 
-	queryableFields := buildQueryableFields(fields)
+	queryableFields := buildQueryableFields(fields, SearchIndexVersion)
 
 	return &DefaultCollection{
-		Id:              id,
-		SchVer:          schVer,
-		Name:            name,
-		Fields:          fields,
-		Indexes:         indexes,
-		Validator:       validator,
-		Schema:          schema,
-		Search:          buildSearchSchema(searchCollectionName, queryableFields),
-		QueryableFields: queryableFields,
+		Id:                 id,
+		SchVer:             schVer,
+		Name:               name,
+		Fields:             fields,
+		Indexes:            indexes,
+		Validator:          validator,
+		Schema:             schema,
+		Search:             buildSearchSchema(searchCollectionName, queryableFields),
+		QueryableFields:    queryableFields,
+		Options:            getCollectionOptions(schema),
+		SearchIndexVersion: SearchIndexVersion,
 	}
 }
 
+// WithSearchIndex returns a copy of the collection that is searched through the search collection "name", which is in
+// the format "version".
+func (d *DefaultCollection) WithSearchIndex(name string, version int) *DefaultCollection {
+	c := *d
+	c.SearchIndexVersion = version
+	c.QueryableFields = buildQueryableFields(d.Fields, version)
+	c.Search = buildSearchSchema(name, c.QueryableFields)
+
+	return &c
+}
+
+func getCollectionOptions(schema jsoniter.RawMessage) CollectionOptions {
+	var options CollectionOptions
+	if raw, dtp, _, err := jsonparser.Get(schema, OptionsSchemaK); err == nil && dtp == jsonparser.Object {
+		// the options are already validated while building the schema
+		_ = jsoniter.Unmarshal(raw, &options)
+	}
+
+	return options
+}
+
 func (d *DefaultCollection) GetName() string {
 	return d.Name
 }
@@ -108,11 +153,14 @@ func (d *DefaultCollection) GetIndexes() *Indexes {
 	return d.Indexes
 }
 
-// Validate expects an unmarshalled document which it will validate again the schema of this collection.
+// Validate expects an unmarshalled document which it will validate again the schema of this collection. The nullable
+// fields that are set to null are removed from the document before validating it.
 func (d *DefaultCollection) Validate(document interface{}) error {
+	removeNullValues(d.Fields, document)
+
 	err := d.Validator.Validate(document)
 	if err == nil {
-		return nil
+		return d.validateVectors(document)
 	}
 
 	if v, ok := err.(*jsonschema.ValidationError); ok {
@@ -128,33 +176,80 @@ func (d *DefaultCollection) Validate(document interface{}) error {
 	return api.Errorf(api.Code_INVALID_ARGUMENT, err.Error())
 }
 
+// validateVectors checks the dimension of the vector fields as it can't be expressed through the format validator.
+func (d *DefaultCollection) validateVectors(document interface{}) error {
+	for _, f := range d.QueryableFields {
+		if f.DataType != VectorType {
+			continue
+		}
+
+		var value = document
+		for _, key := range strings.Split(f.FieldName, ObjFlattenDelimiter) {
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = obj[key]
+		}
+
+		if vector, ok := value.([]interface{}); ok && len(vector) != f.Dimensions {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "json schema validation failed for field '%s' reason 'expected %d dimensions, but got %d'", f.FieldName, f.Dimensions, len(vector))
+		}
+	}
+
+	return nil
+}
+
 func (d *DefaultCollection) SearchCollectionName() string {
 	return d.Search.Name
 }
 
-func GetSearchDeltaFields(existingFields []*QueryableField, incomingFields []*Field) []tsApi.Field {
-	incomingQueryable := buildQueryableFields(incomingFields)
+// GetSearchDeltaFields returns the fields that need to be sent to the search backend to move it from the existing
+// fields to the incoming fields, both in the format "version" of the search collection. New fields are added, dropped
+// fields are removed and the fields that changed their search type or whether they are faceted are removed and added
+// back.
+func GetSearchDeltaFields(existingFields []*QueryableField, incomingFields []*Field, version int) []tsApi.Field {
+	incomingQueryable := buildQueryableFields(incomingFields, version)
 
-	var existingFieldSet = set.New()
+	var existingFieldMap = make(map[string]*QueryableField)
 	for _, f := range existingFields {
-		existingFieldSet.Insert(f.FieldName)
+		existingFieldMap[f.FieldName] = f
+	}
+
+	var incomingFieldSet = set.New()
+	for _, f := range incomingQueryable {
+		incomingFieldSet.Insert(f.FieldName)
 	}
 
 	var ptrTrue = true
 	var tsFields []tsApi.Field
-	for _, f := range incomingQueryable {
-		if existingFieldSet.Contains(f.FieldName) {
-			continue
+	for _, f := range existingFields {
+		if !incomingFieldSet.Contains(f.FieldName) {
+			for _, sf := range f.searchFields() {
+				tsFields = append(tsFields, tsApi.Field{
+					Name: sf.Name,
+					Drop: &ptrTrue,
+				})
+			}
 		}
+	}
 
-		tsFields = append(tsFields, tsApi.Field{
-			Name:     f.FieldName,
-			Type:     f.SearchType,
-			Facet:    &f.Faceted,
-			Index:    &f.Indexed,
-			Optional: &ptrTrue,
-		})
+	for _, f := range incomingQueryable {
+		if e, ok := existingFieldMap[f.FieldName]; ok {
+			if e.SearchType == f.SearchType && e.Faceted == f.Faceted {
+				continue
+			}
 
+			for _, sf := range e.searchFields() {
+				tsFields = append(tsFields, tsApi.Field{
+					Name: sf.Name,
+					Drop: &ptrTrue,
+				})
+			}
+		}
+
+		tsFields = append(tsFields, f.searchFields()...)
 	}
 
 	return tsFields
@@ -164,14 +259,14 @@ func buildSearchSchema(name string, queryableFields []*QueryableField) *tsApi.Co
 	var ptrTrue = true
 	var tsFields []tsApi.Field
 	for _, s := range queryableFields {
-		tsFields = append(tsFields, tsApi.Field{
-			Name:     s.FieldName,
-			Type:     s.SearchType,
-			Facet:    &s.Faceted,
-			Index:    &s.Indexed,
-			Optional: &ptrTrue,
-		})
+		tsFields = append(tsFields, s.searchFields()...)
 	}
+	// the creation time is indexed to order the hits of a search cursor, it is the tiebreaker of every sort order
+	tsFields = append(tsFields, tsApi.Field{
+		Name:     ReservedFields[CreatedAt],
+		Type:     toSearchFieldType(Int64Type),
+		Optional: &ptrTrue,
+	})
 
 	return &tsApi.CollectionSchema{
 		Name:   name,
@@ -203,6 +298,14 @@ func init() {
 		_, err := parseInt(i)
 		return err == nil
 	}
+	jsonschema.Formats[FieldNames[GeoPointType]] = func(i interface{}) bool {
+		_, err := NewGeoPoint(i)
+		return err == nil
+	}
+	jsonschema.Formats[FieldNames[VectorType]] = func(i interface{}) bool {
+		_, err := NewVector(i)
+		return err == nil
+	}
 }
 
 func parseInt(i interface{}) (int64, error) {
@@ -216,3 +319,17 @@ func parseInt(i interface{}) (int64, error) {
 	}
 	return 0, api.Errorf(api.Code_INVALID_ARGUMENT, "expected integer but found %T", i)
 }
+
+func parseFloat(i interface{}) (float64, error) {
+	switch v := i.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	}
+	return 0, api.Errorf(api.Code_INVALID_ARGUMENT, "expected number but found %T", i)
+}
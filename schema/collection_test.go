@@ -50,6 +50,15 @@ func TestCollection_SchemaValidate(t *testing.T) {
 			"price": {
 				"type": "number"
 			},
+			"location": {
+				"type": "object",
+				"format": "geopoint"
+			},
+			"embedding": {
+				"type": "array",
+				"format": "vector",
+				"dimensions": 3
+			},
 			"simple_items": {
 				"type": "array",
 				"items": {
@@ -162,6 +171,27 @@ func TestCollection_SchemaValidate(t *testing.T) {
 		}, {
 			document: []byte(`{"id": 123456789, "id_32": 2147483647, "id_64": 9223372036854775808}`),
 			expError: "reason '9223372036854775808 is not valid 'int64'",
+		}, {
+			document: []byte(`{"id": 1, "location": {"lat": 48.8584, "lon": 2.2945}}`),
+			expError: "",
+		}, {
+			document: []byte(`{"id": 1, "location": {"lat": 91, "lon": 2.2945}}`),
+			expError: "is not valid 'geopoint'",
+		}, {
+			document: []byte(`{"id": 1, "location": {"lat": 48.8584}}`),
+			expError: "is not valid 'geopoint'",
+		}, {
+			document: []byte(`{"id": 1, "location": [48.8584, 2.2945]}`),
+			expError: "expected object, but got array",
+		}, {
+			document: []byte(`{"id": 1, "embedding": [0.1, 0.2, 0.3]}`),
+			expError: "",
+		}, {
+			document: []byte(`{"id": 1, "embedding": [0.1, 0.2]}`),
+			expError: "field 'embedding' reason 'expected 3 dimensions, but got 2'",
+		}, {
+			document: []byte(`{"id": 1, "embedding": [0.1, "0.2", 0.3]}`),
+			expError: "is not valid 'vector'",
 		},
 	}
 	for _, c := range cases {
@@ -268,13 +298,75 @@ func TestCollection_SearchSchema(t *testing.T) {
 	schFactory, err := Build("t1", reqSchema)
 	require.NoError(t, err)
 
-	expFlattenedFields := []string{"id", "id_32", "product", "id_uuid", "ts", "price", "simple_items", "simple_object.name",
+	expFlattenedFields := []string{"id", "id_32", "product", "id_uuid", "ts", "_tigris_ts_ts", "price", "simple_items", "simple_object.name",
 		"simple_object.phone", "simple_object.address.street", "simple_object.details.nested_id", "simple_object.details.nested_obj.id",
 		"simple_object.details.nested_obj.name", "simple_object.details.nested_array", "simple_object.details.nested_string",
+		"created_at",
 	}
 
 	coll := NewDefaultCollection("t1", 1, 1, schFactory.Fields, schFactory.Indexes, schFactory.Schema, "t1")
+	require.Len(t, coll.Search.Fields, len(expFlattenedFields))
 	for i, f := range coll.Search.Fields {
 		require.Equal(t, expFlattenedFields[i], f.Name)
 	}
 }
+
+func TestCollection_Options(t *testing.T) {
+	t.Run("pre_image", func(t *testing.T) {
+		reqSchema := []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"], "options": {"pre_image": true}}`)
+		schFactory, err := Build("t1", reqSchema)
+		require.NoError(t, err)
+
+		coll := NewDefaultCollection("t1", 1, 1, schFactory.Fields, schFactory.Indexes, schFactory.Schema, "t1")
+		require.True(t, coll.Options.PreImage)
+	})
+	t.Run("default", func(t *testing.T) {
+		reqSchema := []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"]}`)
+		schFactory, err := Build("t1", reqSchema)
+		require.NoError(t, err)
+
+		coll := NewDefaultCollection("t1", 1, 1, schFactory.Fields, schFactory.Indexes, schFactory.Schema, "t1")
+		require.False(t, coll.Options.PreImage)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		reqSchema := []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"], "options": {"pre_image": "yes"}}`)
+		_, err := Build("t1", reqSchema)
+		require.Error(t, err)
+	})
+}
+
+func TestCollection_WithSearchIndex(t *testing.T) {
+	reqSchema := []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "tags": {"type": "array", "items": {"type": "string"}}, "ts": {"type": "string", "format": "date-time"}}, "primary_key": ["id"]}`)
+	schFactory, err := Build("t1", reqSchema)
+	require.NoError(t, err)
+
+	searchFieldNames := func(c *DefaultCollection) []string {
+		var names []string
+		for _, f := range c.Search.Fields {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.Fields, schFactory.Indexes, schFactory.Schema, "ns-db-t1")
+	require.Equal(t, SearchIndexVersion, coll.SearchIndexVersion)
+	require.Contains(t, searchFieldNames(coll), "_tigris_ts_ts")
+	require.True(t, coll.QueryableFields[2].Faceted)
+
+	legacy := coll.WithSearchIndex("ns-db-t1", SearchIndexV0)
+	require.Equal(t, SearchIndexV0, legacy.SearchIndexVersion)
+	require.Equal(t, "ns-db-t1", legacy.SearchCollectionName())
+	// the legacy search collections don't facet the datetime fields and pack the arrays of strings
+	require.NotContains(t, searchFieldNames(legacy), "_tigris_ts_ts")
+	require.False(t, legacy.QueryableFields[2].Faceted)
+	require.True(t, coll.QueryableFields[2].Faceted)
+	require.True(t, legacy.QueryableFields[1].ShouldPack())
+	require.Equal(t, "string", legacy.Search.Fields[1].Type)
+	require.False(t, coll.QueryableFields[1].ShouldPack())
+	require.Equal(t, "string[]", coll.Search.Fields[1].Type)
+
+	aliased := legacy.WithSearchIndex("ns-db-t1-alias", SearchIndexVersion)
+	require.Equal(t, "ns-db-t1-alias", aliased.SearchCollectionName())
+	require.Equal(t, "ns-db-t1", legacy.SearchCollectionName())
+	require.Equal(t, coll.Search.Fields, aliased.Search.Fields)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"sort"
+)
+
+const (
+	FieldAdded    = "added"
+	FieldRemoved  = "removed"
+	FieldModified = "modified"
+)
+
+// FieldDiff is the change of a single field between two schemas. Nested fields are named using the dotted notation.
+// The types are only set when the field exists in the corresponding schema.
+type FieldDiff struct {
+	Name    string
+	Change  string
+	OldType string
+	NewType string
+}
+
+// SchemaDiff is the structured difference between two schemas of a collection.
+type SchemaDiff struct {
+	Fields            []FieldDiff
+	PrimaryKeyChanged bool
+}
+
+// Diff returns the difference from the existing schema to the incoming schema. The fields are sorted by the name. A nil
+// existing schema is treated as an empty schema i.e. all the incoming fields are added.
+func Diff(existing *Factory, incoming *Factory) *SchemaDiff {
+	var existingFields = make(map[string]*Field)
+	if existing != nil {
+		existingFields = flattenFields("", existing.Fields)
+	}
+	incomingFields := flattenFields("", incoming.Fields)
+
+	var diff = &SchemaDiff{}
+	for name, e := range existingFields {
+		i, ok := incomingFields[name]
+		switch {
+		case !ok:
+			diff.Fields = append(diff.Fields, FieldDiff{
+				Name:    name,
+				Change:  FieldRemoved,
+				OldType: FieldNames[e.DataType],
+			})
+		case isFieldModified(e, i):
+			diff.Fields = append(diff.Fields, FieldDiff{
+				Name:    name,
+				Change:  FieldModified,
+				OldType: FieldNames[e.DataType],
+				NewType: FieldNames[i.DataType],
+			})
+		}
+	}
+	for name, i := range incomingFields {
+		if _, ok := existingFields[name]; !ok {
+			diff.Fields = append(diff.Fields, FieldDiff{
+				Name:    name,
+				Change:  FieldAdded,
+				NewType: FieldNames[i.DataType],
+			})
+		}
+	}
+
+	sort.Slice(diff.Fields, func(i, j int) bool {
+		return diff.Fields[i].Name < diff.Fields[j].Name
+	})
+
+	if existing != nil {
+		diff.PrimaryKeyChanged = existing.Indexes.PrimaryKey.IsCompatible(incoming.Indexes.PrimaryKey) != nil
+	}
+
+	return diff
+}
+
+func isFieldModified(existing *Field, incoming *Field) bool {
+	return existing.DataType != incoming.DataType ||
+		existing.IsNullable() != incoming.IsNullable() ||
+		existing.IsPrimaryKey() != incoming.IsPrimaryKey() ||
+		existing.GetDimensions() != incoming.GetDimensions() ||
+		maxLength(existing) != maxLength(incoming)
+}
+
+func maxLength(f *Field) int32 {
+	if f.MaxLength == nil {
+		return 0
+	}
+	return *f.MaxLength
+}
+
+func flattenFields(parent string, fields []*Field) map[string]*Field {
+	var flattened = make(map[string]*Field)
+	for _, f := range fields {
+		name := f.FieldName
+		if len(parent) > 0 {
+			name = parent + ObjFlattenDelimiter + name
+		}
+
+		flattened[name] = f
+		if f.DataType == ObjectType {
+			for nestedName, nested := range flattenFields(name, f.Fields) {
+				flattened[nestedName] = nested
+			}
+		}
+	}
+
+	return flattened
+}
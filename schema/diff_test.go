@@ -0,0 +1,76 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	existing, err := Build("t1", []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"i": { "type": "integer", "format": "int32" },
+		"s": { "type": "string" },
+		"address": { "type": "object", "properties": { "city": { "type": "string" } } }
+	},
+	"primary_key": ["id"]
+}`))
+	require.NoError(t, err)
+
+	incoming, err := Build("t1", []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"i": { "type": "integer" },
+		"b": { "type": "boolean" },
+		"address": { "type": "object", "properties": { "city": { "type": "string", "nullable": true }, "zip": { "type": "string" } } }
+	},
+	"primary_key": ["id"]
+}`))
+	require.NoError(t, err)
+
+	t.Run("changes", func(t *testing.T) {
+		diff := Diff(existing, incoming)
+		require.False(t, diff.PrimaryKeyChanged)
+		require.Equal(t, []FieldDiff{
+			{Name: "address.city", Change: FieldModified, OldType: "string", NewType: "string"},
+			{Name: "address.zip", Change: FieldAdded, NewType: "string"},
+			{Name: "b", Change: FieldAdded, NewType: "bool"},
+			{Name: "i", Change: FieldModified, OldType: "int32", NewType: "int64"},
+			{Name: "s", Change: FieldRemoved, OldType: "string"},
+		}, diff.Fields)
+	})
+	t.Run("no_changes", func(t *testing.T) {
+		diff := Diff(existing, existing)
+		require.False(t, diff.PrimaryKeyChanged)
+		require.Empty(t, diff.Fields)
+	})
+	t.Run("empty_existing", func(t *testing.T) {
+		diff := Diff(nil, existing)
+		require.Len(t, diff.Fields, 5)
+		for _, f := range diff.Fields {
+			require.Equal(t, FieldAdded, f.Change)
+		}
+	})
+	t.Run("primary_key", func(t *testing.T) {
+		pk, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "s": {"type": "string"}}, "primary_key": ["s"]}`))
+		require.NoError(t, err)
+		require.True(t, Diff(existing, pk).PrimaryKeyChanged)
+	})
+}
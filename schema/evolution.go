@@ -0,0 +1,163 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"strings"
+
+	"github.com/buger/jsonparser"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+// widening is the list of type changes that are allowed on an existing field. All of these are stored as JSON numbers
+// so the documents written with the old type are still valid for the new type and don't need to be rewritten.
+var widening = map[FieldType][]FieldType{
+	Int32Type: {Int64Type, DoubleType},
+	Int64Type: {DoubleType},
+}
+
+// CanWiden returns true if an existing field of type "from" can be changed to type "to".
+func CanWiden(from FieldType, to FieldType) bool {
+	for _, t := range widening[from] {
+		if t == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpgradeDocument brings a document written with schema version "ver" to the current schema version of the collection.
+// Documents that are already on the current version are returned as-is. Otherwise, the fields that are dropped from the
+// schema after the document was written are removed from the document, and so are the fields that were dropped and
+// added back with another type since, their value was written for the old type. Widened fields don't need any change.
+// The documents without a version were written before the versions were recorded, so it isn't known which revision
+// they were written with and only the fields that are not in the schema anymore are removed.
+func (d *DefaultCollection) UpgradeDocument(ver int32, doc []byte) ([]byte, error) {
+	if int(ver) >= d.SchVer || len(doc) == 0 {
+		return doc, nil
+	}
+
+	revisions := d.FieldRevisions
+	if ver == 0 {
+		revisions = nil
+	}
+	return removeDroppedFields(d.Fields, revisions, int(ver), doc)
+}
+
+// NextFieldRevisions returns the FieldRevisions of the schema revision that replaces the fields "previous" with the
+// fields "next". A field keeps the revision it had if its type didn't change or was widened, otherwise it gets the new
+// revision. The revisions of the first schema revision are built by passing no previous fields.
+func NextFieldRevisions(revisions map[string]int, previous []*Field, next []*Field, revision int) map[string]int {
+	var nextRevisions = make(map[string]int)
+	nextFieldRevisions(revisions, flattenFields("", previous), next, "", revision, nextRevisions)
+	return nextRevisions
+}
+
+func nextFieldRevisions(revisions map[string]int, previous map[string]*Field, next []*Field, parent string, revision int, nextRevisions map[string]int) {
+	for _, f := range next {
+		name := f.FieldName
+		if len(parent) > 0 {
+			name = parent + ObjFlattenDelimiter + f.FieldName
+		}
+
+		nextRevisions[name] = revision
+		if p, ok := previous[name]; ok && keepsValues(p, f) {
+			if r, ok := revisions[name]; ok {
+				nextRevisions[name] = r
+			}
+		}
+
+		if f.DataType == ObjectType {
+			nextFieldRevisions(revisions, previous, f.Fields, name, revision, nextRevisions)
+		}
+	}
+}
+
+// keepsValues returns true if the values written for the field "previous" are still valid for the field "next".
+func keepsValues(previous *Field, next *Field) bool {
+	if previous.DataType != next.DataType {
+		return CanWiden(previous.DataType, next.DataType)
+	}
+	if previous.GetDimensions() != next.GetDimensions() {
+		return false
+	}
+	if previous.DataType == ArrayType {
+		if len(previous.Fields) != len(next.Fields) {
+			return false
+		}
+		for i := range previous.Fields {
+			if previous.Fields[i].FieldName != next.Fields[i].FieldName || !keepsValues(previous.Fields[i], next.Fields[i]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func removeDroppedFields(fields []*Field, revisions map[string]int, ver int, doc []byte, path ...string) ([]byte, error) {
+	var known = make(map[string]*Field)
+	for _, f := range fields {
+		known[f.FieldName] = f
+	}
+
+	var dropped []string
+	var nested []*Field
+	err := jsonparser.ObjectEach(doc, func(k []byte, _ []byte, dataType jsonparser.ValueType, _ int) error {
+		f, ok := known[string(k)]
+		switch {
+		case !ok || revisions[strings.Join(append(path, string(k)), ObjFlattenDelimiter)] > ver:
+			dropped = append(dropped, string(k))
+		case f.DataType == ObjectType && dataType == jsonparser.Object:
+			nested = append(nested, f)
+		}
+		return nil
+	}, path...)
+	if err != nil {
+		return nil, api.Errorf(api.Code_INTERNAL, "unable to upgrade document %s", err.Error())
+	}
+
+	for _, f := range nested {
+		if doc, err = removeDroppedFields(f.Fields, revisions, ver, doc, append(path, f.FieldName)...); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range dropped {
+		doc = jsonparser.Delete(doc, append(path, name)...)
+	}
+
+	return doc, nil
+}
+
+// removeNullValues removes the nullable fields that are explicitly set to null in the document so that the JSON schema
+// validation is not applied on them.
+func removeNullValues(fields []*Field, document interface{}) {
+	doc, ok := document.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for _, f := range fields {
+		value, found := doc[f.FieldName]
+		switch {
+		case !found:
+		case value == nil && f.IsNullable():
+			delete(doc, f.FieldName)
+		case f.DataType == ObjectType:
+			removeNullValues(f.Fields, value)
+		}
+	}
+}
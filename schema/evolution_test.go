@@ -0,0 +1,232 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanWiden(t *testing.T) {
+	require.True(t, CanWiden(Int32Type, Int64Type))
+	require.True(t, CanWiden(Int32Type, DoubleType))
+	require.True(t, CanWiden(Int64Type, DoubleType))
+	require.False(t, CanWiden(Int64Type, Int32Type))
+	require.False(t, CanWiden(DoubleType, Int64Type))
+	require.False(t, CanWiden(StringType, ByteType))
+}
+
+func TestUpgradeDocument(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"name": { "type": "string" },
+		"address": { "type": "object", "properties": { "city": { "type": "string" } } }
+	},
+	"primary_key": ["id"]
+}`)
+	factory, err := Build("t1", reqSchema)
+	require.NoError(t, err)
+
+	coll := NewDefaultCollection("t1", 1, 3, factory.Fields, factory.Indexes, factory.Schema, "t1")
+
+	t.Run("current_version", func(t *testing.T) {
+		doc := []byte(`{"id":1,"name":"a","dropped":true}`)
+		upgraded, err := coll.UpgradeDocument(3, doc)
+		require.NoError(t, err)
+		require.Equal(t, doc, upgraded)
+	})
+	t.Run("older_version", func(t *testing.T) {
+		upgraded, err := coll.UpgradeDocument(1, []byte(`{"id":1,"name":"a","dropped":true,"address":{"city":"b","zip":123}}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"name":"a","address":{"city":"b"}}`, string(upgraded))
+	})
+	t.Run("nothing_dropped", func(t *testing.T) {
+		upgraded, err := coll.UpgradeDocument(0, []byte(`{"id":1,"name":"a"}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"name":"a"}`, string(upgraded))
+	})
+}
+
+func TestUpgradeDocumentReAddedField(t *testing.T) {
+	build := func(properties string) *Factory {
+		factory, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": { "type": "integer" }, `+properties+`}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+		return factory
+	}
+
+	v1 := build(`"name": { "type": "string" }, "address": { "type": "object", "properties": { "zip": { "type": "string" } } }, "age": { "type": "integer", "format": "int32" }`)
+	v2 := build(`"address": { "type": "object", "properties": {} }, "age": { "type": "integer" }`)
+	v3 := build(`"name": { "type": "integer" }, "address": { "type": "object", "properties": { "zip": { "type": "integer" } } }, "age": { "type": "integer" }`)
+
+	revisions := NextFieldRevisions(nil, nil, v1.Fields, 1)
+	revisions = NextFieldRevisions(revisions, v1.Fields, v2.Fields, 2)
+	revisions = NextFieldRevisions(revisions, v2.Fields, v3.Fields, 3)
+	require.Equal(t, map[string]int{"id": 1, "name": 3, "address": 1, "address.zip": 3, "age": 1}, revisions)
+
+	coll := NewDefaultCollection("t1", 1, 3, v3.Fields, v3.Indexes, v3.Schema, "t1")
+	coll.FieldRevisions = revisions
+
+	t.Run("written_before_drop", func(t *testing.T) {
+		upgraded, err := coll.UpgradeDocument(1, []byte(`{"id":1,"name":"a","address":{"zip":"b"},"age":10}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"address":{},"age":10}`, string(upgraded))
+	})
+	t.Run("written_after_drop", func(t *testing.T) {
+		upgraded, err := coll.UpgradeDocument(2, []byte(`{"id":1,"address":{},"age":10}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"address":{},"age":10}`, string(upgraded))
+	})
+	t.Run("written_without_version", func(t *testing.T) {
+		// the revision of the document isn't known, so only the fields that are not in the schema are removed
+		upgraded, err := coll.UpgradeDocument(0, []byte(`{"id":1,"name":2,"address":{"zip":3,"city":"c"},"age":10,"extra":true}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"name":2,"address":{"zip":3},"age":10}`, string(upgraded))
+	})
+}
+
+func TestValidateNullable(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"address": {
+			"type": "object",
+			"properties": {
+				"city": { "type": "string", "nullable": true },
+				"zip": { "type": "string" }
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+	factory, err := Build("t1", reqSchema)
+	require.NoError(t, err)
+
+	coll := NewDefaultCollection("t1", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "t1")
+	require.NoError(t, coll.Validate(map[string]interface{}{
+		"id":      1,
+		"address": map[string]interface{}{"city": nil},
+	}))
+	require.Error(t, coll.Validate(map[string]interface{}{
+		"id":      1,
+		"address": map[string]interface{}{"zip": nil},
+	}))
+
+	_, err = Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer", "nullable": true}}, "primary_key": ["id"]}`))
+	require.Error(t, err)
+
+	factory, err = Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "name": {"type": "string", "nullable": false}, "age": {"type": "integer"}}, "primary_key": ["id"]}`))
+	require.NoError(t, err)
+	require.True(t, factory.Fields[1].IsNotNullable())
+	require.False(t, factory.Fields[2].IsNotNullable())
+
+	coll = NewDefaultCollection("t1", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "t1")
+	require.Error(t, coll.Validate(map[string]interface{}{"id": 1, "name": nil}))
+}
+
+func TestGetSearchDeltaFields(t *testing.T) {
+	existing, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "i": {"type": "integer"}, "s": {"type": "string"}}, "primary_key": ["id"]}`))
+	require.NoError(t, err)
+	incoming, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "i": {"type": "number"}, "b": {"type": "boolean"}}, "primary_key": ["id"]}`))
+	require.NoError(t, err)
+
+	coll := NewDefaultCollection("t1", 1, 1, existing.Fields, existing.Indexes, existing.Schema, "t1")
+
+	var names []string
+	var dropped []string
+	for _, f := range GetSearchDeltaFields(coll.QueryableFields, incoming.Fields, coll.SearchIndexVersion) {
+		if f.Drop != nil && *f.Drop {
+			dropped = append(dropped, f.Name)
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	require.ElementsMatch(t, []string{"s", "i"}, dropped)
+	require.ElementsMatch(t, []string{"i", "b"}, names)
+
+	t.Run("datetime", func(t *testing.T) {
+		withDate, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "d": {"type": "string", "format": "date-time"}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+
+		// the unix time of a datetime field is added and dropped along with it
+		names, dropped = nil, nil
+		for _, f := range GetSearchDeltaFields(coll.QueryableFields, withDate.Fields, SearchIndexVersion) {
+			if f.Drop != nil && *f.Drop {
+				dropped = append(dropped, f.Name)
+				continue
+			}
+			names = append(names, f.Name)
+		}
+		require.ElementsMatch(t, []string{"i", "s"}, dropped)
+		require.ElementsMatch(t, []string{"d", "_tigris_ts_d"}, names)
+
+		dated := NewDefaultCollection("t1", 1, 2, withDate.Fields, withDate.Indexes, withDate.Schema, "t1")
+		names, dropped = nil, nil
+		for _, f := range GetSearchDeltaFields(dated.QueryableFields, existing.Fields, SearchIndexVersion) {
+			if f.Drop != nil && *f.Drop {
+				dropped = append(dropped, f.Name)
+				continue
+			}
+			names = append(names, f.Name)
+		}
+		require.ElementsMatch(t, []string{"d", "_tigris_ts_d"}, dropped)
+		require.ElementsMatch(t, []string{"i", "s"}, names)
+
+		// a legacy search collection doesn't get them
+		legacy := dated.WithSearchIndex("t1", SearchIndexV0)
+		require.Empty(t, GetSearchDeltaFields(legacy.QueryableFields, withDate.Fields, SearchIndexV0))
+	})
+}
+
+func TestStringArraySearchFields(t *testing.T) {
+	factory, err := Build("t1", []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"tags": { "type": "array", "items": { "type": "string" } },
+		"scores": { "type": "array", "items": { "type": "integer" } },
+		"variants": { "type": "array", "items": { "type": "object", "properties": { "color": { "type": "string" } } } },
+		"details": {
+			"type": "object",
+			"properties": {
+				"labels": { "type": "array", "items": { "type": "string" } }
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`))
+	require.NoError(t, err)
+
+	coll := NewDefaultCollection("t1", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "t1")
+	fields := make(map[string]*QueryableField)
+	for _, qf := range coll.QueryableFields {
+		fields[qf.FieldName] = qf
+	}
+
+	for _, name := range []string{"tags", "details.labels"} {
+		require.True(t, fields[name].IsTextSearchable(), name)
+		require.False(t, fields[name].ShouldPack(), name)
+		require.True(t, fields[name].Indexed, name)
+		require.Equal(t, "string[]", fields[name].SearchType, name)
+	}
+	for _, name := range []string{"scores", "variants"} {
+		require.False(t, fields[name].IsTextSearchable(), name)
+		require.True(t, fields[name].ShouldPack(), name)
+		require.Equal(t, "string", fields[name].SearchType, name)
+	}
+}
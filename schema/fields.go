@@ -21,12 +21,15 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/lib/set"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
 )
 
 type FieldType int
 
 const (
-	searchDoubleType = "float"
+	searchDoubleType      = "float"
+	searchGeoPointType    = "geopoint"
+	searchStringArrayType = "string[]"
 )
 
 const (
@@ -45,6 +48,10 @@ const (
 	DateTimeType
 	ArrayType
 	ObjectType
+	// GeoPointType is an object with "lat" and "lon" properties, it is declared as an object with format "geopoint".
+	GeoPointType
+	// VectorType is an array of numbers of a fixed dimension, it is declared as an array with format "vector".
+	VectorType
 )
 
 var FieldNames = [...]string{
@@ -60,6 +67,8 @@ var FieldNames = [...]string{
 	DateTimeType: "datetime",
 	ArrayType:    "array",
 	ObjectType:   "object",
+	GeoPointType: "geopoint",
+	VectorType:   "vector",
 }
 
 var (
@@ -101,6 +110,13 @@ const (
 	jsonSpecFormatByte     = "byte"
 	jsonSpecFormatInt32    = "int32"
 	jsonSpecFormatInt64    = "int64"
+	jsonSpecFormatGeoPoint = "geopoint"
+	jsonSpecFormatVector   = "vector"
+)
+
+const (
+	// MaxVectorDimensions is the maximum dimension allowed for a vector field.
+	MaxVectorDimensions = 4096
 )
 
 func ToFieldType(jsonType string, encoding string, format string) FieldType {
@@ -152,8 +168,26 @@ func ToFieldType(jsonType string, encoding string, format string) FieldType {
 
 		return StringType
 	case jsonSpecArray:
+		switch format {
+		case jsonSpecFormatVector:
+			return VectorType
+		default:
+			if len(format) > 0 {
+				return UnknownType
+			}
+		}
+
 		return ArrayType
 	case jsonSpecObject:
+		switch format {
+		case jsonSpecFormatGeoPoint:
+			return GeoPointType
+		default:
+			if len(format) > 0 {
+				return UnknownType
+			}
+		}
+
 		return ObjectType
 	default:
 		return UnknownType
@@ -171,7 +205,7 @@ func IsValidIndexType(t FieldType) bool {
 
 func IndexableField(fieldType FieldType) bool {
 	switch fieldType {
-	case BoolType, Int32Type, Int64Type, UUIDType, StringType, DateTimeType, DoubleType:
+	case BoolType, Int32Type, Int64Type, UUIDType, StringType, DateTimeType, DoubleType, GeoPointType:
 		return true
 	default:
 		return false
@@ -180,7 +214,7 @@ func IndexableField(fieldType FieldType) bool {
 
 func FacetableField(fieldType FieldType) bool {
 	switch fieldType {
-	case Int32Type, Int64Type, StringType, DoubleType:
+	case Int32Type, Int64Type, StringType, DoubleType, DateTimeType:
 		return true
 	default:
 		return false
@@ -197,8 +231,10 @@ func toSearchFieldType(fieldType FieldType) string {
 		return FieldNames[StringType]
 	case DoubleType:
 		return searchDoubleType
-	case ArrayType:
+	case ArrayType, VectorType:
 		return FieldNames[StringType]
+	case GeoPointType:
+		return searchGeoPointType
 	}
 
 	return ""
@@ -213,6 +249,8 @@ var SupportedFieldProperties = set.New(
 	"contentEncoding",
 	"properties",
 	"autoGenerate",
+	"dimensions",
+	"nullable",
 )
 
 // Indexes is to wrap different index that a collection can have.
@@ -269,6 +307,8 @@ type FieldBuilder struct {
 	Format      string              `json:"format,omitempty"`
 	Encoding    string              `json:"contentEncoding,omitempty"`
 	MaxLength   *int32              `json:"maxLength,omitempty"`
+	Dimensions  *int                `json:"dimensions,omitempty"`
+	Nullable    *bool               `json:"nullable,omitempty"`
 	Auto        *bool               `json:"autoGenerate,omitempty"`
 	Items       *FieldBuilder       `json:"items,omitempty"`
 	Properties  jsoniter.RawMessage `json:"properties,omitempty"`
@@ -324,6 +364,21 @@ func (f *FieldBuilder) Build(isArrayElement bool) (*Field, error) {
 			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported primary key type detected '%s'", f.Type)
 		}
 	}
+	if fieldType == VectorType {
+		if f.Dimensions == nil || *f.Dimensions <= 0 || *f.Dimensions > MaxVectorDimensions {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "vector field '%s' needs 'dimensions' between 1 and %d", f.FieldName, MaxVectorDimensions)
+		}
+		for _, item := range f.Fields {
+			if item.DataType != DoubleType {
+				return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "vector field '%s' can only have items of type 'number'", f.FieldName)
+			}
+		}
+	} else if f.Dimensions != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "'dimensions' is only allowed for vector fields, found on '%s'", f.FieldName)
+	}
+	if f.Primary != nil && *f.Primary && f.Nullable != nil && *f.Nullable {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "primary key field can't be nullable '%s'", f.FieldName)
+	}
 	if f.Primary == nil && f.Auto != nil && *f.Auto {
 		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "only primary fields can be set as auto-generated '%s'", f.FieldName)
 	}
@@ -335,6 +390,8 @@ func (f *FieldBuilder) Build(isArrayElement bool) (*Field, error) {
 	field.PrimaryKeyField = f.Primary
 	field.Fields = f.Fields
 	field.AutoGenerated = f.Auto
+	field.Dimensions = f.Dimensions
+	field.Nullable = f.Nullable
 	return field, nil
 }
 
@@ -345,6 +402,8 @@ type Field struct {
 	UniqueKeyField  *bool
 	PrimaryKeyField *bool
 	AutoGenerated   *bool
+	Dimensions      *int
+	Nullable        *bool
 	Fields          []*Field
 }
 
@@ -364,11 +423,37 @@ func (f *Field) IsAutoGenerated() bool {
 	return f.AutoGenerated != nil && *f.AutoGenerated
 }
 
+func (f *Field) IsNullable() bool {
+	return f.Nullable != nil && *f.Nullable
+}
+
+// IsNotNullable returns true only if the field is explicitly declared with "nullable": false.
+func (f *Field) IsNotNullable() bool {
+	return f.Nullable != nil && !*f.Nullable
+}
+
+func (f *Field) GetDimensions() int {
+	if f.Dimensions == nil {
+		return 0
+	}
+	return *f.Dimensions
+}
+
+// IsCompatible checks whether the field f1 of the incoming schema can replace the existing field f. The only type
+// changes allowed are the ones that widen the type, see CanWiden, and a field can become nullable but can't go back.
 func (f *Field) IsCompatible(f1 *Field) error {
-	if f.DataType != f1.DataType {
+	if f.DataType != f1.DataType && (f.IsPrimaryKey() || !CanWiden(f.DataType, f1.DataType)) {
 		return api.Errorf(api.Code_INVALID_ARGUMENT, "data type mismatch for field %q", f.FieldName)
 	}
 
+	if f.IsNullable() && !f1.IsNullable() {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "nullable field %q can't be changed to not nullable", f.FieldName)
+	}
+
+	if f.GetDimensions() != f1.GetDimensions() {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "dimensions can't be changed for vector field %q", f.FieldName)
+	}
+
 	if f.IsPrimaryKey() != f1.IsPrimaryKey() {
 		return api.Errorf(api.Code_INVALID_ARGUMENT, "primary key changes are not allowed %q", f.FieldName)
 	}
@@ -382,6 +467,10 @@ type QueryableField struct {
 	Indexed    bool
 	DataType   FieldType
 	SearchType string
+	// Dimensions is only set for vector fields.
+	Dimensions int
+	// ItemType is only set for arrays of strings, they are indexed as arrays instead of being packed.
+	ItemType FieldType
 }
 
 func NewQueryableField(name string, tigrisType FieldType) *QueryableField {
@@ -399,41 +488,82 @@ func (q *QueryableField) Name() string {
 }
 
 func (q *QueryableField) ShouldPack() bool {
-	return q.DataType == ArrayType
+	return (q.DataType == ArrayType && q.ItemType != StringType) || q.DataType == VectorType
+}
+
+// searchFields returns the fields of the search schema that index the queryable field. The faceted datetime fields
+// also index their unix time in milliseconds, the interval facets of the fields with too many distinct values are
+// counted with range filters on it.
+func (q *QueryableField) searchFields() []tsApi.Field {
+	var ptrTrue = true
+	fields := []tsApi.Field{{
+		Name:     q.FieldName,
+		Type:     q.SearchType,
+		Facet:    &q.Faceted,
+		Index:    &q.Indexed,
+		Optional: &ptrTrue,
+	}}
+	if q.DataType == DateTimeType && q.Faceted {
+		fields = append(fields, tsApi.Field{
+			Name:     ToSearchDateTimeKey(q.FieldName),
+			Type:     toSearchFieldType(Int64Type),
+			Optional: &ptrTrue,
+		})
+	}
+
+	return fields
+}
+
+// IsTextSearchable is true for the fields the text query is matched against, the strings and the arrays of strings.
+func (q *QueryableField) IsTextSearchable() bool {
+	return q.DataType == StringType || (q.DataType == ArrayType && q.ItemType == StringType)
 }
 
-func buildQueryableFields(fields []*Field) []*QueryableField {
+func buildQueryableFields(fields []*Field, version int) []*QueryableField {
 	var queryableFields []*QueryableField
 
 	for _, f := range fields {
 		if f.DataType == ObjectType {
-			queryableFields = append(queryableFields, buildQueryableForObject(f.FieldName, f.Fields)...)
+			queryableFields = append(queryableFields, buildQueryableForObject(f.FieldName, f.Fields, version)...)
 		} else {
-			queryableFields = append(queryableFields, buildQueryableField("", f))
+			queryableFields = append(queryableFields, buildQueryableField("", f, version))
 		}
 	}
 
 	return queryableFields
 }
 
-func buildQueryableForObject(parent string, fields []*Field) []*QueryableField {
+func buildQueryableForObject(parent string, fields []*Field, version int) []*QueryableField {
 	var queryable []*QueryableField
 	for _, nested := range fields {
 		if nested.DataType != ObjectType {
-			queryable = append(queryable, buildQueryableField(parent, nested))
+			queryable = append(queryable, buildQueryableField(parent, nested, version))
 		} else {
-			queryable = append(queryable, buildQueryableForObject(parent+ObjFlattenDelimiter+nested.FieldName, nested.Fields)...)
+			queryable = append(queryable, buildQueryableForObject(parent+ObjFlattenDelimiter+nested.FieldName, nested.Fields, version)...)
 		}
 	}
 
 	return queryable
 }
 
-func buildQueryableField(parent string, f *Field) *QueryableField {
+// buildQueryableField returns the queryable field of the field in the format "version" of the search collection.
+func buildQueryableField(parent string, f *Field, version int) *QueryableField {
 	name := f.FieldName
 	if len(parent) > 0 {
 		name = parent + ObjFlattenDelimiter + f.FieldName
 	}
 
-	return NewQueryableField(name, f.Type())
+	q := NewQueryableField(name, f.Type())
+	q.Dimensions = f.GetDimensions()
+	if f.DataType == ArrayType && len(f.Fields) == 1 && len(f.Fields[0].FieldName) == 0 && f.Fields[0].DataType == StringType && version >= SearchIndexV1 {
+		// the legacy search collections have the arrays of strings packed in a string
+		q.ItemType = StringType
+		q.Indexed = true
+		q.SearchType = searchStringArrayType
+	}
+	if f.DataType == DateTimeType && version < SearchIndexV1 {
+		// the datetime fields can only be faceted once their unix time is indexed
+		q.Faceted = false
+	}
+	return q
 }
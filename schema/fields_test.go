@@ -30,8 +30,13 @@ func TestFieldBuilder_Build(t *testing.T) {
 		require.Equal(t, UUIDType, ToFieldType("string", "", jsonSpecFormatUUID))
 		require.Equal(t, DateTimeType, ToFieldType("string", "", jsonSpecFormatDateTime))
 		require.Equal(t, UnknownType, ToFieldType("string", "random", ""))
+		require.Equal(t, ObjectType, ToFieldType("object", "", ""))
+		require.Equal(t, GeoPointType, ToFieldType("object", "", jsonSpecFormatGeoPoint))
+		require.Equal(t, UnknownType, ToFieldType("object", "", "random"))
+		require.Equal(t, VectorType, ToFieldType("array", "", jsonSpecFormatVector))
 	})
 	t.Run("test supported types", func(t *testing.T) {
+		dims := 3
 		cases := []struct {
 			builder  *FieldBuilder
 			expError error
@@ -60,6 +65,18 @@ func TestFieldBuilder_Build(t *testing.T) {
 				builder:  &FieldBuilder{FieldName: "test", Type: "integer", Primary: &boolTrue},
 				expError: nil,
 			},
+			{
+				builder:  &FieldBuilder{FieldName: "test", Type: "array", Format: "vector", Dimensions: &dims},
+				expError: nil,
+			},
+			{
+				builder:  &FieldBuilder{FieldName: "test", Type: "array", Format: "vector"},
+				expError: api.Errorf(api.Code_INVALID_ARGUMENT, "vector field 'test' needs 'dimensions' between 1 and 4096"),
+			},
+			{
+				builder:  &FieldBuilder{FieldName: "test", Type: "number", Dimensions: &dims},
+				expError: api.Errorf(api.Code_INVALID_ARGUMENT, "'dimensions' is only allowed for vector fields, found on 'test'"),
+			},
 		}
 		for _, c := range cases {
 			_, err := c.builder.Build(false)
@@ -0,0 +1,99 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+const (
+	GeoPointLatitude  = "lat"
+	GeoPointLongitude = "lon"
+)
+
+// GeoPoint is a point on the earth. In the document it is stored as an object {"lat": 48.85, "lon": 2.29} whereas
+// the search backend expects it as a [lat, lon] pair.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// NewGeoPoint converts the decoded JSON value of a geopoint field to GeoPoint. It returns an error if the value is not
+// an object with only "lat" and "lon" set or if the coordinates are out of range.
+func NewGeoPoint(v interface{}) (GeoPoint, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 2 {
+		return GeoPoint{}, api.Errorf(api.Code_INVALID_ARGUMENT, "geopoint should be an object with only 'lat' and 'lon'")
+	}
+
+	lat, err := parseFloat(m[GeoPointLatitude])
+	if err != nil {
+		return GeoPoint{}, err
+	}
+	lon, err := parseFloat(m[GeoPointLongitude])
+	if err != nil {
+		return GeoPoint{}, err
+	}
+
+	p := GeoPoint{Lat: lat, Lon: lon}
+	if err = p.Validate(); err != nil {
+		return GeoPoint{}, err
+	}
+
+	return p, nil
+}
+
+// NewGeoPointFromSearch converts the [lat, lon] pair returned by the search backend to GeoPoint.
+func NewGeoPointFromSearch(v interface{}) (GeoPoint, error) {
+	pair, ok := v.([]interface{})
+	if !ok || len(pair) != 2 {
+		return GeoPoint{}, api.Errorf(api.Code_INTERNAL, "geopoint should be a [lat, lon] pair")
+	}
+
+	lat, err := parseFloat(pair[0])
+	if err != nil {
+		return GeoPoint{}, err
+	}
+	lon, err := parseFloat(pair[1])
+	if err != nil {
+		return GeoPoint{}, err
+	}
+
+	return GeoPoint{Lat: lat, Lon: lon}, nil
+}
+
+func (g GeoPoint) Validate() error {
+	if g.Lat < -90 || g.Lat > 90 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "latitude should be between -90 and 90, found '%v'", g.Lat)
+	}
+	if g.Lon < -180 || g.Lon > 180 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "longitude should be between -180 and 180, found '%v'", g.Lon)
+	}
+
+	return nil
+}
+
+// ToSearch returns the representation of the point as expected by the search backend.
+func (g GeoPoint) ToSearch() []float64 {
+	return []float64{g.Lat, g.Lon}
+}
+
+// ToDocument returns the representation of the point as stored in the document.
+func (g GeoPoint) ToDocument() map[string]interface{} {
+	return map[string]interface{}{
+		GeoPointLatitude:  g.Lat,
+		GeoPointLongitude: g.Lon,
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"sort"
+
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+const (
+	IndexAdded     = "added"
+	IndexUnchanged = "unchanged"
+)
+
+// PlanField is a field of the incoming schema as parsed by the server. Nested fields are named using the dotted
+// notation.
+type PlanField struct {
+	Name       string
+	Type       string
+	PrimaryKey bool
+	Nullable   bool
+}
+
+// PlanIndex is an index of the incoming schema and whether it already exists on the collection.
+type PlanIndex struct {
+	Name   string
+	Fields []string
+	Change string
+}
+
+// Plan describes what a CreateOrUpdateCollection request would do to the collection, without doing it.
+type Plan struct {
+	// Create is set if the collection doesn't exist and the request would create it.
+	Create bool
+	// Revision is the schema revision the collection would be on after the request.
+	Revision int
+	Fields   []PlanField
+	Indexes  []PlanIndex
+	// Diff is only set when the collection exists.
+	Diff              *SchemaDiff
+	SearchDeltaFields []tsApi.Field
+	// Err is the reason the change is rejected by the schema rules, it is nil if the change is compatible.
+	Err error
+}
+
+// NewPlan returns the plan of applying the incoming schema to the existing collection. The existing collection is nil
+// if the collection doesn't exist yet. The incoming factory is not modified.
+func NewPlan(existing *DefaultCollection, incoming *Factory) *Plan {
+	var plan = &Plan{}
+
+	flattened := flattenFields("", incoming.Fields)
+	for name, f := range flattened {
+		plan.Fields = append(plan.Fields, PlanField{
+			Name:       name,
+			Type:       FieldNames[f.DataType],
+			PrimaryKey: f.IsPrimaryKey(),
+			Nullable:   f.IsNullable(),
+		})
+	}
+	sort.Slice(plan.Fields, func(i, j int) bool {
+		return plan.Fields[i].Name < plan.Fields[j].Name
+	})
+
+	var existingIndexes = make(map[string]*Index)
+	if existing != nil {
+		for _, idx := range existing.Indexes.GetIndexes() {
+			existingIndexes[idx.Name] = idx
+		}
+	}
+
+	// indexes are copied so that the dictionary encoded ids of the existing indexes can be set for applying the rules
+	var indexes = &Indexes{}
+	for _, idx := range incoming.Indexes.GetIndexes() {
+		planIdx := PlanIndex{
+			Name:   idx.Name,
+			Change: IndexAdded,
+		}
+		for _, f := range idx.Fields {
+			planIdx.Fields = append(planIdx.Fields, f.FieldName)
+		}
+
+		copied := &Index{Name: idx.Name, Fields: idx.Fields, Id: idx.Id}
+		if e, ok := existingIndexes[idx.Name]; ok {
+			planIdx.Change = IndexUnchanged
+			copied.Id = e.Id
+		}
+		if idx == incoming.Indexes.PrimaryKey {
+			indexes.PrimaryKey = copied
+		}
+		plan.Indexes = append(plan.Indexes, planIdx)
+	}
+
+	if existing == nil {
+		plan.Create = true
+		plan.Revision = 1
+		plan.SearchDeltaFields = GetSearchDeltaFields(nil, incoming.Fields, SearchIndexVersion)
+		return plan
+	}
+
+	planned := &Factory{
+		Name:       incoming.Name,
+		Fields:     incoming.Fields,
+		Indexes:    indexes,
+		Schema:     incoming.Schema,
+		DropFields: incoming.DropFields,
+	}
+
+	plan.Revision = existing.SchVer + 1
+	plan.Diff = Diff(&Factory{Fields: existing.Fields, Indexes: existing.Indexes}, planned)
+	plan.SearchDeltaFields = GetSearchDeltaFields(existing.QueryableFields, incoming.Fields, existing.SearchIndexVersion)
+	plan.Err = ApplySchemaRules(existing, planned)
+
+	return plan
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+func TestNewPlan(t *testing.T) {
+	existingFactory, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "s": {"type": "string"}}, "primary_key": ["id"]}`))
+	require.NoError(t, err)
+	existingFactory.Indexes.PrimaryKey.Id = 10
+	existing := NewDefaultCollection("t1", 1, 2, existingFactory.Fields, existingFactory.Indexes, existingFactory.Schema, "t1")
+
+	t.Run("create", func(t *testing.T) {
+		incoming, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "s": {"type": "string", "nullable": true}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+
+		plan := NewPlan(nil, incoming)
+		require.True(t, plan.Create)
+		require.Equal(t, 1, plan.Revision)
+		require.NoError(t, plan.Err)
+		require.Nil(t, plan.Diff)
+		require.Equal(t, []PlanField{
+			{Name: "id", Type: "int64", PrimaryKey: true},
+			{Name: "s", Type: "string", Nullable: true},
+		}, plan.Fields)
+		require.Equal(t, []PlanIndex{{Name: PrimaryKeyIndexName, Fields: []string{"id"}, Change: IndexAdded}}, plan.Indexes)
+		require.Len(t, plan.SearchDeltaFields, 2)
+	})
+	t.Run("compatible_update", func(t *testing.T) {
+		incoming, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "s": {"type": "string"}, "b": {"type": "boolean"}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+
+		plan := NewPlan(existing, incoming)
+		require.False(t, plan.Create)
+		require.Equal(t, 3, plan.Revision)
+		require.NoError(t, plan.Err)
+		require.False(t, plan.Diff.PrimaryKeyChanged)
+		require.Equal(t, []FieldDiff{{Name: "b", Change: FieldAdded, NewType: "bool"}}, plan.Diff.Fields)
+		require.Equal(t, []PlanIndex{{Name: PrimaryKeyIndexName, Fields: []string{"id"}, Change: IndexUnchanged}}, plan.Indexes)
+		require.Len(t, plan.SearchDeltaFields, 1)
+		require.Equal(t, "b", plan.SearchDeltaFields[0].Name)
+
+		// the incoming indexes are not modified by the plan
+		require.Equal(t, uint32(0), incoming.Indexes.PrimaryKey.Id)
+	})
+	t.Run("incompatible_update", func(t *testing.T) {
+		incoming, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+
+		plan := NewPlan(existing, incoming)
+		require.Equal(t, ErrMissingField, plan.Err)
+		require.Equal(t, []FieldDiff{{Name: "s", Change: FieldRemoved, OldType: "string"}}, plan.Diff.Fields)
+
+		incoming.DropFields = true
+		plan = NewPlan(existing, incoming)
+		require.NoError(t, plan.Err)
+	})
+	t.Run("type_change", func(t *testing.T) {
+		incoming, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "s": {"type": "string", "format": "byte"}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+
+		plan := NewPlan(existing, incoming)
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "data type mismatch for field \"s\""), plan.Err)
+	})
+}
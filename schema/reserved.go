@@ -7,6 +7,7 @@ const (
 	UpdatedAt
 	Metadata
 	IdToSearchKey
+	SchemaVersion
 )
 
 var ReservedFields = [...]string{
@@ -14,6 +15,16 @@ var ReservedFields = [...]string{
 	UpdatedAt:     "updated_at",
 	Metadata:      "metadata",
 	IdToSearchKey: "_tigris_id",
+	SchemaVersion: "_tigris_ver",
+}
+
+// searchDateTimePrefix is the prefix of the search fields that have the unix time of the datetime fields.
+const searchDateTimePrefix = "_tigris_ts_"
+
+// ToSearchDateTimeKey returns the search field that has the unix time in milliseconds of the datetime field, the
+// datetime values are strings in the search backend and can't be filtered by range.
+func ToSearchDateTimeKey(name string) string {
+	return searchDateTimePrefix + name
 }
 
 func IsReservedField(name string) bool {
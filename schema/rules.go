@@ -43,6 +43,9 @@ func (v *FieldSchemaValidator) Validate(existing *DefaultCollection, current *Fa
 	for name, f := range existingFields {
 		c, ok := currentFields[name]
 		if !ok {
+			if current.DropFields {
+				continue
+			}
 			return ErrMissingField
 		}
 
@@ -58,10 +61,11 @@ func (v *FieldSchemaValidator) Validate(existing *DefaultCollection, current *Fa
 // following validations,
 //  - Primary Key Changed, or order of fields part of the primary key is changed
 //  - Collection name change
-//  - Type of existing field is changed
+//  - Type of existing field is changed, unless it is widened i.e. int32 to int64 or an integer to number
+//  - A nullable field is changed back to not nullable
 //  - A validation on field property is also applied like for instance if existing field has some property but it is
 //    removed in the new schema
-//  - Removing a field
+//  - Removing a field, unless DropFields is set in the incoming schema
 //  - Any index exist on the collection will also have same checks like type, etc
 func ApplySchemaRules(existing *DefaultCollection, current *Factory) error {
 	if existing.Name != current.Name {
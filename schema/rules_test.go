@@ -61,3 +61,73 @@ func TestApplySchemaRules(t *testing.T) {
 		require.Equal(t, c.expErr, err)
 	}
 }
+
+func TestApplySchemaRules_Evolution(t *testing.T) {
+	cases := []struct {
+		existing   []byte
+		incoming   []byte
+		dropFields bool
+		expErr     error
+	}{
+		{
+			// int32 widened to int64
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "i": { "type": "integer", "format": "int32"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "i": { "type": "integer"}},"primary_key": ["id"]}`),
+			false,
+			nil,
+		}, {
+			// int64 widened to double
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "i": { "type": "integer"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "i": { "type": "number"}},"primary_key": ["id"]}`),
+			false,
+			nil,
+		}, {
+			// int64 narrowed to int32
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "i": { "type": "integer"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "i": { "type": "integer", "format": "int32"}},"primary_key": ["id"]}`),
+			false,
+			api.Errorf(api.Code_INVALID_ARGUMENT, "data type mismatch for field \"i\""),
+		}, {
+			// primary key can't be widened
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer", "format": "int32"}, "s": { "type": "string"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string"}},"primary_key": ["id"]}`),
+			false,
+			api.Errorf(api.Code_INVALID_ARGUMENT, "data type mismatch for field \"id\""),
+		}, {
+			// field made nullable
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string", "nullable": true}},"primary_key": ["id"]}`),
+			false,
+			nil,
+		}, {
+			// nullable removed
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string", "nullable": true}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string"}},"primary_key": ["id"]}`),
+			false,
+			api.Errorf(api.Code_INVALID_ARGUMENT, "nullable field \"s\" can't be changed to not nullable"),
+		}, {
+			// field dropped
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}},"primary_key": ["id"]}`),
+			true,
+			nil,
+		}, {
+			// primary key field can't be dropped
+			[]byte(`{ "title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string"}},"primary_key": ["id"]}`),
+			[]byte(`{ "title": "t1", "properties": { "s": { "type": "string"}},"primary_key": ["s"]}`),
+			true,
+			api.Errorf(api.Code_INVALID_ARGUMENT, "index fields modified expected \"id\", found \"s\""),
+		},
+	}
+	for _, c := range cases {
+		f1, err := Build("t1", c.existing)
+		require.NoError(t, err)
+		f2, err := Build("t1", c.incoming)
+		require.NoError(t, err)
+		f2.DropFields = c.dropFields
+
+		existingC := NewDefaultCollection(f1.Name, 1, 1, f1.Fields, f1.Indexes, f1.Schema, "f")
+		err = ApplySchemaRules(existingC, f2)
+		require.Equal(t, c.expErr, err)
+	}
+}
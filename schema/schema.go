@@ -66,6 +66,7 @@ const (
 	PrimaryKeyIndexName = "pkey"
 	AutoPrimaryKeyF     = "id"
 	PrimaryKeySchemaK   = "primary_key"
+	OptionsSchemaK      = "options"
 )
 
 var (
@@ -77,6 +78,13 @@ type JSONSchema struct {
 	Description string              `json:"description,omitempty"`
 	Properties  jsoniter.RawMessage `json:"properties,omitempty"`
 	PrimaryKeys []string            `json:"primary_key,omitempty"`
+	Options     *CollectionOptions  `json:"options,omitempty"`
+}
+
+// CollectionOptions are the collection level options that are set in the schema next to the properties.
+type CollectionOptions struct {
+	// PreImage adds the document as it was before the change to the replace, update and delete change events.
+	PreImage bool `json:"pre_image,omitempty"`
 }
 
 // Factory is used as an intermediate step so that collection can be initialized with properly encoded values.
@@ -91,6 +99,9 @@ type Factory struct {
 	// Schema is the raw JSON schema received as part of CreateOrUpdateCollection request. This is stored as-is in the
 	// schema subspace.
 	Schema jsoniter.RawMessage
+	// DropFields allows the fields of the existing collection to be removed from the schema. It is only used when
+	// updating the collection.
+	DropFields bool
 }
 
 // Build is used to deserialize the user json schema into a schema factory.
@@ -199,10 +210,14 @@ func deserializeProperties(properties jsoniter.RawMessage, primaryKeysSet set.Ha
 		if err = jsoniter.Unmarshal(v, &builder); err != nil {
 			return api.Errorf(api.Code_INTERNAL, err.Error())
 		}
-		if builder.Type == jsonSpecArray && builder.Items == nil {
+		if builder.Type == jsonSpecArray && builder.Items == nil && builder.Format != jsonSpecFormatVector {
 			return api.Errorf(api.Code_INVALID_ARGUMENT, "missing items for array field")
 		}
-		if builder.Type == jsonSpecObject && len(builder.Properties) == 0 {
+		if builder.Type == jsonSpecObject && builder.Format == jsonSpecFormatGeoPoint {
+			if len(builder.Properties) > 0 {
+				return api.Errorf(api.Code_INVALID_ARGUMENT, "properties are not allowed for geopoint field")
+			}
+		} else if builder.Type == jsonSpecObject && len(builder.Properties) == 0 {
 			return api.Errorf(api.Code_INVALID_ARGUMENT, "missing properties for object field")
 		}
 
@@ -0,0 +1,39 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+// NewVector converts the decoded JSON value of a vector field to a slice of float64. It returns an error if the value
+// is not an array of numbers.
+func NewVector(v interface{}) ([]float64, error) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "vector should be an array of numbers")
+	}
+
+	vector := make([]float64, len(arr))
+	for i, e := range arr {
+		f, err := parseFloat(e)
+		if err != nil {
+			return nil, err
+		}
+		vector[i] = f
+	}
+
+	return vector, nil
+}
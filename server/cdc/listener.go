@@ -19,6 +19,7 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 )
@@ -26,10 +27,13 @@ import (
 type Tx struct {
 	Id  []byte
 	Ops []*kv.Event
+	// ResumeToken and CreatedAt are filled in by the streamer from the change log entry.
+	ResumeToken []byte              `json:",omitempty"`
+	CreatedAt   *internal.Timestamp `json:",omitempty"`
 }
 
 func (p *Publisher) OnCommit(ctx context.Context, tx transaction.Tx, listener kv.EventListener) error {
-	events := listener.GetEvents()
+	events := publishedEvents(listener.GetEvents())
 	if len(events) == 0 {
 		return nil
 	}
@@ -52,7 +56,25 @@ func (p *Publisher) OnCommit(ctx context.Context, tx transaction.Tx, listener kv
 		return err
 	}
 
-	return tx.SetVersionstampedKey(ctx, key, enc)
+	if err = tx.SetVersionstampedKey(ctx, key, enc); err != nil {
+		return err
+	}
+
+	// wakes up the readers watching the change log
+	return tx.SetVersionstampedValue(ctx, p.keySpace.watchKey, watchValue)
+}
+
+// publishedEvents returns the events of the transaction that are part of the change stream i.e. the writes to the
+// collections and the DDL operations. The writes to the metadata subspaces made by the DDL operations are skipped.
+func publishedEvents(events []*kv.Event) []*kv.Event {
+	published := events[:0:0]
+	for _, e := range events {
+		if kv.IsDDLEvent(e.Op) || metadata.IsUserTable(e.Table) {
+			published = append(published, e)
+		}
+	}
+
+	return published
 }
 
 func (p *Publisher) OnRollback(_ context.Context, _ kv.EventListener) {}
@@ -47,6 +47,19 @@ func (m *Manager) GetPublisher(dbName string) *Publisher {
 	return m.pubs[dbName]
 }
 
+// getKeySpaces returns the change log key spaces of the databases that have a publisher.
+func (m *Manager) getKeySpaces() map[string]*PublisherKeySpace {
+	m.RLock()
+	defer m.RUnlock()
+
+	keySpaces := make(map[string]*PublisherKeySpace, len(m.pubs))
+	for dbName, p := range m.pubs {
+		keySpaces[dbName] = p.keySpace
+	}
+
+	return keySpaces
+}
+
 func (m *Manager) WrapContext(ctx context.Context, dbName string) context.Context {
 	if len(dbName) == 0 {
 		return ctx
@@ -15,30 +15,45 @@
 package cdc
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/store/kv"
 	ulog "github.com/tigrisdata/tigris/util/log"
 )
 
 type Publisher struct {
+	sync.Mutex
+
 	keySpace *PublisherKeySpace
+	// tailer is the reader of the change log shared by all the streamers of the database, it only runs while there is
+	// at least one streamer.
+	tailer *Tailer
 }
 
 type PublisherKeySpace struct {
 	cdcBytes []byte
 	beginKey fdb.Key
 	endKey   fdb.Key
+	// watchKey is changed by every transaction appended to the change log, so that the readers can watch it instead
+	// of polling the change log. It is outside the range of the change log entries.
+	watchKey fdb.Key
 }
 
+var watchValue = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
 func NewPublisherKeySpace(dbName string) *PublisherKeySpace {
 	cdcBytes := []byte("cdc_" + dbName)
 	return &PublisherKeySpace{
 		cdcBytes: cdcBytes,
 		beginKey: getKey(cdcBytes, [10]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}),
 		endKey:   getKey(cdcBytes, [10]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE}),
+		watchKey: subspace.FromBytes(cdcBytes).Pack(tuple.Tuple{"watch"}),
 	}
 }
 
@@ -50,6 +65,35 @@ func getKey(cdcBytes []byte, tv [10]byte) fdb.Key {
 	return k
 }
 
+// getResumeToken returns the versionstamp of the change log key, this is what is handed out to the consumers to resume
+// the stream from.
+func (p *PublisherKeySpace) getResumeToken(key fdb.Key) ([]byte, error) {
+	t, err := subspace.FromBytes(p.cdcBytes).Unpack(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(t) != 1 {
+		return nil, fmt.Errorf("unexpected change log key %v", key)
+	}
+	v, ok := t[0].(tuple.Versionstamp)
+	if !ok {
+		return nil, fmt.Errorf("unexpected change log key %v", key)
+	}
+
+	return v.TransactionVersion[:], nil
+}
+
+// getResumeKey is the inverse of getResumeToken, it returns the change log key of the resume token.
+func (p *PublisherKeySpace) getResumeKey(token []byte) (fdb.Key, error) {
+	var tv [10]byte
+	if len(token) != len(tv) {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid resume token")
+	}
+	copy(tv[:], token)
+
+	return getKey(p.cdcBytes, tv), nil
+}
+
 func (p *PublisherKeySpace) getNextKey() (fdb.Key, error) {
 	s := subspace.FromBytes(p.cdcBytes)
 	v := tuple.IncompleteVersionstamp(0)
@@ -63,20 +107,60 @@ func NewPublisher(dbName string) *Publisher {
 	}
 }
 
-func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore) (*Streamer, error) {
+func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore, opts StreamerOptions) (*Streamer, error) {
 	intDb, err := kvStore.GetInternalDatabase()
 	if ulog.E(err) {
 		return nil, err
 	}
-	s := Streamer{
-		keySpace: p.keySpace,
-		db:       intDb.(fdb.Database),
-		cfg:      config.DefaultConfig.Cdc,
+	s := &Streamer{
+		keySpace:  p.keySpace,
+		db:        intDb.(fdb.Database),
+		cfg:       config.DefaultConfig.Cdc,
+		publisher: p,
+		startTime: opts.StartTime,
+		done:      make(chan struct{}),
+		Txs:       make(chan Tx, config.DefaultConfig.Cdc.StreamBuffer),
 	}
 
-	if err = s.start(); err != nil {
+	if err = s.start(opts); err != nil {
 		return nil, err
 	}
 
-	return &s, nil
+	return s, nil
+}
+
+// subscribe adds the streamer to the tailer of the database, the tailer is started if it is not running. A live
+// streamer starts from the current position of the tailer, otherwise the streamer is expected to catch up on its own.
+func (p *Publisher) subscribe(s *Streamer, live bool) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.tailer == nil {
+		t, err := newTailer(s.db, s.cfg, p.keySpace)
+		if err != nil {
+			return err
+		}
+		p.tailer = t
+	}
+
+	s.tailer = p.tailer
+	p.tailer.subscribe(s, live)
+
+	return nil
+}
+
+// unsubscribe removes the streamer from the tailer and stops the tailer once there are no more streamers.
+func (p *Publisher) unsubscribe(s *Streamer) {
+	p.Lock()
+	defer p.Unlock()
+
+	if s.tailer == nil {
+		return
+	}
+	if s.tailer.unsubscribe(s) == 0 {
+		s.tailer.close()
+		if p.tailer == s.tailer {
+			p.tailer = nil
+		}
+	}
 }
@@ -0,0 +1,64 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestResumeToken(t *testing.T) {
+	ks := NewPublisherKeySpace("db1")
+
+	t.Run("round_trip", func(t *testing.T) {
+		tv := [10]byte{0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x00, 0x01}
+		token, err := ks.getResumeToken(getKey(ks.cdcBytes, tv))
+		require.NoError(t, err)
+		require.Equal(t, tv[:], token)
+
+		key, err := ks.getResumeKey(token)
+		require.NoError(t, err)
+		require.Equal(t, getKey(ks.cdcBytes, tv), key)
+		require.Equal(t, 1, bytes.Compare(key, ks.beginKey))
+	})
+
+	t.Run("invalid_token", func(t *testing.T) {
+		_, err := ks.getResumeKey([]byte{0x01, 0x02})
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid resume token"), err)
+	})
+
+	t.Run("other_database", func(t *testing.T) {
+		_, err := ks.getResumeToken(NewPublisherKeySpace("db2").beginKey)
+		require.Error(t, err)
+	})
+}
+
+func TestPublishedEvents(t *testing.T) {
+	userTable := append([]byte("data"), 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03)
+	events := []*kv.Event{
+		{Op: kv.InsertEvent, Table: []byte("encoding"), Key: []byte{0x01}},
+		{Op: kv.InsertEvent, Table: []byte("schema"), Key: []byte{0x01}},
+		{Op: kv.CreateCollectionEvent, Data: []byte(`{"collection":"c1","revision":1}`)},
+		{Op: kv.InsertEvent, Table: userTable, Key: []byte{0x01}},
+	}
+
+	published := publishedEvents(events)
+	require.Equal(t, []*kv.Event{events[2], events[3]}, published)
+	require.Empty(t, publishedEvents(events[:2]))
+}
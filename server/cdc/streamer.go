@@ -16,104 +16,195 @@ package cdc
 
 import (
 	"bytes"
+	"sync"
 	"time"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
-	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
-	"github.com/tigrisdata/tigris/internal"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/server/config"
 )
 
-type Streamer struct {
-	db       fdb.Database
-	lastKey  fdb.Key
-	cfg      config.CdcConfig
-	keySpace *PublisherKeySpace
-	ticker   *time.Ticker
-	Txs      chan Tx
+// StreamerOptions is the position in the change log the streamer starts from. At most one of them is expected to be
+// set, the zero value starts the stream after the last transaction that is already in the change log.
+type StreamerOptions struct {
+	// ResumeToken is the resume token of the last transaction processed by the consumer.
+	ResumeToken []byte
+	// FromBeginning starts the stream from the oldest transaction in the change log.
+	FromBeginning bool
+	// StartTime starts the stream from the first transaction committed at or after this time.
+	StartTime time.Time
 }
 
-func (s *Streamer) start() error {
-	key, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
-		kr := fdb.KeyRange{Begin: s.keySpace.beginKey, End: s.keySpace.endKey}
-		r := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1, Reverse: true})
+const (
+	// SlowConsumerDrop drops the transactions of a subscriber whose buffer is full.
+	SlowConsumerDrop = "drop"
+	// SlowConsumerDisconnect ends the stream of a subscriber whose buffer is full, the subscriber can resume the stream
+	// from the resume token of the last transaction it processed.
+	SlowConsumerDisconnect = "disconnect"
+	// SlowConsumerBlock waits for the subscriber to make room in its buffer, which holds back all the other subscribers
+	// of the database as well.
+	SlowConsumerBlock = "block"
+)
+
+var ErrSlowConsumer = api.Errorf(api.Code_RESOURCE_EXHAUSTED, "change stream is disconnected as the subscriber is too slow")
 
-		i := r.Iterator()
-		if i.Advance() {
-			kv, err := i.Get()
-			if err != nil {
-				return nil, err
-			}
-			return kv.Key, nil
-		} else {
-			return s.keySpace.beginKey, nil
+// Streamer is a subscriber of the change log of a database. The transactions are sent on the Txs channel, which is
+// closed if the stream ends because of an error, in which case Err returns the reason. A streamer that starts from an
+// older position of the change log first reads the change log on its own until it catches up with the tailer of the
+// database, after that it only receives the transactions read by the tailer.
+type Streamer struct {
+	db        fdb.Database
+	lastKey   fdb.Key
+	startTime time.Time
+	cfg       config.CdcConfig
+	keySpace  *PublisherKeySpace
+	publisher *Publisher
+	tailer    *Tailer
+	// live is set once the streamer is caught up with the tailer, it is protected by the tailer lock.
+	live      bool
+	err       error
+	closeOnce sync.Once
+	done      chan struct{}
+	Txs       chan Tx
+}
+
+func (s *Streamer) start(opts StreamerOptions) error {
+	switch {
+	case len(opts.ResumeToken) > 0:
+		key, err := s.resumeKey(opts.ResumeToken)
+		if err != nil {
+			return err
 		}
+		s.lastKey = key
+	case opts.FromBeginning, !opts.StartTime.IsZero():
+		s.lastKey = s.keySpace.beginKey
+	default:
+		// starts after the last transaction that is read by the tailer
+		return s.publisher.subscribe(s, true)
+	}
+
+	if err := s.publisher.subscribe(s, false); err != nil {
+		return err
+	}
+	go s.catchUp()
+
+	return nil
+}
+
+// resumeKey returns the change log key of the resume token. The entry of the token must still be in the change log,
+// otherwise the transactions after it may have been trimmed as well.
+func (s *Streamer) resumeKey(token []byte) (fdb.Key, error) {
+	key, err := s.keySpace.getResumeKey(token)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		return rtx.Get(key).Get()
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if value.([]byte) == nil {
+		return nil, api.Errorf(api.Code_OUT_OF_RANGE, "resume token is no longer in the change log")
+	}
+
+	return key, nil
+}
 
-	s.lastKey = key.(fdb.Key)
-	s.Txs = make(chan Tx, s.cfg.StreamBuffer)
-	s.ticker = time.NewTicker(s.cfg.StreamInterval)
-	go func() {
-		for range s.ticker.C {
-			if err := s.read(); err != nil {
-				log.Err(err).Msg("read failed")
+// catchUp reads the change log from the start position of the streamer until it is caught up with the tailer.
+func (s *Streamer) catchUp() {
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		txs, err := s.read()
+		if err != nil {
+			log.Err(err).Msg("read failed")
+			s.err = api.Errorf(api.Code_INTERNAL, "reading the change log failed")
+			close(s.Txs)
+			return
+		}
+
+		for _, tx := range txs {
+			if !s.deliver(tx) {
 				return
 			}
 		}
-	}()
 
-	return nil
+		if len(txs) < s.cfg.StreamBatch && s.tailer.join(s) {
+			return
+		}
+	}
 }
 
-func (s *Streamer) read() error {
-	_, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
-		kr := fdb.KeyRange{Begin: s.lastKey, End: s.keySpace.endKey}
-		r := rtx.GetRange(kr, fdb.RangeOptions{Limit: s.cfg.StreamBatch})
-
-		i := r.Iterator()
-		for i.Advance() {
-			kv, err := i.Get()
-			if err != nil {
-				return nil, err
-			}
-
-			if bytes.Equal(s.lastKey, kv.Key) {
-				continue
-			}
+func (s *Streamer) read() ([]Tx, error) {
+	txs, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		return readTxs(rtx, s.keySpace, s.lastKey, s.cfg.StreamBatch)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			data, err := internal.Decode(kv.Value)
-			if err != nil {
-				return nil, err
-			}
+	return txs.([]Tx), nil
+}
 
-			tx := Tx{}
-			err = jsoniter.Unmarshal(data.RawData, &tx)
-			if err != nil {
-				return nil, err
-			}
+// deliver sends the transaction to the subscriber based on the slow consumer policy. The transactions that are already
+// delivered or are before the start time of the streamer are skipped. It returns false once the streamer is done and
+// must not be sent any more transactions.
+func (s *Streamer) deliver(tx Tx) bool {
+	if bytes.Compare(tx.Id, s.lastKey) <= 0 {
+		return true
+	}
 
-			tx.Id = kv.Key
+	if !s.startTime.IsZero() {
+		if tx.CreatedAt != nil && tx.CreatedAt.UnixNano() < s.startTime.UnixNano() {
+			s.lastKey = tx.Id
+			return true
+		}
+		// the change log is ordered by the commit, so there is no need to check the rest of the transactions
+		s.startTime = time.Time{}
+	}
 
-			if len(s.Txs) < cap(s.Txs) {
-				s.lastKey = kv.Key
-				s.Txs <- tx
-			} else {
-				// buffer overflow
-				close(s.Txs)
-				break
-			}
+	switch s.cfg.SlowConsumerPolicy {
+	case SlowConsumerBlock:
+		select {
+		case s.Txs <- tx:
+		case <-s.done:
+			return false
 		}
+	case SlowConsumerDrop:
+		select {
+		case s.Txs <- tx:
+		default:
+			log.Debug().Bytes("tx", tx.ResumeToken).Msg("subscriber buffer is full, dropping transaction")
+		}
+	default:
+		select {
+		case s.Txs <- tx:
+		default:
+			s.err = ErrSlowConsumer
+			close(s.Txs)
+			return false
+		}
+	}
 
-		return nil, nil
-	})
+	s.lastKey = tx.Id
+	return true
+}
 
-	return err
+// Err returns the reason the stream ended, it is only set once the Txs channel is closed.
+func (s *Streamer) Err() error {
+	return s.err
 }
 
 func (s *Streamer) Close() {
-	s.ticker.Stop()
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.publisher.unsubscribe(s)
+	})
 }
@@ -0,0 +1,131 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func testTx(id byte) Tx {
+	return Tx{Id: fdb.Key{id}, CreatedAt: internal.CreateNewTimestamp(int64(id) * int64(time.Second))}
+}
+
+func testStreamer(policy string, buffer int) *Streamer {
+	return &Streamer{
+		lastKey: fdb.Key{0x00},
+		cfg:     config.CdcConfig{SlowConsumerPolicy: policy, StreamBuffer: buffer, StreamBatch: 10},
+		done:    make(chan struct{}),
+		Txs:     make(chan Tx, buffer),
+	}
+}
+
+func TestStreamerDeliver(t *testing.T) {
+	t.Run("disconnect", func(t *testing.T) {
+		s := testStreamer(SlowConsumerDisconnect, 1)
+		require.True(t, s.deliver(testTx(1)))
+		require.False(t, s.deliver(testTx(2)))
+		require.Equal(t, ErrSlowConsumer, s.Err())
+
+		tx, ok := <-s.Txs
+		require.True(t, ok)
+		require.Equal(t, testTx(1), tx)
+		_, ok = <-s.Txs
+		require.False(t, ok)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		s := testStreamer(SlowConsumerDrop, 1)
+		require.True(t, s.deliver(testTx(1)))
+		require.True(t, s.deliver(testTx(2)))
+		require.Equal(t, testTx(1), <-s.Txs)
+		require.True(t, s.deliver(testTx(3)))
+		require.Equal(t, testTx(3), <-s.Txs)
+		require.NoError(t, s.Err())
+	})
+
+	t.Run("block", func(t *testing.T) {
+		s := testStreamer(SlowConsumerBlock, 1)
+		require.True(t, s.deliver(testTx(1)))
+
+		delivered := make(chan bool)
+		go func() {
+			delivered <- s.deliver(testTx(2))
+		}()
+		require.Equal(t, testTx(1), <-s.Txs)
+		require.True(t, <-delivered)
+		require.Equal(t, testTx(2), <-s.Txs)
+
+		// the subscriber going away unblocks the delivery
+		require.True(t, s.deliver(testTx(3)))
+		go func() {
+			delivered <- s.deliver(testTx(4))
+		}()
+		close(s.done)
+		require.False(t, <-delivered)
+	})
+
+	t.Run("skip_delivered_and_start_time", func(t *testing.T) {
+		s := testStreamer(SlowConsumerDisconnect, 10)
+		s.lastKey = fdb.Key{2}
+		s.startTime = time.Unix(4, 0)
+
+		for _, id := range []byte{1, 2, 3, 4, 5} {
+			require.True(t, s.deliver(testTx(id)))
+		}
+		require.Equal(t, 2, len(s.Txs))
+		require.Equal(t, testTx(4), <-s.Txs)
+		require.Equal(t, testTx(5), <-s.Txs)
+		require.Equal(t, fdb.Key{5}, s.lastKey)
+	})
+}
+
+func TestTailerFanOut(t *testing.T) {
+	tailer := &Tailer{lastKey: fdb.Key{2}, subs: make(map[*Streamer]struct{})}
+
+	live := testStreamer(SlowConsumerDisconnect, 10)
+	tailer.subscribe(live, true)
+	require.Equal(t, fdb.Key{2}, live.lastKey)
+
+	catchingUp := testStreamer(SlowConsumerDisconnect, 10)
+	tailer.subscribe(catchingUp, false)
+
+	tailer.publish([]Tx{testTx(3), testTx(4)})
+	require.Equal(t, 2, len(live.Txs))
+	require.Equal(t, 0, len(catchingUp.Txs))
+
+	// the catching up streamer can only join once it has read up to the tailer
+	require.False(t, tailer.join(catchingUp))
+	for _, id := range []byte{1, 2, 3, 4} {
+		require.True(t, catchingUp.deliver(testTx(id)))
+	}
+	require.True(t, tailer.join(catchingUp))
+
+	slow := testStreamer(SlowConsumerDisconnect, 0)
+	tailer.subscribe(slow, true)
+
+	tailer.publish([]Tx{testTx(4), testTx(5)})
+	require.Equal(t, 3, len(live.Txs))
+	require.Equal(t, 5, len(catchingUp.Txs))
+	require.Equal(t, ErrSlowConsumer, slow.Err())
+
+	require.Equal(t, 1, tailer.unsubscribe(live))
+	require.Equal(t, 0, tailer.unsubscribe(catchingUp))
+}
@@ -0,0 +1,219 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// Tailer is the single reader of the end of the change log of a database, the transactions it reads are fanned out
+// to all the live streamers of the database. Once the tailer is caught up with the change log, it waits on the watch
+// key of the change log instead of polling it, the stream interval is only the upper bound of the wait.
+type Tailer struct {
+	sync.Mutex
+
+	db       fdb.Database
+	cfg      config.CdcConfig
+	keySpace *PublisherKeySpace
+	lastKey  fdb.Key
+	subs     map[*Streamer]struct{}
+	stop     chan struct{}
+}
+
+func newTailer(db fdb.Database, cfg config.CdcConfig, keySpace *PublisherKeySpace) (*Tailer, error) {
+	t := &Tailer{
+		db:       db,
+		cfg:      cfg,
+		keySpace: keySpace,
+		subs:     make(map[*Streamer]struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	key, err := db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		kr := fdb.KeyRange{Begin: keySpace.beginKey, End: keySpace.endKey}
+		kvs, err := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1, Reverse: true}).GetSliceWithError()
+		if err != nil {
+			return nil, err
+		}
+		if len(kvs) == 0 {
+			return keySpace.beginKey, nil
+		}
+		return kvs[0].Key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.lastKey = key.(fdb.Key)
+
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Tailer) run() {
+	for {
+		txs, watch, err := t.read()
+		if err != nil {
+			log.Err(err).Msg("read failed")
+		}
+
+		t.publish(txs)
+
+		// a full batch means the tailer is behind the change log, so read again right away
+		if err == nil && len(txs) >= t.cfg.StreamBatch {
+			continue
+		}
+		if !t.wait(watch) {
+			return
+		}
+	}
+}
+
+// read returns the next batch of the change log. If the batch is not full the tailer is caught up, and the watch of
+// the change log is returned along with it.
+func (t *Tailer) read() ([]Tx, fdb.FutureNil, error) {
+	var txs []Tx
+	watch, err := t.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		var err error
+		if txs, err = readTxs(tr, t.keySpace, t.lastKey, t.cfg.StreamBatch); err != nil {
+			return nil, err
+		}
+		if len(txs) < t.cfg.StreamBatch {
+			return tr.Watch(t.keySpace.watchKey), nil
+		}
+		return nil, nil
+	})
+	if err != nil || watch == nil {
+		return txs, nil, err
+	}
+
+	return txs, watch.(fdb.FutureNil), nil
+}
+
+// wait blocks until the watch fires or the stream interval passes. It returns false if the tailer is closed.
+func (t *Tailer) wait(watch fdb.FutureNil) bool {
+	timer := time.NewTimer(t.cfg.StreamInterval)
+	defer timer.Stop()
+
+	fired := make(chan struct{})
+	if watch != nil {
+		defer watch.Cancel()
+		go func() {
+			_ = watch.Get()
+			close(fired)
+		}()
+	}
+
+	select {
+	case <-t.stop:
+		return false
+	case <-timer.C:
+	case <-fired:
+	}
+
+	return true
+}
+
+// publish fans out the transactions to the live streamers. The streamers that are done are removed.
+func (t *Tailer) publish(txs []Tx) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, tx := range txs {
+		t.lastKey = tx.Id
+		for s := range t.subs {
+			if s.live && !s.deliver(tx) {
+				delete(t.subs, s)
+			}
+		}
+	}
+}
+
+func (t *Tailer) subscribe(s *Streamer, live bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	if live {
+		s.lastKey = t.lastKey
+		s.live = true
+	}
+	t.subs[s] = struct{}{}
+}
+
+// join makes the streamer live if it has read the change log at least up to the position of the tailer. Otherwise,
+// the streamer needs to read the change log further.
+func (t *Tailer) join(s *Streamer) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if bytes.Compare(s.lastKey, t.lastKey) < 0 {
+		return false
+	}
+	s.live = true
+
+	return true
+}
+
+// unsubscribe removes the streamer and returns the number of remaining streamers.
+func (t *Tailer) unsubscribe(s *Streamer) int {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.subs, s)
+	return len(t.subs)
+}
+
+func (t *Tailer) close() {
+	close(t.stop)
+}
+
+// readTxs reads up to limit transactions of the change log that come after the key.
+func readTxs(rtx fdb.ReadTransaction, keySpace *PublisherKeySpace, after fdb.Key, limit int) ([]Tx, error) {
+	begin := append(append(fdb.Key{}, after...), 0x00)
+	kvs, err := rtx.GetRange(fdb.KeyRange{Begin: begin, End: keySpace.endKey}, fdb.RangeOptions{Limit: limit}).GetSliceWithError()
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []Tx
+	for _, kv := range kvs {
+		data, err := internal.Decode(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		tx := Tx{}
+		if err = jsoniter.Unmarshal(data.RawData, &tx); err != nil {
+			return nil, err
+		}
+
+		tx.Id = kv.Key
+		tx.CreatedAt = data.CreatedAt
+		if tx.ResumeToken, err = keySpace.getResumeToken(kv.Key); err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
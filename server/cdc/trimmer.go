@@ -0,0 +1,220 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	trimReasonAge  = "age"
+	trimReasonSize = "size"
+)
+
+// Trimmer periodically clears the oldest entries of the change log of the databases that are out of the retention
+// configured for the database. The databases are listed from the tenants loaded by the server, along with the
+// databases that have a publisher in the manager, so the change log of a database is trimmed even if it isn't written
+// to or streamed from since the server started.
+type Trimmer struct {
+	db        fdb.Database
+	mgr       *Manager
+	databases func() []string
+	cfg       config.CdcConfig
+	ticker    *time.Ticker
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// StartTrimmer starts trimming the change logs of the databases returned by "databases" every trim interval.
+func (m *Manager) StartTrimmer(kvStore kv.KeyValueStore, databases func() []string) (*Trimmer, error) {
+	intDb, err := kvStore.GetInternalDatabase()
+	if ulog.E(err) {
+		return nil, err
+	}
+
+	t := &Trimmer{
+		db:        intDb.(fdb.Database),
+		mgr:       m,
+		databases: databases,
+		cfg:       config.DefaultConfig.Cdc,
+		ticker:    time.NewTicker(config.DefaultConfig.Cdc.TrimInterval),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Trimmer) run() {
+	defer close(t.done)
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-t.ticker.C:
+		}
+
+		for dbName, keySpace := range t.keySpaces() {
+			if err := t.trim(dbName, keySpace, time.Now()); err != nil {
+				log.Err(err).Str("db", dbName).Msg("trimming change log failed")
+			}
+		}
+	}
+}
+
+// keySpaces returns the change log key spaces of all the databases known to the server.
+func (t *Trimmer) keySpaces() map[string]*PublisherKeySpace {
+	keySpaces := t.mgr.getKeySpaces()
+	for _, dbName := range t.databases() {
+		if _, ok := keySpaces[dbName]; !ok {
+			keySpaces[dbName] = NewPublisherKeySpace(dbName)
+		}
+	}
+
+	return keySpaces
+}
+
+// Stop stops the trimmer and waits for the trimming in progress, if any, to finish.
+func (t *Trimmer) Stop() {
+	t.ticker.Stop()
+	close(t.stop)
+	<-t.done
+}
+
+func (t *Trimmer) trim(dbName string, keySpace *PublisherKeySpace, now time.Time) error {
+	retention := t.cfg.GetRetention(dbName)
+	if retention.MaxAge > 0 {
+		cutoff := now.Add(-retention.MaxAge).UnixNano()
+		for {
+			trimmed, err := t.trimBatch(keySpace, func(td *internal.TableData) bool {
+				return td.CreatedAt != nil && td.CreatedAt.UnixNano() < cutoff
+			})
+			if err != nil {
+				return err
+			}
+			metrics.IncCdcTrimmed(dbName, trimReasonAge, int64(trimmed))
+
+			if trimmed < t.cfg.TrimBatch {
+				break
+			}
+		}
+	}
+
+	size, oldest, err := t.backlog(keySpace)
+	if err != nil {
+		return err
+	}
+
+	// the size is an estimate that is not updated right away, so only a single batch is cleared in every run
+	if retention.MaxSize > 0 && size > retention.MaxSize {
+		trimmed, err := t.trimBatch(keySpace, func(*internal.TableData) bool { return true })
+		if err != nil {
+			return err
+		}
+		metrics.IncCdcTrimmed(dbName, trimReasonSize, int64(trimmed))
+
+		if size, oldest, err = t.backlog(keySpace); err != nil {
+			return err
+		}
+	}
+
+	var oldestAge float64
+	if oldest != nil {
+		oldestAge = now.Sub(time.Unix(0, oldest.UnixNano())).Seconds()
+	}
+	metrics.UpdateCdcBacklog(dbName, size, oldestAge)
+
+	return nil
+}
+
+// trimBatch clears the oldest entries of the change log for which expired returns true. It stops at the first entry
+// that is not expired or after the trim batch size. It returns the number of cleared entries.
+func (t *Trimmer) trimBatch(keySpace *PublisherKeySpace, expired func(*internal.TableData) bool) (int, error) {
+	trimmed, err := t.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		kr := fdb.KeyRange{Begin: keySpace.beginKey, End: keySpace.endKey}
+		kvs, err := tr.Snapshot().GetRange(kr, fdb.RangeOptions{Limit: t.cfg.TrimBatch}).GetSliceWithError()
+		if err != nil {
+			return 0, err
+		}
+
+		count, err := expiredPrefix(kvs, expired)
+		if err != nil || count == 0 {
+			return 0, err
+		}
+
+		tr.ClearRange(fdb.KeyRange{Begin: keySpace.beginKey, End: append(kvs[count-1].Key, 0x00)})
+		return count, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return trimmed.(int), nil
+}
+
+// backlog returns the estimated size in bytes of the change log and the creation time of the oldest retained entry.
+// The oldest entry is nil if the change log is empty.
+func (t *Trimmer) backlog(keySpace *PublisherKeySpace) (int64, *internal.Timestamp, error) {
+	var size int64
+	var oldest *internal.Timestamp
+	_, err := t.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		kr := fdb.KeyRange{Begin: keySpace.beginKey, End: keySpace.endKey}
+
+		var err error
+		if size, err = rtx.GetEstimatedRangeSizeBytes(kr).Get(); err != nil {
+			return nil, err
+		}
+
+		kvs, err := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1}).GetSliceWithError()
+		if err != nil || len(kvs) == 0 {
+			return nil, err
+		}
+
+		td, err := internal.Decode(kvs[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		oldest = td.CreatedAt
+
+		return nil, nil
+	})
+
+	return size, oldest, err
+}
+
+// expiredPrefix returns the number of entries from the start of the change log entries for which expired returns true.
+func expiredPrefix(kvs []fdb.KeyValue, expired func(*internal.TableData) bool) (int, error) {
+	for i, kv := range kvs {
+		td, err := internal.Decode(kv.Value)
+		if err != nil {
+			return 0, err
+		}
+		if !expired(td) {
+			return i, nil
+		}
+	}
+
+	return len(kvs), nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestExpiredPrefix(t *testing.T) {
+	now := time.Now()
+	entry := func(age time.Duration) fdb.KeyValue {
+		td := internal.NewTableDataWithTS(internal.CreateNewTimestamp(now.Add(-age).UnixNano()), nil, []byte(`{}`))
+		enc, err := internal.Encode(td)
+		require.NoError(t, err)
+		return fdb.KeyValue{Value: enc}
+	}
+	olderThan := func(age time.Duration) func(*internal.TableData) bool {
+		cutoff := now.Add(-age).UnixNano()
+		return func(td *internal.TableData) bool {
+			return td.CreatedAt.UnixNano() < cutoff
+		}
+	}
+
+	kvs := []fdb.KeyValue{entry(3 * time.Hour), entry(2 * time.Hour), entry(time.Minute), entry(2 * time.Hour)}
+	for _, c := range []struct {
+		age      time.Duration
+		expected int
+	}{
+		{4 * time.Hour, 0},
+		{150 * time.Minute, 1},
+		// the entries after the first retained one are never trimmed
+		{time.Hour, 2},
+		{time.Second, 4},
+	} {
+		count, err := expiredPrefix(kvs, olderThan(c.age))
+		require.NoError(t, err)
+		require.Equal(t, c.expected, count)
+	}
+
+	count, err := expiredPrefix(nil, olderThan(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestGetRetention(t *testing.T) {
+	cfg := config.CdcConfig{
+		Retention: config.CdcRetentionConfig{MaxAge: time.Hour},
+		DatabaseRetention: map[string]config.CdcRetentionConfig{
+			"db2": {MaxSize: 1024},
+		},
+	}
+
+	require.Equal(t, config.CdcRetentionConfig{MaxAge: time.Hour}, cfg.GetRetention("db1"))
+	require.Equal(t, config.CdcRetentionConfig{MaxSize: 1024}, cfg.GetRetention("db2"))
+}
+
+func TestTrimmerKeySpaces(t *testing.T) {
+	mgr := NewManager()
+	mgr.GetPublisher("db1")
+
+	trimmer := &Trimmer{
+		mgr:       mgr,
+		databases: func() []string { return []string{"db1", "db2"} },
+		ticker:    time.NewTicker(time.Hour),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	keySpaces := trimmer.keySpaces()
+	require.Len(t, keySpaces, 2)
+	require.Equal(t, mgr.GetPublisher("db1").keySpace, keySpaces["db1"])
+	require.Equal(t, NewPublisherKeySpace("db2"), keySpaces["db2"])
+
+	go trimmer.run()
+	trimmer.Stop()
+
+	select {
+	case <-trimmer.done:
+	default:
+		require.Fail(t, "trimmer is still running")
+	}
+}
@@ -28,12 +28,15 @@ type ServerConfig struct {
 
 type Config struct {
 	Log          log.LogConfig
-	Server       ServerConfig    `yaml:"server" json:"server"`
-	Auth         AuthConfig      `yaml:"auth" json:"auth"`
-	Cdc          CdcConfig       `yaml:"cdc" json:"cdc"`
-	Search       SearchConfig    `yaml:"search" json:"search"`
-	Tracing      TracingConfig   `yaml:"tracing" json:"tracing"`
-	Profiling    ProfilingConfig `yaml:"profiling" json:"profiling"`
+	Server       ServerConfig      `yaml:"server" json:"server"`
+	Auth         AuthConfig        `yaml:"auth" json:"auth"`
+	Cdc          CdcConfig         `yaml:"cdc" json:"cdc"`
+	Search       SearchConfig      `yaml:"search" json:"search"`
+	Schema       SchemaConfig      `yaml:"schema" json:"schema"`
+	Trigger      TriggerConfig     `yaml:"trigger" json:"trigger"`
+	Transaction  TransactionConfig `yaml:"transaction" json:"transaction"`
+	Tracing      TracingConfig     `yaml:"tracing" json:"tracing"`
+	Profiling    ProfilingConfig   `yaml:"profiling" json:"profiling"`
 	Metrics      MetricsConfig
 	FoundationDB FoundationDBConfig
 }
@@ -48,10 +51,83 @@ type AuthConfig struct {
 }
 
 type CdcConfig struct {
-	Enabled        bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// StreamInterval is the longest the change log reader of a database waits for a new transaction before reading the
+	// change log again, in case the watch of the change log didn't fire.
 	StreamInterval time.Duration
 	StreamBatch    int
-	StreamBuffer   int
+	// StreamBuffer is the number of transactions buffered for every subscriber, SlowConsumerPolicy is what happens once
+	// the buffer of a subscriber is full. It is one of "drop", "disconnect" or "block".
+	StreamBuffer       int
+	SlowConsumerPolicy string `mapstructure:"slow_consumer_policy" yaml:"slow_consumer_policy" json:"slow_consumer_policy"`
+	// Retention is applied to the change log of every database that is not listed in DatabaseRetention.
+	Retention         CdcRetentionConfig            `mapstructure:"retention" yaml:"retention" json:"retention"`
+	DatabaseRetention map[string]CdcRetentionConfig `mapstructure:"database_retention" yaml:"database_retention" json:"database_retention"`
+	// TrimInterval is how often the change log is trimmed, TrimBatch is the number of entries cleared at a time.
+	TrimInterval time.Duration `mapstructure:"trim_interval" yaml:"trim_interval" json:"trim_interval"`
+	TrimBatch    int           `mapstructure:"trim_batch" yaml:"trim_batch" json:"trim_batch"`
+	// Webhook controls the delivery of the change events to the webhooks registered on the databases.
+	Webhook WebhookConfig `mapstructure:"webhook" yaml:"webhook" json:"webhook"`
+}
+
+type WebhookConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// BatchSize is the most events posted in a single request, BatchWait is how long the events are held back to fill
+	// up a batch.
+	BatchSize int           `mapstructure:"batch_size" yaml:"batch_size" json:"batch_size"`
+	BatchWait time.Duration `mapstructure:"batch_wait" yaml:"batch_wait" json:"batch_wait"`
+	Timeout   time.Duration `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
+	// MaxAttempts is the number of times a batch is posted before it is given up on and moved to the dead letter
+	// collection of the webhook. The wait between the attempts doubles from MinBackoff up to MaxBackoff.
+	MaxAttempts int           `mapstructure:"max_attempts" yaml:"max_attempts" json:"max_attempts"`
+	MinBackoff  time.Duration `mapstructure:"min_backoff" yaml:"min_backoff" json:"min_backoff"`
+	MaxBackoff  time.Duration `mapstructure:"max_backoff" yaml:"max_backoff" json:"max_backoff"`
+	// RefreshInterval is how often the registered webhooks are reloaded and their leases renewed, a lease that is not
+	// renewed for LeaseTimeout is taken over by another server.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" yaml:"refresh_interval" json:"refresh_interval"`
+	LeaseTimeout    time.Duration `mapstructure:"lease_timeout" yaml:"lease_timeout" json:"lease_timeout"`
+	// AllowedHosts are the hosts that the webhooks can post to even though they are, or resolve to, a loopback,
+	// link-local or private address. The webhooks can't post to any of these addresses otherwise.
+	AllowedHosts []string `mapstructure:"allowed_hosts" yaml:"allowed_hosts" json:"allowed_hosts"`
+}
+
+// CdcRetentionConfig limits the change log of a database by the age of the entries and by the size of the change log
+// in bytes. A zero value disables the corresponding limit.
+type CdcRetentionConfig struct {
+	MaxAge  time.Duration `mapstructure:"max_age" yaml:"max_age" json:"max_age"`
+	MaxSize int64         `mapstructure:"max_size" yaml:"max_size" json:"max_size"`
+}
+
+// GetRetention returns the retention of the change log of the database.
+func (c *CdcConfig) GetRetention(dbName string) CdcRetentionConfig {
+	if r, ok := c.DatabaseRetention[dbName]; ok {
+		return r
+	}
+
+	return c.Retention
+}
+
+// SchemaConfig controls the rewrite of the documents once fields are dropped from the schema of a collection. If the
+// rewrite is disabled then the dropped fields are only removed while reading the documents.
+type SchemaConfig struct {
+	BackgroundRewrite bool `mapstructure:"background_rewrite" yaml:"background_rewrite" json:"background_rewrite"`
+	RewriteBatchSize  int  `mapstructure:"rewrite_batch_size" yaml:"rewrite_batch_size" json:"rewrite_batch_size"`
+}
+
+// TriggerConfig controls the triggers of the collections. MaxDepth is the longest chain of triggers fired by the writes
+// of other triggers in a single transaction.
+type TriggerConfig struct {
+	Enabled  bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	MaxDepth int  `mapstructure:"max_depth" yaml:"max_depth" json:"max_depth"`
+}
+
+// TransactionConfig limits the explicit transactions started by BeginTransaction. A transaction that is not used for
+// IdleTimeout, or that is still open after MaxLifetime, is rolled back by the sweep that runs every SweepInterval. A
+// zero value disables the corresponding limit.
+type TransactionConfig struct {
+	IdleTimeout   time.Duration `mapstructure:"idle_timeout" yaml:"idle_timeout" json:"idle_timeout"`
+	MaxLifetime   time.Duration `mapstructure:"max_lifetime" yaml:"max_lifetime" json:"max_lifetime"`
+	SweepInterval time.Duration `mapstructure:"sweep_interval" yaml:"sweep_interval" json:"sweep_interval"`
 }
 
 type TracingConfig struct {
@@ -77,9 +153,11 @@ type MetricsConfig struct {
 	// Global switch
 	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
 	// Individual metric group configs
-	Grpc   GrpcMetricsConfig
-	Fdb    FdbMetricsConfig
-	Search SearchMetricsConfig
+	Grpc    GrpcMetricsConfig
+	Fdb     FdbMetricsConfig
+	Search  SearchMetricsConfig
+	Cdc     CdcMetricsConfig
+	Session SessionMetricsConfig
 }
 
 type GrpcMetricsConfig struct {
@@ -94,6 +172,14 @@ type FdbMetricsConfig struct {
 	ResponseTime bool `mapstructure:"response_time" yaml:"response_time" json:"response_time"`
 }
 
+type CdcMetricsConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+}
+
+type SessionMetricsConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+}
+
 type SearchMetricsConfig struct {
 	Enabled      bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
 	Counters     bool `mapstructure:"counters" yaml:"counters" json:"counters"`
@@ -119,16 +205,61 @@ var DefaultConfig = Config{
 		AdminNamespaces:          []string{"tigris-admin"},
 	},
 	Cdc: CdcConfig{
-		Enabled:        false,
-		StreamInterval: 500 * time.Millisecond,
-		StreamBatch:    100,
-		StreamBuffer:   200,
+		Enabled:            false,
+		StreamInterval:     500 * time.Millisecond,
+		StreamBatch:        100,
+		StreamBuffer:       200,
+		SlowConsumerPolicy: "disconnect",
+		Retention: CdcRetentionConfig{
+			MaxAge:  24 * time.Hour,
+			MaxSize: 1024 * 1024 * 1024,
+		},
+		TrimInterval: time.Minute,
+		TrimBatch:    1000,
+		Webhook: WebhookConfig{
+			Enabled:         true,
+			BatchSize:       100,
+			BatchWait:       time.Second,
+			Timeout:         10 * time.Second,
+			MaxAttempts:     8,
+			MinBackoff:      500 * time.Millisecond,
+			MaxBackoff:      time.Minute,
+			RefreshInterval: 10 * time.Second,
+			LeaseTimeout:    30 * time.Second,
+		},
 	},
 	Search: SearchConfig{
+		Backend:      "typesense",
 		Host:         "localhost",
 		Port:         8108,
 		ReadEnabled:  true,
 		WriteEnabled: true,
+		Indexing: SearchIndexingConfig{
+			Async:            true,
+			Partitions:       16,
+			BatchSize:        100,
+			PollInterval:     100 * time.Millisecond,
+			MaxAttempts:      8,
+			MinBackoff:       100 * time.Millisecond,
+			MaxBackoff:       30 * time.Second,
+			RefreshInterval:  10 * time.Second,
+			LeaseTimeout:     30 * time.Second,
+			WaitTimeout:      5 * time.Second,
+			ReindexBatchSize: 500,
+		},
+	},
+	Schema: SchemaConfig{
+		BackgroundRewrite: false,
+		RewriteBatchSize:  500,
+	},
+	Trigger: TriggerConfig{
+		Enabled:  true,
+		MaxDepth: 8,
+	},
+	Transaction: TransactionConfig{
+		IdleTimeout:   10 * time.Second,
+		MaxLifetime:   time.Minute,
+		SweepInterval: time.Second,
 	},
 	Tracing: TracingConfig{
 		Enabled:             false,
@@ -158,6 +289,12 @@ var DefaultConfig = Config{
 			Counters:     true,
 			ResponseTime: true,
 		},
+		Cdc: CdcMetricsConfig{
+			Enabled: true,
+		},
+		Session: SessionMetricsConfig{
+			Enabled: true,
+		},
 	},
 }
 
@@ -167,9 +304,40 @@ type FoundationDBConfig struct {
 }
 
 type SearchConfig struct {
+	// Backend is "typesense" for the Typesense server at Host and Port, or "embedded" to search in process.
+	Backend      string `mapstructure:"backend" json:"backend" yaml:"backend"`
 	Host         string `mapstructure:"host" json:"host" yaml:"host"`
 	Port         int16  `mapstructure:"port" json:"port" yaml:"port"`
 	AuthKey      string `mapstructure:"auth_key" json:"auth_key" yaml:"auth_key"`
 	ReadEnabled  bool   `mapstructure:"read_enabled" yaml:"read_enabled" json:"read_enabled"`
 	WriteEnabled bool   `mapstructure:"write_enabled" yaml:"write_enabled" json:"write_enabled"`
+	// Indexing controls how the changes to the collections are indexed in the search store.
+	Indexing SearchIndexingConfig `mapstructure:"indexing" yaml:"indexing" json:"indexing"`
+}
+
+// SearchIndexingConfig controls the indexing of the changes in the search store. With Async the changes are queued in
+// the transaction making them and the background workers index them, otherwise they are indexed once the transaction
+// is committed.
+type SearchIndexingConfig struct {
+	Async bool `mapstructure:"async" yaml:"async" json:"async"`
+	// Partitions is the number of queues the collections are spread over, the changes of a queue are indexed in the
+	// commit order by a single worker at a time.
+	Partitions int `mapstructure:"partitions" yaml:"partitions" json:"partitions"`
+	// BatchSize is the most queued documents indexed at a time, PollInterval is how often an empty queue is read.
+	BatchSize    int           `mapstructure:"batch_size" yaml:"batch_size" json:"batch_size"`
+	PollInterval time.Duration `mapstructure:"poll_interval" yaml:"poll_interval" json:"poll_interval"`
+	// MaxAttempts is the number of times a batch is indexed before the documents rejected by the search store are
+	// skipped. The failures of the search store itself are retried until they succeed, the wait between the attempts
+	// doubles from MinBackoff up to MaxBackoff.
+	MaxAttempts int           `mapstructure:"max_attempts" yaml:"max_attempts" json:"max_attempts"`
+	MinBackoff  time.Duration `mapstructure:"min_backoff" yaml:"min_backoff" json:"min_backoff"`
+	MaxBackoff  time.Duration `mapstructure:"max_backoff" yaml:"max_backoff" json:"max_backoff"`
+	// RefreshInterval is how often the leases of the queues are renewed, a lease that is not renewed for LeaseTimeout
+	// is taken over by another server.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" yaml:"refresh_interval" json:"refresh_interval"`
+	LeaseTimeout    time.Duration `mapstructure:"lease_timeout" yaml:"lease_timeout" json:"lease_timeout"`
+	// WaitTimeout is the longest a write asking to wait for its changes to be indexed is held back after the commit.
+	WaitTimeout time.Duration `mapstructure:"wait_timeout" yaml:"wait_timeout" json:"wait_timeout"`
+	// ReindexBatchSize is the number of rows read in a transaction and indexed at a time when a collection is reindexed.
+	ReindexBatchSize int `mapstructure:"reindex_batch_size" yaml:"reindex_batch_size" json:"reindex_batch_size"`
 }
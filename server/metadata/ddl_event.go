@@ -0,0 +1,58 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// DDLEvent is the data of the change event of a DDL operation. The change event is self-contained i.e. it carries the
+// names and not the dictionary encoded ids, so that it can be decoded even after the collection or the database is
+// dropped.
+type DDLEvent struct {
+	Collection string              `json:"collection,omitempty"`
+	Schema     jsoniter.RawMessage `json:"schema,omitempty"`
+	Revision   int                 `json:"revision,omitempty"`
+}
+
+// DecodeDDLEvent returns the description of the change from the data of a DDL change event.
+func DecodeDDLEvent(data []byte) (*DDLEvent, error) {
+	var event DDLEvent
+	if err := jsoniter.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// addDDLEvent buffers the change event in the event listener of the transaction, so that it is published in the same
+// order as the writes to the collections made in the transaction.
+func addDDLEvent(ctx context.Context, op string, event *DDLEvent) error {
+	listener := kv.GetEventListener(ctx)
+	if !listener.Buffering() {
+		return nil
+	}
+
+	data, err := jsoniter.Marshal(event)
+	if err != nil {
+		return err
+	}
+	listener.OnDDL(op, data)
+
+	return nil
+}
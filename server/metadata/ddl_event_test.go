@@ -0,0 +1,49 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestDDLEvent(t *testing.T) {
+	ctx := kv.WrapEventListenerCtx(context.Background())
+	require.NoError(t, addDDLEvent(ctx, kv.UpdateCollectionEvent, &DDLEvent{
+		Collection: "orders",
+		Schema:     []byte(`{"title":"orders"}`),
+		Revision:   2,
+	}))
+	require.NoError(t, addDDLEvent(ctx, kv.DropDatabaseEvent, &DDLEvent{}))
+
+	events := kv.GetEventListener(ctx).GetEvents()
+	require.Len(t, events, 2)
+	require.Equal(t, kv.UpdateCollectionEvent, events[0].Op)
+	require.Nil(t, events[0].Table)
+
+	ddl, err := DecodeDDLEvent(events[0].Data)
+	require.NoError(t, err)
+	require.Equal(t, &DDLEvent{Collection: "orders", Schema: []byte(`{"title":"orders"}`), Revision: 2}, ddl)
+
+	ddl, err = DecodeDDLEvent(events[1].Data)
+	require.NoError(t, err)
+	require.Equal(t, &DDLEvent{}, ddl)
+
+	// nothing is buffered without the listener of the session
+	require.NoError(t, addDDLEvent(context.Background(), kv.DropDatabaseEvent, &DDLEvent{}))
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// lease is the value of a lease key, it is held by the owner until ExpiresAt, a unix time in nanoseconds.
+type lease struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// acquireLease acquires or extends the lease stored in the key. The lease is acquired if there is no lease, the lease is
+// expired, or it is already held by the owner. It returns false if the lease is held by someone else.
+func acquireLease(ctx context.Context, tx transaction.Tx, key keys.Key, owner string, now time.Time, ttl time.Duration) (bool, error) {
+	data, err := readValue(ctx, tx, key)
+	if err != nil {
+		return false, err
+	}
+
+	if data != nil {
+		var l lease
+		if err := jsoniter.Unmarshal(data.RawData, &l); err != nil {
+			return false, err
+		}
+		if l.Owner != owner && l.ExpiresAt > now.UnixNano() {
+			return false, nil
+		}
+	}
+
+	value, err := jsoniter.Marshal(&lease{Owner: owner, ExpiresAt: now.Add(ttl).UnixNano()})
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Replace(ctx, key, internal.NewTableData(value)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func readValue(ctx context.Context, tx transaction.Tx, key keys.Key) (*internal.TableData, error) {
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var row kv.KeyValue
+	if it.Next(&row) {
+		return row.Data, nil
+	}
+
+	return nil, it.Err()
+}
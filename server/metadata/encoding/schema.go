@@ -30,6 +30,19 @@ var (
 	schVersion = []byte{0x01}
 )
 
+const (
+	// keyAuthor is used to store the author of a schema revision next to the revision.
+	keyAuthor = "author"
+)
+
+// SchemaRevision is a stored revision of the schema of a collection along with the audit information of the revision.
+type SchemaRevision struct {
+	Revision  int
+	Schema    []byte
+	CreatedAt *internal.Timestamp
+	CreatedBy string
+}
+
 // SchemaSubspace is used to manage schemas in schema subspace.
 type SchemaSubspace struct {
 	MDNameRegistry
@@ -60,6 +73,18 @@ func (s *SchemaSubspace) Put(ctx context.Context, tx transaction.Tx, namespaceId
 	return nil
 }
 
+// PutAuthor is to persist the author of a schema revision. It is stored separately from the schema so that the
+// schemas stored before the audit was added don't need any migration.
+func (s *SchemaSubspace) PutAuthor(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collId uint32, revision int, author string) error {
+	key := keys.NewKey(s.SchemaSubspaceName(), schVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), UInt32ToByte(collId), keyAuthor, UInt32ToByte(uint32(revision)))
+	if err := tx.Replace(ctx, key, internal.NewTableData([]byte(author))); err != nil {
+		log.Debug().Str("key", key.String()).Str("author", author).Err(err).Msg("storing schema author failed")
+		return err
+	}
+
+	return nil
+}
+
 // GetLatest returns the latest version stored for a collection inside a given namespace and database.
 func (s *SchemaSubspace) GetLatest(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collId uint32) ([]byte, int, error) {
 	schemas, revisions, err := s.Get(ctx, tx, namespaceId, dbId, collId)
@@ -107,6 +132,65 @@ func (s *SchemaSubspace) Get(ctx context.Context, tx transaction.Tx, namespaceId
 	return schemas, revisions, nil
 }
 
+// GetRevisions returns all the revisions stored for a collection inside a given namespace and database sorted by the
+// revision number. CreatedBy is empty for the revisions stored without an author.
+func (s *SchemaSubspace) GetRevisions(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collId uint32) ([]*SchemaRevision, error) {
+	authors, err := s.getAuthors(ctx, tx, namespaceId, dbId, collId)
+	if err != nil {
+		return nil, err
+	}
+
+	key := keys.NewKey(s.SchemaSubspaceName(), schVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), UInt32ToByte(collId), keyEnd)
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*SchemaRevision
+	var row kv.KeyValue
+	for it.Next(&row) {
+		revision, ok := row.Key[len(row.Key)-1].([]byte)
+		if !ok {
+			return nil, api.Errorf(api.Code_INTERNAL, "not able to extract revision from schema %v", row.Key)
+		}
+		revisions = append(revisions, &SchemaRevision{
+			Revision:  int(ByteToUInt32(revision)),
+			Schema:    row.Data.RawData,
+			CreatedAt: row.Data.CreatedAt,
+			CreatedBy: authors[ByteToUInt32(revision)],
+		})
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+func (s *SchemaSubspace) getAuthors(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collId uint32) (map[uint32]string, error) {
+	key := keys.NewKey(s.SchemaSubspaceName(), schVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), UInt32ToByte(collId), keyAuthor)
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var authors = make(map[uint32]string)
+	var row kv.KeyValue
+	for it.Next(&row) {
+		revision, ok := row.Key[len(row.Key)-1].([]byte)
+		if !ok {
+			return nil, api.Errorf(api.Code_INTERNAL, "not able to extract revision from schema author %v", row.Key)
+		}
+		authors[ByteToUInt32(revision)] = string(row.Data.RawData)
+	}
+
+	return authors, it.Err()
+}
+
 // Delete is to remove schema for a given namespace, database and collection.
 func (s *SchemaSubspace) Delete(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collId uint32) error {
 	key := keys.NewKey(s.SchemaSubspaceName(), schVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), UInt32ToByte(collId), keyEnd)
@@ -115,6 +199,12 @@ func (s *SchemaSubspace) Delete(ctx context.Context, tx transaction.Tx, namespac
 		return err
 	}
 
+	authorKey := keys.NewKey(s.SchemaSubspaceName(), schVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), UInt32ToByte(collId), keyAuthor)
+	if err := tx.Delete(ctx, authorKey); err != nil {
+		log.Debug().Str("key", authorKey.String()).Err(err).Msg("deleting schema authors failed")
+		return err
+	}
+
 	log.Debug().Str("key", key.String()).Msg("deleting schema succeed")
 	return nil
 }
@@ -167,6 +167,44 @@ func TestSchemaSubspace(t *testing.T) {
 		require.Equal(t, 2, revisions[1])
 		require.NoError(t, tx.Commit(ctx))
 	})
+	t.Run("put_get_revisions", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s := NewSchemaStore(&TestMDNameRegistry{
+			SchemaSB: "test_schema",
+		})
+		_ = kvStore.DropTable(ctx, s.SchemaSubspaceName())
+
+		schema1 := []byte(`{"title": "collection1", "properties": {"K1": {"type": "string"}}, "primary_key": ["K1"]}`)
+		schema2 := []byte(`{"title": "collection1", "properties": {"K1": {"type": "string"}, "K2": {"type": "integer"}}, "primary_key": ["K1"]}`)
+
+		tm := transaction.NewManager(kvStore)
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, s.Put(ctx, tx, 1, 2, 3, schema1, 1))
+		require.NoError(t, s.Put(ctx, tx, 1, 2, 3, schema2, 2))
+		require.NoError(t, s.PutAuthor(ctx, tx, 1, 2, 3, 2, "test@tigrisdata.com"))
+
+		revisions, err := s.GetRevisions(ctx, tx, 1, 2, 3)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		require.Equal(t, 1, revisions[0].Revision)
+		require.Equal(t, schema1, revisions[0].Schema)
+		require.Empty(t, revisions[0].CreatedBy)
+		require.Equal(t, 2, revisions[1].Revision)
+		require.Equal(t, schema2, revisions[1].Schema)
+		require.Equal(t, "test@tigrisdata.com", revisions[1].CreatedBy)
+		require.NotNil(t, revisions[1].CreatedAt)
+
+		// authors are not returned as schema revisions
+		_, revs, err := s.Get(ctx, tx, 1, 2, 3)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, revs)
+		require.NoError(t, tx.Commit(ctx))
+
+		_ = kvStore.DropTable(ctx, s.SchemaSubspaceName())
+	})
 	t.Run("put_delete_get", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
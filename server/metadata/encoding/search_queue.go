@@ -0,0 +1,167 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+var (
+	searchQueueVersion = []byte{0x01}
+)
+
+const (
+	queueEntryKey   = "entry"
+	queuePendingKey = "pending"
+	queueLeaseKey   = "lease"
+)
+
+// SearchQueueEntry is a document changed by a transaction that is yet to be indexed in the search store. Only the key
+// of the document is queued, the document is read from the collection once the entry is indexed so that the search
+// store always ends up with the latest value of the document. The entries are stored in the search queue subspace as
+// below,
+//
+//	["search_queue", 0x01, "entry", 3, <versionstamp>] => {"tx_id": "...", "db": "db1", "collection": "c1", ...}
+//	["search_queue", 0x01, "pending", "<tx id>", 3, 0] => nil
+//	["search_queue", 0x01, "lease", 3] => {"owner": "...", "expires_at": ...}
+//
+// where 3 is the partition of the queue the collection is assigned to. The versionstamp of the committing transaction
+// keeps the entries of a partition in the commit order, the pending key lets a write wait until the changes of its
+// transaction are indexed.
+type SearchQueueEntry struct {
+	TxId       string `json:"tx_id"`
+	Namespace  string `json:"namespace"`
+	Db         string `json:"db"`
+	Collection string `json:"collection"`
+	Table      []byte `json:"table"`
+	Key        []byte `json:"key"`
+
+	// the below are not stored in the value, they are filled in while reading the entries.
+	Partition    int                 `json:"-"`
+	Versionstamp tuple.Versionstamp  `json:"-"`
+	CreatedAt    *internal.Timestamp `json:"-"`
+}
+
+// SearchQueueSubspace is used to manage the queue of the changes that are indexed in the search store asynchronously.
+type SearchQueueSubspace struct {
+	MDNameRegistry
+}
+
+func NewSearchQueueStore(mdNameRegistry MDNameRegistry) *SearchQueueSubspace {
+	return &SearchQueueSubspace{
+		MDNameRegistry: mdNameRegistry,
+	}
+}
+
+// Enqueue appends the entry to the partition as part of the transaction, the key of the entry is only assigned once the
+// transaction is committed. The order distinguishes the entries appended by the same transaction to the same partition.
+func (q *SearchQueueSubspace) Enqueue(ctx context.Context, tx transaction.Tx, partition int, order uint16, entry *SearchQueueEntry) error {
+	if len(entry.TxId) == 0 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "empty transaction id of the search queue entry")
+	}
+
+	value, err := jsoniter.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	enc, err := internal.Encode(internal.NewTableData(value))
+	if err != nil {
+		return err
+	}
+
+	key, err := subspace.FromBytes(q.SearchQueueSubspaceName()).PackWithVersionstamp(tuple.Tuple{
+		searchQueueVersion, queueEntryKey, int64(partition), tuple.IncompleteVersionstamp(order),
+	})
+	if err != nil {
+		return err
+	}
+	if err = tx.SetVersionstampedKey(ctx, key, enc); err != nil {
+		return err
+	}
+
+	pending := keys.NewKey(q.SearchQueueSubspaceName(), searchQueueVersion, queuePendingKey, entry.TxId, int64(partition), int64(order))
+	return tx.Replace(ctx, pending, internal.NewTableData(nil))
+}
+
+// Read returns the oldest entries of the partition, at most limit of them.
+func (q *SearchQueueSubspace) Read(ctx context.Context, tx transaction.Tx, partition int, limit int) ([]*SearchQueueEntry, error) {
+	it, err := tx.Read(ctx, keys.NewKey(q.SearchQueueSubspaceName(), searchQueueVersion, queueEntryKey, int64(partition)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*SearchQueueEntry
+	var row kv.KeyValue
+	for len(entries) < limit && it.Next(&row) {
+		// the key is the version, the type of the entry, the partition and the versionstamp
+		if len(row.Key) != 4 {
+			continue
+		}
+		vs, ok := row.Key[3].(tuple.Versionstamp)
+		if !ok {
+			return nil, api.Errorf(api.Code_INTERNAL, "not able to extract versionstamp from search queue entry %v", row.Key)
+		}
+
+		var entry SearchQueueEntry
+		if err := jsoniter.Unmarshal(row.Data.RawData, &entry); err != nil {
+			return nil, err
+		}
+		entry.Partition = partition
+		entry.Versionstamp = vs
+		entry.CreatedAt = row.Data.CreatedAt
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, it.Err()
+}
+
+// Delete removes the entry once it is indexed, along with the pending key of the entry.
+func (q *SearchQueueSubspace) Delete(ctx context.Context, tx transaction.Tx, entry *SearchQueueEntry) error {
+	for _, key := range []keys.Key{
+		keys.NewKey(q.SearchQueueSubspaceName(), searchQueueVersion, queueEntryKey, int64(entry.Partition), entry.Versionstamp),
+		keys.NewKey(q.SearchQueueSubspaceName(), searchQueueVersion, queuePendingKey, entry.TxId, int64(entry.Partition), int64(entry.Versionstamp.UserVersion)),
+	} {
+		if err := tx.Delete(ctx, key); err != nil {
+			log.Debug().Str("key", key.String()).Err(err).Msg("deleting search queue entry failed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsPending returns true if any of the entries appended by the transaction is not indexed yet.
+func (q *SearchQueueSubspace) IsPending(ctx context.Context, tx transaction.Tx, txId string) (bool, error) {
+	data, err := readValue(ctx, tx, keys.NewKey(q.SearchQueueSubspaceName(), searchQueueVersion, queuePendingKey, txId))
+	return data != nil, err
+}
+
+// AcquireLease makes the owner the only one indexing the entries of the partition until the lease expires.
+func (q *SearchQueueSubspace) AcquireLease(ctx context.Context, tx transaction.Tx, partition int, owner string, now time.Time, ttl time.Duration) (bool, error) {
+	key := keys.NewKey(q.SearchQueueSubspaceName(), searchQueueVersion, queueLeaseKey, int64(partition))
+	return acquireLease(ctx, tx, key, owner, now, ttl)
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestSearchQueueSubspace(t *testing.T) {
+	fdbCfg, err := config.GetTestFDBConfig("../../..")
+	require.NoError(t, err)
+
+	kvStore, err := kv.NewKeyValueStore(fdbCfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	q := NewSearchQueueStore(&TestMDNameRegistry{
+		SearchQueueSB: "test_search_queue",
+	})
+	_ = kvStore.DropTable(ctx, q.SearchQueueSubspaceName())
+
+	tm := transaction.NewManager(kvStore)
+	t.Run("enqueue_read_delete", func(t *testing.T) {
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, q.Enqueue(ctx, tx, 3, 0, &SearchQueueEntry{TxId: "tx1", Namespace: "ns1", Db: "db1", Collection: "c1", Table: []byte("t1"), Key: []byte("k1")}))
+		require.NoError(t, q.Enqueue(ctx, tx, 3, 1, &SearchQueueEntry{TxId: "tx1", Namespace: "ns1", Db: "db1", Collection: "c2", Table: []byte("t1"), Key: []byte("k1")}))
+		require.NoError(t, q.Enqueue(ctx, tx, 4, 0, &SearchQueueEntry{TxId: "tx1", Namespace: "ns1", Db: "db1", Collection: "c3", Table: []byte("t1"), Key: []byte("k1")}))
+		require.NoError(t, tx.Commit(ctx))
+
+		tx, err = tm.StartTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, q.Enqueue(ctx, tx, 3, 0, &SearchQueueEntry{TxId: "tx2", Namespace: "ns1", Db: "db1", Collection: "c1", Table: []byte("t1"), Key: []byte("k1")}))
+		require.NoError(t, tx.Commit(ctx))
+
+		tx, err = tm.StartTx(ctx)
+		require.NoError(t, err)
+		entries, err := q.Read(ctx, tx, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Equal(t, "c1", entries[0].Collection)
+		require.Equal(t, "c2", entries[1].Collection)
+		require.Equal(t, "tx2", entries[2].TxId)
+		require.Equal(t, 3, entries[0].Partition)
+		require.Equal(t, []byte("t1"), entries[0].Table)
+		require.Equal(t, []byte("k1"), entries[0].Key)
+
+		entries, err = q.Read(ctx, tx, 3, 2)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		pending, err := q.IsPending(ctx, tx, "tx1")
+		require.NoError(t, err)
+		require.True(t, pending)
+		for _, entry := range entries {
+			require.NoError(t, q.Delete(ctx, tx, entry))
+		}
+
+		// the entry of the other partition is still pending
+		pending, err = q.IsPending(ctx, tx, "tx1")
+		require.NoError(t, err)
+		require.True(t, pending)
+
+		entries, err = q.Read(ctx, tx, 4, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.NoError(t, q.Delete(ctx, tx, entries[0]))
+
+		pending, err = q.IsPending(ctx, tx, "tx1")
+		require.NoError(t, err)
+		require.False(t, pending)
+		require.NoError(t, tx.Commit(ctx))
+	})
+	t.Run("lease", func(t *testing.T) {
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+
+		now := time.Now()
+		acquired, err := q.AcquireLease(ctx, tx, 1, "server-1", now, time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		acquired, err = q.AcquireLease(ctx, tx, 1, "server-2", now, time.Minute)
+		require.NoError(t, err)
+		require.False(t, acquired)
+		acquired, err = q.AcquireLease(ctx, tx, 2, "server-2", now, time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		require.NoError(t, tx.Rollback(ctx))
+	})
+}
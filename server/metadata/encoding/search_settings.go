@@ -0,0 +1,254 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+var (
+	searchSettingsVersion = []byte{0x01}
+)
+
+const (
+	synonymKey     = "synonym"
+	stopWordsKey   = "stop_words"
+	searchIndexKey = "index"
+)
+
+// Synonym is a set of words that are matched interchangeably by the searches of the collection. Without a root, a search
+// for any of the synonyms matches the others. With a root, a search for the root matches the synonyms but not the other
+// way around. The synonyms are stored in the search settings subspace as below,
+//
+//	["search_settings", 0x01, x, 0x01, "synonym", "products", "tee"] => {"synonyms": ["tee", "t-shirt"]}
+//
+// where x is the value assigned to the namespace and 0x01 is the value assigned to the database.
+type Synonym struct {
+	Id         string   `json:"id"`
+	Collection string   `json:"collection"`
+	Root       string   `json:"root,omitempty"`
+	Synonyms   []string `json:"synonyms"`
+}
+
+// StopWords are the words that are removed from the text of the searches of the collection. They are stored in the
+// search settings subspace as below,
+//
+//	["search_settings", 0x01, x, 0x01, "stop_words", "products"] => {"words": ["a", "the"]}
+type StopWords struct {
+	Collection string   `json:"collection"`
+	Words      []string `json:"words"`
+}
+
+// SearchIndex is the search collection of a collection. The collection is searched through the alias, which points to
+// the search collection built when the collection was created or last reindexed. Version is the format of the search
+// collection. It is stored in the search settings subspace as below,
+//
+//	["search_settings", 0x01, x, 0x01, "index", "products"] => {"alias": "ns-db-products", "version": 1}
+//
+// The collections without a search index were created before the search collections were aliased, their search
+// collection has the name of the collection in the search store.
+type SearchIndex struct {
+	Collection string `json:"collection"`
+	Alias      string `json:"alias"`
+	Version    int    `json:"version"`
+}
+
+// SearchSettingsSubspace is used to manage the synonyms and the stop words of the collections. They are kept apart
+// from the search collections, so that they are not lost when a collection is reindexed.
+type SearchSettingsSubspace struct {
+	MDNameRegistry
+}
+
+func NewSearchSettingsStore(mdNameRegistry MDNameRegistry) *SearchSettingsSubspace {
+	return &SearchSettingsSubspace{
+		MDNameRegistry: mdNameRegistry,
+	}
+}
+
+func (s *SearchSettingsSubspace) key(namespaceId uint32, dbId uint32, parts ...interface{}) keys.Key {
+	return keys.NewKey(s.SearchSettingsSubspaceName(), append([]interface{}{searchSettingsVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId)}, parts...)...)
+}
+
+// PutSynonym creates the synonym set of the collection or replaces it if the collection already has a set with the
+// same id.
+func (s *SearchSettingsSubspace) PutSynonym(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, synonym *Synonym) error {
+	if len(synonym.Id) == 0 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "empty synonym id")
+	}
+
+	value, err := jsoniter.Marshal(synonym)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(namespaceId, dbId, synonymKey, synonym.Collection, synonym.Id)
+	if err = tx.Replace(ctx, key, internal.NewTableData(value)); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("storing synonym failed")
+		return err
+	}
+
+	return nil
+}
+
+// ListSynonyms returns the synonym sets of all the collections of the database.
+func (s *SearchSettingsSubspace) ListSynonyms(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) ([]*Synonym, error) {
+	it, err := tx.Read(ctx, s.key(namespaceId, dbId, synonymKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var synonyms []*Synonym
+	var row kv.KeyValue
+	for it.Next(&row) {
+		var synonym Synonym
+		if err := jsoniter.Unmarshal(row.Data.RawData, &synonym); err != nil {
+			return nil, err
+		}
+
+		synonyms = append(synonyms, &synonym)
+	}
+
+	return synonyms, it.Err()
+}
+
+// DeleteSynonym removes the synonym set of the collection.
+func (s *SearchSettingsSubspace) DeleteSynonym(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collection string, id string) error {
+	key := s.key(namespaceId, dbId, synonymKey, collection, id)
+	if err := tx.Delete(ctx, key); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("deleting synonym failed")
+		return err
+	}
+
+	return nil
+}
+
+// PutStopWords replaces the stop words of the collection, an empty list removes them.
+func (s *SearchSettingsSubspace) PutStopWords(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, stopWords *StopWords) error {
+	key := s.key(namespaceId, dbId, stopWordsKey, stopWords.Collection)
+	if len(stopWords.Words) == 0 {
+		if err := tx.Delete(ctx, key); err != nil {
+			log.Debug().Str("key", key.String()).Err(err).Msg("deleting stop words failed")
+			return err
+		}
+		return nil
+	}
+
+	value, err := jsoniter.Marshal(stopWords)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Replace(ctx, key, internal.NewTableData(value)); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("storing stop words failed")
+		return err
+	}
+
+	return nil
+}
+
+// ListStopWords returns the stop words of all the collections of the database.
+func (s *SearchSettingsSubspace) ListStopWords(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) ([]*StopWords, error) {
+	it, err := tx.Read(ctx, s.key(namespaceId, dbId, stopWordsKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var stopWords []*StopWords
+	var row kv.KeyValue
+	for it.Next(&row) {
+		var words StopWords
+		if err := jsoniter.Unmarshal(row.Data.RawData, &words); err != nil {
+			return nil, err
+		}
+
+		stopWords = append(stopWords, &words)
+	}
+
+	return stopWords, it.Err()
+}
+
+// PutIndex records the search collection of the collection.
+func (s *SearchSettingsSubspace) PutIndex(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, index *SearchIndex) error {
+	value, err := jsoniter.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(namespaceId, dbId, searchIndexKey, index.Collection)
+	if err = tx.Replace(ctx, key, internal.NewTableData(value)); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("storing search index failed")
+		return err
+	}
+
+	return nil
+}
+
+// ListIndexes returns the search collections of all the collections of the database.
+func (s *SearchSettingsSubspace) ListIndexes(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) ([]*SearchIndex, error) {
+	it, err := tx.Read(ctx, s.key(namespaceId, dbId, searchIndexKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*SearchIndex
+	var row kv.KeyValue
+	for it.Next(&row) {
+		var index SearchIndex
+		if err := jsoniter.Unmarshal(row.Data.RawData, &index); err != nil {
+			return nil, err
+		}
+
+		indexes = append(indexes, &index)
+	}
+
+	return indexes, it.Err()
+}
+
+// DeleteCollection removes the synonyms, the stop words and the search index of the collection, it is used when the
+// collection is dropped.
+func (s *SearchSettingsSubspace) DeleteCollection(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, collection string) error {
+	for _, key := range []keys.Key{
+		s.key(namespaceId, dbId, synonymKey, collection),
+		s.key(namespaceId, dbId, stopWordsKey, collection),
+		s.key(namespaceId, dbId, searchIndexKey, collection),
+	} {
+		if err := tx.Delete(ctx, key); err != nil {
+			log.Debug().Str("key", key.String()).Err(err).Msg("deleting search settings failed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteAll removes the search settings of all the collections of the database, it is used when the
+// database is dropped.
+func (s *SearchSettingsSubspace) DeleteAll(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) error {
+	key := s.key(namespaceId, dbId)
+	if err := tx.Delete(ctx, key); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("deleting search settings failed")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestSearchSettingsSubspace(t *testing.T) {
+	fdbCfg, err := config.GetTestFDBConfig("../../..")
+	require.NoError(t, err)
+
+	kvStore, err := kv.NewKeyValueStore(fdbCfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewSearchSettingsStore(&TestMDNameRegistry{
+		SearchSettingsSB: "test_search_settings",
+	})
+	_ = kvStore.DropTable(ctx, s.SearchSettingsSubspaceName())
+
+	tm := transaction.NewManager(kvStore)
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, s.PutSynonym(ctx, tx, 1, 2, &Synonym{Id: "tee", Collection: "products", Synonyms: []string{"tee", "t-shirt"}}))
+	require.NoError(t, s.PutSynonym(ctx, tx, 1, 2, &Synonym{Id: "tee", Collection: "products", Root: "tee", Synonyms: []string{"t-shirt"}}))
+	require.NoError(t, s.PutSynonym(ctx, tx, 1, 2, &Synonym{Id: "tee", Collection: "products_v2", Synonyms: []string{"tee", "shirt"}}))
+	require.NoError(t, s.PutStopWords(ctx, tx, 1, 2, &StopWords{Collection: "products", Words: []string{"the", "a"}}))
+	require.NoError(t, s.PutIndex(ctx, tx, 1, 2, &SearchIndex{Collection: "products", Alias: "ns-db-products", Version: 1}))
+	require.NoError(t, s.PutIndex(ctx, tx, 1, 2, &SearchIndex{Collection: "products_v2", Alias: "ns-db-products_v2", Version: 1}))
+
+	synonyms, err := s.ListSynonyms(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*Synonym{
+		{Id: "tee", Collection: "products", Root: "tee", Synonyms: []string{"t-shirt"}},
+		{Id: "tee", Collection: "products_v2", Synonyms: []string{"tee", "shirt"}},
+	}, synonyms)
+
+	stopWords, err := s.ListStopWords(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*StopWords{{Collection: "products", Words: []string{"the", "a"}}}, stopWords)
+
+	indexes, err := s.ListIndexes(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*SearchIndex{
+		{Collection: "products", Alias: "ns-db-products", Version: 1},
+		{Collection: "products_v2", Alias: "ns-db-products_v2", Version: 1},
+	}, indexes)
+
+	require.NoError(t, s.DeleteCollection(ctx, tx, 1, 2, "products"))
+	synonyms, err = s.ListSynonyms(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, synonyms, 1)
+	stopWords, err = s.ListStopWords(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, stopWords, 0)
+	indexes, err = s.ListIndexes(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*SearchIndex{{Collection: "products_v2", Alias: "ns-db-products_v2", Version: 1}}, indexes)
+
+	require.NoError(t, s.DeleteSynonym(ctx, tx, 1, 2, "products_v2", "tee"))
+	require.NoError(t, s.PutStopWords(ctx, tx, 1, 2, &StopWords{Collection: "products", Words: []string{"the"}}))
+	require.NoError(t, s.PutStopWords(ctx, tx, 1, 2, &StopWords{Collection: "products"}))
+	require.NoError(t, s.DeleteAll(ctx, tx, 1, 2))
+
+	synonyms, err = s.ListSynonyms(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, synonyms, 0)
+	require.NoError(t, tx.Rollback(ctx))
+}
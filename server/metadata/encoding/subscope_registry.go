@@ -4,6 +4,11 @@ const (
 	reservedSubspaceName = "reserved"
 	encodingSubspaceName = "encoding"
 	schemaSubspaceName   = "schema"
+	webhookSubspaceName  = "webhook"
+	triggerSubspaceName  = "trigger"
+
+	searchQueueSubspaceName    = "search_queue"
+	searchSettingsSubspaceName = "search_settings"
 )
 
 // MDNameRegistry provides the names of the internal tables(subspaces) maintained by the metadata package. The interface
@@ -29,6 +34,21 @@ type MDNameRegistry interface {
 	//    - "created" is keyword.
 	//
 	SchemaSubspaceName() []byte
+
+	// WebhookSubspaceName is the name of the table(subspace) where the webhooks registered on the databases are stored
+	// along with their delivery checkpoints.
+	WebhookSubspaceName() []byte
+
+	// TriggerSubspaceName is the name of the table(subspace) where the triggers of the collections are stored.
+	TriggerSubspaceName() []byte
+
+	// SearchQueueSubspaceName is the name of the table(subspace) where the changes to the collections are queued until
+	// they are indexed in the search store.
+	SearchQueueSubspaceName() []byte
+
+	// SearchSettingsSubspaceName is the name of the table(subspace) where the synonyms and the stop words of the
+	// collections are stored.
+	SearchSettingsSubspaceName() []byte
 }
 
 // DefaultMDNameRegistry provides the names of the subspaces used by the metadata package for managing dictionary
@@ -47,11 +67,31 @@ func (d *DefaultMDNameRegistry) SchemaSubspaceName() []byte {
 	return []byte(schemaSubspaceName)
 }
 
+func (d *DefaultMDNameRegistry) WebhookSubspaceName() []byte {
+	return []byte(webhookSubspaceName)
+}
+
+func (d *DefaultMDNameRegistry) TriggerSubspaceName() []byte {
+	return []byte(triggerSubspaceName)
+}
+
+func (d *DefaultMDNameRegistry) SearchQueueSubspaceName() []byte {
+	return []byte(searchQueueSubspaceName)
+}
+
+func (d *DefaultMDNameRegistry) SearchSettingsSubspaceName() []byte {
+	return []byte(searchSettingsSubspaceName)
+}
+
 // TestMDNameRegistry is used by tests to inject table names that can be used by tests
 type TestMDNameRegistry struct {
-	ReserveSB  string
-	EncodingSB string
-	SchemaSB   string
+	ReserveSB        string
+	EncodingSB       string
+	SchemaSB         string
+	WebhookSB        string
+	TriggerSB        string
+	SearchQueueSB    string
+	SearchSettingsSB string
 }
 
 func (d *TestMDNameRegistry) ReservedSubspaceName() []byte {
@@ -65,3 +105,19 @@ func (d *TestMDNameRegistry) EncodingSubspaceName() []byte {
 func (d *TestMDNameRegistry) SchemaSubspaceName() []byte {
 	return []byte(d.SchemaSB)
 }
+
+func (d *TestMDNameRegistry) WebhookSubspaceName() []byte {
+	return []byte(d.WebhookSB)
+}
+
+func (d *TestMDNameRegistry) TriggerSubspaceName() []byte {
+	return []byte(d.TriggerSB)
+}
+
+func (d *TestMDNameRegistry) SearchQueueSubspaceName() []byte {
+	return []byte(d.SearchQueueSB)
+}
+
+func (d *TestMDNameRegistry) SearchSettingsSubspaceName() []byte {
+	return []byte(d.SearchSettingsSB)
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+var (
+	triggerVersion = []byte{0x01}
+)
+
+// Trigger writes to the target collection whenever a document of the collection is changed. The filter is evaluated
+// on the changed document, the key is the filter on the primary key of the target document and the update is the
+// update operators applied to it. The string values of the key and the update that start with "$" are the fields of
+// the changed document. The triggers are stored in the trigger subspace as below,
+//
+//	["trigger", 0x01, x, 0x01, "trigger-1"] => {"collection": "orders", "target": "order_stats", ...}
+//
+// where x is the value assigned to the namespace and 0x01 is the value assigned to the database.
+type Trigger struct {
+	Name       string              `json:"name"`
+	Collection string              `json:"collection"`
+	Ops        []string            `json:"ops,omitempty"`
+	Filter     jsoniter.RawMessage `json:"filter,omitempty"`
+	Target     string              `json:"target"`
+	Key        jsoniter.RawMessage `json:"key"`
+	Update     jsoniter.RawMessage `json:"update"`
+	Upsert     bool                `json:"upsert,omitempty"`
+
+	// CreatedAt is not stored in the value, it is filled in while reading the triggers.
+	CreatedAt *internal.Timestamp `json:"-"`
+}
+
+// TriggerSubspace is used to manage the triggers of the databases in the trigger subspace.
+type TriggerSubspace struct {
+	MDNameRegistry
+}
+
+func NewTriggerStore(mdNameRegistry MDNameRegistry) *TriggerSubspace {
+	return &TriggerSubspace{
+		MDNameRegistry: mdNameRegistry,
+	}
+}
+
+// Put persists a new trigger of a database, it returns kv.ErrDuplicateKey if the database already has a trigger with
+// the same name.
+func (t *TriggerSubspace) Put(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, trigger *Trigger) error {
+	if len(trigger.Name) == 0 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "empty trigger name")
+	}
+
+	value, err := jsoniter.Marshal(trigger)
+	if err != nil {
+		return err
+	}
+
+	key := keys.NewKey(t.TriggerSubspaceName(), triggerVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), trigger.Name)
+	if err := tx.Insert(ctx, key, internal.NewTableData(value)); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("storing trigger failed")
+		return err
+	}
+
+	return nil
+}
+
+// List returns all the triggers of the database.
+func (t *TriggerSubspace) List(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) ([]*Trigger, error) {
+	it, err := tx.Read(ctx, keys.NewKey(t.TriggerSubspaceName(), triggerVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId)))
+	if err != nil {
+		return nil, err
+	}
+
+	var triggers []*Trigger
+	var row kv.KeyValue
+	for it.Next(&row) {
+		var trigger Trigger
+		if err := jsoniter.Unmarshal(row.Data.RawData, &trigger); err != nil {
+			return nil, err
+		}
+		trigger.CreatedAt = row.Data.CreatedAt
+
+		triggers = append(triggers, &trigger)
+	}
+
+	return triggers, it.Err()
+}
+
+// Delete removes the trigger of the database.
+func (t *TriggerSubspace) Delete(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, name string) error {
+	key := keys.NewKey(t.TriggerSubspaceName(), triggerVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), name)
+	if err := tx.Delete(ctx, key); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("deleting trigger failed")
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAll removes all the triggers of the database, it is used when the database is dropped.
+func (t *TriggerSubspace) DeleteAll(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) error {
+	key := keys.NewKey(t.TriggerSubspaceName(), triggerVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId))
+	if err := tx.Delete(ctx, key); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("deleting triggers failed")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestTriggerSubspace(t *testing.T) {
+	fdbCfg, err := config.GetTestFDBConfig("../../..")
+	require.NoError(t, err)
+
+	kvStore, err := kv.NewKeyValueStore(fdbCfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewTriggerStore(&TestMDNameRegistry{
+		TriggerSB: "test_trigger",
+	})
+	_ = kvStore.DropTable(ctx, s.TriggerSubspaceName())
+
+	tm := transaction.NewManager(kvStore)
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+
+	trigger := &Trigger{
+		Name:       "order_stats",
+		Collection: "orders",
+		Ops:        []string{"insert"},
+		Target:     "order_stats",
+		Key:        []byte(`{"customer":"$customer"}`),
+		Update:     []byte(`{"$increment":{"orders":1}}`),
+		Upsert:     true,
+	}
+	require.NoError(t, s.Put(ctx, tx, 1, 2, trigger))
+	require.NoError(t, s.Put(ctx, tx, 1, 2, &Trigger{Name: "other", Collection: "users", Target: "user_stats"}))
+	require.NoError(t, s.Put(ctx, tx, 1, 3, &Trigger{Name: "order_stats", Collection: "orders", Target: "order_stats"}))
+	require.Equal(t, kv.ErrDuplicateKey, s.Put(ctx, tx, 1, 2, trigger))
+
+	triggers, err := s.List(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, triggers, 2)
+	require.Equal(t, "order_stats", triggers[0].Name)
+	require.Equal(t, []byte(`{"$increment":{"orders":1}}`), []byte(triggers[0].Update))
+	require.True(t, triggers[0].Upsert)
+	require.NotNil(t, triggers[0].CreatedAt)
+
+	require.NoError(t, s.Delete(ctx, tx, 1, 2, "order_stats"))
+	triggers, err = s.List(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+
+	require.NoError(t, s.DeleteAll(ctx, tx, 1, 2))
+	triggers, err = s.List(ctx, tx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, triggers, 0)
+
+	triggers, err = s.List(ctx, tx, 1, 3)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	require.NoError(t, tx.Rollback(ctx))
+}
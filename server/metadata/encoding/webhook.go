@@ -0,0 +1,196 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+var (
+	webhookVersion = []byte{0x01}
+)
+
+const (
+	webhookKey    = "hook"
+	checkpointKey = "checkpoint"
+	leaseKey      = "lease"
+)
+
+// Webhook is a subscription of an HTTP endpoint to the change events of a database. The webhooks are stored in the
+// webhook subspace as below,
+//
+//	["webhook", 0x01, x, 0x01, "hook", "hook-1"] => {"url": "https://...", "collection": "coll-1", ...}
+//	["webhook", 0x01, x, 0x01, "checkpoint", "hook-1"] => resume token of the last delivered transaction
+//	["webhook", 0x01, x, 0x01, "lease", "hook-1"] => {"owner": "...", "expires_at": ...}
+//
+// where x is the value assigned to the namespace and 0x01 is the value assigned to the database.
+type Webhook struct {
+	Name                 string              `json:"name"`
+	Collection           string              `json:"collection,omitempty"`
+	Url                  string              `json:"url"`
+	Secret               string              `json:"secret,omitempty"`
+	Ops                  []string            `json:"ops,omitempty"`
+	Filter               jsoniter.RawMessage `json:"filter,omitempty"`
+	DeadLetterCollection string              `json:"dead_letter_collection,omitempty"`
+
+	// the below are not stored in the value, they are filled in while reading the webhooks.
+	NamespaceId uint32              `json:"-"`
+	DbId        uint32              `json:"-"`
+	CreatedAt   *internal.Timestamp `json:"-"`
+}
+
+// WebhookSubspace is used to manage the webhooks and their delivery state in the webhook subspace.
+type WebhookSubspace struct {
+	MDNameRegistry
+}
+
+func NewWebhookStore(mdNameRegistry MDNameRegistry) *WebhookSubspace {
+	return &WebhookSubspace{
+		MDNameRegistry: mdNameRegistry,
+	}
+}
+
+// Put persists a new webhook of a database, it returns kv.ErrDuplicateKey if the database already has a webhook with
+// the same name.
+func (w *WebhookSubspace) Put(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, hook *Webhook) error {
+	if len(hook.Name) == 0 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "empty webhook name")
+	}
+
+	value, err := jsoniter.Marshal(hook)
+	if err != nil {
+		return err
+	}
+
+	key := keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), webhookKey, hook.Name)
+	if err := tx.Insert(ctx, key, internal.NewTableData(value)); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("storing webhook failed")
+		return err
+	}
+
+	return nil
+}
+
+// Get returns the webhook of the database with the name, or nil if there is no such webhook.
+func (w *WebhookSubspace) Get(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, name string) (*Webhook, error) {
+	hooks, err := w.read(ctx, tx, keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), webhookKey, name))
+	if err != nil || len(hooks) == 0 {
+		return nil, err
+	}
+
+	return hooks[0], nil
+}
+
+// List returns all the webhooks of the database.
+func (w *WebhookSubspace) List(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) ([]*Webhook, error) {
+	return w.read(ctx, tx, keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId)))
+}
+
+// ListAll returns the webhooks of all the databases of all the namespaces.
+func (w *WebhookSubspace) ListAll(ctx context.Context, tx transaction.Tx) ([]*Webhook, error) {
+	return w.read(ctx, tx, keys.NewKey(w.WebhookSubspaceName(), webhookVersion))
+}
+
+func (w *WebhookSubspace) read(ctx context.Context, tx transaction.Tx, key keys.Key) ([]*Webhook, error) {
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []*Webhook
+	var row kv.KeyValue
+	for it.Next(&row) {
+		// the key is the version, the namespace, the database, the type of the entry and the name of the webhook
+		if len(row.Key) != 5 || row.Key[3] != webhookKey {
+			continue
+		}
+
+		namespaceId, ok1 := row.Key[1].([]byte)
+		dbId, ok2 := row.Key[2].([]byte)
+		if !ok1 || !ok2 {
+			return nil, api.Errorf(api.Code_INTERNAL, "not able to extract database from webhook %v", row.Key)
+		}
+
+		var hook Webhook
+		if err := jsoniter.Unmarshal(row.Data.RawData, &hook); err != nil {
+			return nil, err
+		}
+		hook.NamespaceId = ByteToUInt32(namespaceId)
+		hook.DbId = ByteToUInt32(dbId)
+		hook.CreatedAt = row.Data.CreatedAt
+
+		hooks = append(hooks, &hook)
+	}
+
+	return hooks, it.Err()
+}
+
+// Delete removes the webhook along with its delivery checkpoint and lease.
+func (w *WebhookSubspace) Delete(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, name string) error {
+	for _, entry := range []string{webhookKey, checkpointKey, leaseKey} {
+		key := keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), entry, name)
+		if err := tx.Delete(ctx, key); err != nil {
+			log.Debug().Str("key", key.String()).Err(err).Msg("deleting webhook failed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteAll removes all the webhooks of the database, it is used when the database is dropped.
+func (w *WebhookSubspace) DeleteAll(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32) error {
+	key := keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId))
+	if err := tx.Delete(ctx, key); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("deleting webhooks failed")
+		return err
+	}
+
+	return nil
+}
+
+// PutCheckpoint persists the resume token of the last transaction delivered to the webhook.
+func (w *WebhookSubspace) PutCheckpoint(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, name string, token []byte) error {
+	key := keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), checkpointKey, name)
+	return tx.Replace(ctx, key, internal.NewTableData(token))
+}
+
+// GetCheckpoint returns the resume token of the last transaction delivered to the webhook, or nil if nothing is
+// delivered yet.
+func (w *WebhookSubspace) GetCheckpoint(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, name string) ([]byte, error) {
+	data, err := readValue(ctx, tx, keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), checkpointKey, name))
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return data.RawData, nil
+}
+
+// AcquireLease makes the owner the only one delivering the events to the webhook until the lease expires. The lease is
+// acquired if there is no lease, the lease is expired, or it is already held by the owner, in which case it is
+// extended. It returns false if the lease is held by someone else.
+func (w *WebhookSubspace) AcquireLease(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbId uint32, name string, owner string, now time.Time, ttl time.Duration) (bool, error) {
+	key := keys.NewKey(w.WebhookSubspaceName(), webhookVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbId), leaseKey, name)
+	return acquireLease(ctx, tx, key, owner, now, ttl)
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestWebhookSubspace(t *testing.T) {
+	fdbCfg, err := config.GetTestFDBConfig("../../..")
+	require.NoError(t, err)
+
+	kvStore, err := kv.NewKeyValueStore(fdbCfg)
+	require.NoError(t, err)
+
+	t.Run("put_get_list_delete", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		w := NewWebhookStore(&TestMDNameRegistry{
+			WebhookSB: "test_webhook",
+		})
+		_ = kvStore.DropTable(ctx, w.WebhookSubspaceName())
+
+		tm := transaction.NewManager(kvStore)
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+
+		hook1 := &Webhook{Name: "hook-1", Collection: "orders", Url: "https://example.com/1", Filter: []byte(`{"status":"shipped"}`)}
+		hook10 := &Webhook{Name: "hook-10", Url: "https://example.com/10"}
+		require.NoError(t, w.Put(ctx, tx, 1, 2, hook1))
+		require.NoError(t, w.Put(ctx, tx, 1, 2, hook10))
+		require.NoError(t, w.Put(ctx, tx, 1, 3, &Webhook{Name: "hook-1", Url: "https://example.com/other"}))
+		require.Equal(t, kv.ErrDuplicateKey, w.Put(ctx, tx, 1, 2, hook1))
+
+		hook, err := w.Get(ctx, tx, 1, 2, "hook-1")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/1", hook.Url)
+		require.Equal(t, []byte(`{"status":"shipped"}`), []byte(hook.Filter))
+		require.Equal(t, uint32(1), hook.NamespaceId)
+		require.Equal(t, uint32(2), hook.DbId)
+		require.NotNil(t, hook.CreatedAt)
+
+		hooks, err := w.List(ctx, tx, 1, 2)
+		require.NoError(t, err)
+		require.Len(t, hooks, 2)
+
+		require.NoError(t, w.PutCheckpoint(ctx, tx, 1, 2, "hook-1", []byte{0x01}))
+		hooks, err = w.ListAll(ctx, tx)
+		require.NoError(t, err)
+		require.Len(t, hooks, 3)
+
+		require.NoError(t, w.Delete(ctx, tx, 1, 2, "hook-1"))
+		hook, err = w.Get(ctx, tx, 1, 2, "hook-1")
+		require.NoError(t, err)
+		require.Nil(t, hook)
+		checkpoint, err := w.GetCheckpoint(ctx, tx, 1, 2, "hook-1")
+		require.NoError(t, err)
+		require.Nil(t, checkpoint)
+		hook, err = w.Get(ctx, tx, 1, 2, "hook-10")
+		require.NoError(t, err)
+		require.NotNil(t, hook)
+
+		require.NoError(t, w.DeleteAll(ctx, tx, 1, 2))
+		hooks, err = w.ListAll(ctx, tx)
+		require.NoError(t, err)
+		require.Len(t, hooks, 1)
+		require.NoError(t, tx.Rollback(ctx))
+	})
+	t.Run("checkpoint_and_lease", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		w := NewWebhookStore(&TestMDNameRegistry{
+			WebhookSB: "test_webhook",
+		})
+		_ = kvStore.DropTable(ctx, w.WebhookSubspaceName())
+
+		tm := transaction.NewManager(kvStore)
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+
+		checkpoint, err := w.GetCheckpoint(ctx, tx, 1, 2, "hook-1")
+		require.NoError(t, err)
+		require.Nil(t, checkpoint)
+		require.NoError(t, w.PutCheckpoint(ctx, tx, 1, 2, "hook-1", []byte{0x01}))
+		require.NoError(t, w.PutCheckpoint(ctx, tx, 1, 2, "hook-1", []byte{0x02}))
+		checkpoint, err = w.GetCheckpoint(ctx, tx, 1, 2, "hook-1")
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x02}, checkpoint)
+
+		now := time.Now()
+		acquired, err := w.AcquireLease(ctx, tx, 1, 2, "hook-1", "server-1", now, time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		acquired, err = w.AcquireLease(ctx, tx, 1, 2, "hook-1", "server-2", now.Add(time.Second), time.Minute)
+		require.NoError(t, err)
+		require.False(t, acquired)
+		acquired, err = w.AcquireLease(ctx, tx, 1, 2, "hook-1", "server-1", now.Add(time.Second), time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		// the lease is taken over once it expires
+		acquired, err = w.AcquireLease(ctx, tx, 1, 2, "hook-1", "server-2", now.Add(2*time.Minute), time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		require.NoError(t, tx.Rollback(ctx))
+	})
+}
@@ -97,8 +97,14 @@ func (d *DictKeyEncoder) encodedIdxName(idx *schema.Index) []byte {
 	return encoding.UInt32ToByte(idx.Id)
 }
 
+// IsUserTable returns true if the table name is an encoded collection table i.e. it is not a table of the metadata
+// subspaces.
+func IsUserTable(tableName []byte) bool {
+	return len(tableName) >= 16 && bytes.Equal(tableName[0:4], userTableKeyPrefix)
+}
+
 func (d *DictKeyEncoder) DecodeTableName(tableName []byte) (string, string, string, bool) {
-	if len(tableName) < 16 || !bytes.Equal(tableName[0:4], userTableKeyPrefix) {
+	if !IsUserTable(tableName) {
 		return "", "", "", false
 	}
 
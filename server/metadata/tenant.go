@@ -19,7 +19,9 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
@@ -27,6 +29,7 @@ import (
 	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
@@ -106,6 +109,10 @@ type TenantManager struct {
 
 	encoder        *encoding.DictionaryEncoder
 	schemaStore    *encoding.SchemaSubspace
+	webhookStore   *encoding.WebhookSubspace
+	triggerStore   *encoding.TriggerSubspace
+	settingsStore  *encoding.SearchSettingsSubspace
+	queueStore     *encoding.SearchQueueSubspace
 	kvStore        kv.KeyValueStore
 	tenants        map[string]*Tenant
 	idToTenantMap  map[uint32]string
@@ -124,6 +131,10 @@ func newTenantManager(kvStore kv.KeyValueStore, mdNameRegistry encoding.MDNameRe
 		kvStore:        kvStore,
 		encoder:        encoding.NewDictionaryEncoder(mdNameRegistry),
 		schemaStore:    encoding.NewSchemaStore(mdNameRegistry),
+		webhookStore:   encoding.NewWebhookStore(mdNameRegistry),
+		triggerStore:   encoding.NewTriggerStore(mdNameRegistry),
+		settingsStore:  encoding.NewSearchSettingsStore(mdNameRegistry),
+		queueStore:     encoding.NewSearchQueueStore(mdNameRegistry),
 		tenants:        make(map[string]*Tenant),
 		idToTenantMap:  make(map[uint32]string),
 		versionH:       &VersionHandler{},
@@ -252,7 +263,7 @@ func (m *TenantManager) GetTenant(ctx context.Context, namespaceName string, txM
 	}
 
 	namespace := NewTenantNamespace(namespaceName, id)
-	tenant = NewTenant(namespace, m.kvStore, m.encoder, m.schemaStore, m.versionH, currentVersion)
+	tenant = NewTenant(namespace, m.kvStore, m.encoder, m.schemaStore, m.webhookStore, m.triggerStore, m.settingsStore, m.versionH, currentVersion)
 	if err = tenant.reload(ctx, tx, currentVersion); err != nil {
 		return nil, err
 	}
@@ -288,7 +299,7 @@ func (m *TenantManager) createOrGetTenantInternal(ctx context.Context, tx transa
 			return nil, err
 		}
 
-		tenant := NewTenant(namespace, m.kvStore, m.encoder, m.schemaStore, m.versionH, currentVersion)
+		tenant := NewTenant(namespace, m.kvStore, m.encoder, m.schemaStore, m.webhookStore, m.triggerStore, m.settingsStore, m.versionH, currentVersion)
 		tenant.Lock()
 		err = tenant.reload(ctx, tx, currentVersion)
 		tenant.Unlock()
@@ -306,7 +317,7 @@ func (m *TenantManager) createOrGetTenantInternal(ctx context.Context, tx transa
 		return nil, err
 	}
 
-	return NewTenant(namespace, m.kvStore, m.encoder, m.schemaStore, m.versionH, nil), nil
+	return NewTenant(namespace, m.kvStore, m.encoder, m.schemaStore, m.webhookStore, m.triggerStore, m.settingsStore, m.versionH, nil), nil
 }
 
 // GetTableNameFromId returns tenant name, database name, collection name corresponding to their encoded ids.
@@ -342,6 +353,63 @@ func (m *TenantManager) GetTableNameFromId(tenantId uint32, dbId uint32, collId
 	return tenantName, dbName, collName, ok
 }
 
+// GetDatabaseNameFromId returns tenant name and database name corresponding to their encoded ids.
+func (m *TenantManager) GetDatabaseNameFromId(tenantId uint32, dbId uint32) (string, string, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	tenantName, ok := m.idToTenantMap[tenantId]
+	if !ok {
+		return "", "", ok
+	}
+	tenant, ok := m.tenants[tenantName]
+	if !ok {
+		return "", "", ok
+	}
+
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	dbName, ok := tenant.idToDatabaseMap[dbId]
+	return tenantName, dbName, ok
+}
+
+// ListTenants returns all the tenants loaded in the cache.
+func (m *TenantManager) ListTenants() []*Tenant {
+	m.RLock()
+	defer m.RUnlock()
+
+	tenants := make([]*Tenant, 0, len(m.tenants))
+	for _, tenant := range m.tenants {
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants
+}
+
+// ListDatabaseNames returns the names of the databases of all the tenants loaded in the cache.
+func (m *TenantManager) ListDatabaseNames() []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	var databases []string
+	for _, tenant := range m.tenants {
+		databases = append(databases, tenant.ListDatabases(context.TODO(), nil)...)
+	}
+
+	return databases
+}
+
+// GetWebhookStore returns the store of the webhooks of all the tenants, it is used by the delivery of the webhooks.
+func (m *TenantManager) GetWebhookStore() *encoding.WebhookSubspace {
+	return m.webhookStore
+}
+
+// GetSearchQueueStore returns the queue of the changes that are yet to be indexed in the search store.
+func (m *TenantManager) GetSearchQueueStore() *encoding.SearchQueueSubspace {
+	return m.queueStore
+}
+
 // Reload reads all the namespaces exists in the disk and build the in-memory map of the manager to track the tenants.
 // As this is an expensive call, the reloading happens during start time for now. It is possible that reloading
 // fails during start time then we rely on lazily reloading cache during serving user requests.
@@ -372,7 +440,7 @@ func (m *TenantManager) reload(ctx context.Context, tx transaction.Tx, currentVe
 
 	for namespace, id := range namespaces {
 		if _, ok := m.tenants[namespace]; !ok {
-			m.tenants[namespace] = NewTenant(NewTenantNamespace(namespace, id), m.kvStore, m.encoder, m.schemaStore, m.versionH, currentVersion)
+			m.tenants[namespace] = NewTenant(NewTenantNamespace(namespace, id), m.kvStore, m.encoder, m.schemaStore, m.webhookStore, m.triggerStore, m.settingsStore, m.versionH, currentVersion)
 			m.idToTenantMap[id] = namespace
 		}
 	}
@@ -397,6 +465,9 @@ type Tenant struct {
 	kvStore         kv.KeyValueStore
 	encoder         *encoding.DictionaryEncoder
 	schemaStore     *encoding.SchemaSubspace
+	webhookStore    *encoding.WebhookSubspace
+	triggerStore    *encoding.TriggerSubspace
+	settingsStore   *encoding.SearchSettingsSubspace
 	databases       map[string]*Database
 	idToDatabaseMap map[uint32]string
 	namespace       Namespace
@@ -404,12 +475,15 @@ type Tenant struct {
 	versionH        *VersionHandler
 }
 
-func NewTenant(namespace Namespace, kvStore kv.KeyValueStore, encoder *encoding.DictionaryEncoder, schemaStore *encoding.SchemaSubspace, versionH *VersionHandler, currentVersion Version) *Tenant {
+func NewTenant(namespace Namespace, kvStore kv.KeyValueStore, encoder *encoding.DictionaryEncoder, schemaStore *encoding.SchemaSubspace, webhookStore *encoding.WebhookSubspace, triggerStore *encoding.TriggerSubspace, settingsStore *encoding.SearchSettingsSubspace, versionH *VersionHandler, currentVersion Version) *Tenant {
 	return &Tenant{
 		kvStore:         kvStore,
 		namespace:       namespace,
 		encoder:         encoder,
 		schemaStore:     schemaStore,
+		webhookStore:    webhookStore,
+		triggerStore:    triggerStore,
+		settingsStore:   settingsStore,
 		databases:       make(map[string]*Database),
 		idToDatabaseMap: make(map[uint32]string),
 		versionH:        versionH,
@@ -509,8 +583,11 @@ func (tenant *Tenant) CreateDatabase(ctx context.Context, tx transaction.Tx, dbN
 
 	// otherwise, proceed to create the database if there are concurrent requests on different workers then one of
 	// them will fail with duplicate entry and only one will succeed.
-	_, err := tenant.encoder.EncodeDatabaseName(ctx, tx, dbName, tenant.namespace.Id())
-	return false, err
+	if _, err := tenant.encoder.EncodeDatabaseName(ctx, tx, dbName, tenant.namespace.Id()); err != nil {
+		return false, err
+	}
+
+	return false, addDDLEvent(ctx, kv.CreateDatabaseEvent, &DDLEvent{})
 }
 
 // DropDatabase is responsible for first dropping a dictionary encoding of the database and then adding a corresponding
@@ -537,7 +614,17 @@ func (tenant *Tenant) DropDatabase(ctx context.Context, tx transaction.Tx, dbNam
 		}
 	}
 
-	return true, nil
+	if err := tenant.webhookStore.DeleteAll(ctx, tx, tenant.namespace.Id(), db.id); err != nil {
+		return true, err
+	}
+	if err := tenant.triggerStore.DeleteAll(ctx, tx, tenant.namespace.Id(), db.id); err != nil {
+		return true, err
+	}
+	if err := tenant.settingsStore.DeleteAll(ctx, tx, tenant.namespace.Id(), db.id); err != nil {
+		return true, err
+	}
+
+	return true, addDDLEvent(ctx, kv.DropDatabaseEvent, &DDLEvent{})
 }
 
 func (tenant *Tenant) InvalidateDBCache(dbName string) {
@@ -577,6 +664,15 @@ func (tenant *Tenant) reloadDatabase(ctx context.Context, tx transaction.Tx, dbN
 		return nil, err
 	}
 
+	searchIndexes, err := tenant.settingsStore.ListIndexes(ctx, tx, tenant.namespace.Id(), database.id)
+	if err != nil {
+		return nil, err
+	}
+	var collToSearchIndex = make(map[string]*encoding.SearchIndex)
+	for _, index := range searchIndexes {
+		collToSearchIndex[index.Collection] = index
+	}
+
 	for coll, id := range collNameToId {
 		idxNameToId, err := tenant.encoder.GetIndexes(ctx, tx, tenant.namespace.Id(), database.id, id)
 		if err != nil {
@@ -585,24 +681,41 @@ func (tenant *Tenant) reloadDatabase(ctx context.Context, tx transaction.Tx, dbN
 			continue
 		}
 
-		userSchema, version, err := tenant.schemaStore.GetLatest(ctx, tx, tenant.namespace.Id(), database.id, id)
-		if err != nil {
+		schemas, versions, err := tenant.schemaStore.Get(ctx, tx, tenant.namespace.Id(), database.id, id)
+		if err != nil || len(schemas) == 0 {
 			database.needFixingCollections[coll] = struct{}{}
 			log.Debug().Err(err).Str("collection", coll).Msg("skipping loading collection")
 			continue
 		}
 
+		userSchema, version := schemas[len(schemas)-1], versions[len(versions)-1]
 		collection, err := createCollection(id, version, coll, userSchema, idxNameToId, tenant.getSearchCollName(dbName, coll))
 		if err != nil {
 			database.needFixingCollections[coll] = struct{}{}
 			log.Debug().Err(err).Str("collection", coll).Msg("skipping loading collection")
 			continue
 		}
+		collection.FieldRevisions = buildFieldRevisions(coll, schemas, versions)
+		if index, ok := collToSearchIndex[coll]; ok {
+			collection = collection.WithSearchIndex(index.Alias, index.Version)
+		} else {
+			collection = collection.WithSearchIndex(collection.SearchCollectionName(), schema.SearchIndexV0)
+		}
 
 		database.collections[coll] = NewCollectionHolder(id, coll, collection, idxNameToId)
 		database.idToCollectionMap[id] = coll
 	}
 
+	if database.triggers, err = tenant.triggerStore.List(ctx, tx, tenant.namespace.Id(), database.id); err != nil {
+		return nil, err
+	}
+	if database.synonyms, err = tenant.settingsStore.ListSynonyms(ctx, tx, tenant.namespace.Id(), database.id); err != nil {
+		return nil, err
+	}
+	if database.stopWords, err = tenant.settingsStore.ListStopWords(ctx, tx, tenant.namespace.Id(), database.id); err != nil {
+		return nil, err
+	}
+
 	return database, nil
 }
 
@@ -656,21 +769,41 @@ func (tenant *Tenant) CreateCollection(ctx context.Context, tx transaction.Tx, d
 	if err := tenant.schemaStore.Put(ctx, tx, tenant.namespace.Id(), database.id, collectionId, schFactory.Schema, baseSchemaVersion); err != nil {
 		return err
 	}
+	if err := tenant.putSchemaAuthor(ctx, tx, database, collectionId, baseSchemaVersion); err != nil {
+		return err
+	}
 
 	// store the collection to the databaseObject, this is actually cloned database object passed by the query runner.
 	// So failure of the transaction won't impact the consistency of the cache
 	collection := schema.NewDefaultCollection(schFactory.Name, collectionId, baseSchemaVersion, schFactory.Fields, schFactory.Indexes, schFactory.Schema, tenant.getSearchCollName(database.name, schFactory.Name))
+	collection.FieldRevisions = schema.NextFieldRevisions(nil, nil, schFactory.Fields, baseSchemaVersion)
 	database.collections[schFactory.Name] = NewCollectionHolder(collectionId, schFactory.Name, collection, idxNameToId)
 
+	if err := tenant.settingsStore.PutIndex(ctx, tx, tenant.namespace.Id(), database.id, &encoding.SearchIndex{
+		Collection: schFactory.Name,
+		Alias:      collection.SearchCollectionName(),
+		Version:    collection.SearchIndexVersion,
+	}); err != nil {
+		return err
+	}
+
 	if config.DefaultConfig.Search.WriteEnabled {
-		if err := searchStore.CreateCollection(ctx, collection.Search); err != nil {
-			if err != search.ErrDuplicateEntity {
-				return err
-			}
+		// the search collection is created behind an alias, so that a reindex can replace it without any downtime
+		searchColl := *collection.Search
+		searchColl.Name = fmt.Sprintf("%s-r%d", collection.SearchCollectionName(), time.Now().UnixNano())
+		if err := searchStore.CreateCollection(ctx, &searchColl); err != nil {
+			return err
+		}
+		if err := searchStore.SwapAlias(ctx, collection.SearchCollectionName(), searchColl.Name); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	return addDDLEvent(ctx, kv.CreateCollectionEvent, &DDLEvent{
+		Collection: schFactory.Name,
+		Schema:     schFactory.Schema,
+		Revision:   baseSchemaVersion,
+	})
 }
 
 func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, database *Database, c *collectionHolder, schFactory *schema.Factory, searchStore search.Store) error {
@@ -707,12 +840,17 @@ func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, d
 	if err := tenant.schemaStore.Put(ctx, tx, tenant.namespace.Id(), database.id, c.id, schFactory.Schema, schRevision); err != nil {
 		return err
 	}
+	if err := tenant.putSchemaAuthor(ctx, tx, database, c.id, schRevision); err != nil {
+		return err
+	}
 
-	deltaFields := schema.GetSearchDeltaFields(c.collection.QueryableFields, schFactory.Fields)
+	deltaFields := schema.GetSearchDeltaFields(c.collection.QueryableFields, schFactory.Fields, c.collection.SearchIndexVersion)
 
 	// store the collection to the databaseObject, this is actually cloned database object passed by the query runner.
 	// So failure of the transaction won't impact the consistency of the cache
 	collection := schema.NewDefaultCollection(schFactory.Name, c.id, schRevision, schFactory.Fields, schFactory.Indexes, schFactory.Schema, tenant.getSearchCollName(database.name, schFactory.Name))
+	collection.FieldRevisions = schema.NextFieldRevisions(c.collection.FieldRevisions, c.collection.Fields, schFactory.Fields, schRevision)
+	collection = collection.WithSearchIndex(c.collection.SearchCollectionName(), c.collection.SearchIndexVersion)
 
 	// recreating collection holder is fine because we are working on databaseClone and also has a lock on the tenant
 	database.collections[schFactory.Name] = NewCollectionHolder(c.id, schFactory.Name, collection, c.idxNameToId)
@@ -721,8 +859,324 @@ func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, d
 	}); err != nil {
 		return err
 	}
+
+	return addDDLEvent(ctx, kv.UpdateCollectionEvent, &DDLEvent{
+		Collection: schFactory.Name,
+		Schema:     schFactory.Schema,
+		Revision:   schRevision,
+	})
+}
+
+// putSchemaAuthor stores the subject of the access token as the author of the schema revision. Nothing is stored if
+// the request is not authenticated.
+func (tenant *Tenant) putSchemaAuthor(ctx context.Context, tx transaction.Tx, database *Database, collId uint32, revision int) error {
+	token, err := request.GetAccessToken(ctx)
+	if err != nil || token == nil || len(token.Sub) == 0 {
+		return nil
+	}
+
+	return tenant.schemaStore.PutAuthor(ctx, tx, tenant.namespace.Id(), database.id, collId, revision, token.Sub)
+}
+
+// ListSchemaRevisions returns all the revisions of the schema of a collection, the oldest revision first.
+func (tenant *Tenant) ListSchemaRevisions(ctx context.Context, tx transaction.Tx, database *Database, collectionName string) ([]*encoding.SchemaRevision, error) {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return nil, api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	c, ok := database.collections[collectionName]
+	if !ok {
+		return nil, api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", collectionName)
+	}
+
+	return tenant.schemaStore.GetRevisions(ctx, tx, tenant.namespace.Id(), database.id, c.id)
+}
+
+// CreateWebhook registers the webhook on the database. The collection of the webhook, if set, must exist in the
+// database.
+func (tenant *Tenant) CreateWebhook(ctx context.Context, tx transaction.Tx, database *Database, hook *encoding.Webhook) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	if len(hook.Collection) > 0 {
+		if _, ok := database.collections[hook.Collection]; !ok {
+			return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", hook.Collection)
+		}
+	}
+
+	if err := tenant.webhookStore.Put(ctx, tx, tenant.namespace.Id(), database.id, hook); err != nil {
+		if err == kv.ErrDuplicateKey {
+			return api.Errorf(api.Code_ALREADY_EXISTS, "webhook already exist '%s'", hook.Name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ListWebhooks returns the webhooks registered on the database.
+func (tenant *Tenant) ListWebhooks(ctx context.Context, tx transaction.Tx, database *Database) ([]*encoding.Webhook, error) {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return nil, api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	return tenant.webhookStore.List(ctx, tx, tenant.namespace.Id(), database.id)
+}
+
+// DeleteWebhook removes the webhook from the database along with its delivery state.
+func (tenant *Tenant) DeleteWebhook(ctx context.Context, tx transaction.Tx, database *Database, name string) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	hook, err := tenant.webhookStore.Get(ctx, tx, tenant.namespace.Id(), database.id, name)
+	if err != nil {
+		return err
+	}
+	if hook == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "webhook doesn't exist '%s'", name)
+	}
+
+	return tenant.webhookStore.Delete(ctx, tx, tenant.namespace.Id(), database.id, name)
+}
+
+// CreateTrigger registers the trigger on the database. Both the collection and the target collection of the trigger
+// must exist in the database and the trigger must not form a cycle with the existing triggers, as the writes of the
+// trigger would otherwise fire it again.
+func (tenant *Tenant) CreateTrigger(ctx context.Context, tx transaction.Tx, database *Database, trigger *encoding.Trigger) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	for _, c := range []string{trigger.Collection, trigger.Target} {
+		if _, ok := database.collections[c]; !ok {
+			return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", c)
+		}
+	}
+
+	if cycle := findTriggerCycle(database.triggers, trigger); len(cycle) > 0 {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "trigger '%s' creates a cycle '%s'", trigger.Name, strings.Join(cycle, " -> "))
+	}
+
+	if err := tenant.triggerStore.Put(ctx, tx, tenant.namespace.Id(), database.id, trigger); err != nil {
+		if err == kv.ErrDuplicateKey {
+			return api.Errorf(api.Code_ALREADY_EXISTS, "trigger already exist '%s'", trigger.Name)
+		}
+		return err
+	}
+
+	// same as the collections, the database is the staged clone so the trigger is visible to the rest of the
+	// transaction
+	database.triggers = append(database.triggers, trigger)
+
+	return nil
+}
+
+// findTriggerCycle returns the collections forming the cycle if the trigger is added to the existing triggers, or nil
+// if there is no cycle. A cycle exists if the collection of the trigger is reachable from its target.
+func findTriggerCycle(triggers []*encoding.Trigger, trigger *encoding.Trigger) []string {
+	targets := make(map[string][]string)
+	for _, t := range triggers {
+		targets[t.Collection] = append(targets[t.Collection], t.Target)
+	}
+
+	visited := make(map[string]bool)
+	var path func(collection string) []string
+	path = func(collection string) []string {
+		if collection == trigger.Collection {
+			return []string{collection}
+		}
+		if visited[collection] {
+			return nil
+		}
+		visited[collection] = true
+
+		for _, target := range targets[collection] {
+			if p := path(target); p != nil {
+				return append([]string{collection}, p...)
+			}
+		}
+		return nil
+	}
+
+	if p := path(trigger.Target); p != nil {
+		return append([]string{trigger.Collection}, p...)
+	}
+	return nil
+}
+
+// DropTrigger removes the trigger from the database.
+func (tenant *Tenant) DropTrigger(ctx context.Context, tx transaction.Tx, database *Database, name string) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	for i, t := range database.triggers {
+		if t.Name == name {
+			if err := tenant.triggerStore.Delete(ctx, tx, tenant.namespace.Id(), database.id, name); err != nil {
+				return err
+			}
+
+			database.triggers = append(database.triggers[:i:i], database.triggers[i+1:]...)
+			return nil
+		}
+	}
+
+	return api.Errorf(api.Code_NOT_FOUND, "trigger doesn't exist '%s'", name)
+}
+
+// PutSearchIndex records the search collection that the collection is searched through, once a reindex has replaced
+// it. The metadata version is bumped in the transaction, so that all the servers switch to the new search collection.
+// The transaction must not have read the metadata version.
+func (tenant *Tenant) PutSearchIndex(ctx context.Context, tx transaction.Tx, dbName string, index *encoding.SearchIndex) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	database := tenant.databases[dbName]
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database doesn't exist '%s'", dbName)
+	}
+	if _, ok := database.collections[index.Collection]; !ok {
+		return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", index.Collection)
+	}
+
+	if err := tenant.settingsStore.PutIndex(ctx, tx, tenant.namespace.Id(), database.id, index); err != nil {
+		return err
+	}
+
+	return tenant.versionH.Increment(ctx, tx)
+}
+
+// UpsertSynonym creates or replaces the synonym set of the collection and pushes it to the search store.
+func (tenant *Tenant) UpsertSynonym(ctx context.Context, tx transaction.Tx, database *Database, synonym *encoding.Synonym, searchStore search.Store) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	cHolder, ok := database.collections[synonym.Collection]
+	if !ok {
+		return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", synonym.Collection)
+	}
+
+	if err := tenant.settingsStore.PutSynonym(ctx, tx, tenant.namespace.Id(), database.id, synonym); err != nil {
+		return err
+	}
+
+	if config.DefaultConfig.Search.WriteEnabled {
+		if err := searchStore.UpsertSynonym(ctx, cHolder.collection.SearchCollectionName(), synonym.Id, ToSearchSynonym(synonym)); err != nil {
+			return err
+		}
+	}
+
+	synonyms := make([]*encoding.Synonym, 0, len(database.synonyms)+1)
+	for _, s := range database.synonyms {
+		if s.Collection != synonym.Collection || s.Id != synonym.Id {
+			synonyms = append(synonyms, s)
+		}
+	}
+	database.synonyms = append(synonyms, synonym)
+
+	return nil
+}
+
+// DeleteSynonym removes the synonym set from the collection and from the search store.
+func (tenant *Tenant) DeleteSynonym(ctx context.Context, tx transaction.Tx, database *Database, collection string, id string, searchStore search.Store) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	cHolder, ok := database.collections[collection]
+	if !ok {
+		return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", collection)
+	}
+
+	for i, s := range database.synonyms {
+		if s.Collection != collection || s.Id != id {
+			continue
+		}
+
+		if err := tenant.settingsStore.DeleteSynonym(ctx, tx, tenant.namespace.Id(), database.id, collection, id); err != nil {
+			return err
+		}
+		if config.DefaultConfig.Search.WriteEnabled {
+			if err := searchStore.DeleteSynonym(ctx, cHolder.collection.SearchCollectionName(), id); err != nil && err != search.ErrNotFound {
+				return err
+			}
+		}
+
+		database.synonyms = append(database.synonyms[:i:i], database.synonyms[i+1:]...)
+		return nil
+	}
+
+	return api.Errorf(api.Code_NOT_FOUND, "synonym doesn't exist '%s'", id)
+}
+
+// SetStopWords replaces the stop words of the collection, an empty list removes them. The stop words are removed from
+// the text of the searches before they are sent to the search store.
+func (tenant *Tenant) SetStopWords(ctx context.Context, tx transaction.Tx, database *Database, stopWords *encoding.StopWords) error {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	if database == nil {
+		return api.Errorf(api.Code_NOT_FOUND, "database missing")
+	}
+
+	if _, ok := database.collections[stopWords.Collection]; !ok {
+		return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", stopWords.Collection)
+	}
+
+	if err := tenant.settingsStore.PutStopWords(ctx, tx, tenant.namespace.Id(), database.id, stopWords); err != nil {
+		return err
+	}
+
+	var all []*encoding.StopWords
+	for _, s := range database.stopWords {
+		if s.Collection != stopWords.Collection {
+			all = append(all, s)
+		}
+	}
+	if len(stopWords.Words) > 0 {
+		all = append(all, stopWords)
+	}
+	database.stopWords = all
+
 	return nil
+}
 
+// ToSearchSynonym returns the synonym set in the form of the search store.
+func ToSearchSynonym(synonym *encoding.Synonym) *tsApi.SearchSynonymSchema {
+	s := &tsApi.SearchSynonymSchema{
+		Synonyms: synonym.Synonyms,
+	}
+	if len(synonym.Root) > 0 {
+		s.Root = &synonym.Root
+	}
+	return s
 }
 
 // DropCollection is to drop a collection and its associated indexes. It removes the "created" entry from the encoding
@@ -766,6 +1220,37 @@ func (tenant *Tenant) dropCollection(ctx context.Context, tx transaction.Tx, db
 		return err
 	}
 
+	// the triggers reading from or writing to the collection can't run anymore
+	var triggers []*encoding.Trigger
+	for _, trigger := range db.triggers {
+		if trigger.Collection != collectionName && trigger.Target != collectionName {
+			triggers = append(triggers, trigger)
+			continue
+		}
+		if err := tenant.triggerStore.Delete(ctx, tx, tenant.namespace.Id(), db.id, trigger.Name); err != nil {
+			return err
+		}
+	}
+	db.triggers = triggers
+
+	if err := tenant.settingsStore.DeleteCollection(ctx, tx, tenant.namespace.Id(), db.id, collectionName); err != nil {
+		return err
+	}
+	var synonyms []*encoding.Synonym
+	for _, s := range db.synonyms {
+		if s.Collection != collectionName {
+			synonyms = append(synonyms, s)
+		}
+	}
+	db.synonyms = synonyms
+	var stopWords []*encoding.StopWords
+	for _, s := range db.stopWords {
+		if s.Collection != collectionName {
+			stopWords = append(stopWords, s)
+		}
+	}
+	db.stopWords = stopWords
+
 	if config.DefaultConfig.Server.FDBDelete {
 		tableName, err := rowKeyEncoder.EncodeTableName(tenant.namespace, db, cHolder.collection)
 		if err != nil {
@@ -785,7 +1270,10 @@ func (tenant *Tenant) dropCollection(ctx context.Context, tx transaction.Tx, db
 		}
 	}
 
-	return nil
+	return addDDLEvent(ctx, kv.DropCollectionEvent, &DDLEvent{
+		Collection: cHolder.name,
+		Revision:   cHolder.collection.SchVer,
+	})
 }
 
 func (tenant *Tenant) getSearchCollName(dbName string, collName string) string {
@@ -805,6 +1293,9 @@ type Database struct {
 	collections           map[string]*collectionHolder
 	needFixingCollections map[string]struct{}
 	idToCollectionMap     map[uint32]string
+	triggers              []*encoding.Trigger
+	synonyms              []*encoding.Synonym
+	stopWords             []*encoding.StopWords
 }
 
 func NewDatabase(id uint32, name string) *Database {
@@ -833,6 +1324,9 @@ func (d *Database) Clone() *Database {
 	for k, v := range d.idToCollectionMap {
 		copyDB.idToCollectionMap[k] = v
 	}
+	copyDB.triggers = append([]*encoding.Trigger(nil), d.triggers...)
+	copyDB.synonyms = append([]*encoding.Synonym(nil), d.synonyms...)
+	copyDB.stopWords = append([]*encoding.StopWords(nil), d.stopWords...)
 
 	return &copyDB
 }
@@ -859,6 +1353,55 @@ func (d *Database) ListCollection() []*schema.DefaultCollection {
 	return collections
 }
 
+// ListTriggers returns all the triggers of this database.
+func (d *Database) ListTriggers() []*encoding.Trigger {
+	d.RLock()
+	defer d.RUnlock()
+
+	return append([]*encoding.Trigger(nil), d.triggers...)
+}
+
+// GetTriggers returns the triggers that are fired by the changes to the collection.
+func (d *Database) GetTriggers(cname string) []*encoding.Trigger {
+	d.RLock()
+	defer d.RUnlock()
+
+	var triggers []*encoding.Trigger
+	for _, t := range d.triggers {
+		if t.Collection == cname {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+// GetSynonyms returns the synonym sets of the collection.
+func (d *Database) GetSynonyms(cname string) []*encoding.Synonym {
+	d.RLock()
+	defer d.RUnlock()
+
+	var synonyms []*encoding.Synonym
+	for _, s := range d.synonyms {
+		if s.Collection == cname {
+			synonyms = append(synonyms, s)
+		}
+	}
+	return synonyms
+}
+
+// GetStopWords returns the stop words of the collection.
+func (d *Database) GetStopWords(cname string) []string {
+	d.RLock()
+	defer d.RUnlock()
+
+	for _, s := range d.stopWords {
+		if s.Collection == cname {
+			return s.Words
+		}
+	}
+	return nil
+}
+
 // GetCollection returns the collection object, or null if the collection map contains no mapping for the database. At
 // this point collection is fully formed and safe to use.
 func (d *Database) GetCollection(cname string) *schema.DefaultCollection {
@@ -909,6 +1452,8 @@ func (c *collectionHolder) clone() *collectionHolder {
 	if err != nil {
 		panic(err)
 	}
+	copyC.collection.FieldRevisions = c.collection.FieldRevisions
+	copyC.collection = copyC.collection.WithSearchIndex(c.collection.SearchCollectionName(), c.collection.SearchIndexVersion)
 	copyC.idxNameToId = make(map[string]uint32)
 	for k, v := range c.idxNameToId {
 		copyC.idxNameToId[k] = v
@@ -952,6 +1497,25 @@ func createCollection(id uint32, schVer int, name string, revision []byte, idxNa
 	return schema.NewDefaultCollection(name, id, schVer, schFactory.Fields, schFactory.Indexes, revision, searchCollectionName), nil
 }
 
+// buildFieldRevisions replays the schema revisions of a collection, the oldest first, to find since which revision
+// every field has its current type. The revisions that can't be built anymore are skipped.
+func buildFieldRevisions(name string, schemas [][]byte, versions []int) map[string]int {
+	var fieldRevisions map[string]int
+	var fields []*schema.Field
+	for i, revision := range schemas {
+		schFactory, err := schema.Build(name, revision)
+		if err != nil {
+			log.Debug().Err(err).Str("collection", name).Int("revision", versions[i]).Msg("skipping schema revision")
+			continue
+		}
+
+		fieldRevisions = schema.NextFieldRevisions(fieldRevisions, fields, schFactory.Fields, versions[i])
+		fields = schFactory.Fields
+	}
+
+	return fieldRevisions
+}
+
 func IsSchemaEq(s1, s2 []byte) (bool, error) {
 	var j, j2 interface{}
 	if err := jsoniter.Unmarshal(s1, &j); err != nil {
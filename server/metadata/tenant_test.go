@@ -40,9 +40,12 @@ func TestTenantManager_CreateOrGetTenant(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	t.Run("create_tenant", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -63,9 +66,12 @@ func TestTenantManager_CreateOrGetTenant(t *testing.T) {
 
 	t.Run("create_multiple_tenants", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -95,9 +101,12 @@ func TestTenantManager_CreateOrGetTenant(t *testing.T) {
 	})
 	t.Run("create_duplicate_tenant_error", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -114,9 +123,12 @@ func TestTenantManager_CreateOrGetTenant(t *testing.T) {
 	})
 	t.Run("create_duplicate_tenant_id_error", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -146,9 +158,12 @@ func TestTenantManager_CreateTenant(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	t.Run("create_tenant", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -167,9 +182,12 @@ func TestTenantManager_CreateTenant(t *testing.T) {
 	})
 	t.Run("create_multiple_tenants", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -195,9 +213,12 @@ func TestTenantManager_CreateTenant(t *testing.T) {
 	})
 	t.Run("create_duplicate_tenant_error", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -215,9 +236,12 @@ func TestTenantManager_CreateTenant(t *testing.T) {
 	})
 	t.Run("create_duplicate_tenant_id_error", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -244,9 +268,12 @@ func TestTenantManager_CreateDatabases(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	t.Run("create_databases", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -291,9 +318,12 @@ func TestTenantManager_CreateCollections(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	t.Run("create_collections", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -374,9 +404,12 @@ func TestTenantManager_DropCollection(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	t.Run("drop_collection", func(t *testing.T) {
 		m := newTenantManager(kvStore, &encoding.TestMDNameRegistry{
-			ReserveSB:  "test_tenant_reserve",
-			EncodingSB: "test_tenant_encoding",
-			SchemaSB:   "test_tenant_schema",
+			ReserveSB:        "test_tenant_reserve",
+			EncodingSB:       "test_tenant_encoding",
+			SchemaSB:         "test_tenant_schema",
+			WebhookSB:        "test_tenant_webhook",
+			TriggerSB:        "test_tenant_trigger",
+			SearchSettingsSB: "test_tenant_search_settings",
 		})
 
 		ctx := context.TODO()
@@ -448,6 +481,27 @@ func TestTenantManager_DropCollection(t *testing.T) {
 	})
 }
 
+func TestFindTriggerCycle(t *testing.T) {
+	triggers := []*encoding.Trigger{
+		{Name: "t1", Collection: "orders", Target: "order_stats"},
+		{Name: "t2", Collection: "order_stats", Target: "customers"},
+		{Name: "t3", Collection: "users", Target: "customers"},
+	}
+
+	for _, c := range []struct {
+		trigger *encoding.Trigger
+		cycle   []string
+	}{
+		{&encoding.Trigger{Collection: "customers", Target: "audit"}, nil},
+		{&encoding.Trigger{Collection: "users", Target: "order_stats"}, nil},
+		{&encoding.Trigger{Collection: "orders", Target: "orders"}, []string{"orders", "orders"}},
+		{&encoding.Trigger{Collection: "customers", Target: "orders"}, []string{"customers", "orders", "order_stats", "customers"}},
+		{&encoding.Trigger{Collection: "order_stats", Target: "orders"}, []string{"order_stats", "orders", "order_stats"}},
+	} {
+		require.Equal(t, c.cycle, findTriggerCycle(triggers, c.trigger))
+	}
+}
+
 func TestMain(m *testing.M) {
 	ulog.Configure(ulog.LogConfig{Level: "disabled"})
 	os.Exit(m.Run())
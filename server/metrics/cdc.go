@@ -0,0 +1,90 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/uber-go/tally"
+)
+
+var (
+	CdcRetention tally.Scope
+	CdcWebhook   tally.Scope
+)
+
+func InitializeCdcScopes() {
+	CdcRetention = CdcMetrics.SubScope("retention")
+	CdcWebhook = CdcMetrics.SubScope("webhook")
+}
+
+func GetCdcTags(dbName string) map[string]string {
+	return map[string]string{
+		"db": dbName,
+	}
+}
+
+// UpdateCdcBacklog reports the size of the change log of the database and the age of the oldest retained entry.
+func UpdateCdcBacklog(dbName string, sizeBytes int64, oldestAge float64) {
+	if CdcRetention == nil {
+		return
+	}
+
+	scope := CdcRetention.Tagged(GetCdcTags(dbName))
+	scope.Gauge("backlog_bytes").Update(float64(sizeBytes))
+	scope.Gauge("oldest_event_age_seconds").Update(oldestAge)
+}
+
+// IncCdcTrimmed counts the change log entries cleared by the retention.
+func IncCdcTrimmed(dbName string, reason string, count int64) {
+	if CdcRetention == nil {
+		return
+	}
+
+	tags := GetCdcTags(dbName)
+	tags["reason"] = reason
+	CdcRetention.Tagged(tags).Counter("trimmed").Inc(count)
+}
+
+func GetWebhookTags(dbName string, webhook string) map[string]string {
+	tags := GetCdcTags(dbName)
+	tags["webhook"] = webhook
+	return tags
+}
+
+// IncWebhookDelivered counts the events posted to a webhook.
+func IncWebhookDelivered(dbName string, webhook string, count int64) {
+	if CdcWebhook == nil {
+		return
+	}
+
+	CdcWebhook.Tagged(GetWebhookTags(dbName, webhook)).Counter("delivered").Inc(count)
+}
+
+// IncWebhookFailedAttempts counts the requests to a webhook that are retried.
+func IncWebhookFailedAttempts(dbName string, webhook string) {
+	if CdcWebhook == nil {
+		return
+	}
+
+	CdcWebhook.Tagged(GetWebhookTags(dbName, webhook)).Counter("failed_attempts").Inc(1)
+}
+
+// IncWebhookDeadLettered counts the events that couldn't be delivered to a webhook.
+func IncWebhookDeadLettered(dbName string, webhook string, count int64) {
+	if CdcWebhook == nil {
+		return
+	}
+
+	CdcWebhook.Tagged(GetWebhookTags(dbName, webhook)).Counter("dead_lettered").Inc(count)
+}
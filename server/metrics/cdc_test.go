@@ -0,0 +1,42 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestCdcMetrics(t *testing.T) {
+	config.DefaultConfig.Metrics.Cdc.Enabled = true
+	InitializeMetrics()
+
+	t.Run("Test Cdc gauges", func(t *testing.T) {
+		UpdateCdcBacklog("db1", 1024, 3600)
+		UpdateCdcBacklog("db2", 0, 0)
+	})
+
+	t.Run("Test Cdc counters", func(t *testing.T) {
+		IncCdcTrimmed("db1", "age", 100)
+		IncCdcTrimmed("db1", "size", 10)
+	})
+
+	t.Run("Test Cdc webhook counters", func(t *testing.T) {
+		IncWebhookDelivered("db1", "hook1", 100)
+		IncWebhookFailedAttempts("db1", "hook1")
+		IncWebhookDeadLettered("db1", "hook1", 10)
+	})
+}
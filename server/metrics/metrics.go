@@ -38,6 +38,10 @@ var (
 	FdbMetrics tally.Scope
 	// Search related metrics scopes
 	SearchMetrics tally.Scope
+	// Cdc related metrics scopes
+	CdcMetrics tally.Scope
+	// Explicit transaction sessions related metrics scopes
+	SessionMetrics tally.Scope
 )
 
 func GetGlobalTags() map[string]string {
@@ -74,5 +78,14 @@ func InitializeMetrics() io.Closer {
 		SearchMetrics = root.SubScope("search")
 		InitializeSearchScopes()
 	}
+	// Change log retention metrics
+	if config.DefaultConfig.Metrics.Cdc.Enabled {
+		CdcMetrics = root.SubScope("cdc")
+		InitializeCdcScopes()
+	}
+	// Explicit transaction session metrics
+	if config.DefaultConfig.Metrics.Session.Enabled {
+		SessionMetrics = root.SubScope("session")
+	}
 	return closer
 }
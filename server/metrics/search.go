@@ -22,11 +22,13 @@ import (
 var (
 	SearchRequests      tally.Scope
 	SearchErrorRequests tally.Scope
+	SearchIndexing      tally.Scope
 )
 
 func InitializeSearchScopes() {
 	SearchRequests = SearchMetrics.SubScope("requests")
 	SearchErrorRequests = SearchRequests.SubScope("error")
+	SearchIndexing = SearchMetrics.SubScope("indexing")
 }
 
 func GetSearchTags(ctx context.Context, reqMethodName string) map[string]string {
@@ -39,3 +41,47 @@ func getSearchReqTags(reqMethodName string) map[string]string {
 		"tigris_tenant": DefaultReportedTigrisTenant,
 	}
 }
+
+func GetSearchIndexingTags(namespace string, dbName string, collection string) map[string]string {
+	return map[string]string{
+		"tigris_tenant": namespace,
+		"db":            dbName,
+		"collection":    collection,
+	}
+}
+
+// UpdateSearchIndexingLag reports how long the oldest change of the collection that is being indexed has been queued.
+func UpdateSearchIndexingLag(namespace string, dbName string, collection string, lag float64) {
+	if SearchIndexing == nil {
+		return
+	}
+
+	SearchIndexing.Tagged(GetSearchIndexingTags(namespace, dbName, collection)).Gauge("lag_seconds").Update(lag)
+}
+
+// IncSearchIndexed counts the changes of the collection indexed from the queue.
+func IncSearchIndexed(namespace string, dbName string, collection string, count int64) {
+	if SearchIndexing == nil {
+		return
+	}
+
+	SearchIndexing.Tagged(GetSearchIndexingTags(namespace, dbName, collection)).Counter("indexed").Inc(count)
+}
+
+// IncSearchIndexingFailedAttempts counts the batches of the collection whose indexing is retried.
+func IncSearchIndexingFailedAttempts(namespace string, dbName string, collection string) {
+	if SearchIndexing == nil {
+		return
+	}
+
+	SearchIndexing.Tagged(GetSearchIndexingTags(namespace, dbName, collection)).Counter("failed_attempts").Inc(1)
+}
+
+// IncSearchIndexingSkipped counts the changes of the collection that are rejected by the search store and skipped.
+func IncSearchIndexingSkipped(namespace string, dbName string, collection string, count int64) {
+	if SearchIndexing == nil {
+		return
+	}
+
+	SearchIndexing.Tagged(GetSearchIndexingTags(namespace, dbName, collection)).Counter("skipped").Inc(count)
+}
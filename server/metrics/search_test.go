@@ -49,4 +49,11 @@ func TestSearchMetrics(t *testing.T) {
 		testHistogramTags := GetSearchTags(ctx, "IndexDocuments")
 		defer SearchMetrics.Tagged(testHistogramTags).Histogram("histogram", tally.DefaultBuckets).Start().Stop()
 	})
+
+	t.Run("Test Search indexing metrics", func(t *testing.T) {
+		UpdateSearchIndexingLag("ns1", "db1", "coll1", 2.5)
+		IncSearchIndexed("ns1", "db1", "coll1", 100)
+		IncSearchIndexingFailedAttempts("ns1", "db1", "coll1")
+		IncSearchIndexingSkipped("ns1", "db1", "coll1", 1)
+	})
 }
@@ -0,0 +1,34 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// UpdateActiveSessions reports the number of explicit transactions that are open.
+func UpdateActiveSessions(count int) {
+	if SessionMetrics == nil {
+		return
+	}
+
+	SessionMetrics.Gauge("active").Update(float64(count))
+}
+
+// IncExpiredSessions counts the explicit transactions rolled back because they were idle or open for too long, the
+// reason is either "idle" or "lifetime".
+func IncExpiredSessions(reason string) {
+	if SessionMetrics == nil {
+		return
+	}
+
+	SessionMetrics.Tagged(map[string]string{"reason": reason}).Counter("expired").Inc(1)
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestSessionMetrics(t *testing.T) {
+	config.DefaultConfig.Metrics.Session.Enabled = true
+	InitializeMetrics()
+
+	t.Run("Test session gauges", func(t *testing.T) {
+		UpdateActiveSessions(3)
+		UpdateActiveSessions(0)
+	})
+
+	t.Run("Test session counters", func(t *testing.T) {
+		IncExpiredSessions("idle")
+		IncExpiredSessions("lifetime")
+	})
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/tigrisdata/tigris/lib/set"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/store/search"
 	"google.golang.org/grpc"
 )
 
@@ -45,12 +47,16 @@ type adminService struct {
 	api.UnimplementedAdminServer
 	tenantMgr *metadata.TenantManager
 	txMgr     *transaction.Manager
+	reindexer *searchReindexer
+	verifier  *searchVerifier
 }
 
-func newAdminService(tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) *adminService {
+func newAdminService(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) *adminService {
 	return &adminService{
 		tenantMgr: tenantMgr,
 		txMgr:     txMgr,
+		reindexer: newSearchReindexer(kvStore, searchStore, tenantMgr, txMgr),
+		verifier:  newSearchVerifier(kvStore, searchStore, tenantMgr, txMgr),
 	}
 }
 
@@ -108,6 +114,17 @@ func (a *adminService) ListNamespaces(ctx context.Context, _ *api.ListNamespaces
 	}, nil
 }
 
+// ReindexCollection rebuilds the search index of a collection from the rows of the collection, the progress is streamed
+// until the new index replaces the previous one.
+func (a *adminService) ReindexCollection(req *api.ReindexCollectionRequest, stream api.Admin_ReindexCollectionServer) error {
+	return a.reindexer.Reindex(stream.Context(), req.GetNamespace(), req.GetDb(), req.GetCollection(), stream.Send)
+}
+
+// VerifyCollection compares a collection with its search index and, when asked to, repairs the differences.
+func (a *adminService) VerifyCollection(ctx context.Context, req *api.VerifyCollectionRequest) (*api.VerifyCollectionResponse, error) {
+	return a.verifier.Verify(ctx, req.GetNamespace(), req.GetDb(), req.GetCollection(), req.GetRepair())
+}
+
 func (a *adminService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel) error {
 	mux := runtime.NewServeMux(
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, &api.CustomMarshaler{JSONBuiltin: &runtime.JSONBuiltin{}}),
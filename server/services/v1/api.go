@@ -28,6 +28,7 @@ import (
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
@@ -46,6 +47,8 @@ const (
 	documentPath        = collectionPath + "/documents"
 	documentPathPattern = documentPath + "/*"
 
+	multiSearchPath = "/multi-search"
+
 	infoPath    = "/info"
 	metricsPath = "/metrics"
 )
@@ -87,8 +90,23 @@ func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *met
 	u.tenantMgr = tenantMgr
 	u.encoder = metadata.NewEncoder(u.tenantMgr)
 	u.cdcMgr = cdc.NewManager()
+	if config.DefaultConfig.Cdc.Enabled {
+		if _, err := u.cdcMgr.StartTrimmer(kv, tenantMgr.ListDatabaseNames); ulog.E(err) {
+			log.Err(err).Msgf("error starting server: starting change log trimmer failed")
+		}
+	}
 	u.sessions = NewSessionManager(u.txMgr, u.tenantMgr, u.versionH, u.cdcMgr, u.searchStore, u.encoder)
-	u.runnerFactory = NewQueryRunnerFactory(u.txMgr, u.encoder, u.cdcMgr, u.searchStore)
+	u.sessions.startSweeper()
+	u.runnerFactory = NewQueryRunnerFactory(u.txMgr, u.encoder, u.cdcMgr, u.searchStore, u.kvStore)
+	if config.DefaultConfig.Cdc.Enabled && config.DefaultConfig.Cdc.Webhook.Enabled {
+		newWebhookDispatcher(u).start()
+	}
+	if config.DefaultConfig.Search.WriteEnabled {
+		newSearchRecovery(u).start()
+	}
+	if config.DefaultConfig.Search.WriteEnabled && config.DefaultConfig.Search.Indexing.Async {
+		newSearchQueueDrainer(u).start()
+	}
 	return u
 }
 
@@ -114,6 +132,9 @@ func (s *apiService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel)
 	router.HandleFunc(apiPathPrefix+documentPathPattern, func(w http.ResponseWriter, r *http.Request) {
 		mux.ServeHTTP(w, r)
 	})
+	router.HandleFunc(apiPathPrefix+multiSearchPath, func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+	})
 	router.HandleFunc(apiPathPrefix+infoPath, func(w http.ResponseWriter, r *http.Request) {
 		mux.ServeHTTP(w, r)
 	})
@@ -126,27 +147,43 @@ func (s *apiService) RegisterGRPC(grpc *grpc.Server) error {
 	return nil
 }
 
-func (s *apiService) BeginTransaction(ctx context.Context, _ *api.BeginTransactionRequest) (*api.BeginTransactionResponse, error) {
+func (s *apiService) BeginTransaction(ctx context.Context, r *api.BeginTransactionRequest) (*api.BeginTransactionResponse, error) {
 	// explicit transactions needed to be tracked
-	session, err := s.sessions.Create(ctx, true, true)
+	session, err := s.sessions.Create(ctx, true, true, transaction.TxOptions{
+		ReadOnly:    r.GetOptions().GetReadOnly(),
+		ReadVersion: r.GetOptions().GetReadVersion(),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &api.BeginTransactionResponse{
+	resp := &api.BeginTransactionResponse{
 		TxCtx: session.txCtx,
-	}, nil
+	}
+	if session.tx.ReadOnly() {
+		// the read version lets the client start more transactions reading the same snapshot
+		if resp.ReadVersion, err = session.tx.GetReadVersion(session.ctx); err != nil {
+			_ = session.Rollback()
+			s.sessions.Remove(session.txCtx.Id)
+			return nil, err
+		}
+	}
+
+	return resp, nil
 }
 
 func (s *apiService) CommitTransaction(ctx context.Context, _ *api.CommitTransactionRequest) (*api.CommitTransactionResponse, error) {
 	txCtx := api.GetTransaction(ctx)
-	session := s.sessions.Get(txCtx.GetId())
+	session, err := s.sessions.Get(txCtx.GetId())
+	if err != nil {
+		return nil, err
+	}
 	if session == nil {
 		return nil, api.Errorf(api.Code_NOT_FOUND, "session not found")
 	}
 	defer s.sessions.Remove(session.txCtx.Id)
 
-	err := session.Commit(s.versionH, session.tx.Context().GetStagedDatabase() != nil, nil)
+	err = session.Commit(s.versionH, session.tx.Context().GetStagedDatabase() != nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +193,10 @@ func (s *apiService) CommitTransaction(ctx context.Context, _ *api.CommitTransac
 
 func (s *apiService) RollbackTransaction(ctx context.Context, _ *api.RollbackTransactionRequest) (*api.RollbackTransactionResponse, error) {
 	txCtx := api.GetTransaction(ctx)
-	session := s.sessions.Get(txCtx.GetId())
+	session, err := s.sessions.Get(txCtx.GetId())
+	if err != nil {
+		return nil, err
+	}
 	if session == nil {
 		return nil, api.Errorf(api.Code_NOT_FOUND, "session not found")
 	}
@@ -271,18 +311,53 @@ func (s *apiService) CreateOrUpdateCollection(ctx context.Context, r *api.Create
 	resp, err := s.sessions.Execute(ctx, &ReqOptions{
 		txCtx:          api.GetTransaction(ctx),
 		queryRunner:    runner,
-		metadataChange: true,
+		metadataChange: !r.GetDryRun(),
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if r.GetDryRun() {
+		planResp := resp.Response.(*api.CreateOrUpdateCollectionResponse)
+		planResp.Status = resp.status
+		planResp.Message = "collection plan generated successfully"
+		return planResp, nil
+	}
+
+	if r.GetDropFields() && api.GetTransaction(ctx) == nil && config.DefaultConfig.Schema.BackgroundRewrite {
+		// the dropped fields are already hidden from the reads, the rewrite is only reclaiming the storage
+		namespace, err := request.GetNamespace(ctx)
+		if err != nil {
+			return nil, err
+		}
+		go s.rewriteCollection(request.SetNamespace(context.Background(), namespace), r.GetDb(), r.GetCollection())
+	}
+
 	return &api.CreateOrUpdateCollectionResponse{
 		Status:  resp.status,
 		Message: "collection created successfully",
 	}, nil
 }
 
+// rewriteCollection rewrites the documents of the collection in batches, every batch in its own transaction and
+// starting after the last row of the previous batch, till the whole collection is read.
+func (s *apiService) rewriteCollection(ctx context.Context, db string, collection string) {
+	batchSize := config.DefaultConfig.Schema.RewriteBatchSize
+	runner := s.runnerFactory.GetRewriteQueryRunner(db, collection, batchSize)
+	for {
+		if _, err := s.sessions.Execute(ctx, &ReqOptions{
+			queryRunner: runner,
+		}); err != nil {
+			log.Err(err).Str("db", db).Str("collection", collection).Msg("rewriting collection failed")
+			return
+		}
+		if runner.read < batchSize {
+			return
+		}
+		runner.resume()
+	}
+}
+
 func (s *apiService) DropCollection(ctx context.Context, r *api.DropCollectionRequest) (*api.DropCollectionResponse, error) {
 	runner := s.runnerFactory.GetCollectionQueryRunner()
 	runner.SetDropCollectionReq(r)
@@ -379,6 +454,216 @@ func (s *apiService) DescribeCollection(ctx context.Context, r *api.DescribeColl
 	return resp.Response.(*api.DescribeCollectionResponse), nil
 }
 
+func (s *apiService) ListSchemaRevisions(ctx context.Context, r *api.ListSchemaRevisionsRequest) (*api.ListSchemaRevisionsResponse, error) {
+	runner := s.runnerFactory.GetCollectionQueryRunner()
+	runner.SetListSchemaRevisionsReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner: runner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Response.(*api.ListSchemaRevisionsResponse), nil
+}
+
+func (s *apiService) GetSchemaRevision(ctx context.Context, r *api.GetSchemaRevisionRequest) (*api.GetSchemaRevisionResponse, error) {
+	runner := s.runnerFactory.GetCollectionQueryRunner()
+	runner.SetGetSchemaRevisionReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner: runner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Response.(*api.GetSchemaRevisionResponse), nil
+}
+
+func (s *apiService) RollbackSchema(ctx context.Context, r *api.RollbackSchemaRequest) (*api.RollbackSchemaResponse, error) {
+	runner := s.runnerFactory.GetCollectionQueryRunner()
+	runner.SetRollbackSchemaReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rollbackResp := resp.Response.(*api.RollbackSchemaResponse)
+	rollbackResp.Status = resp.status
+	rollbackResp.Message = "schema rolled back successfully"
+	return rollbackResp, nil
+}
+
+func (s *apiService) CreateWebhook(ctx context.Context, r *api.CreateWebhookRequest) (*api.CreateWebhookResponse, error) {
+	if !config.DefaultConfig.Cdc.Enabled || !config.DefaultConfig.Cdc.Webhook.Enabled {
+		return nil, api.Errorf(api.Code_METHOD_NOT_ALLOWED, "webhooks are disabled")
+	}
+	if err := validateWebhookURL(config.DefaultConfig.Cdc.Webhook, r.GetUrl()); err != nil {
+		return nil, err
+	}
+
+	runner := s.runnerFactory.GetWebhookQueryRunner()
+	runner.SetCreateWebhookReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateWebhookResponse{
+		Status:  resp.status,
+		Message: "webhook created successfully",
+	}, nil
+}
+
+func (s *apiService) ListWebhooks(ctx context.Context, r *api.ListWebhooksRequest) (*api.ListWebhooksResponse, error) {
+	runner := s.runnerFactory.GetWebhookQueryRunner()
+	runner.SetListWebhooksReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner: runner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Response.(*api.ListWebhooksResponse), nil
+}
+
+func (s *apiService) DeleteWebhook(ctx context.Context, r *api.DeleteWebhookRequest) (*api.DeleteWebhookResponse, error) {
+	runner := s.runnerFactory.GetWebhookQueryRunner()
+	runner.SetDeleteWebhookReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner: runner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.DeleteWebhookResponse{
+		Status:  resp.status,
+		Message: "webhook deleted successfully",
+	}, nil
+}
+
+func (s *apiService) CreateTrigger(ctx context.Context, r *api.CreateTriggerRequest) (*api.CreateTriggerResponse, error) {
+	if !config.DefaultConfig.Trigger.Enabled {
+		return nil, api.Errorf(api.Code_METHOD_NOT_ALLOWED, "triggers are disabled")
+	}
+
+	runner := s.runnerFactory.GetTriggerQueryRunner()
+	runner.SetCreateTriggerReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateTriggerResponse{
+		Status:  resp.status,
+		Message: "trigger created successfully",
+	}, nil
+}
+
+func (s *apiService) ListTriggers(ctx context.Context, r *api.ListTriggersRequest) (*api.ListTriggersResponse, error) {
+	runner := s.runnerFactory.GetTriggerQueryRunner()
+	runner.SetListTriggersReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner: runner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Response.(*api.ListTriggersResponse), nil
+}
+
+func (s *apiService) DropTrigger(ctx context.Context, r *api.DropTriggerRequest) (*api.DropTriggerResponse, error) {
+	runner := s.runnerFactory.GetTriggerQueryRunner()
+	runner.SetDropTriggerReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.DropTriggerResponse{
+		Status:  resp.status,
+		Message: "trigger dropped successfully",
+	}, nil
+}
+
+func (s *apiService) CreateOrUpdateSynonymSet(ctx context.Context, r *api.CreateOrUpdateSynonymSetRequest) (*api.CreateOrUpdateSynonymSetResponse, error) {
+	runner := s.runnerFactory.GetSearchSettingsQueryRunner()
+	runner.SetCreateOrUpdateSynonymSetReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateOrUpdateSynonymSetResponse{
+		Status:  resp.status,
+		Message: "synonym set created or updated successfully",
+	}, nil
+}
+
+func (s *apiService) DeleteSynonymSet(ctx context.Context, r *api.DeleteSynonymSetRequest) (*api.DeleteSynonymSetResponse, error) {
+	runner := s.runnerFactory.GetSearchSettingsQueryRunner()
+	runner.SetDeleteSynonymSetReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.DeleteSynonymSetResponse{
+		Status:  resp.status,
+		Message: "synonym set deleted successfully",
+	}, nil
+}
+
+func (s *apiService) SetStopWords(ctx context.Context, r *api.SetStopWordsRequest) (*api.SetStopWordsResponse, error) {
+	runner := s.runnerFactory.GetSearchSettingsQueryRunner()
+	runner.SetStopWordsReq(r)
+
+	resp, err := s.sessions.Execute(ctx, &ReqOptions{
+		queryRunner:    runner,
+		metadataChange: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SetStopWordsResponse{
+		Status:  resp.status,
+		Message: "stop words updated successfully",
+	}, nil
+}
+
 func (s *apiService) DescribeDatabase(ctx context.Context, r *api.DescribeDatabaseRequest) (*api.DescribeDatabaseResponse, error) {
 	runner := s.runnerFactory.GetDatabaseQueryRunner()
 	runner.SetDescribeDatabaseReq(r)
@@ -403,49 +688,189 @@ func (s *apiService) Events(r *api.EventsRequest, stream api.Tigris_EventsServer
 	if !config.DefaultConfig.Cdc.Enabled {
 		return api.Errorf(api.Code_METHOD_NOT_ALLOWED, "change streams is disabled for this collection")
 	}
+	opts := cdc.StreamerOptions{
+		ResumeToken:   r.GetResumeToken(),
+		FromBeginning: r.GetFromBeginning(),
+	}
+	if r.GetStartTime() != nil {
+		opts.StartTime = r.GetStartTime().AsTime()
+	}
+
+	tenant, err := s.getEventsTenant(stream.Context())
+	if err != nil {
+		return err
+	}
+	eventF, err := newEventFilter(r, tenant.GetCollection(r.GetDb(), r.GetCollection()))
+	if err != nil {
+		return err
+	}
+
 	publisher := s.cdcMgr.GetPublisher(r.GetDb())
-	streamer, err := publisher.NewStreamer(s.kvStore)
+	streamer, err := publisher.NewStreamer(s.kvStore, opts)
 	if err != nil {
 		return err
 	}
 	defer streamer.Close()
 
-	for tx := range streamer.Txs {
-		for _, op := range tx.Ops {
-			_, _, collection, ok := s.encoder.DecodeTableName(op.Table)
+	for {
+		var tx cdc.Tx
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case next, ok := <-streamer.Txs:
 			if !ok {
-				log.Err(err).Str("table", string(op.Table)).Msg("failed to decode collection name")
-				return api.Errorf(api.Code_INTERNAL, "failed to decode collection name")
+				return streamer.Err()
 			}
+			tx = next
+		}
 
-			if r.Collection == "" || r.Collection == collection {
-				td, err := internal.Decode(op.Data)
-				if err != nil {
-					log.Err(err).Str("data", string(op.Data)).Msg("failed to decode data")
-					return api.Errorf(api.Code_INTERNAL, "failed to decode data")
-				}
+		events, err := streamEvents(s.encoder, tenant, r.GetDb(), eventF, &tx)
+		if err != nil {
+			return err
+		}
 
-				event := &api.StreamEvent{
-					TxId:       tx.Id,
-					Collection: collection,
-					Op:         op.Op,
-					Key:        op.Key,
-					Lkey:       op.LKey,
-					Rkey:       op.RKey,
-					Data:       td.RawData,
-					Last:       op.Last,
-				}
+		for _, event := range events {
+			response := &api.EventsResponse{
+				Event: event,
+			}
 
-				response := &api.EventsResponse{
-					Event: event,
-				}
+			if err := stream.Send(response); ulog.E(err) {
+				return err
+			}
+		}
+	}
+}
+
+// getEventsTenant returns the tenant of the events request, it is needed to decode the events using the schema of
+// the collections.
+func (s *apiService) getEventsTenant(ctx context.Context) (*metadata.Tenant, error) {
+	namespace, err := request.GetNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace, s.txMgr)
+	if err != nil || tenant == nil {
+		return nil, api.Errorf(api.Code_NOT_FOUND, "Tenant %s not found", namespace)
+	}
+
+	return tenant, nil
+}
+
+// streamEvents returns the events of the operations of the change log transaction that pass the filter.
+func streamEvents(encoder metadata.Encoder, tenant *metadata.Tenant, db string, eventF *eventFilter, tx *cdc.Tx) ([]*api.StreamEvent, error) {
+	var events []*api.StreamEvent
+	for _, op := range tx.Ops {
+		if kv.IsDDLEvent(op.Op) {
+			ddl, err := metadata.DecodeDDLEvent(op.Data)
+			if err != nil {
+				log.Err(err).Str("op", op.Op).Msg("failed to decode ddl event")
+				return nil, api.Errorf(api.Code_INTERNAL, "failed to decode ddl event")
+			}
 
-				if err := stream.Send(response); ulog.E(err) {
-					return err
+			if eventF.matchesEvent(ddl.Collection, op.Op) {
+				events = append(events, &api.StreamEvent{
+					TxId:           tx.Id,
+					Collection:     ddl.Collection,
+					Op:             op.Op,
+					Last:           op.Last,
+					ResumeToken:    tx.ResumeToken,
+					Schema:         ddl.Schema,
+					SchemaRevision: int32(ddl.Revision),
+				})
+			}
+			continue
+		}
+
+		_, _, collection, ok := encoder.DecodeTableName(op.Table)
+		if !ok {
+			log.Error().Str("table", string(op.Table)).Msg("failed to decode collection name")
+			return nil, api.Errorf(api.Code_INTERNAL, "failed to decode collection name")
+		}
+
+		if !eventF.matchesEvent(collection, op.Op) {
+			continue
+		}
+
+		data, err := decodeEventData(op.Data)
+		if err != nil {
+			return nil, err
+		}
+		oldData, err := decodeEventData(op.OldData)
+		if err != nil {
+			return nil, err
+		}
+
+		// deletes only carry the document if the pre-image is enabled
+		filterData := data
+		if len(filterData) == 0 {
+			filterData = oldData
+		}
+		if matches, err := eventF.matchesData(filterData); err != nil {
+			log.Err(err).Str("data", string(filterData)).Msg("failed to filter data")
+			return nil, api.Errorf(api.Code_INTERNAL, "failed to filter data")
+		} else if !matches {
+			continue
+		}
+
+		event := &api.StreamEvent{
+			TxId:        tx.Id,
+			Collection:  collection,
+			Op:          op.Op,
+			Key:         op.Key,
+			Lkey:        op.LKey,
+			Rkey:        op.RKey,
+			Data:        data,
+			OldData:     oldData,
+			Last:        op.Last,
+			ResumeToken: tx.ResumeToken,
+		}
+
+		// range deletes that are not expanded don't belong to a single document
+		if op.Op != kv.DeleteRangeEvent {
+			if coll := tenant.GetCollection(db, collection); coll != nil {
+				if event.PrimaryKey, err = primaryKeyToJSON(op.Table, op.Key, coll.Indexes.PrimaryKey); err != nil {
+					log.Err(err).Str("collection", collection).Msg("failed to decode primary key")
+					return nil, api.Errorf(api.Code_INTERNAL, "failed to decode primary key")
 				}
 			}
 		}
+
+		events = append(events, event)
 	}
 
-	return nil
+	return events, nil
+}
+
+// decodeEventData returns the JSON document of the encoded table data of the event.
+func decodeEventData(encoded []byte) ([]byte, error) {
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+
+	td, err := internal.Decode(encoded)
+	if err != nil {
+		log.Err(err).Str("data", string(encoded)).Msg("failed to decode data")
+		return nil, api.Errorf(api.Code_INTERNAL, "failed to decode data")
+	}
+
+	return td.RawData, nil
+}
+
+func (s *apiService) MultiSearch(ctx context.Context, r *api.MultiSearchRequest) (*api.MultiSearchResponse, error) {
+	txCtx := api.GetTransaction(ctx)
+	m := &multiSearch{
+		req: r,
+		// the searches in an explicit transaction share it, so they run one after the other
+		parallel: txCtx == nil,
+		run: func(ctx context.Context, req *api.SearchRequest, streaming SearchStreaming) error {
+			_, err := s.sessions.Execute(ctx, &ReqOptions{
+				txCtx:       txCtx,
+				queryRunner: s.runnerFactory.GetSearchQueryRunner(req, streaming),
+			})
+			return err
+		},
+	}
+
+	return m.execute(ctx)
 }
@@ -0,0 +1,110 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+var streamEventOps = map[string]struct{}{
+	kv.InsertEvent:      {},
+	kv.ReplaceEvent:     {},
+	kv.UpdateEvent:      {},
+	kv.UpdateRangeEvent: {},
+	kv.DeleteEvent:      {},
+	kv.DeleteRangeEvent: {},
+
+	kv.CreateDatabaseEvent:   {},
+	kv.DropDatabaseEvent:     {},
+	kv.CreateCollectionEvent: {},
+	kv.UpdateCollectionEvent: {},
+	kv.DropCollectionEvent:   {},
+}
+
+// eventFilter drops the change events that the subscriber is not interested in, so that they are not decoded and sent
+// on the stream.
+type eventFilter struct {
+	collection string
+	ops        map[string]struct{}
+	wrappedF   *filter.WrappedFilter
+}
+
+// newEventFilter builds the filter of the events request. The collection is only needed if the request has a document
+// filter, as the filter is built on the queryable fields of the collection.
+func newEventFilter(r *api.EventsRequest, collection *schema.DefaultCollection) (*eventFilter, error) {
+	f := &eventFilter{
+		collection: r.GetCollection(),
+	}
+
+	if len(r.GetOps()) > 0 {
+		f.ops = make(map[string]struct{})
+		for _, op := range r.GetOps() {
+			if _, ok := streamEventOps[op]; !ok {
+				return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported event operation '%s'", op)
+			}
+			f.ops[op] = struct{}{}
+		}
+	}
+
+	if !filter.All(r.GetFilter()) {
+		if collection == nil {
+			return nil, api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", r.GetCollection())
+		}
+
+		wrappedF, err := filter.NewFactory(collection.QueryableFields).WrappedFilter(r.GetFilter())
+		if err != nil {
+			return nil, err
+		}
+		f.wrappedF = wrappedF
+	}
+
+	return f, nil
+}
+
+// matchesEvent returns true if the collection and the operation of the event are the one subscribed to. The events of
+// the database operations don't have a collection and are passed to the subscribers of any collection.
+func (f *eventFilter) matchesEvent(collection string, op string) bool {
+	if len(f.collection) > 0 && len(collection) > 0 && f.collection != collection {
+		return false
+	}
+	if f.ops != nil {
+		if _, ok := f.ops[op]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesData returns true if the document of the event passes the document filter. The nested fields of the filter are
+// looked up in the nested objects of the document and a document without one of the fields doesn't pass it. Events that
+// don't carry a document, like the deletes of a collection without the pre-image, can't be evaluated against the filter
+// and are always passed.
+func (f *eventFilter) matchesData(data []byte) (bool, error) {
+	if f.wrappedF == nil || len(data) == 0 {
+		return true, nil
+	}
+
+	var doc map[string]interface{}
+	if err := jsoniter.Unmarshal(data, &doc); err != nil {
+		return false, err
+	}
+
+	return f.wrappedF.Filter.MatchesDoc(doc), nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestEventFilter(t *testing.T) {
+	reqSchema := []byte(`{"title": "orders", "properties": {"id": {"type": "integer"}, "status": {"type": "string"}}, "primary_key": ["id"]}`)
+	factory, err := schema.Build("orders", reqSchema)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("orders", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "orders")
+
+	t.Run("collection_and_ops", func(t *testing.T) {
+		f, err := newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders", Ops: []string{kv.InsertEvent, kv.DeleteEvent}}, nil)
+		require.NoError(t, err)
+		require.True(t, f.matchesEvent("orders", kv.InsertEvent))
+		require.True(t, f.matchesEvent("orders", kv.DeleteEvent))
+		require.False(t, f.matchesEvent("orders", kv.UpdateEvent))
+		require.False(t, f.matchesEvent("users", kv.InsertEvent))
+		matches, err := f.matchesData([]byte(`{"id": 1}`))
+		require.NoError(t, err)
+		require.True(t, matches)
+	})
+
+	t.Run("no_filter", func(t *testing.T) {
+		f, err := newEventFilter(&api.EventsRequest{Db: "db1"}, nil)
+		require.NoError(t, err)
+		require.True(t, f.matchesEvent("orders", kv.ReplaceEvent))
+		require.True(t, f.matchesEvent("users", kv.DeleteRangeEvent))
+	})
+
+	t.Run("ddl_ops", func(t *testing.T) {
+		f, err := newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders", Ops: []string{kv.UpdateCollectionEvent, kv.DropDatabaseEvent}}, nil)
+		require.NoError(t, err)
+		require.True(t, f.matchesEvent("orders", kv.UpdateCollectionEvent))
+		require.False(t, f.matchesEvent("users", kv.UpdateCollectionEvent))
+		require.False(t, f.matchesEvent("orders", kv.DropCollectionEvent))
+		// the database events don't belong to a collection
+		require.True(t, f.matchesEvent("", kv.DropDatabaseEvent))
+		require.False(t, f.matchesEvent("", kv.CreateDatabaseEvent))
+	})
+
+	t.Run("document_filter", func(t *testing.T) {
+		f, err := newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders", Filter: []byte(`{"status": "shipped"}`)}, coll)
+		require.NoError(t, err)
+		for _, c := range []struct {
+			data    []byte
+			matches bool
+		}{
+			{[]byte(`{"id": 1, "status": "shipped"}`), true},
+			{[]byte(`{"id": 2, "status": "pending"}`), false},
+			// deletes don't carry the document
+			{nil, true},
+		} {
+			matches, err := f.matchesData(c.data)
+			require.NoError(t, err)
+			require.Equal(t, c.matches, matches)
+		}
+	})
+
+	t.Run("document_filter_fields", func(t *testing.T) {
+		reqSchema := []byte(`{"title": "orders", "properties": {
+			"id": {"type": "integer"},
+			"status": {"type": "string"},
+			"shipped_at": {"type": "string", "format": "date-time"},
+			"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+		}, "primary_key": ["id"]}`)
+		factory, err := schema.Build("orders", reqSchema)
+		require.NoError(t, err)
+		coll := schema.NewDefaultCollection("orders", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "orders")
+
+		for _, c := range []struct {
+			filter  string
+			data    string
+			matches bool
+		}{
+			{`{"address.city": "Paris"}`, `{"id": 1, "address": {"city": "Paris"}}`, true},
+			{`{"address.city": "Paris"}`, `{"id": 1, "address": {"city": "Lyon"}}`, false},
+			{`{"address.city": "Paris"}`, `{"id": 1, "address": null}`, false},
+			{`{"status": "shipped"}`, `{"id": 1, "status": null}`, false},
+			{`{"status": "shipped"}`, `{"id": 1}`, false},
+			{`{"shipped_at": {"$gte": "2022-10-03T12:00:00Z"}}`, `{"id": 1, "shipped_at": "2022-10-03T14:30:00+02:00"}`, true},
+			{`{"shipped_at": {"$gte": "2022-10-03T12:00:00Z"}}`, `{"id": 1, "shipped_at": "2022-10-03T13:30:00+02:00"}`, false},
+			{`{"shipped_at": {"$gte": "2022-10-03T12:00:00Z"}}`, `{"id": 1, "shipped_at": null}`, false},
+		} {
+			f, err := newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders", Filter: []byte(c.filter)}, coll)
+			require.NoError(t, err)
+
+			matches, err := f.matchesData([]byte(c.data))
+			require.NoError(t, err)
+			require.Equal(t, c.matches, matches, "%s on %s", c.filter, c.data)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := newEventFilter(&api.EventsRequest{Db: "db1", Ops: []string{"truncate"}}, nil)
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported event operation 'truncate'"), err)
+
+		_, err = newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders", Filter: []byte(`{"status": "shipped"}`)}, nil)
+		require.Equal(t, api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist 'orders'"), err)
+
+		_, err = newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders", Filter: []byte(`{"unknown": "shipped"}`)}, coll)
+		require.Error(t, err)
+	})
+}
+
+func TestStreamDDLEvents(t *testing.T) {
+	tx := &cdc.Tx{
+		Id:          []byte{0x01},
+		ResumeToken: []byte{0x02},
+		Ops: []*kv.Event{
+			{Op: kv.CreateCollectionEvent, Data: []byte(`{"collection":"orders","schema":{"title":"orders"},"revision":1}`)},
+			{Op: kv.DropCollectionEvent, Data: []byte(`{"collection":"users","revision":3}`)},
+			{Op: kv.DropDatabaseEvent, Data: []byte(`{}`), Last: true},
+		},
+	}
+
+	f, err := newEventFilter(&api.EventsRequest{Db: "db1", Collection: "orders"}, nil)
+	require.NoError(t, err)
+
+	// the ddl events are decoded without the encoder and the tenant as they are not tied to a table
+	events, err := streamEvents(nil, nil, "db1", f, tx)
+	require.NoError(t, err)
+	require.Equal(t, []*api.StreamEvent{
+		{
+			TxId:           []byte{0x01},
+			Collection:     "orders",
+			Op:             kv.CreateCollectionEvent,
+			ResumeToken:    []byte{0x02},
+			Schema:         []byte(`{"title":"orders"}`),
+			SchemaRevision: 1,
+		},
+		{
+			TxId:        []byte{0x01},
+			Op:          kv.DropDatabaseEvent,
+			Last:        true,
+			ResumeToken: []byte{0x02},
+		},
+	}, events)
+
+	tx.Ops = []*kv.Event{{Op: kv.UpdateCollectionEvent, Data: []byte(`{`)}}
+	_, err = streamEvents(nil, nil, "db1", f, tx)
+	require.Equal(t, api.Errorf(api.Code_INTERNAL, "failed to decode ddl event"), err)
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/internal"
@@ -120,6 +123,36 @@ func (k *keyGenerator) getJsonQuotedValue(fieldType schema.FieldType, jsonVal []
 	}
 }
 
+// primaryKeyToJSON decodes the storage key of a document back to the JSON object of the primary key fields, in the
+// same form as the keys returned in the Insert/Replace responses.
+func primaryKeyToJSON(table []byte, key []byte, index *schema.Index) ([]byte, error) {
+	tp, err := subspace.FromBytes(table).Unpack(fdb.Key(key))
+	if err != nil {
+		return nil, err
+	}
+
+	// the zeroth entry represents index key name
+	if len(tp) != len(index.Fields)+1 {
+		return nil, fmt.Errorf("key doesn't match the index '%s'", index.Name)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range index.Fields {
+		jsonVal, err := jsoniter.Marshal(tp[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(fmt.Sprintf(`"%s":%s`, f.FieldName, jsonVal))
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
 type generator struct {
 	txMgr *transaction.Manager
 }
@@ -0,0 +1,58 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestPrimaryKeyToJSON(t *testing.T) {
+	table := []byte("t1")
+
+	cases := []struct {
+		schema []byte
+		parts  tuple.Tuple
+		exp    []byte
+	}{
+		{
+			[]byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"]}`),
+			tuple.Tuple{int64(10)},
+			[]byte(`{"id":10}`),
+		}, {
+			[]byte(`{"title": "t1", "properties": {"b": {"type": "string", "format": "byte"}, "s": {"type": "string"}}, "primary_key": ["s", "b"]}`),
+			tuple.Tuple{"foo", []byte("bar")},
+			[]byte(`{"s":"foo","b":"YmFy"}`),
+		},
+	}
+	for _, c := range cases {
+		factory, err := schema.Build("t1", c.schema)
+		require.NoError(t, err)
+
+		key := subspace.FromBytes(table).Pack(append(tuple.Tuple{int64(1)}, c.parts...))
+		actual, err := primaryKeyToJSON(table, key, factory.Indexes.PrimaryKey)
+		require.NoError(t, err)
+		require.Equal(t, c.exp, actual)
+	}
+
+	factory, err := schema.Build("t1", cases[1].schema)
+	require.NoError(t, err)
+	_, err = primaryKeyToJSON(table, subspace.FromBytes(table).Pack(tuple.Tuple{int64(1), "foo"}), factory.Indexes.PrimaryKey)
+	require.Error(t, err)
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"google.golang.org/grpc"
+)
+
+// maxMergedSearchHits is the maximum number of hits a merged multi search ranks, it bounds how far it can page as
+// every search needs to return all the hits up to the requested page.
+const maxMergedSearchHits = 250
+
+// searchCollector collects the responses of a search instead of streaming them, so that the searches of a multi
+// search run like any other search.
+type searchCollector struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	responses []*api.SearchResponse
+}
+
+func (c *searchCollector) Context() context.Context {
+	return c.ctx
+}
+
+func (c *searchCollector) Send(resp *api.SearchResponse) error {
+	c.responses = append(c.responses, resp)
+	return nil
+}
+
+// multiSearch runs the searches of a multi search and returns their results, either per search or merged into a
+// single list of hits ranked by their score.
+type multiSearch struct {
+	req      *api.MultiSearchRequest
+	parallel bool
+	run      func(ctx context.Context, req *api.SearchRequest, streaming SearchStreaming) error
+}
+
+func (m *multiSearch) execute(ctx context.Context) (*api.MultiSearchResponse, error) {
+	page, pageSize := int(m.req.GetPage()), int(m.req.GetPageSize())
+	if page == 0 {
+		page = defaultPageNo
+	}
+	if pageSize == 0 {
+		pageSize = defaultPerPage
+	}
+	if m.req.GetMerge() && page*pageSize > maxMergedSearchHits {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "merged multi search can rank at most %d hits", maxMergedSearchHits)
+	}
+
+	searches := m.req.GetSearches()
+	for _, search := range searches {
+		if m.req.GetMerge() {
+			// every search returns its best hits up to the requested page of the merged hits
+			search.Page, search.PageSize = defaultPageNo, int32(page*pageSize)
+		} else if search.Page == 0 {
+			// a single page is returned per search
+			search.Page = defaultPageNo
+		}
+	}
+
+	results := make([]*api.SearchResponse, len(searches))
+	errs := make([]error, len(searches))
+	runSearch := func(i int) {
+		collector := &searchCollector{ctx: ctx}
+		if errs[i] = m.run(ctx, searches[i], collector); errs[i] == nil && len(collector.responses) > 0 {
+			results[i] = collector.responses[0]
+		}
+	}
+
+	if m.parallel {
+		var wg sync.WaitGroup
+		for i := range searches {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				runSearch(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range searches {
+			runSearch(i)
+		}
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+		if results[i] == nil {
+			results[i] = &api.SearchResponse{}
+		}
+	}
+
+	if !m.req.GetMerge() {
+		return &api.MultiSearchResponse{Results: results}, nil
+	}
+	return mergeSearchResults(searches, results, page, pageSize), nil
+}
+
+// mergeSearchResults ranks the hits of all the searches by their score and returns the requested page of them. The
+// hits with the same score keep the order of the searches and then their order in the search. The results of the
+// searches keep their facets and metadata.
+func mergeSearchResults(searches []*api.SearchRequest, results []*api.SearchResponse, page int, pageSize int) *api.MultiSearchResponse {
+	var found int64
+	var hits []*api.MultiSearchHit
+	for i, result := range results {
+		for _, hit := range result.Hits {
+			hits = append(hits, &api.MultiSearchHit{
+				Search:     int32(i),
+				Db:         searches[i].GetDb(),
+				Collection: searches[i].GetCollection(),
+				Hit:        hit,
+			})
+		}
+		if result.Meta != nil {
+			found += result.Meta.Found
+		}
+		result.Hits = nil
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Hit.GetMetadata().GetScore() > hits[j].Hit.GetMetadata().GetScore()
+	})
+
+	start, end := (page-1)*pageSize, page*pageSize
+	if start > len(hits) {
+		start = len(hits)
+	}
+	if end > len(hits) {
+		end = len(hits)
+	}
+
+	return &api.MultiSearchResponse{
+		Results: results,
+		Hits:    hits[start:end],
+		Meta: &api.SearchMetadata{
+			Found:      found,
+			TotalPages: int32(math.Ceil(float64(found) / float64(pageSize))),
+			Page: &api.Page{
+				Current: int32(page),
+				Size:    int32(pageSize),
+			},
+		},
+	}
+}
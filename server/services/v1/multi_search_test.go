@@ -0,0 +1,164 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+func testSearchHit(id string, score int64) *api.SearchHit {
+	return &api.SearchHit{
+		Data:     []byte(fmt.Sprintf(`{"id":"%s"}`, id)),
+		Metadata: &api.SearchHitMeta{Score: score},
+	}
+}
+
+func testHitIds(hits []*api.MultiSearchHit) []string {
+	var ids []string
+	for _, hit := range hits {
+		ids = append(ids, string(hit.Hit.Data))
+	}
+	return ids
+}
+
+func TestMergeSearchResults(t *testing.T) {
+	searches := []*api.SearchRequest{
+		{Db: "db1", Collection: "c1"},
+		{Db: "db2", Collection: "c2"},
+	}
+	newResults := func() []*api.SearchResponse {
+		return []*api.SearchResponse{
+			{
+				Hits: []*api.SearchHit{testSearchHit("a", 30), testSearchHit("b", 10)},
+				Meta: &api.SearchMetadata{Found: 2},
+			},
+			{
+				Hits: []*api.SearchHit{testSearchHit("c", 20), testSearchHit("d", 10), testSearchHit("e", 5)},
+				Meta: &api.SearchMetadata{Found: 3},
+			},
+		}
+	}
+
+	t.Run("ranks the hits by score", func(t *testing.T) {
+		resp := mergeSearchResults(searches, newResults(), 1, 10)
+		require.Equal(t, []string{`{"id":"a"}`, `{"id":"c"}`, `{"id":"b"}`, `{"id":"d"}`, `{"id":"e"}`}, testHitIds(resp.Hits))
+		require.Equal(t, int32(1), resp.Hits[1].Search)
+		require.Equal(t, "db2", resp.Hits[1].Db)
+		require.Equal(t, "c2", resp.Hits[1].Collection)
+		require.Equal(t, &api.SearchMetadata{
+			Found:      5,
+			TotalPages: 1,
+			Page:       &api.Page{Current: 1, Size: 10},
+		}, resp.Meta)
+		for _, result := range resp.Results {
+			require.Nil(t, result.Hits)
+			require.NotNil(t, result.Meta)
+		}
+	})
+	t.Run("pages the merged hits", func(t *testing.T) {
+		resp := mergeSearchResults(searches, newResults(), 2, 2)
+		require.Equal(t, []string{`{"id":"b"}`, `{"id":"d"}`}, testHitIds(resp.Hits))
+		require.Equal(t, int32(3), resp.Meta.TotalPages)
+
+		resp = mergeSearchResults(searches, newResults(), 4, 2)
+		require.Empty(t, resp.Hits)
+	})
+}
+
+func TestMultiSearch(t *testing.T) {
+	hits := map[string][]*api.SearchHit{
+		"c1": {testSearchHit("a", 10)},
+		"c2": {testSearchHit("b", 20)},
+	}
+	run := func(ctx context.Context, req *api.SearchRequest, streaming SearchStreaming) error {
+		if req.Collection == "bad" {
+			return api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist")
+		}
+		return streaming.Send(&api.SearchResponse{
+			Hits: hits[req.Collection],
+			Meta: &api.SearchMetadata{
+				Found: int64(len(hits[req.Collection])),
+				Page:  &api.Page{Current: req.Page, Size: req.PageSize},
+			},
+		})
+	}
+
+	for _, parallel := range []bool{true, false} {
+		t.Run(fmt.Sprintf("per search parallel=%v", parallel), func(t *testing.T) {
+			m := &multiSearch{
+				req: &api.MultiSearchRequest{
+					Searches: []*api.SearchRequest{{Collection: "c1"}, {Collection: "c2", Page: 2}},
+				},
+				parallel: parallel,
+				run:      run,
+			}
+			resp, err := m.execute(context.Background())
+			require.NoError(t, err)
+			require.Len(t, resp.Results, 2)
+			require.Empty(t, resp.Hits)
+			require.Nil(t, resp.Meta)
+			require.Equal(t, hits["c1"], resp.Results[0].Hits)
+			require.Equal(t, int32(1), resp.Results[0].Meta.Page.Current)
+			require.Equal(t, int32(2), resp.Results[1].Meta.Page.Current)
+		})
+	}
+	t.Run("merged", func(t *testing.T) {
+		m := &multiSearch{
+			req: &api.MultiSearchRequest{
+				Searches: []*api.SearchRequest{{Collection: "c1", Page: 3}, {Collection: "c2"}},
+				Merge:    true,
+				Page:     2,
+				PageSize: 1,
+			},
+			run: run,
+		}
+		resp, err := m.execute(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{`{"id":"a"}`}, testHitIds(resp.Hits))
+		require.Equal(t, int32(0), resp.Hits[0].Search)
+		for _, result := range resp.Results {
+			require.Equal(t, &api.Page{Current: 1, Size: 2}, result.Meta.Page)
+		}
+	})
+	t.Run("merged beyond the ranked hits", func(t *testing.T) {
+		m := &multiSearch{
+			req: &api.MultiSearchRequest{
+				Searches: []*api.SearchRequest{{Collection: "c1"}},
+				Merge:    true,
+				Page:     3,
+				PageSize: 100,
+			},
+			run: run,
+		}
+		_, err := m.execute(context.Background())
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "merged multi search can rank at most 250 hits"), err)
+	})
+	t.Run("error", func(t *testing.T) {
+		m := &multiSearch{
+			req: &api.MultiSearchRequest{
+				Searches: []*api.SearchRequest{{Collection: "c1"}, {Collection: "bad"}},
+			},
+			parallel: true,
+			run:      run,
+		}
+		_, err := m.execute(context.Background())
+		require.Equal(t, api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist"), err)
+	})
+}
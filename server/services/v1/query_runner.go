@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"math"
+	"sort"
 
 	jsoniter "github.com/json-iterator/go"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -29,7 +30,9 @@ import (
 	"github.com/tigrisdata/tigris/query/update"
 	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
@@ -47,15 +50,17 @@ type QueryRunnerFactory struct {
 	encoder     metadata.Encoder
 	cdcMgr      *cdc.Manager
 	searchStore search.Store
+	kvStore     kv.KeyValueStore
 }
 
 // NewQueryRunnerFactory returns QueryRunnerFactory object
-func NewQueryRunnerFactory(txMgr *transaction.Manager, encoder metadata.Encoder, cdcMgr *cdc.Manager, searchStore search.Store) *QueryRunnerFactory {
+func NewQueryRunnerFactory(txMgr *transaction.Manager, encoder metadata.Encoder, cdcMgr *cdc.Manager, searchStore search.Store, kvStore kv.KeyValueStore) *QueryRunnerFactory {
 	return &QueryRunnerFactory{
 		txMgr:       txMgr,
 		encoder:     encoder,
 		cdcMgr:      cdcMgr,
 		searchStore: searchStore,
+		kvStore:     kvStore,
 	}
 }
 
@@ -87,6 +92,16 @@ func (f *QueryRunnerFactory) GetDeleteQueryRunner(r *api.DeleteRequest) *DeleteQ
 	}
 }
 
+// GetRewriteQueryRunner returns RewriteQueryRunner that reads at most batchSize rows of the collection per run.
+func (f *QueryRunnerFactory) GetRewriteQueryRunner(db string, collection string, batchSize int) *RewriteQueryRunner {
+	return &RewriteQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
+		db:              db,
+		collection:      collection,
+		batchSize:       batchSize,
+	}
+}
+
 // GetStreamingQueryRunner returns StreamingQueryRunner
 func (f *QueryRunnerFactory) GetStreamingQueryRunner(r *api.ReadRequest, streaming Streaming) *StreamingQueryRunner {
 	return &StreamingQueryRunner{
@@ -102,6 +117,7 @@ func (f *QueryRunnerFactory) GetSearchQueryRunner(r *api.SearchRequest, streamin
 		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
 		req:             r,
 		streaming:       streaming,
+		kvStore:         f.kvStore,
 	}
 }
 
@@ -117,6 +133,24 @@ func (f *QueryRunnerFactory) GetDatabaseQueryRunner() *DatabaseQueryRunner {
 	}
 }
 
+func (f *QueryRunnerFactory) GetWebhookQueryRunner() *WebhookQueryRunner {
+	return &WebhookQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
+	}
+}
+
+func (f *QueryRunnerFactory) GetTriggerQueryRunner() *TriggerQueryRunner {
+	return &TriggerQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
+	}
+}
+
+func (f *QueryRunnerFactory) GetSearchSettingsQueryRunner() *SearchSettingsQueryRunner {
+	return &SearchSettingsQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
+	}
+}
+
 type BaseQueryRunner struct {
 	encoder     metadata.Encoder
 	cdcMgr      *cdc.Manager
@@ -166,6 +200,17 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 	var err error
 	var ts = internal.NewTimestamp()
 	var allKeys [][]byte
+	if !insert {
+		if err = runner.enablePreImage(ctx, tenant, db, coll); err != nil {
+			return nil, nil, err
+		}
+	}
+	var notNullable = make(map[string]struct{})
+	for _, f := range coll.Fields {
+		if f.IsNotNullable() {
+			notNullable[f.FieldName] = struct{}{}
+		}
+	}
 	for _, doc := range documents {
 		var deserializedDoc map[string]interface{}
 		dec := jsoniter.NewDecoder(bytes.NewReader(doc))
@@ -174,8 +219,9 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 			return nil, nil, err
 		}
 		for k, v := range deserializedDoc {
-			// for schema validation, if the field is set to null, remove it.
-			if v == nil {
+			// for schema validation, if the field is set to null, remove it. The fields that are explicitly declared
+			// with "nullable": false are kept, so that the validation rejects the null value.
+			if _, ok := notNullable[k]; v == nil && !ok {
 				delete(deserializedDoc, k)
 			}
 		}
@@ -197,6 +243,7 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 
 		// we need to use keyGen updated document as it may be mutated by adding auto-generated keys.
 		tableData := internal.NewTableDataWithTS(ts, nil, keyGen.document)
+		tableData.Ver = int32(coll.SchVer)
 		if insert || keyGen.forceInsert {
 			// we use Insert API, in case user is using autogenerated primary key and has primary key field
 			// as Int64 or timestamp to ensure uniqueness if multiple workers end up generating same timestamp.
@@ -212,6 +259,31 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 	return ts, allKeys, err
 }
 
+// enablePreImage makes the change events of the collection carry the document as it was before the change, if the
+// pre-image option is set on the collection. The pre-image is also needed by the triggers of the collection, as the
+// deletes don't carry the document otherwise.
+func (runner *BaseQueryRunner) enablePreImage(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database, coll *schema.DefaultCollection) error {
+	if !coll.Options.PreImage && len(db.GetTriggers(coll.Name)) == 0 {
+		return nil
+	}
+
+	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		return err
+	}
+	kv.GetEventListener(ctx).EnablePreImage(table)
+
+	return nil
+}
+
+// enableRangeExpansion makes the range deletes buffer a delete event for every deleted document, which is only needed
+// if the change events are published or the collection has triggers.
+func (runner *BaseQueryRunner) enableRangeExpansion(ctx context.Context, db *metadata.Database, coll *schema.DefaultCollection) {
+	if config.DefaultConfig.Cdc.Enabled || len(db.GetTriggers(coll.Name)) > 0 {
+		kv.GetEventListener(ctx).EnableRangeExpansion()
+	}
+}
+
 func (runner *BaseQueryRunner) buildKeysUsingFilter(tenant *metadata.Tenant, db *metadata.Database, coll *schema.DefaultCollection, reqFilter []byte) ([]keys.Key, error) {
 	filterFactory := filter.NewFactory(coll.QueryableFields)
 	filters, err := filterFactory.Factorize(reqFilter)
@@ -245,6 +317,9 @@ func (runner *InsertQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	}
 
 	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+	if runner.req.GetOptions().GetWriteOptions().GetWaitForIndex() {
+		ctx = waitForIndex(ctx, tx.GetTxCtx().GetId())
+	}
 
 	coll, err := runner.GetCollections(db, runner.req.GetCollection())
 	if err != nil {
@@ -279,6 +354,9 @@ func (runner *ReplaceQueryRunner) Run(ctx context.Context, tx transaction.Tx, te
 	}
 
 	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+	if runner.req.GetOptions().GetWriteOptions().GetWaitForIndex() {
+		ctx = waitForIndex(ctx, tx.GetTxCtx().GetId())
+	}
 
 	coll, err := runner.GetCollections(db, runner.req.GetCollection())
 	if err != nil {
@@ -310,6 +388,9 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	}
 
 	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+	if runner.req.GetOptions().GetWriteOptions().GetWaitForIndex() {
+		ctx = waitForIndex(ctx, tx.GetTxCtx().GetId())
+	}
 
 	collection, err := runner.GetCollections(db, runner.req.GetCollection())
 	if err != nil {
@@ -320,6 +401,9 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	if err != nil {
 		return nil, ctx, err
 	}
+	if err = runner.enablePreImage(ctx, tenant, db, collection); err != nil {
+		return nil, ctx, err
+	}
 
 	var factory *update.FieldOperatorFactory
 	factory, err = update.BuildFieldOperators(runner.req.Fields)
@@ -344,13 +428,7 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		// decode the fields now
 		modified := int32(0)
 		if modified, err = tx.Update(ctx, key, func(existing *internal.TableData) (*internal.TableData, error) {
-			merged, er := factory.MergeAndGet(existing.RawData)
-			if er != nil {
-				return nil, er
-			}
-
-			// ToDo: may need to change the schema version
-			return internal.NewTableDataWithTS(existing.CreatedAt, ts, merged), nil
+			return updateDocument(collection, factory, existing, ts)
 		}); ulog.E(err) {
 			return nil, ctx, err
 		}
@@ -364,6 +442,24 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	}, ctx, err
 }
 
+// updateDocument applies the field operators of an update to the existing row. The merged document is written with the
+// current schema version, so the existing document is upgraded first.
+func updateDocument(collection *schema.DefaultCollection, factory *update.FieldOperatorFactory, existing *internal.TableData, ts *internal.Timestamp) (*internal.TableData, error) {
+	doc, err := collection.UpgradeDocument(existing.Ver, existing.RawData)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := factory.MergeAndGet(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	tableData := internal.NewTableDataWithTS(existing.CreatedAt, ts, merged)
+	tableData.Ver = int32(collection.SchVer)
+	return tableData, nil
+}
+
 type DeleteQueryRunner struct {
 	*BaseQueryRunner
 
@@ -378,6 +474,9 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	}
 
 	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+	if runner.req.GetOptions().GetWriteOptions().GetWaitForIndex() {
+		ctx = waitForIndex(ctx, tx.GetTxCtx().GetId())
+	}
 
 	collection, err := runner.GetCollections(db, runner.req.GetCollection())
 	if err != nil {
@@ -388,6 +487,10 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	if err != nil {
 		return nil, ctx, err
 	}
+	if err = runner.enablePreImage(ctx, tenant, db, collection); err != nil {
+		return nil, ctx, err
+	}
+	runner.enableRangeExpansion(ctx, db, collection)
 
 	for _, key := range iKeys {
 		if err = tx.Delete(ctx, key); ulog.E(err) {
@@ -401,6 +504,113 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	}, ctx, nil
 }
 
+// RewriteQueryRunner is used to rewrite the documents that are written with an older schema version of the collection,
+// so that the fields dropped from the schema are removed from the storage as well. Every run reads at most batchSize
+// rows in the primary key order, starting after the last row read by the previous run, so that a run doesn't have to
+// skip the documents already rewritten. The caller keeps running it, every run in its own transaction, until fewer
+// rows than the batch size are read.
+type RewriteQueryRunner struct {
+	*BaseQueryRunner
+
+	db         string
+	collection string
+	batchSize  int
+
+	// from is the last row read by the previous run, nil for the first run
+	from *kv.KeyValue
+	// last and read are set by the run to the last row read and the number of rows read
+	last *kv.KeyValue
+	read int
+}
+
+// resume makes the next run start after the last row read by the previous run.
+func (runner *RewriteQueryRunner) resume() {
+	runner.from = runner.last
+}
+
+func (runner *RewriteQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	db, err := runner.GetDatabase(ctx, tx, tenant, runner.db)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+	// the documents are only rewritten to the current schema version, they are not changed by the user
+	ctx = skipTriggers(ctx)
+
+	collection, err := runner.GetCollections(db, runner.collection)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, collection)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	pk := collection.Indexes.PrimaryKey
+	lKey := keys.NewKey(table, runner.encoder.EncodeIndexName(pk))
+	if runner.from != nil {
+		lKey = keys.NewKey(table, keyParts(runner.from.Key)...)
+	}
+	it, err := tx.ReadRange(ctx, lKey, keys.NewKey(table, encoding.UInt32ToByte(pk.Id+1)))
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	// collect the stale rows first so that the rows are not replaced while the iterator is still open
+	var stale []kv.KeyValue
+	var rows []kv.KeyValue
+	for len(rows) < runner.batchSize {
+		var row kv.KeyValue
+		if !it.Next(&row) {
+			break
+		}
+		if runner.from != nil && bytes.Equal(row.FDBKey, runner.from.FDBKey) {
+			// the range starts with the last row of the previous run
+			continue
+		}
+		rows = append(rows, row)
+		if int(row.Data.Ver) < collection.SchVer {
+			stale = append(stale, row)
+		}
+	}
+	if it.Err() != nil {
+		return nil, ctx, it.Err()
+	}
+
+	for _, r := range stale {
+		doc, err := collection.UpgradeDocument(r.Data.Ver, r.Data.RawData)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		tableData := internal.NewTableDataWithTS(r.Data.CreatedAt, r.Data.UpdatedAt, doc)
+		tableData.Ver = int32(collection.SchVer)
+		if err = tx.Replace(ctx, keys.NewKey(table, keyParts(r.Key)...), tableData); err != nil {
+			return nil, ctx, err
+		}
+	}
+
+	runner.read = len(rows)
+	if len(rows) > 0 {
+		runner.last = &rows[len(rows)-1]
+	}
+
+	return &Response{
+		status:        UpdatedStatus,
+		modifiedCount: int32(len(stale)),
+	}, ctx, nil
+}
+
+func keyParts(key kv.Key) []interface{} {
+	var parts []interface{}
+	for _, p := range key {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
 // StreamingQueryRunner is a runner used for Queries that are reads and needs to return result in streaming fashion
 type StreamingQueryRunner struct {
 	*BaseQueryRunner
@@ -459,14 +669,14 @@ func (runner *StreamingQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 		}
 	}
 
-	if err = runner.iterate(ctx, rowReader, fieldFactory); err != nil {
+	if err = runner.iterate(ctx, collection, rowReader, fieldFactory); err != nil {
 		return nil, ctx, err
 	}
 
 	return &Response{}, ctx, nil
 }
 
-func (runner *StreamingQueryRunner) iterate(ctx context.Context, reader RowReader, fieldFactory *read.FieldFactory) error {
+func (runner *StreamingQueryRunner) iterate(ctx context.Context, collection *schema.DefaultCollection, reader RowReader, fieldFactory *read.FieldFactory) error {
 	limit, totalResults := int64(0), int64(0)
 	if runner.req.GetOptions() != nil {
 		limit = runner.req.GetOptions().Limit
@@ -478,7 +688,12 @@ func (runner *StreamingQueryRunner) iterate(ctx context.Context, reader RowReade
 			return nil
 		}
 
-		newValue, err := fieldFactory.Apply(row.Data.RawData)
+		rawData, err := collection.UpgradeDocument(row.Data.Ver, row.Data.RawData)
+		if ulog.E(err) {
+			return err
+		}
+
+		newValue, err := fieldFactory.Apply(rawData)
 		if ulog.E(err) {
 			return err
 		}
@@ -506,6 +721,8 @@ type SearchQueryRunner struct {
 
 	req       *api.SearchRequest
 	streaming SearchStreaming
+	// kvStore is used to scan the collection for the vector queries outside the transaction of the request.
+	kvStore kv.KeyValueStore
 }
 
 func (runner *SearchQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
@@ -526,12 +743,43 @@ func (runner *SearchQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		return nil, ctx, err
 	}
 
-	searchFields, err := runner.getSearchFields(collection.GetFields())
+	searchFields, err := runner.getSearchFields(collection.QueryableFields)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	facets, err := runner.getFacetFields(collection.QueryableFields)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	sortOrder, err := runner.getSortOrdering(collection.QueryableFields)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	var order *cursorOrder
+	if runner.req.Cursor != nil || runner.req.Export {
+		if order, err = newCursorOrder(sortOrder, collection.QueryableFields); err != nil {
+			return nil, ctx, err
+		}
+		sortOrder = order.ordering
+	}
+
+	vectorQ, err := runner.getVectorQuery(collection.QueryableFields)
+	if err != nil {
+		return nil, ctx, err
+	}
+	if vectorQ != nil && order != nil {
+		return nil, ctx, api.Errorf(api.Code_INVALID_ARGUMENT, "vector search can't be combined with a cursor or an export")
+	}
+
+	boost, err := runner.getBoost(searchFields)
 	if err != nil {
 		return nil, ctx, err
 	}
 
-	facets, err := runner.getFacetFields(collection.GetFields())
+	fieldFactory, err := read.BuildFields(runner.req.GetFields())
 	if err != nil {
 		return nil, ctx, err
 	}
@@ -548,10 +796,25 @@ func (runner *SearchQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		Facets(facets).
 		PageSize(pageSize).
 		Filter(wrappedF).
+		SortOrder(sortOrder).
+		Vector(vectorQ).
+		Boost(boost).
+		Prefix(runner.req.Prefix).
+		TypoTolerance(qsearch.NewTypoTolerance(runner.req.TypoTolerance)).
+		StopWords(db.GetStopWords(collection.Name)).
 		Build()
 
-	var rowReader *SearchRowReader
-	if runner.req.Page != 0 {
+	if order != nil {
+		if err = runner.readCursor(ctx, collection, searchQ, order, fieldFactory); err != nil {
+			return nil, ctx, err
+		}
+		return &Response{}, ctx, nil
+	}
+
+	var rowReader searchReader
+	if vectorQ != nil {
+		rowReader, err = runner.scanVectors(ctx, tenant, db, collection, searchQ)
+	} else if runner.req.Page != 0 {
 		rowReader, err = SinglePageSearchReader(ctx, runner.searchStore, collection, searchQ, runner.req.Page)
 	} else {
 		rowReader, err = NewSearchReader(ctx, runner.searchStore, collection, searchQ)
@@ -568,13 +831,11 @@ func (runner *SearchQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		var resp = &api.SearchResponse{}
 		var row Row
 		for rowReader.Next(ctx, &row) {
-			resp.Hits = append(resp.Hits, &api.SearchHit{
-				Data: row.Data.RawData,
-				Metadata: &api.SearchHitMeta{
-					CreatedAt: row.Data.CreateToProtoTS(),
-					UpdatedAt: row.Data.UpdatedToProtoTS(),
-				},
-			})
+			hit, err := runner.toSearchHit(collection, fieldFactory, &row)
+			if err != nil {
+				return nil, ctx, err
+			}
+			resp.Hits = append(resp.Hits, hit)
 
 			if len(resp.Hits) == pageSize {
 				break
@@ -600,8 +861,8 @@ func (runner *SearchQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		// if some hits, got an error, send current hits and then error (will be zero hits next time)
 		// if some hits, no error, continue to send response
 		if len(resp.Hits) == 0 {
-			if rowReader.err != nil {
-				return nil, ctx, rowReader.err
+			if rowReader.Err() != nil {
+				return nil, ctx, rowReader.Err()
 			}
 			if pageNo > defaultPageNo && pageNo > runner.req.Page {
 				break
@@ -618,24 +879,149 @@ func (runner *SearchQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	return &Response{}, ctx, nil
 }
 
-func (runner *SearchQueryRunner) getSearchFields(collFields []*schema.Field) ([]string, error) {
+func (runner *SearchQueryRunner) toSearchHit(collection *schema.DefaultCollection, fieldFactory *read.FieldFactory, row *Row) (*api.SearchHit, error) {
+	rawData, err := collection.UpgradeDocument(row.Data.Ver, row.Data.RawData)
+	if err != nil {
+		return nil, err
+	}
+	if rawData, err = fieldFactory.Apply(rawData); err != nil {
+		return nil, err
+	}
+
+	return &api.SearchHit{
+		Data: rawData,
+		Metadata: &api.SearchHitMeta{
+			CreatedAt:  row.Data.CreateToProtoTS(),
+			UpdatedAt:  row.Data.UpdatedToProtoTS(),
+			Highlights: row.Highlights,
+			Score:      row.Score,
+		},
+	}, nil
+}
+
+// readCursor sends a page of hits after the cursor of the request along with the cursor to continue from, or all the
+// pages after it when the request is an export.
+func (runner *SearchQueryRunner) readCursor(ctx context.Context, collection *schema.DefaultCollection, query *qsearch.Query, order *cursorOrder, fieldFactory *read.FieldFactory) error {
+	cursor, err := decodeSearchCursor(runner.req.GetCursor(), order)
+	if err != nil {
+		return err
+	}
+
+	reader := newCursorReader(runner.searchStore, collection, query, order, cursor)
+	for {
+		pg, err := reader.read(ctx, query.PageSize)
+		if err != nil {
+			return err
+		}
+
+		var resp = &api.SearchResponse{}
+		var row Row
+		for pg.readRow(&row) {
+			hit, err := runner.toSearchHit(collection, fieldFactory, &row)
+			if err != nil {
+				return err
+			}
+			resp.Hits = append(resp.Hits, hit)
+		}
+		if pg.err != nil {
+			return pg.err
+		}
+
+		next, err := reader.nextCursor()
+		if err != nil {
+			return err
+		}
+
+		resp.Facets = reader.facets
+		resp.Meta = &api.SearchMetadata{
+			Found:      reader.found,
+			TotalPages: int32(math.Ceil(float64(reader.found) / float64(query.PageSize))),
+			Page: &api.Page{
+				Size: int32(query.PageSize),
+			},
+			NextCursor: next,
+		}
+		if err = runner.streaming.Send(resp); err != nil {
+			return err
+		}
+
+		if !runner.req.Export || len(next) == 0 {
+			return nil
+		}
+	}
+}
+
+// scanVectors answers the vector queries, the vectors are packed in the search store so it can't find the nearest ones.
+// It reads the rows of the collection a batch at a time, each batch in its own transaction, and keeps the closest ones,
+// so the parts of the query that need the search store are rejected. The collections with more rows than the scan is
+// allowed to read are rejected too.
+func (runner *SearchQueryRunner) scanVectors(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database, collection *schema.DefaultCollection, query *qsearch.Query) (searchReader, error) {
+	if len(runner.req.Q) > 0 && runner.req.Q != "*" {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "text search can't be combined with vector search")
+	}
+	if len(query.Facets.Fields) > 0 {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "facets can't be combined with vector search")
+	}
+	if len(query.SortOrder) > 0 {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "vector search results are ordered by distance, sort is not allowed")
+	}
+
+	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := &collectionScanner{
+		kvStore:   runner.kvStore,
+		table:     table,
+		pk:        collection.Indexes.PrimaryKey,
+		encoder:   runner.encoder,
+		batchSize: vectorScanBatchSize,
+	}
+
+	return NewVectorRowReader(ctx, scanner, query, runner.req.Page, vectorScanMaxRows)
+}
+
+func (runner *SearchQueryRunner) getVectorQuery(queryableFields []*schema.QueryableField) (*qsearch.VectorQuery, error) {
+	vectorQ, err := qsearch.UnmarshalVectorQuery(runner.req.Vector)
+	if err != nil || vectorQ == nil {
+		return nil, err
+	}
+
+	for _, qf := range queryableFields {
+		if qf.FieldName != vectorQ.Field {
+			continue
+		}
+		if qf.DataType != schema.VectorType {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a vector field", vectorQ.Field)
+		}
+		if qf.Dimensions != len(vectorQ.Vector) {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` has %d dimensions but the query vector has %d", vectorQ.Field, qf.Dimensions, len(vectorQ.Vector))
+		}
+		return vectorQ, nil
+	}
+
+	return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a schema field", vectorQ.Field)
+}
+
+func (runner *SearchQueryRunner) getSearchFields(queryableFields []*schema.QueryableField) ([]string, error) {
 	var searchFields = runner.req.SearchFields
 	if len(searchFields) == 0 {
 		// this is to include all searchable fields if not present in the query
-		for _, cf := range collFields {
-			if cf.DataType == schema.StringType {
-				searchFields = append(searchFields, cf.FieldName)
+		for _, qf := range queryableFields {
+			if qf.IsTextSearchable() {
+				searchFields = append(searchFields, qf.FieldName)
 			}
 		}
 	} else {
 		for _, sf := range searchFields {
 			found := false
-			for _, cf := range collFields {
-				if sf != cf.FieldName {
+			for _, qf := range queryableFields {
+				if sf != qf.FieldName {
 					continue
 				}
-				if cf.DataType != schema.StringType {
-					return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a searchable field. Only string fields can be queried", sf)
+				if !qf.IsTextSearchable() {
+					return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a searchable field. Only string fields and arrays of strings can be queried", sf)
 				}
 				found = true
 				break
@@ -648,33 +1034,105 @@ func (runner *SearchQueryRunner) getSearchFields(collFields []*schema.Field) ([]
 	return searchFields, nil
 }
 
-func (runner *SearchQueryRunner) getFacetFields(collFields []*schema.Field) (qsearch.Facets, error) {
+func (runner *SearchQueryRunner) getBoost(searchFields []string) (qsearch.Boost, error) {
+	boost, err := qsearch.UnmarshalBoost(runner.req.Boost)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range boost {
+		found := false
+		for _, sf := range searchFields {
+			if name == sf {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot boost `%s`. Only the search fields can be boosted", name)
+		}
+	}
+
+	return boost, nil
+}
+
+func (runner *SearchQueryRunner) getFacetFields(queryableFields []*schema.QueryableField) (qsearch.Facets, error) {
 	facets, err := qsearch.UnmarshalFacet(runner.req.Facet)
 	if err != nil {
 		return qsearch.Facets{}, err
 	}
 
 	for _, ff := range facets.Fields {
-		found := false
-		for _, cf := range collFields {
-			if ff.Name != cf.FieldName {
-				continue
-			}
-			if cf.DataType != schema.StringType {
-				return qsearch.Facets{}, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot generate facets for `%s`. Faceting is only supported for text fields", ff.Name)
+		var field *schema.QueryableField
+		for _, qf := range queryableFields {
+			if ff.Name == qf.FieldName {
+				field = qf
+				break
 			}
-			found = true
-			break
-
 		}
-		if !found {
+		if field == nil {
 			return qsearch.Facets{}, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a schema field", ff.Name)
 		}
+		if !field.Faceted && field.DataType == schema.DateTimeType {
+			return qsearch.Facets{}, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot generate facets for `%s`. The collection needs to be reindexed to facet on datetime fields", ff.Name)
+		}
+		if !field.Faceted {
+			return qsearch.Facets{}, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot generate facets for `%s`. Faceting is only supported for text, numeric and datetime fields", ff.Name)
+		}
+
+		switch {
+		case len(ff.Ranges) > 0 && field.DataType != schema.Int32Type && field.DataType != schema.Int64Type && field.DataType != schema.DoubleType:
+			return qsearch.Facets{}, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot generate range facets for `%s`. Ranges are only supported for numeric fields", ff.Name)
+		case len(ff.Interval) > 0 && field.DataType != schema.DateTimeType:
+			return qsearch.Facets{}, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot generate interval facets for `%s`. Intervals are only supported for datetime fields", ff.Name)
+		}
 	}
 
 	return facets, nil
 }
 
+func (runner *SearchQueryRunner) getSortOrdering(queryableFields []*schema.QueryableField) (qsearch.Ordering, error) {
+	ordering, err := qsearch.UnmarshalSort(runner.req.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sf := range ordering {
+		if sf.Name == qsearch.TextMatchSortField {
+			if sf.GeoOrigin != nil {
+				return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a geopoint field", sf.Name)
+			}
+			continue
+		}
+
+		var field *schema.QueryableField
+		for _, qf := range queryableFields {
+			if sf.Name == qf.FieldName {
+				field = qf
+				break
+			}
+		}
+		if field == nil {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a schema field", sf.Name)
+		}
+
+		switch field.DataType {
+		case schema.GeoPointType:
+			if sf.GeoOrigin == nil {
+				return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "Sorting on geopoint field `%s` needs a point to measure the distance from", sf.Name)
+			}
+		case schema.Int32Type, schema.Int64Type, schema.DoubleType:
+			if sf.GeoOrigin != nil {
+				return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "`%s` is not a geopoint field", sf.Name)
+			}
+		default:
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "Cannot sort on `%s`. Sorting is only supported for numeric and geopoint fields", sf.Name)
+		}
+	}
+
+	return ordering, nil
+}
+
 type CollectionQueryRunner struct {
 	*BaseQueryRunner
 
@@ -682,6 +1140,9 @@ type CollectionQueryRunner struct {
 	listReq           *api.ListCollectionsRequest
 	createOrUpdateReq *api.CreateOrUpdateCollectionRequest
 	describeReq       *api.DescribeCollectionRequest
+	listRevisionsReq  *api.ListSchemaRevisionsRequest
+	getRevisionReq    *api.GetSchemaRevisionRequest
+	rollbackReq       *api.RollbackSchemaRequest
 }
 
 func (runner *CollectionQueryRunner) SetCreateOrUpdateCollectionReq(create *api.CreateOrUpdateCollectionRequest) {
@@ -700,6 +1161,18 @@ func (runner *CollectionQueryRunner) SetDescribeCollectionReq(describe *api.Desc
 	runner.describeReq = describe
 }
 
+func (runner *CollectionQueryRunner) SetListSchemaRevisionsReq(list *api.ListSchemaRevisionsRequest) {
+	runner.listRevisionsReq = list
+}
+
+func (runner *CollectionQueryRunner) SetGetSchemaRevisionReq(get *api.GetSchemaRevisionRequest) {
+	runner.getRevisionReq = get
+}
+
+func (runner *CollectionQueryRunner) SetRollbackSchemaReq(rollback *api.RollbackSchemaRequest) {
+	runner.rollbackReq = rollback
+}
+
 func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
 	if runner.dropReq != nil {
 		db, err := runner.GetDatabase(ctx, tx, tenant, runner.dropReq.GetDb())
@@ -713,6 +1186,7 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			tx.Context().StageDatabase(db)
 		}
 
+		ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
 		if err = tenant.DropCollection(ctx, tx, db, runner.dropReq.GetCollection(), runner.searchStore, runner.encoder); err != nil {
 			return nil, ctx, err
 		}
@@ -735,6 +1209,21 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 		if err != nil {
 			return nil, ctx, err
 		}
+		schFactory.DropFields = runner.createOrUpdateReq.GetDropFields()
+
+		if runner.createOrUpdateReq.GetDryRun() {
+			plan, err := planCollection(db.GetCollection(schFactory.Name), schFactory)
+			if err != nil {
+				return nil, ctx, err
+			}
+
+			return &Response{
+				status: PlannedStatus,
+				Response: &api.CreateOrUpdateCollectionResponse{
+					Plan: plan,
+				},
+			}, ctx, nil
+		}
 
 		if tx.Context().GetStagedDatabase() == nil {
 			// do not modify the actual database object yet, just work on the clone
@@ -742,6 +1231,7 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			tx.Context().StageDatabase(db)
 		}
 
+		ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
 		if err = tenant.CreateCollection(ctx, tx, db, schFactory, runner.searchStore); err != nil {
 			if err == kv.ErrDuplicateKey {
 				// this simply means, concurrently CreateCollection is called,
@@ -781,18 +1271,282 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 		if err != nil {
 			return nil, ctx, err
 		}
+
+		var synonyms []*api.SynonymSet
+		for _, s := range db.GetSynonyms(coll.Name) {
+			synonyms = append(synonyms, &api.SynonymSet{
+				Id:       s.Id,
+				Root:     s.Root,
+				Synonyms: s.Synonyms,
+			})
+		}
+
 		return &Response{
 			Response: &api.DescribeCollectionResponse{
 				Collection: coll.Name,
 				Metadata:   &api.CollectionMetadata{},
 				Schema:     coll.Schema,
+				Synonyms:   synonyms,
+				StopWords:  db.GetStopWords(coll.Name),
 			},
 		}, ctx, nil
-	}
+	} else if runner.listRevisionsReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.listRevisionsReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		revisions, err := tenant.ListSchemaRevisions(ctx, tx, db, runner.listRevisionsReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		fields, err := auditSchemaFields(runner.listRevisionsReq.GetCollection(), revisions)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		var apiRevisions = make([]*api.SchemaRevision, len(revisions))
+		for i, r := range revisions {
+			apiRevisions[i] = toApiSchemaRevision(r)
+		}
+		return &Response{
+			Response: &api.ListSchemaRevisionsResponse{
+				Revisions: apiRevisions,
+				Fields:    fields,
+			},
+		}, ctx, nil
+	} else if runner.getRevisionReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.getRevisionReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		revisions, err := tenant.ListSchemaRevisions(ctx, tx, db, runner.getRevisionReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		revision, err := findSchemaRevision(revisions, runner.getRevisionReq.GetRevision())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		resp := &api.GetSchemaRevisionResponse{
+			Revision: toApiSchemaRevision(revision),
+		}
+		if runner.getRevisionReq.GetCompareTo() > 0 {
+			compareTo, err := findSchemaRevision(revisions, runner.getRevisionReq.GetCompareTo())
+			if err != nil {
+				return nil, ctx, err
+			}
+
+			if resp.Diff, err = diffSchemaRevisions(runner.getRevisionReq.GetCollection(), compareTo, revision); err != nil {
+				return nil, ctx, err
+			}
+		}
+
+		return &Response{
+			Response: resp,
+		}, ctx, nil
+	} else if runner.rollbackReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.rollbackReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		revisions, err := tenant.ListSchemaRevisions(ctx, tx, db, runner.rollbackReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		revision, err := findSchemaRevision(revisions, runner.rollbackReq.GetRevision())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		// the rollback is stored as a new revision, so it has to pass the same compatibility rules as any other update
+		schFactory, err := schema.Build(runner.rollbackReq.GetCollection(), revision.Schema)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		if tx.Context().GetStagedDatabase() == nil {
+			// do not modify the actual database object yet, just work on the clone
+			db = db.Clone()
+			tx.Context().StageDatabase(db)
+		}
+
+		ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+		if err = tenant.CreateCollection(ctx, tx, db, schFactory, runner.searchStore); err != nil {
+			if err == kv.ErrDuplicateKey {
+				return nil, ctx, api.Errorf(api.Code_ABORTED, "concurrent schema update request, aborting")
+			}
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: RolledBackStatus,
+			Response: &api.RollbackSchemaResponse{
+				Revision: int32(db.GetCollection(runner.rollbackReq.GetCollection()).SchVer),
+			},
+		}, ctx, nil
+	}
 
 	return &Response{}, ctx, api.Errorf(api.Code_UNKNOWN, "unknown request path")
 }
 
+// planCollection returns the plan of the CreateOrUpdateCollection request without modifying the collection.
+func planCollection(existing *schema.DefaultCollection, schFactory *schema.Factory) (*api.CollectionPlan, error) {
+	plan := schema.NewPlan(existing, schFactory)
+	if existing != nil {
+		// same as the update, the revision is only bumped if the schema is changed
+		eq, err := metadata.IsSchemaEq(existing.Schema, schFactory.Schema)
+		if err != nil {
+			return nil, err
+		}
+		if eq {
+			plan.Revision = existing.SchVer
+		}
+	}
+
+	apiPlan := &api.CollectionPlan{
+		Create:     plan.Create,
+		Revision:   int32(plan.Revision),
+		Compatible: plan.Err == nil,
+	}
+	if plan.Err != nil {
+		apiPlan.Reason = plan.Err.Error()
+	}
+	for _, f := range plan.Fields {
+		apiPlan.Fields = append(apiPlan.Fields, &api.PlanField{
+			Name:       f.Name,
+			Type:       f.Type,
+			PrimaryKey: f.PrimaryKey,
+			Nullable:   f.Nullable,
+		})
+	}
+	for _, idx := range plan.Indexes {
+		apiPlan.Indexes = append(apiPlan.Indexes, &api.PlanIndex{
+			Name:   idx.Name,
+			Fields: idx.Fields,
+			Change: idx.Change,
+		})
+	}
+	for _, f := range plan.SearchDeltaFields {
+		apiPlan.SearchDeltaFields = append(apiPlan.SearchDeltaFields, &api.SearchDeltaField{
+			Name:  f.Name,
+			Type:  f.Type,
+			Facet: f.Facet != nil && *f.Facet,
+			Index: f.Index != nil && *f.Index,
+			Drop:  f.Drop != nil && *f.Drop,
+		})
+	}
+	if plan.Diff != nil {
+		apiPlan.Diff = toApiSchemaDiff(plan.Diff)
+	}
+
+	return apiPlan, nil
+}
+
+func findSchemaRevision(revisions []*encoding.SchemaRevision, revision int32) (*encoding.SchemaRevision, error) {
+	for _, r := range revisions {
+		if r.Revision == int(revision) {
+			return r, nil
+		}
+	}
+
+	return nil, api.Errorf(api.Code_NOT_FOUND, "schema revision '%d' doesn't exist", revision)
+}
+
+func toApiSchemaRevision(r *encoding.SchemaRevision) *api.SchemaRevision {
+	revision := &api.SchemaRevision{
+		Revision:  int32(r.Revision),
+		Schema:    r.Schema,
+		CreatedBy: r.CreatedBy,
+	}
+	if r.CreatedAt != nil {
+		revision.CreatedAt = r.CreatedAt.GetProtoTS()
+	}
+
+	return revision
+}
+
+// diffSchemaRevisions returns the difference from one revision to the other, if "from" is nil then all the fields of
+// "to" are returned as added.
+func diffSchemaRevisions(collection string, from *encoding.SchemaRevision, to *encoding.SchemaRevision) (*api.SchemaDiff, error) {
+	var fromFactory *schema.Factory
+	var err error
+	if from != nil {
+		if fromFactory, err = schema.Build(collection, from.Schema); err != nil {
+			return nil, err
+		}
+	}
+	toFactory, err := schema.Build(collection, to.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return toApiSchemaDiff(schema.Diff(fromFactory, toFactory)), nil
+}
+
+func toApiSchemaDiff(diff *schema.SchemaDiff) *api.SchemaDiff {
+	apiDiff := &api.SchemaDiff{
+		PrimaryKeyChanged: diff.PrimaryKeyChanged,
+	}
+	for _, f := range diff.Fields {
+		apiDiff.Fields = append(apiDiff.Fields, &api.FieldDiff{
+			Name:    f.Name,
+			Change:  f.Change,
+			OldType: f.OldType,
+			NewType: f.NewType,
+		})
+	}
+
+	return apiDiff
+}
+
+// auditSchemaFields returns for every field of the latest revision, the revision in which the field was last added
+// along with the time and the author of that revision.
+func auditSchemaFields(collection string, revisions []*encoding.SchemaRevision) ([]*api.FieldAudit, error) {
+	var audits = make(map[string]*api.FieldAudit)
+	var previous *encoding.SchemaRevision
+	for _, r := range revisions {
+		diff, err := diffSchemaRevisions(collection, previous, r)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range diff.Fields {
+			switch f.Change {
+			case schema.FieldAdded:
+				audit := &api.FieldAudit{
+					Name:            f.Name,
+					AddedInRevision: int32(r.Revision),
+					AddedBy:         r.CreatedBy,
+				}
+				if r.CreatedAt != nil {
+					audit.AddedAt = r.CreatedAt.GetProtoTS()
+				}
+				audits[f.Name] = audit
+			case schema.FieldRemoved:
+				delete(audits, f.Name)
+			}
+		}
+		previous = r
+	}
+
+	var fields []*api.FieldAudit
+	for _, a := range audits {
+		fields = append(fields, a)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields, nil
+}
+
 type DatabaseQueryRunner struct {
 	*BaseQueryRunner
 
@@ -820,6 +1574,7 @@ func (runner *DatabaseQueryRunner) SetDescribeDatabaseReq(describe *api.Describe
 
 func (runner *DatabaseQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
 	if runner.drop != nil {
+		ctx = runner.cdcMgr.WrapContext(ctx, runner.drop.GetDb())
 		exist, err := tenant.DropDatabase(ctx, tx, runner.drop.GetDb(), runner.searchStore, runner.encoder)
 		if err != nil {
 			return nil, ctx, err
@@ -832,6 +1587,7 @@ func (runner *DatabaseQueryRunner) Run(ctx context.Context, tx transaction.Tx, t
 			status: DroppedStatus,
 		}, ctx, nil
 	} else if runner.create != nil {
+		ctx = runner.cdcMgr.WrapContext(ctx, runner.create.GetDb())
 		exist, err := tenant.CreateDatabase(ctx, tx, runner.create.GetDb())
 		if err != nil {
 			return nil, ctx, err
@@ -885,3 +1641,349 @@ func (runner *DatabaseQueryRunner) Run(ctx context.Context, tx transaction.Tx, t
 
 	return &Response{}, ctx, api.Errorf(api.Code_UNKNOWN, "unknown request path")
 }
+
+type WebhookQueryRunner struct {
+	*BaseQueryRunner
+
+	createReq *api.CreateWebhookRequest
+	listReq   *api.ListWebhooksRequest
+	deleteReq *api.DeleteWebhookRequest
+}
+
+func (runner *WebhookQueryRunner) SetCreateWebhookReq(create *api.CreateWebhookRequest) {
+	runner.createReq = create
+}
+
+func (runner *WebhookQueryRunner) SetListWebhooksReq(list *api.ListWebhooksRequest) {
+	runner.listReq = list
+}
+
+func (runner *WebhookQueryRunner) SetDeleteWebhookReq(del *api.DeleteWebhookRequest) {
+	runner.deleteReq = del
+}
+
+func (runner *WebhookQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	if runner.createReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.createReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		// the filter is only built to validate the ops and the filter of the webhook
+		if _, err = newEventFilter(&api.EventsRequest{
+			Db:         runner.createReq.GetDb(),
+			Collection: runner.createReq.GetCollection(),
+			Ops:        runner.createReq.GetOps(),
+			Filter:     runner.createReq.GetFilter(),
+		}, db.GetCollection(runner.createReq.GetCollection())); err != nil {
+			return nil, ctx, err
+		}
+
+		if dlq := runner.createReq.GetDeadLetterCollection(); len(dlq) > 0 && db.GetCollection(dlq) == nil {
+			schFactory, err := schema.Build(dlq, deadLetterSchema(dlq))
+			if err != nil {
+				return nil, ctx, err
+			}
+
+			if tx.Context().GetStagedDatabase() == nil {
+				// do not modify the actual database object yet, just work on the clone
+				db = db.Clone()
+				tx.Context().StageDatabase(db)
+			}
+
+			ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+			if err = tenant.CreateCollection(ctx, tx, db, schFactory, runner.searchStore); err != nil {
+				if err == kv.ErrDuplicateKey {
+					return nil, ctx, api.Errorf(api.Code_ABORTED, "concurrent create collection request, aborting")
+				}
+				return nil, ctx, err
+			}
+		}
+
+		if err = tenant.CreateWebhook(ctx, tx, db, &encoding.Webhook{
+			Name:                 runner.createReq.GetName(),
+			Collection:           runner.createReq.GetCollection(),
+			Url:                  runner.createReq.GetUrl(),
+			Secret:               runner.createReq.GetSecret(),
+			Ops:                  runner.createReq.GetOps(),
+			Filter:               runner.createReq.GetFilter(),
+			DeadLetterCollection: runner.createReq.GetDeadLetterCollection(),
+		}); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: CreatedStatus,
+		}, ctx, nil
+	} else if runner.listReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.listReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		hooks, err := tenant.ListWebhooks(ctx, tx, db)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		// the secret is never returned once the webhook is created
+		var webhooks = make([]*api.Webhook, len(hooks))
+		for i, h := range hooks {
+			webhooks[i] = &api.Webhook{
+				Name:                 h.Name,
+				Collection:           h.Collection,
+				Url:                  h.Url,
+				Ops:                  h.Ops,
+				Filter:               h.Filter,
+				DeadLetterCollection: h.DeadLetterCollection,
+			}
+			if h.CreatedAt != nil {
+				webhooks[i].CreatedAt = h.CreatedAt.GetProtoTS()
+			}
+		}
+
+		return &Response{
+			Response: &api.ListWebhooksResponse{
+				Webhooks: webhooks,
+			},
+		}, ctx, nil
+	} else if runner.deleteReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.deleteReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		if err = tenant.DeleteWebhook(ctx, tx, db, runner.deleteReq.GetName()); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: DeletedStatus,
+		}, ctx, nil
+	}
+
+	return &Response{}, ctx, api.Errorf(api.Code_UNKNOWN, "unknown request path")
+}
+
+type TriggerQueryRunner struct {
+	*BaseQueryRunner
+
+	createReq *api.CreateTriggerRequest
+	listReq   *api.ListTriggersRequest
+	dropReq   *api.DropTriggerRequest
+}
+
+func (runner *TriggerQueryRunner) SetCreateTriggerReq(create *api.CreateTriggerRequest) {
+	runner.createReq = create
+}
+
+func (runner *TriggerQueryRunner) SetListTriggersReq(list *api.ListTriggersRequest) {
+	runner.listReq = list
+}
+
+func (runner *TriggerQueryRunner) SetDropTriggerReq(drop *api.DropTriggerRequest) {
+	runner.dropReq = drop
+}
+
+func (runner *TriggerQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	if runner.createReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.createReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		coll, err := runner.GetCollections(db, runner.createReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+		target, err := runner.GetCollections(db, runner.createReq.GetTarget())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		for _, op := range runner.createReq.GetOps() {
+			if _, ok := triggerOps[op]; !ok {
+				return nil, ctx, api.Errorf(api.Code_INVALID_ARGUMENT, "unsupported trigger operation '%s'", op)
+			}
+		}
+		// the filter is only built to validate it against the collection
+		if _, err = newEventFilter(&api.EventsRequest{
+			Collection: runner.createReq.GetCollection(),
+			Filter:     runner.createReq.GetFilter(),
+		}, coll); err != nil {
+			return nil, ctx, err
+		}
+		if err = validateTriggerKey(runner.createReq.GetKey(), target); err != nil {
+			return nil, ctx, err
+		}
+		if _, err = update.BuildFieldOperators(runner.createReq.GetUpdate()); err != nil {
+			return nil, ctx, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid trigger update '%s'", runner.createReq.GetUpdate())
+		}
+
+		if tx.Context().GetStagedDatabase() == nil {
+			// do not modify the actual database object yet, just work on the clone
+			db = db.Clone()
+			tx.Context().StageDatabase(db)
+		}
+
+		if err = tenant.CreateTrigger(ctx, tx, db, &encoding.Trigger{
+			Name:       runner.createReq.GetName(),
+			Collection: runner.createReq.GetCollection(),
+			Ops:        runner.createReq.GetOps(),
+			Filter:     runner.createReq.GetFilter(),
+			Target:     runner.createReq.GetTarget(),
+			Key:        runner.createReq.GetKey(),
+			Update:     runner.createReq.GetUpdate(),
+			Upsert:     runner.createReq.GetUpsert(),
+		}); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: CreatedStatus,
+		}, ctx, nil
+	} else if runner.listReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.listReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		triggerList := db.ListTriggers()
+		var triggers = make([]*api.Trigger, len(triggerList))
+		for i, t := range triggerList {
+			triggers[i] = &api.Trigger{
+				Name:       t.Name,
+				Collection: t.Collection,
+				Ops:        t.Ops,
+				Filter:     t.Filter,
+				Target:     t.Target,
+				Key:        t.Key,
+				Update:     t.Update,
+				Upsert:     t.Upsert,
+			}
+			if t.CreatedAt != nil {
+				triggers[i].CreatedAt = t.CreatedAt.GetProtoTS()
+			}
+		}
+
+		return &Response{
+			Response: &api.ListTriggersResponse{
+				Triggers: triggers,
+			},
+		}, ctx, nil
+	} else if runner.dropReq != nil {
+		db, err := runner.GetDatabase(ctx, tx, tenant, runner.dropReq.GetDb())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		if tx.Context().GetStagedDatabase() == nil {
+			// do not modify the actual database object yet, just work on the clone
+			db = db.Clone()
+			tx.Context().StageDatabase(db)
+		}
+
+		if err = tenant.DropTrigger(ctx, tx, db, runner.dropReq.GetName()); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: DroppedStatus,
+		}, ctx, nil
+	}
+
+	return &Response{}, ctx, api.Errorf(api.Code_UNKNOWN, "unknown request path")
+}
+
+type SearchSettingsQueryRunner struct {
+	*BaseQueryRunner
+
+	synonymReq       *api.CreateOrUpdateSynonymSetRequest
+	deleteSynonymReq *api.DeleteSynonymSetRequest
+	stopWordsReq     *api.SetStopWordsRequest
+}
+
+func (runner *SearchSettingsQueryRunner) SetCreateOrUpdateSynonymSetReq(synonym *api.CreateOrUpdateSynonymSetRequest) {
+	runner.synonymReq = synonym
+}
+
+func (runner *SearchSettingsQueryRunner) SetDeleteSynonymSetReq(del *api.DeleteSynonymSetRequest) {
+	runner.deleteSynonymReq = del
+}
+
+func (runner *SearchSettingsQueryRunner) SetStopWordsReq(stopWords *api.SetStopWordsRequest) {
+	runner.stopWordsReq = stopWords
+}
+
+// stagedDatabase returns the database that is changed by the request, the changes are made on a clone that replaces
+// the database once the transaction commits.
+func (runner *SearchSettingsQueryRunner) stagedDatabase(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant, dbName string, collName string) (*metadata.Database, error) {
+	db, err := runner.GetDatabase(ctx, tx, tenant, dbName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = runner.GetCollections(db, collName); err != nil {
+		return nil, err
+	}
+
+	if tx.Context().GetStagedDatabase() == nil {
+		// do not modify the actual database object yet, just work on the clone
+		db = db.Clone()
+		tx.Context().StageDatabase(db)
+	}
+
+	return db, nil
+}
+
+func (runner *SearchSettingsQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	if runner.synonymReq != nil {
+		db, err := runner.stagedDatabase(ctx, tx, tenant, runner.synonymReq.GetDb(), runner.synonymReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		if err = tenant.UpsertSynonym(ctx, tx, db, &encoding.Synonym{
+			Id:         runner.synonymReq.GetId(),
+			Collection: runner.synonymReq.GetCollection(),
+			Root:       runner.synonymReq.GetRoot(),
+			Synonyms:   runner.synonymReq.GetSynonyms(),
+		}, runner.searchStore); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: CreatedStatus,
+		}, ctx, nil
+	} else if runner.deleteSynonymReq != nil {
+		db, err := runner.stagedDatabase(ctx, tx, tenant, runner.deleteSynonymReq.GetDb(), runner.deleteSynonymReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		if err = tenant.DeleteSynonym(ctx, tx, db, runner.deleteSynonymReq.GetCollection(), runner.deleteSynonymReq.GetId(), runner.searchStore); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: DeletedStatus,
+		}, ctx, nil
+	} else if runner.stopWordsReq != nil {
+		db, err := runner.stagedDatabase(ctx, tx, tenant, runner.stopWordsReq.GetDb(), runner.stopWordsReq.GetCollection())
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		if err = tenant.SetStopWords(ctx, tx, db, &encoding.StopWords{
+			Collection: runner.stopWordsReq.GetCollection(),
+			Words:      runner.stopWordsReq.GetStopWords(),
+		}); err != nil {
+			return nil, ctx, err
+		}
+
+		return &Response{
+			status: UpdatedStatus,
+		}, ctx, nil
+	}
+
+	return &Response{}, ctx, api.Errorf(api.Code_UNKNOWN, "unknown request path")
+}
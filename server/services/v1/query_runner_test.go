@@ -0,0 +1,169 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/update"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+)
+
+func TestSchemaRevisions(t *testing.T) {
+	revisions := []*encoding.SchemaRevision{
+		{
+			Revision:  1,
+			Schema:    []byte(`{"title": "c1", "properties": {"id": {"type": "integer"}, "s": {"type": "string"}}}`),
+			CreatedAt: internal.CreateNewTimestamp(1000),
+			CreatedBy: "alice",
+		}, {
+			Revision:  2,
+			Schema:    []byte(`{"title": "c1", "properties": {"id": {"type": "integer"}, "s": {"type": "string"}, "b": {"type": "boolean"}}}`),
+			CreatedAt: internal.CreateNewTimestamp(2000),
+			CreatedBy: "bob",
+		}, {
+			Revision: 3,
+			Schema:   []byte(`{"title": "c1", "properties": {"id": {"type": "integer"}, "b": {"type": "boolean"}}}`),
+		},
+	}
+
+	t.Run("find", func(t *testing.T) {
+		r, err := findSchemaRevision(revisions, 2)
+		require.NoError(t, err)
+		require.Equal(t, revisions[1], r)
+
+		_, err = findSchemaRevision(revisions, 4)
+		require.Equal(t, api.Errorf(api.Code_NOT_FOUND, "schema revision '4' doesn't exist"), err)
+	})
+	t.Run("diff", func(t *testing.T) {
+		diff, err := diffSchemaRevisions("c1", revisions[0], revisions[2])
+		require.NoError(t, err)
+		require.Equal(t, []*api.FieldDiff{
+			{Name: "b", Change: schema.FieldAdded, NewType: "bool"},
+			{Name: "s", Change: schema.FieldRemoved, OldType: "string"},
+		}, diff.Fields)
+	})
+	t.Run("audit", func(t *testing.T) {
+		fields, err := auditSchemaFields("c1", revisions)
+		require.NoError(t, err)
+		require.Len(t, fields, 2)
+		require.Equal(t, "b", fields[0].Name)
+		require.Equal(t, int32(2), fields[0].AddedInRevision)
+		require.Equal(t, "bob", fields[0].AddedBy)
+		require.Equal(t, revisions[1].CreatedAt.GetProtoTS(), fields[0].AddedAt)
+		require.Equal(t, "id", fields[1].Name)
+		require.Equal(t, int32(1), fields[1].AddedInRevision)
+		require.Equal(t, "alice", fields[1].AddedBy)
+	})
+}
+
+func TestUpgradeRowsWithoutVersion(t *testing.T) {
+	build := func(properties string) *schema.Factory {
+		factory, err := schema.Build("c1", []byte(`{"title": "c1", "properties": {"id": {"type": "integer"}, `+properties+`}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+		return factory
+	}
+
+	// the schema is updated after the row is written, the revisions are replayed from the first one
+	v1 := build(`"name": {"type": "string"}, "price": {"type": "integer"}`)
+	v2 := build(`"name": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}`)
+	coll := schema.NewDefaultCollection("c1", 1, 2, v2.Fields, v2.Indexes, v2.Schema, "ns1-db1-c1")
+	coll.FieldRevisions = schema.NextFieldRevisions(schema.NextFieldRevisions(nil, nil, v1.Fields, 1), v1.Fields, v2.Fields, 2)
+
+	// the rows written before the schema versions were recorded have no version
+	row := internal.NewTableData([]byte(`{"id":1,"name":"a","price":3}`))
+	require.Equal(t, int32(0), row.Ver)
+
+	t.Run("read", func(t *testing.T) {
+		doc, err := coll.UpgradeDocument(row.Ver, row.RawData)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"name":"a"}`, string(doc))
+	})
+	t.Run("update", func(t *testing.T) {
+		factory, err := update.BuildFieldOperators([]byte(`{"$set": {"tags": ["x"]}}`))
+		require.NoError(t, err)
+
+		updated, err := updateDocument(coll, factory, row, internal.NewTimestamp())
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"name":"a","tags":["x"]}`, string(updated.RawData))
+		require.Equal(t, int32(2), updated.Ver)
+	})
+	t.Run("search_hit", func(t *testing.T) {
+		upgradeHit := func(data *internal.TableData) (int32, []byte) {
+			packed, err := PackSearchFields(data, coll, "1")
+			require.NoError(t, err)
+
+			var searchDoc map[string]interface{}
+			require.NoError(t, jsoniter.Unmarshal(packed, &searchDoc))
+			_, tableData, doc, err := UnpackSearchFields(searchDoc, coll)
+			require.NoError(t, err)
+
+			raw, err := jsoniter.Marshal(doc)
+			require.NoError(t, err)
+			upgraded, err := coll.UpgradeDocument(tableData.Ver, raw)
+			require.NoError(t, err)
+			return tableData.Ver, upgraded
+		}
+
+		ver, doc := upgradeHit(internal.NewTableDataWithTS(internal.NewTimestamp(), nil, row.RawData))
+		require.Equal(t, int32(0), ver)
+		require.JSONEq(t, `{"id":1,"name":"a"}`, string(doc))
+
+		// the hits carry the version of the rows they were indexed from
+		written := internal.NewTableDataWithTS(internal.NewTimestamp(), nil, []byte(`{"id":1,"name":"a","tags":["x"]}`))
+		written.Ver = 2
+		ver, doc = upgradeHit(written)
+		require.Equal(t, int32(2), ver)
+		require.JSONEq(t, `{"id":1,"name":"a","tags":["x"]}`, string(doc))
+	})
+}
+
+func TestPlanCollection(t *testing.T) {
+	reqSchema := []byte(`{"title": "c1", "properties": {"id": {"type": "integer"}, "s": {"type": "string"}}, "primary_key": ["id"]}`)
+	factory, err := schema.Build("c1", reqSchema)
+	require.NoError(t, err)
+	existing := schema.NewDefaultCollection("c1", 1, 4, factory.Fields, factory.Indexes, factory.Schema, "c1")
+
+	t.Run("unchanged", func(t *testing.T) {
+		incoming, err := schema.Build("c1", reqSchema)
+		require.NoError(t, err)
+
+		plan, err := planCollection(existing, incoming)
+		require.NoError(t, err)
+		require.True(t, plan.Compatible)
+		require.Equal(t, int32(4), plan.Revision)
+		require.Empty(t, plan.Diff.Fields)
+		require.Empty(t, plan.SearchDeltaFields)
+	})
+	t.Run("incompatible", func(t *testing.T) {
+		incoming, err := schema.Build("c1", []byte(`{"title": "c1", "properties": {"id": {"type": "integer"}, "s": {"type": "integer"}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+
+		plan, err := planCollection(existing, incoming)
+		require.NoError(t, err)
+		require.False(t, plan.Compatible)
+		require.Equal(t, int32(5), plan.Revision)
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "data type mismatch for field \"s\"").Error(), plan.Reason)
+		require.Equal(t, []*api.SearchDeltaField{
+			{Name: "s", Drop: true},
+			{Name: "s", Type: "int64", Facet: true, Index: true},
+		}, plan.SearchDeltaFields)
+	})
+}
@@ -26,6 +26,9 @@ const (
 	DeletedStatus  string = "deleted"
 	CreatedStatus  string = "created"
 	DroppedStatus  string = "dropped"
+
+	RolledBackStatus string = "rolled_back"
+	PlannedStatus    string = "planned"
 )
 
 // Streaming is a wrapper interface for passing around for streaming reads
@@ -28,6 +28,10 @@ import (
 type Row struct {
 	Key  []byte
 	Data *internal.TableData
+	// Highlights are the snippets of the fields that matched the text query and Score is how well the row matched it,
+	// only the search readers set them.
+	Highlights []*api.SearchHighlight
+	Score      int64
 }
 
 type RowReader interface {
@@ -0,0 +1,579 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"math"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/search"
+	"github.com/tigrisdata/tigris/value"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+// cursorWindowPageSize is the page size used to read the hits that have the same sort values as the cursor, it is the
+// largest page the search backend returns.
+const cursorWindowPageSize = 250
+
+// searchCursor is the position of a cursor in the hits of a search. It is returned to the client as an opaque token
+// that the next request passes back to continue after the last hit it got.
+type searchCursor struct {
+	// Sort is the sort order of the search the cursor was created for.
+	Sort string `json:"sort"`
+	// Values are the sort values of the last hit.
+	Values []float64 `json:"values"`
+	// Key is the search key of the last hit, it is empty when none of the hits with the values were returned yet.
+	Key string `json:"key"`
+	// Missing is set once all the hits that have the sort values are read. The cursor then goes through the hits that
+	// miss one of them, in the order of their creation time, and Values only has the creation time.
+	Missing bool `json:"missing,omitempty"`
+}
+
+func (c *searchCursor) encode() (string, error) {
+	data, err := jsoniter.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeSearchCursor returns the position of the cursor token, nil for an empty token which starts from the first hit.
+func decodeSearchCursor(token string, order *cursorOrder) (*searchCursor, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid search cursor")
+	}
+
+	var cursor searchCursor
+	if err = jsoniter.Unmarshal(data, &cursor); err != nil {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid search cursor")
+	}
+	switch {
+	case !cursor.Missing && len(cursor.Values) == len(order.fields):
+	case cursor.Missing && order.missing != nil && (len(cursor.Values) == 0 || len(cursor.Values) == len(order.missing.fields)):
+	default:
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid search cursor")
+	}
+	if cursor.Sort != order.String() {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "the search cursor was created for another sort order")
+	}
+
+	return &cursor, nil
+}
+
+// cursorOrder is the order of the hits of a cursor: the sort fields of the search and then the creation time of the
+// documents. The hits with the same values are ordered by their search key, so that every hit has its own position.
+// The hits that miss one of the sort fields come after all the others, in the order of the creation time.
+type cursorOrder struct {
+	ordering qsearch.Ordering
+	fields   []*schema.QueryableField
+	// missing is the order of the hits that miss one of the sort fields, it is nil when there are no sort fields.
+	missing *cursorOrder
+}
+
+func newCursorOrder(ordering qsearch.Ordering, queryableFields []*schema.QueryableField) (*cursorOrder, error) {
+	if len(ordering) >= qsearch.MaxSortFields {
+		return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "a search cursor can sort on maximum %d fields", qsearch.MaxSortFields-1)
+	}
+
+	var order = &cursorOrder{}
+	for _, sf := range ordering {
+		if sf.Name == qsearch.TextMatchSortField || sf.GeoOrigin != nil {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "a search cursor can't sort on `%s`, only numeric fields are supported", sf.Name)
+		}
+
+		for _, qf := range queryableFields {
+			if qf.FieldName == sf.Name {
+				order.ordering = append(order.ordering, sf)
+				order.fields = append(order.fields, qf)
+				break
+			}
+		}
+	}
+
+	creation := newCreationOrder()
+	order.ordering = append(order.ordering, creation.ordering...)
+	order.fields = append(order.fields, creation.fields...)
+	if len(order.ordering) > len(creation.ordering) {
+		order.missing = creation
+	}
+
+	return order, nil
+}
+
+// newCreationOrder returns the order of the hits by their creation time.
+func newCreationOrder() *cursorOrder {
+	createdAt := schema.ReservedFields[schema.CreatedAt]
+	return &cursorOrder{
+		ordering: qsearch.Ordering{{Name: createdAt, Ascending: true}},
+		fields:   []*schema.QueryableField{schema.NewQueryableField(createdAt, schema.Int64Type)},
+	}
+}
+
+func (o *cursorOrder) String() string {
+	var fields []string
+	for _, sf := range o.ordering {
+		fields = append(fields, sf.ToSearchSort())
+	}
+	return strings.Join(fields, ",")
+}
+
+// values returns the sort values of a search document, false if the document doesn't have all of them.
+func (o *cursorOrder) values(doc map[string]interface{}) ([]float64, bool) {
+	var values = make([]float64, len(o.fields))
+	for i, f := range o.fields {
+		v, ok := doc[f.FieldName].(float64)
+		if !ok {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
+
+func (o *cursorOrder) compareValues(a []float64, b []float64) int {
+	for i, sf := range o.ordering {
+		cmp := 0
+		if a[i] < b[i] {
+			cmp = -1
+		} else if a[i] > b[i] {
+			cmp = 1
+		}
+		if !sf.Ascending {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func (o *cursorOrder) compare(a *cursorHit, b *cursorHit) int {
+	if cmp := o.compareValues(a.values, b.values); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a.key, b.key)
+}
+
+// equal returns the conditions on the i-th sort field of the hits that have the value v. The search backend returns
+// the integers as doubles, so a value above 2^53 stands for all the integers that are rounded to it.
+func (o *cursorOrder) equal(i int, v float64) ([]filter.Filter, error) {
+	f := o.fields[i]
+	if f.DataType == schema.DoubleType {
+		eq, err := newCursorSelector(f, filter.EQ, value.NewDoubleUsingFloat(v))
+		return []filter.Filter{eq}, err
+	}
+
+	lo, hi := cursorIntRange(v)
+	if lo == hi {
+		eq, err := newCursorSelector(f, filter.EQ, value.NewIntValue(lo))
+		return []filter.Filter{eq}, err
+	}
+
+	gte, err := newCursorSelector(f, filter.GTE, value.NewIntValue(lo))
+	if err != nil {
+		return nil, err
+	}
+	lte, err := newCursorSelector(f, filter.LTE, value.NewIntValue(hi))
+	return []filter.Filter{gte, lte}, err
+}
+
+// after returns the condition on the i-th sort field of the hits that have a value past v in the sort order.
+func (o *cursorOrder) after(i int, v float64) (filter.Filter, error) {
+	f, ascending := o.fields[i], o.ordering[i].Ascending
+	if f.DataType == schema.DoubleType {
+		if ascending {
+			return newCursorSelector(f, filter.GT, value.NewDoubleUsingFloat(v))
+		}
+		return newCursorSelector(f, filter.LT, value.NewDoubleUsingFloat(v))
+	}
+
+	lo, hi := cursorIntRange(v)
+	if ascending {
+		return newCursorSelector(f, filter.GT, value.NewIntValue(hi))
+	}
+	return newCursorSelector(f, filter.LT, value.NewIntValue(lo))
+}
+
+// windowFilter returns the conditions of the hits that have the sort values.
+func (o *cursorOrder) windowFilter(values []float64) ([]filter.Filter, error) {
+	var conditions []filter.Filter
+	for i, v := range values {
+		eq, err := o.equal(i, v)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, eq...)
+	}
+	return conditions, nil
+}
+
+// afterFilter returns the condition of the hits that come after all the hits with the sort values. It has a branch
+// per sort field, where the previous fields have the same values and the field has a value past the cursor.
+func (o *cursorOrder) afterFilter(values []float64) (filter.Filter, error) {
+	var branches []filter.Filter
+	for i := range values {
+		conditions, err := o.windowFilter(values[:i])
+		if err != nil {
+			return nil, err
+		}
+
+		after, err := o.after(i, values[i])
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, filter.NewWrappedFilter(append(conditions, after)).Filter)
+	}
+
+	if len(branches) == 1 {
+		return branches[0], nil
+	}
+	return filter.NewOrFilter(branches)
+}
+
+func newCursorSelector(field *schema.QueryableField, op string, v value.Value) (filter.Filter, error) {
+	matcher, err := filter.NewMatcher(op, v)
+	if err != nil {
+		return nil, err
+	}
+	return filter.NewSelector(field, matcher), nil
+}
+
+// cursorIntRange returns the smallest and the largest integers that are rounded to the double v.
+func cursorIntRange(v float64) (int64, int64) {
+	if math.Abs(v) < 1<<53 {
+		return int64(v), int64(v)
+	}
+
+	lo := int64(v) - (int64(v)-int64(math.Nextafter(v, math.Inf(-1))))/2
+	if float64(lo) != v {
+		lo++
+	}
+	hi := int64(v) + (int64(math.Nextafter(v, math.Inf(1)))-int64(v))/2
+	if float64(hi) != v {
+		hi--
+	}
+	return lo, hi
+}
+
+type cursorHit struct {
+	hit    tsApi.SearchResultHit
+	values []float64
+	key    string
+	// sorted is set for the hits read while going through the hits that miss a sort field when the hit has all of
+	// them, it was returned before and is only used to move the cursor.
+	sorted bool
+}
+
+// cursorReader reads the hits of a search in the order of a cursor. Instead of paging through the hits, every read
+// asks the search backend for the hits after the cursor, so a read is as fast at the end of the hits as at the start,
+// and the hits are neither repeated nor skipped when the hits before the cursor change. The search backend can't filter
+// the documents without a value for one of the sort fields, so once the other hits are read, the reader goes through
+// all the hits again in the order of their creation and returns the ones it skipped.
+type cursorReader struct {
+	store      search.Store
+	collection *schema.DefaultCollection
+	query      *qsearch.Query
+	order      *cursorOrder
+	cursor     *searchCursor
+	done       bool
+	found      int64
+	facets     map[string]*api.SearchFacet
+}
+
+func newCursorReader(store search.Store, coll *schema.DefaultCollection, query *qsearch.Query, order *cursorOrder, cursor *searchCursor) *cursorReader {
+	return &cursorReader{
+		store:      store,
+		collection: coll,
+		query:      query,
+		order:      order,
+		cursor:     cursor,
+		facets:     make(map[string]*api.SearchFacet),
+	}
+}
+
+// read returns a page with the next hits, at most limit of them, and moves the cursor after them. The total number of
+// hits and the facets are only known when the reader starts from the first hit.
+func (r *cursorReader) read(ctx context.Context, limit int) (*page, error) {
+	var hits []*cursorHit
+	for len(hits) < limit && !r.done {
+		next, err := r.readNext(ctx, limit-len(hits))
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, next...)
+
+		if r.done && r.order.missing != nil && (r.cursor == nil || !r.cursor.Missing) {
+			r.cursor = &searchCursor{Sort: r.order.String(), Missing: true}
+			r.done = false
+		}
+	}
+
+	pg := newPage(r.collection, r.query)
+	for _, h := range hits {
+		pg.hits.Append(h.hit)
+	}
+	return pg, nil
+}
+
+// nextCursor returns the token of the cursor to continue the reads, it is empty once all the hits are read.
+func (r *cursorReader) nextCursor() (string, error) {
+	if r.done || r.cursor == nil {
+		return "", nil
+	}
+	return r.cursor.encode()
+}
+
+// currentOrder returns the order of the hits the reader is going through.
+func (r *cursorReader) currentOrder() *cursorOrder {
+	if r.cursor != nil && r.cursor.Missing {
+		return r.order.missing
+	}
+	return r.order
+}
+
+// position returns the cursor, nil when none of the hits in the current order were read yet.
+func (r *cursorReader) position() *searchCursor {
+	if r.cursor == nil || (r.cursor.Missing && len(r.cursor.Values) == 0) {
+		return nil
+	}
+	return r.cursor
+}
+
+func (r *cursorReader) readNext(ctx context.Context, limit int) ([]*cursorHit, error) {
+	var hits []*cursorHit
+	if r.position() != nil {
+		window, err := r.readWindow(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(window) >= limit {
+			r.moveTo(window[limit-1])
+			return window[:limit], nil
+		}
+		hits, limit = window, limit-len(window)
+	}
+
+	after, err := r.readAfter(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(hits, after...), nil
+}
+
+// readWindow returns all the hits after the cursor that have its sort values.
+func (r *cursorReader) readWindow(ctx context.Context) ([]*cursorHit, error) {
+	order := r.currentOrder()
+	conditions, err := order.windowFilter(r.cursor.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.withFilter(conditions...)
+	query.PageSize = cursorWindowPageSize
+
+	var hits []*cursorHit
+	for pageNo := defaultPageNo; ; pageNo++ {
+		result, err := r.store.Search(ctx, r.collection.SearchCollectionName(), query, pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		more := false
+		for _, res := range result {
+			if res.Hits == nil {
+				continue
+			}
+			if len(*res.Hits) >= query.PageSize {
+				more = true
+			}
+			for _, h := range r.cursorHits(*res.Hits) {
+				if order.compareValues(h.values, r.cursor.Values) == 0 && h.key > r.cursor.Key && !h.sorted {
+					hits = append(hits, h)
+				}
+			}
+		}
+		if !more {
+			break
+		}
+	}
+
+	return r.sortHits(hits), nil
+}
+
+// readAfter returns the hits that come after all the hits with the sort values of the cursor. A search backend page
+// that is full may miss the hits that have the same values as its last hit, so only the hits before it are returned
+// and the cursor then moves to the values so that the next read takes them all from the window.
+func (r *cursorReader) readAfter(ctx context.Context, limit int) ([]*cursorHit, error) {
+	order, pos := r.currentOrder(), r.position()
+
+	var query *qsearch.Query
+	switch {
+	case r.cursor == nil:
+		copied := *r.query
+		query = &copied
+	case pos == nil:
+		query = r.withFilter()
+	default:
+		after, err := order.afterFilter(pos.Values)
+		if err != nil {
+			return nil, err
+		}
+		query = r.withFilter(after)
+	}
+	// one more hit than needed tells if there are hits with the same values as the last one returned
+	query.PageSize = limit + 1
+
+	result, err := r.store.Search(ctx, r.collection.SearchCollectionName(), query, defaultPageNo)
+	if err != nil {
+		return nil, err
+	}
+	if r.cursor == nil {
+		r.setFoundAndFacets(result)
+	}
+
+	var candidates []*cursorHit
+	var bound []float64
+	for _, res := range result {
+		if res.Hits == nil {
+			continue
+		}
+		hits := r.cursorHits(*res.Hits)
+		if len(*res.Hits) >= query.PageSize && len(hits) > 0 {
+			if last := hits[len(hits)-1].values; bound == nil || order.compareValues(last, bound) < 0 {
+				bound = last
+			}
+		}
+		candidates = append(candidates, hits...)
+	}
+	candidates = r.sortHits(candidates)
+
+	var hits []*cursorHit
+	var last *cursorHit
+	var complete = bound == nil
+	for _, h := range candidates {
+		if pos != nil && order.compare(h, &cursorHit{values: pos.Values, key: pos.Key}) <= 0 {
+			continue
+		}
+		if bound != nil && order.compareValues(h.values, bound) >= 0 {
+			complete = false
+			break
+		}
+		if !h.sorted {
+			if len(hits) == limit {
+				complete = false
+				break
+			}
+			hits = append(hits, h)
+		}
+		last = h
+	}
+
+	switch {
+	case last != nil:
+		r.moveTo(last)
+		r.done = complete
+	case bound != nil:
+		if pos != nil && order.compareValues(bound, pos.Values) <= 0 {
+			return nil, api.Errorf(api.Code_INTERNAL, "search cursor is not moving forward")
+		}
+		r.cursor = &searchCursor{Sort: r.order.String(), Values: bound, Missing: order != r.order}
+	default:
+		r.done = true
+	}
+	return hits, nil
+}
+
+// withFilter returns the query of the reader in the current order without the facets, with the conditions added to its
+// filter.
+func (r *cursorReader) withFilter(conditions ...filter.Filter) *qsearch.Query {
+	var filters []filter.Filter
+	if _, ok := r.query.WrappedF.Filter.(*filter.EmptyFilter); !ok {
+		filters = append(filters, r.query.WrappedF.Filter)
+	}
+
+	query := *r.query
+	query.WrappedF = filter.NewWrappedFilter(append(filters, conditions...))
+	query.Facets = qsearch.Facets{}
+	query.SortOrder = r.currentOrder().ordering
+	return &query
+}
+
+func (r *cursorReader) cursorHits(hits []tsApi.SearchResultHit) []*cursorHit {
+	order := r.currentOrder()
+
+	var cursorHits []*cursorHit
+	for _, h := range hits {
+		if h.Document == nil {
+			continue
+		}
+		values, ok := order.values(*h.Document)
+		if !ok {
+			continue
+		}
+		key, _ := (*h.Document)[searchID].(string)
+		hit := &cursorHit{hit: h, values: values, key: key}
+		if order != r.order {
+			_, hit.sorted = r.order.values(*h.Document)
+		}
+		cursorHits = append(cursorHits, hit)
+	}
+	return cursorHits
+}
+
+// sortHits sorts the hits in the cursor order and removes the hits returned by more than one search of an "$or".
+func (r *cursorReader) sortHits(hits []*cursorHit) []*cursorHit {
+	order := r.currentOrder()
+	sort.SliceStable(hits, func(i, j int) bool {
+		return order.compare(hits[i], hits[j]) < 0
+	})
+
+	var unique []*cursorHit
+	for i, h := range hits {
+		if i > 0 && h.key == hits[i-1].key {
+			continue
+		}
+		unique = append(unique, h)
+	}
+	return unique
+}
+
+func (r *cursorReader) moveTo(h *cursorHit) {
+	r.cursor = &searchCursor{Sort: r.order.String(), Values: h.values, Key: h.key, Missing: r.currentOrder() != r.order}
+}
+
+func (r *cursorReader) setFoundAndFacets(result []tsApi.SearchResult) {
+	facets := newPageReader(r.store, r.collection, r.query, defaultPageNo)
+	for _, res := range result {
+		if res.Found != nil {
+			r.found += int64(*res.Found)
+		}
+		facets.buildFacets(res.FacetCounts)
+	}
+	r.facets = facets.cachedFacets
+}
@@ -0,0 +1,207 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/search"
+)
+
+func TestSearchCursor(t *testing.T) {
+	reqSchema := []byte(`{"title": "c1", "properties": {"pkey": {"type": "integer"}, "price": {"type": "integer"}}, "primary_key": ["pkey"]}`)
+	factory, err := schema.Build("c1", reqSchema)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("c1", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "ns1-db1-c1")
+
+	newStore := func(t *testing.T, docs ...string) search.Store {
+		store := search.NewEmbeddedStore()
+		require.NoError(t, store.CreateCollection(context.TODO(), coll.Search))
+		indexSearchDocuments(t, store, docs...)
+		return store
+	}
+
+	newQuery := func(t *testing.T, sort string, pageSize int) (*qsearch.Query, *cursorOrder) {
+		ordering, err := qsearch.UnmarshalSort([]byte(sort))
+		require.NoError(t, err)
+		order, err := newCursorOrder(ordering, coll.QueryableFields)
+		require.NoError(t, err)
+
+		wrappedF, err := filter.NewFactory(coll.QueryableFields).WrappedFilter(nil)
+		require.NoError(t, err)
+		return qsearch.NewBuilder().Filter(wrappedF).SortOrder(order.ordering).PageSize(pageSize).Build(), order
+	}
+
+	// readPages reads the hits a page at a time like a client would, resuming every page from the returned cursor
+	readPages := func(t *testing.T, store search.Store, query *qsearch.Query, order *cursorOrder, token string, pages int) ([][]string, string) {
+		var read [][]string
+		for i := 0; i < pages; i++ {
+			cursor, err := decodeSearchCursor(token, order)
+			require.NoError(t, err)
+
+			reader := newCursorReader(store, coll, query, order, cursor)
+			pg, err := reader.read(context.TODO(), query.PageSize)
+			require.NoError(t, err)
+
+			var keys []string
+			var row Row
+			for pg.readRow(&row) {
+				keys = append(keys, string(row.Key))
+			}
+			require.NoError(t, pg.err)
+			read = append(read, keys)
+
+			if token, err = reader.nextCursor(); err != nil || len(token) == 0 {
+				require.NoError(t, err)
+				break
+			}
+		}
+		return read, token
+	}
+
+	docs := []string{
+		`{"id":"a","pkey":1,"price":10,"created_at":100}`,
+		`{"id":"b","pkey":2,"price":30,"created_at":100}`,
+		`{"id":"c","pkey":3,"price":20,"created_at":200}`,
+		`{"id":"d","pkey":4,"price":20,"created_at":100}`,
+		`{"id":"e","pkey":5,"price":30,"created_at":100}`,
+		`{"id":"f","pkey":6,"price":20,"created_at":100}`,
+		`{"id":"g","pkey":7,"created_at":100}`,
+	}
+
+	t.Run("pages through the hits in the sort order", func(t *testing.T) {
+		store := newStore(t, docs...)
+		query, order := newQuery(t, `[{"price":"$desc"}]`, 2)
+
+		pages, next := readPages(t, store, query, order, "", 10)
+		require.Equal(t, [][]string{{"b", "e"}, {"d", "f"}, {"c", "a"}, {"g"}}, pages)
+		require.Empty(t, next)
+	})
+	t.Run("continues after the last hit when the collection changes", func(t *testing.T) {
+		store := newStore(t, docs...)
+		query, order := newQuery(t, `[{"price":"$desc"}]`, 2)
+
+		pages, next := readPages(t, store, query, order, "", 2)
+		require.Equal(t, [][]string{{"b", "e"}, {"d", "f"}}, pages)
+
+		// a hit before the cursor is removed and hits are added before and after it
+		require.NoError(t, store.DeleteDocuments(context.TODO(), coll.SearchCollectionName(), "d"))
+		indexSearchDocuments(t, store, `{"id":"h","pkey":8,"price":40,"created_at":300}`, `{"id":"i","pkey":9,"price":15,"created_at":300}`)
+
+		// the last page ends on the hits created at the same time, so the end is only known on the next read
+		pages, next = readPages(t, store, query, order, next, 10)
+		require.Equal(t, [][]string{{"c", "i"}, {"a", "g"}, nil}, pages)
+		require.Empty(t, next)
+	})
+	t.Run("reads more hits with the same values than fit in a page", func(t *testing.T) {
+		var tied []string
+		for i := 0; i < 7; i++ {
+			tied = append(tied, fmt.Sprintf(`{"id":"k%d","pkey":%d,"price":1,"created_at":500}`, i, i))
+		}
+		store := newStore(t, append(tied, `{"id":"j","pkey":10,"price":1,"created_at":400}`)...)
+		query, order := newQuery(t, ``, 3)
+
+		pages, next := readPages(t, store, query, order, "", 10)
+		require.Equal(t, [][]string{{"j", "k0", "k1"}, {"k2", "k3", "k4"}, {"k5", "k6"}}, pages)
+		require.Empty(t, next)
+	})
+	t.Run("reads all the hits in one go", func(t *testing.T) {
+		store := newStore(t, docs...)
+		query, order := newQuery(t, `[{"price":"$asc"}]`, 2)
+
+		reader := newCursorReader(store, coll, query, order, nil)
+		pg, err := reader.read(context.TODO(), 100)
+		require.NoError(t, err)
+		require.Equal(t, 7, pg.hits.Count())
+		require.Equal(t, int64(7), reader.found)
+
+		next, err := reader.nextCursor()
+		require.NoError(t, err)
+		require.Empty(t, next)
+	})
+	t.Run("reads the hits without the sort values last", func(t *testing.T) {
+		store := newStore(t, append(docs,
+			`{"id":"l","pkey":11,"created_at":50}`,
+			`{"id":"m","pkey":12,"created_at":300}`,
+			`{"id":"n","pkey":13,"created_at":100}`)...)
+		query, order := newQuery(t, `[{"price":"$asc"}]`, 4)
+
+		pages, next := readPages(t, store, query, order, "", 10)
+		require.Equal(t, [][]string{{"a", "d", "f", "c"}, {"b", "e", "l", "g"}, {"n", "m"}}, pages)
+		require.Empty(t, next)
+
+		// the cursor of the hits without the sort values only resumes the same sort order
+		_, next = readPages(t, store, query, order, "", 2)
+		cursor, err := decodeSearchCursor(next, order)
+		require.NoError(t, err)
+		require.True(t, cursor.Missing)
+		require.Len(t, cursor.Values, 1)
+
+		unsorted, err := newCursorOrder(nil, coll.QueryableFields)
+		require.NoError(t, err)
+		_, err = decodeSearchCursor(next, unsorted)
+		require.Error(t, err)
+	})
+	t.Run("filters the hits after the cursor", func(t *testing.T) {
+		_, order := newQuery(t, `[{"price":"$desc"}]`, 2)
+
+		after, err := order.afterFilter([]float64{20, 100})
+		require.NoError(t, err)
+		require.Equal(t, []string{"price:<20", "price:=20&&created_at:>100"}, after.ToSearchFilter())
+
+		window, err := order.windowFilter([]float64{20, 100})
+		require.NoError(t, err)
+		require.Equal(t, []string{"price:=20&&created_at:=100"}, filter.NewWrappedFilter(window).Filter.ToSearchFilter())
+	})
+	t.Run("rejects invalid cursors", func(t *testing.T) {
+		query, order := newQuery(t, `[{"price":"$desc"}]`, 2)
+		_, next := readPages(t, newStore(t, docs...), query, order, "", 1)
+
+		_, err := decodeSearchCursor("not a cursor", order)
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid search cursor"), err)
+
+		_, ascending := newQuery(t, `[{"price":"$asc"}]`, 2)
+		_, err = decodeSearchCursor(next, ascending)
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "the search cursor was created for another sort order"), err)
+
+		ordering, err := qsearch.UnmarshalSort([]byte(`[{"_text_match":"$desc"}]`))
+		require.NoError(t, err)
+		_, err = newCursorOrder(ordering, coll.QueryableFields)
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "a search cursor can't sort on `_text_match`, only numeric fields are supported"), err)
+	})
+}
+
+func TestCursorIntRange(t *testing.T) {
+	for _, v := range []float64{0, -42, 1 << 52, 1668000000123456789, -1668000000123456789} {
+		lo, hi := cursorIntRange(v)
+		require.Equal(t, v, float64(lo))
+		require.Equal(t, v, float64(hi))
+		require.NotEqual(t, v, float64(lo-1))
+		require.NotEqual(t, v, float64(hi+1))
+	}
+}
+
+func indexSearchDocuments(t *testing.T, store search.Store, docs ...string) {
+	err := store.IndexDocuments(context.TODO(), "ns1-db1-c1", strings.NewReader(strings.Join(docs, "\n")), search.IndexDocumentsOptions{Action: "upsert"})
+	require.NoError(t, err)
+}
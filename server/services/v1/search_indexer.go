@@ -19,21 +19,27 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
 )
 
 var (
-	ErrSearchIndexingFailed = fmt.Errorf("failed to index documents")
+	ErrSearchIndexingFailed  = fmt.Errorf("failed to index documents")
+	ErrSearchIndexingPending = fmt.Errorf("changes are committed but not indexed yet")
 )
 
 const (
@@ -45,19 +51,35 @@ const (
 	searchUpdate string = "update"
 )
 
+// SearchIndexer keeps the search store in sync with the collections. With the asynchronous indexing the changes are
+// appended to the search queue in the transaction making them, and are indexed by the searchQueueDrainer, otherwise
+// the changes are indexed once the transaction is committed.
 type SearchIndexer struct {
 	searchStore search.Store
 	encoder     metadata.Encoder
+	txMgr       *transaction.Manager
+	queueStore  *encoding.SearchQueueSubspace
+	cfg         config.SearchIndexingConfig
 }
 
-func NewSearchIndexer(searchStore search.Store, encoder metadata.Encoder) *SearchIndexer {
+func NewSearchIndexer(searchStore search.Store, encoder metadata.Encoder, txMgr *transaction.Manager, queueStore *encoding.SearchQueueSubspace) *SearchIndexer {
 	return &SearchIndexer{
 		searchStore: searchStore,
 		encoder:     encoder,
+		txMgr:       txMgr,
+		queueStore:  queueStore,
+		cfg:         config.DefaultConfig.Search.Indexing,
 	}
 }
 
 func (i *SearchIndexer) OnPostCommit(ctx context.Context, tenant *metadata.Tenant, eventListener kv.EventListener) error {
+	if i.cfg.Async {
+		if txId, ok := ctx.Value(waitForIndexCtxKey{}).(string); ok {
+			return i.waitForIndex(ctx, txId)
+		}
+		return nil
+	}
+
 	for _, event := range eventListener.GetEvents() {
 		var err error
 		_, db, coll, ok := i.encoder.DecodeTableName(event.Table)
@@ -114,10 +136,91 @@ func (i *SearchIndexer) OnPostCommit(ctx context.Context, tenant *metadata.Tenan
 	return nil
 }
 
-func (i *SearchIndexer) OnPreCommit(context.Context, *metadata.Tenant, transaction.Tx, kv.EventListener) error {
+func (i *SearchIndexer) OnPreCommit(ctx context.Context, tenant *metadata.Tenant, tx transaction.Tx, eventListener kv.EventListener) error {
+	if !i.cfg.Async {
+		return nil
+	}
+
+	// the order tells apart the entries of the transaction that are appended to the same partition
+	order := make(map[int]int)
+	for _, entry := range i.queueEntries(tenant, tx.GetTxCtx().GetId(), eventListener.GetEvents()) {
+		partition := searchQueuePartition(entry.Table, i.cfg.Partitions)
+		if order[partition] > math.MaxUint16 {
+			return api.Errorf(api.Code_RESOURCE_EXHAUSTED, "transaction changes more than %d documents to index in search", math.MaxUint16+1)
+		}
+		if err := i.queueStore.Enqueue(ctx, tx, partition, uint16(order[partition]), entry); err != nil {
+			return err
+		}
+		order[partition]++
+	}
+
 	return nil
 }
 
+// queueEntries returns an entry for every document changed by the transaction, in the order the documents are first
+// changed. A document changed more than once is only queued once as the entry is indexed with the value of the
+// document at the time it is indexed.
+func (i *SearchIndexer) queueEntries(tenant *metadata.Tenant, txId string, events []*kv.Event) []*encoding.SearchQueueEntry {
+	var entries []*encoding.SearchQueueEntry
+	queued := make(map[string]struct{})
+	for _, event := range events {
+		switch event.Op {
+		case kv.InsertEvent, kv.ReplaceEvent, kv.UpdateEvent, kv.DeleteEvent:
+		default:
+			continue
+		}
+
+		_, db, coll, ok := i.encoder.DecodeTableName(event.Table)
+		if !ok {
+			continue
+		}
+
+		key := string(event.Table) + "/" + string(event.Key)
+		if _, ok = queued[key]; ok {
+			continue
+		}
+		queued[key] = struct{}{}
+
+		entries = append(entries, &encoding.SearchQueueEntry{
+			TxId:       txId,
+			Namespace:  tenant.GetNamespace().Name(),
+			Db:         db,
+			Collection: coll,
+			Table:      event.Table,
+			Key:        event.Key,
+		})
+	}
+
+	return entries
+}
+
+// waitForIndex holds back the write until the changes of its transaction are removed from the search queue, which
+// happens once they are indexed.
+func (i *SearchIndexer) waitForIndex(ctx context.Context, txId string) error {
+	deadline := time.Now().Add(i.cfg.WaitTimeout)
+	for {
+		tx, err := i.txMgr.StartTx(ctx)
+		if err != nil {
+			return err
+		}
+		pending, err := i.queueStore.IsPending(ctx, tx, txId)
+		_ = tx.Rollback(ctx)
+		if err != nil || !pending {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return ErrSearchIndexingPending
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(i.cfg.PollInterval):
+		}
+	}
+}
+
 func (i *SearchIndexer) OnRollback(context.Context, *metadata.Tenant, kv.EventListener) {}
 
 func CreateSearchKey(table []byte, fdbKey []byte) (string, error) {
@@ -175,9 +278,20 @@ func PackSearchFields(data *internal.TableData, collection *schema.DefaultCollec
 
 	decData = FlattenObjects(decData)
 
-	// now if there is any array we need to pack it
+	// the arrays of strings are indexed as arrays so that their elements can be searched
+	unpacked := make(map[string]struct{})
+	for _, f := range collection.QueryableFields {
+		if f.DataType == schema.ArrayType && !f.ShouldPack() {
+			unpacked[f.Name()] = struct{}{}
+		}
+	}
+
+	// now if there is any other array we need to pack it
 	for key, value := range decData {
 		if _, ok := value.([]any); ok {
+			if _, ok := unpacked[key]; ok {
+				continue
+			}
 			// pack any array field
 			if decData[key], err = jsoniter.MarshalToString(value); err != nil {
 				return nil, err
@@ -185,21 +299,77 @@ func PackSearchFields(data *internal.TableData, collection *schema.DefaultCollec
 		}
 	}
 
+	// geopoint is flattened as "<field>.lat" and "<field>.lon" whereas search needs it as a [lat, lon] pair
+	for _, f := range collection.QueryableFields {
+		if f.DataType == schema.GeoPointType {
+			packGeoPoint(decData, f.Name())
+		}
+	}
+
+	// the faceted datetime fields also have their unix time, the intervals of the facets are counted with it
+	for _, f := range collection.QueryableFields {
+		if f.DataType == schema.DateTimeType && f.Faceted {
+			packDateTime(decData, f.Name())
+		}
+	}
+
 	decData[searchID] = id
 	decData[schema.ReservedFields[schema.CreatedAt]] = data.CreatedAt.UnixNano()
 	if data.UpdatedAt != nil {
 		decData[schema.ReservedFields[schema.UpdatedAt]] = data.UpdatedAt.UnixNano()
 	}
+	// the schema version is kept so that the hits are upgraded like the rows they were indexed from
+	if data.Ver > 0 {
+		decData[schema.ReservedFields[schema.SchemaVersion]] = data.Ver
+	}
 
 	return jsoniter.Marshal(decData)
 }
 
+func packDateTime(decData map[string]any, name string) {
+	value, ok := decData[name].(string)
+	if !ok {
+		return
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		decData[schema.ToSearchDateTimeKey(name)] = t.UnixMilli()
+	}
+}
+
+func packGeoPoint(decData map[string]any, name string) {
+	latKey := name + schema.ObjFlattenDelimiter + schema.GeoPointLatitude
+	lonKey := name + schema.ObjFlattenDelimiter + schema.GeoPointLongitude
+
+	lat, latOk := decData[latKey]
+	lon, lonOk := decData[lonKey]
+	if !latOk || !lonOk {
+		return
+	}
+
+	decData[name] = []any{lat, lon}
+	delete(decData, latKey)
+	delete(decData, lonKey)
+}
+
 func UnpackSearchFields(doc map[string]interface{}, collection *schema.DefaultCollection) (string, *internal.TableData, map[string]interface{}, error) {
 	for _, f := range collection.QueryableFields {
-		if f.ShouldPack() {
+		if f.DataType == schema.DateTimeType {
+			delete(doc, schema.ToSearchDateTimeKey(f.Name()))
+		}
+		if f.DataType == schema.GeoPointType {
 			if v, ok := doc[f.Name()]; ok {
+				point, err := schema.NewGeoPointFromSearch(v)
+				if err != nil {
+					return "", nil, nil, err
+				}
+				doc[f.Name()] = point.ToDocument()
+			}
+		} else if f.ShouldPack() || f.DataType == schema.ArrayType {
+			// the arrays of strings are not packed, unless they were indexed before they could be searched
+			if v, ok := doc[f.Name()].(string); ok {
 				var value interface{}
-				if err := jsoniter.UnmarshalFromString(v.(string), &value); err != nil {
+				if err := jsoniter.UnmarshalFromString(v, &value); err != nil {
 					return "", nil, nil, err
 				}
 				doc[f.Name()] = value
@@ -230,6 +400,12 @@ func UnpackSearchFields(doc map[string]interface{}, collection *schema.DefaultCo
 		tableData.UpdatedAt = internal.CreateNewTimestamp(int64(value.(float64)))
 		delete(doc, schema.ReservedFields[schema.UpdatedAt])
 	}
+	if value, ok := doc[schema.ReservedFields[schema.SchemaVersion]]; ok {
+		if ver, ok := value.(float64); ok {
+			tableData.Ver = int32(ver)
+		}
+		delete(doc, schema.ReservedFields[schema.SchemaVersion])
+	}
 
 	return searchKey, tableData, doc, nil
 }
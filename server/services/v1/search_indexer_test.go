@@ -9,6 +9,8 @@ import (
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
 )
 
 func TestFlattenObj(t *testing.T) {
@@ -24,6 +26,63 @@ func TestFlattenObj(t *testing.T) {
 	require.True(t, reflect.DeepEqual(UnFlattenMap, UnFlattenObjects(flattened)))
 }
 
+func TestPackSearchFields(t *testing.T) {
+	factory, err := schema.Build("t1", []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"tags": { "type": "array", "items": { "type": "string" } },
+		"scores": { "type": "array", "items": { "type": "integer" } },
+		"details": { "type": "object", "properties": {
+			"labels": { "type": "array", "items": { "type": "string" } },
+			"released": { "type": "string", "format": "date-time" }
+		} }
+	},
+	"primary_key": ["id"]
+}`))
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "t1")
+
+	doc := []byte(`{"id":1,"tags":["red","blue"],"scores":[1,2],"details":{"labels":["new"],"released":"2022-10-03T12:00:00+02:00"}}`)
+	packed, err := PackSearchFields(internal.NewTableDataWithTS(internal.NewTimestamp(), nil, doc), coll, "1")
+	require.NoError(t, err)
+
+	var searchDoc map[string]any
+	require.NoError(t, jsoniter.Unmarshal(packed, &searchDoc))
+	// the arrays of strings are indexed as arrays, the other arrays are packed
+	require.Equal(t, []any{"red", "blue"}, searchDoc["tags"])
+	require.Equal(t, []any{"new"}, searchDoc["details.labels"])
+	require.Equal(t, "[1,2]", searchDoc["scores"])
+	// the datetime fields also have their unix time in milliseconds
+	require.Equal(t, float64(1664791200000), searchDoc["_tigris_ts_details.released"])
+
+	_, _, unpacked, err := UnpackSearchFields(searchDoc, coll)
+	require.NoError(t, err)
+	actual, err := jsoniter.Marshal(unpacked)
+	require.NoError(t, err)
+	require.JSONEq(t, string(doc), string(actual))
+
+	// the arrays of strings indexed before they could be searched are packed
+	_, _, unpacked, err = UnpackSearchFields(map[string]any{searchID: "1", schema.ReservedFields[schema.IdToSearchKey]: float64(1), "tags": `["red"]`}, coll)
+	require.NoError(t, err)
+	require.Equal(t, []any{"red"}, unpacked["tags"])
+
+	// the legacy search collections keep them packed until they are reindexed
+	legacy := coll.WithSearchIndex("t1", schema.SearchIndexV0)
+	packed, err = PackSearchFields(internal.NewTableDataWithTS(internal.NewTimestamp(), nil, doc), legacy, "1")
+	require.NoError(t, err)
+	searchDoc = nil
+	require.NoError(t, jsoniter.Unmarshal(packed, &searchDoc))
+	require.Equal(t, `["red","blue"]`, searchDoc["tags"])
+	require.NotContains(t, searchDoc, "_tigris_ts_details.released")
+
+	_, _, unpacked, err = UnpackSearchFields(searchDoc, legacy)
+	require.NoError(t, err)
+	actual, err = jsoniter.Marshal(unpacked)
+	require.NoError(t, err)
+	require.JSONEq(t, string(doc), string(actual))
+}
+
 // Benchmarking to test if it makes sense to decode the data and then add fields to the decoded map and then encode
 // again and this benchmark shows if we are setting more than one field then it is better to decode.
 func BenchmarkEncDec(b *testing.B) {
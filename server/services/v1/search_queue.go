@@ -0,0 +1,452 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/search"
+)
+
+type waitForIndexCtxKey struct{}
+
+// waitForIndex makes the commit of the transaction wait until the changes of the transaction are indexed in the search
+// store.
+func waitForIndex(ctx context.Context, txId string) context.Context {
+	return context.WithValue(ctx, waitForIndexCtxKey{}, txId)
+}
+
+// searchQueuePartition returns the partition of the search queue the changes of the table are appended to, all the
+// changes of a collection go to the same partition so that they are indexed in the commit order.
+func searchQueuePartition(table []byte, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write(table)
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// searchIndexBatch is a run of the changes of a collection that are indexed in a single request to the search store.
+// The deletes and the upserts are in separate batches so that the order of the changes is kept.
+type searchIndexBatch struct {
+	namespace        string
+	db               string
+	collection       string
+	searchCollection string
+	delete           bool
+	ids              []string
+	docs             [][]byte
+}
+
+func (b *searchIndexBatch) size() int {
+	if b.delete {
+		return len(b.ids)
+	}
+	return len(b.docs)
+}
+
+// appendIndexBatch adds the change to the last batch if the change is of the same kind and of the same collection,
+// otherwise the change starts a new batch.
+func appendIndexBatch(batches []*searchIndexBatch, change *searchIndexBatch) []*searchIndexBatch {
+	if len(batches) > 0 {
+		last := batches[len(batches)-1]
+		if last.searchCollection == change.searchCollection && last.delete == change.delete {
+			last.ids = append(last.ids, change.ids...)
+			last.docs = append(last.docs, change.docs...)
+			return batches
+		}
+	}
+
+	return append(batches, change)
+}
+
+// searchQueueDrainer indexes the changes queued in the search queue. The queue is split in partitions, every partition
+// is drained by a single worker, the server running the worker holds a lease on the partition so that only one server
+// is indexing the changes of a partition at a time. The changes are removed from the queue once they are indexed, a
+// new worker starts from the oldest change that is still in the queue which means a change may be indexed more than
+// once.
+type searchQueueDrainer struct {
+	sync.Mutex
+
+	id          string
+	cfg         config.SearchIndexingConfig
+	txMgr       *transaction.Manager
+	tenantMgr   *metadata.TenantManager
+	encoder     metadata.Encoder
+	searchStore search.Store
+	workers     map[int]context.CancelFunc
+}
+
+func newSearchQueueDrainer(s *apiService) *searchQueueDrainer {
+	return &searchQueueDrainer{
+		id:          uuid.New().String(),
+		cfg:         config.DefaultConfig.Search.Indexing,
+		txMgr:       s.txMgr,
+		tenantMgr:   s.tenantMgr,
+		encoder:     s.encoder,
+		searchStore: s.searchStore,
+		workers:     make(map[int]context.CancelFunc),
+	}
+}
+
+func (d *searchQueueDrainer) start() {
+	go func() {
+		ticker := time.NewTicker(d.cfg.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			d.refresh(context.Background())
+			<-ticker.C
+		}
+	}()
+}
+
+// refresh starts a worker for every partition this server holds the lease of and stops the workers of the partitions
+// whose lease is lost.
+func (d *searchQueueDrainer) refresh(ctx context.Context) {
+	for partition := 0; partition < d.cfg.Partitions; partition++ {
+		acquired, err := d.acquireLease(ctx, partition)
+		if err != nil {
+			log.Err(err).Int("partition", partition).Msg("acquiring search queue lease failed")
+		}
+
+		if acquired {
+			d.startWorker(partition)
+		} else {
+			d.stopWorker(partition)
+		}
+	}
+}
+
+func (d *searchQueueDrainer) acquireLease(ctx context.Context, partition int) (bool, error) {
+	tx, err := d.txMgr.StartTx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	acquired, err := d.tenantMgr.GetSearchQueueStore().AcquireLease(ctx, tx, partition, d.id, time.Now(), d.cfg.LeaseTimeout)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return false, err
+	}
+
+	return acquired, tx.Commit(ctx)
+}
+
+func (d *searchQueueDrainer) startWorker(partition int) {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.workers[partition]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.workers[partition] = cancel
+
+	go func() {
+		if err := d.run(ctx, partition); err != nil && ctx.Err() == nil {
+			log.Err(err).Int("partition", partition).Msg("indexing search queue failed")
+		}
+
+		// the worker is started again by the next refresh, from the oldest change in the queue
+		d.stopWorker(partition)
+	}()
+}
+
+func (d *searchQueueDrainer) stopWorker(partition int) {
+	d.Lock()
+	defer d.Unlock()
+
+	if cancel, ok := d.workers[partition]; ok {
+		cancel()
+		delete(d.workers, partition)
+	}
+}
+
+// run drains the partition, the queue is read again right away as long as the batches are full.
+func (d *searchQueueDrainer) run(ctx context.Context, partition int) error {
+	lagging := make(map[string][]string)
+	for {
+		n, err := d.drain(ctx, partition, lagging)
+		if err != nil {
+			return err
+		}
+		if n >= d.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(d.cfg.PollInterval):
+		}
+	}
+}
+
+// drain indexes the oldest batch of the partition and then removes it from the queue. It returns the number of entries
+// that are indexed.
+func (d *searchQueueDrainer) drain(ctx context.Context, partition int, lagging map[string][]string) (int, error) {
+	tx, err := d.txMgr.StartTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	queueStore := d.tenantMgr.GetSearchQueueStore()
+	entries, err := queueStore.Read(ctx, tx, partition, d.cfg.BatchSize)
+	if err == nil {
+		err = d.reloadTenants(ctx, tx, entries)
+	}
+	var batches []*searchIndexBatch
+	if err == nil {
+		batches, err = d.buildBatches(ctx, tx, entries)
+	}
+	_ = tx.Rollback(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	d.reportLag(entries, lagging)
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	for _, batch := range batches {
+		if err = d.index(ctx, batch); err != nil {
+			return 0, err
+		}
+	}
+
+	if tx, err = d.txMgr.StartTx(ctx); err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err = queueStore.Delete(ctx, tx, entry); err != nil {
+			_ = tx.Rollback(ctx)
+			return 0, err
+		}
+	}
+
+	return len(entries), tx.Commit(ctx)
+}
+
+// reloadTenants brings the schemas of the collections of the entries up to date, so that the changes made right after
+// a schema change are indexed with the new schema.
+func (d *searchQueueDrainer) reloadTenants(ctx context.Context, tx transaction.Tx, entries []*encoding.SearchQueueEntry) error {
+	reloaded := make(map[string]struct{})
+	for _, entry := range entries {
+		if _, ok := reloaded[entry.Namespace]; ok {
+			continue
+		}
+		reloaded[entry.Namespace] = struct{}{}
+
+		tenant, err := d.tenantMgr.GetTenant(ctx, entry.Namespace, d.txMgr)
+		if err != nil {
+			return err
+		}
+		if tenant == nil {
+			continue
+		}
+		if err = tenant.ReloadUsingTxVersion(ctx, tx, entry.TxId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportLag reports the age of the oldest entry of every collection in the batch. The collections that had a lag
+// reported earlier and are not in the batch anymore are caught up.
+func (d *searchQueueDrainer) reportLag(entries []*encoding.SearchQueueEntry, lagging map[string][]string) {
+	seen := make(map[string]struct{})
+	for _, entry := range entries {
+		key := fmt.Sprintf("%s/%s/%s", entry.Namespace, entry.Db, entry.Collection)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		var lag time.Duration
+		if entry.CreatedAt != nil {
+			lag = time.Since(time.Unix(0, entry.CreatedAt.UnixNano()))
+		}
+		metrics.UpdateSearchIndexingLag(entry.Namespace, entry.Db, entry.Collection, lag.Seconds())
+		lagging[key] = []string{entry.Namespace, entry.Db, entry.Collection}
+	}
+
+	for key, names := range lagging {
+		if _, ok := seen[key]; !ok {
+			metrics.UpdateSearchIndexingLag(names[0], names[1], names[2], 0)
+			delete(lagging, key)
+		}
+	}
+}
+
+// buildBatches reads the current value of the documents of the entries and converts them to the documents of the
+// search store, the documents that are not in the collection anymore are deleted from the search store. The changes
+// of the collections that are dropped since are skipped, so are the changes of a collection that is dropped and created
+// again as the table of the new collection is different.
+func (d *searchQueueDrainer) buildBatches(ctx context.Context, tx transaction.Tx, entries []*encoding.SearchQueueEntry) ([]*searchIndexBatch, error) {
+	var batches []*searchIndexBatch
+	for _, entry := range entries {
+		tenant, err := d.tenantMgr.GetTenant(ctx, entry.Namespace, d.txMgr)
+		if err != nil {
+			return nil, err
+		}
+		if tenant == nil {
+			continue
+		}
+
+		_, db, coll, ok := d.encoder.DecodeTableName(entry.Table)
+		if !ok {
+			continue
+		}
+		collection := tenant.GetCollection(db, coll)
+		if collection == nil {
+			continue
+		}
+
+		data, err := tx.Get(ctx, entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		change, err := newSearchIndexChange(entry, collection, data)
+		if err != nil {
+			return nil, err
+		}
+
+		batches = appendIndexBatch(batches, change)
+	}
+
+	return batches, nil
+}
+
+// newSearchIndexChange returns the change to the search store for the current value of the document of the entry, the
+// document is deleted from the search store if it is not in the collection anymore.
+func newSearchIndexChange(entry *encoding.SearchQueueEntry, collection *schema.DefaultCollection, data []byte) (*searchIndexBatch, error) {
+	searchKey, err := CreateSearchKey(entry.Table, entry.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &searchIndexBatch{
+		namespace:        entry.Namespace,
+		db:               entry.Db,
+		collection:       entry.Collection,
+		searchCollection: collection.SearchCollectionName(),
+		delete:           data == nil,
+	}
+	if change.delete {
+		change.ids = []string{searchKey}
+		return change, nil
+	}
+
+	tableData, err := internal.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	searchData, err := PackSearchFields(tableData, collection, searchKey)
+	if err != nil {
+		return nil, err
+	}
+	change.docs = [][]byte{searchData}
+
+	return change, nil
+}
+
+// index applies the batch to the search store, the failed attempts are retried with an exponential backoff. Once
+// MaxAttempts is reached, the batch is applied one change at a time and the changes the search store rejects are
+// skipped. The failures that are not caused by the changes themselves are retried until they succeed.
+func (d *searchQueueDrainer) index(ctx context.Context, batch *searchIndexBatch) error {
+	backoff := d.cfg.MinBackoff
+	for attempts := 1; ; attempts++ {
+		err := d.apply(ctx, batch)
+		if err == nil {
+			metrics.IncSearchIndexed(batch.namespace, batch.db, batch.collection, int64(batch.size()))
+			return nil
+		}
+		if attempts >= d.cfg.MaxAttempts && !search.IsRetryable(err) {
+			return d.indexOneByOne(ctx, batch)
+		}
+
+		metrics.IncSearchIndexingFailedAttempts(batch.namespace, batch.db, batch.collection)
+		log.Debug().Err(err).Str("collection", batch.searchCollection).Int("attempts", attempts).Msg("indexing search batch failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+}
+
+func (d *searchQueueDrainer) indexOneByOne(ctx context.Context, batch *searchIndexBatch) error {
+	var single []*searchIndexBatch
+	for _, id := range batch.ids {
+		single = append(single, &searchIndexBatch{searchCollection: batch.searchCollection, delete: true, ids: []string{id}})
+	}
+	for _, doc := range batch.docs {
+		single = append(single, &searchIndexBatch{searchCollection: batch.searchCollection, docs: [][]byte{doc}})
+	}
+
+	for _, change := range single {
+		err := d.apply(ctx, change)
+		if err == nil {
+			metrics.IncSearchIndexed(batch.namespace, batch.db, batch.collection, 1)
+			continue
+		}
+		if search.IsRetryable(err) {
+			return err
+		}
+
+		metrics.IncSearchIndexingSkipped(batch.namespace, batch.db, batch.collection, 1)
+		log.Error().Err(err).Str("db", batch.db).Str("collection", batch.collection).Msg("skipping change rejected by the search store")
+	}
+
+	return nil
+}
+
+func (d *searchQueueDrainer) apply(ctx context.Context, batch *searchIndexBatch) error {
+	if batch.delete {
+		for _, id := range batch.ids {
+			if err := d.searchStore.DeleteDocuments(ctx, batch.searchCollection, id); err != nil && err != search.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// the documents are the full documents even for the updates, so they are all upserted
+	return d.searchStore.IndexDocuments(ctx, batch.searchCollection, bytes.NewReader(bytes.Join(batch.docs, []byte("\n"))), search.IndexDocumentsOptions{
+		Action:    searchUpsert,
+		BatchSize: len(batch.docs),
+	})
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/store/search"
+)
+
+type testIndexingStore struct {
+	search.NoopStore
+
+	// failures are returned by the requests to the store in order, nil once they run out
+	failures []error
+	indexed  []string
+	deleted  []string
+}
+
+func (s *testIndexingStore) next() error {
+	if len(s.failures) == 0 {
+		return nil
+	}
+	err := s.failures[0]
+	s.failures = s.failures[1:]
+	return err
+}
+
+func (s *testIndexingStore) IndexDocuments(_ context.Context, _ string, documents io.Reader, _ search.IndexDocumentsOptions) error {
+	if err := s.next(); err != nil {
+		return err
+	}
+
+	docs, err := io.ReadAll(documents)
+	if err != nil {
+		return err
+	}
+	s.indexed = append(s.indexed, string(docs))
+	return nil
+}
+
+func (s *testIndexingStore) DeleteDocuments(_ context.Context, _ string, key string) error {
+	if err := s.next(); err != nil {
+		return err
+	}
+
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+type testTableDecoder struct {
+	metadata.Encoder
+}
+
+func (d *testTableDecoder) DecodeTableName(table []byte) (string, string, string, bool) {
+	if string(table) == "internal" {
+		return "", "", "", false
+	}
+	return "ns1", "db1", string(table), true
+}
+
+func TestSearchQueuePartition(t *testing.T) {
+	for _, table := range []string{"t1", "t2", "t3"} {
+		p := searchQueuePartition([]byte(table), 16)
+		require.True(t, p >= 0 && p < 16)
+		require.Equal(t, p, searchQueuePartition([]byte(table), 16))
+	}
+	require.Equal(t, 0, searchQueuePartition([]byte("t1"), 1))
+}
+
+func TestAppendIndexBatch(t *testing.T) {
+	upsert := func(coll string, doc string) *searchIndexBatch {
+		return &searchIndexBatch{searchCollection: coll, docs: [][]byte{[]byte(doc)}}
+	}
+	del := func(coll string, id string) *searchIndexBatch {
+		return &searchIndexBatch{searchCollection: coll, delete: true, ids: []string{id}}
+	}
+
+	var batches []*searchIndexBatch
+	for _, change := range []*searchIndexBatch{
+		upsert("c1", "1"), upsert("c1", "2"), del("c1", "1"), del("c1", "2"), upsert("c2", "1"), upsert("c1", "1"),
+	} {
+		batches = appendIndexBatch(batches, change)
+	}
+
+	require.Len(t, batches, 4)
+	require.Equal(t, [][]byte{[]byte("1"), []byte("2")}, batches[0].docs)
+	require.Equal(t, []string{"1", "2"}, batches[1].ids)
+	require.Equal(t, "c2", batches[2].searchCollection)
+	require.Equal(t, 1, batches[3].size())
+}
+
+func TestSearchIndexerQueueEntries(t *testing.T) {
+	indexer := &SearchIndexer{encoder: &testTableDecoder{}}
+	tenant := metadata.NewTenant(metadata.NewTenantNamespace("ns1", 1), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	entries := indexer.queueEntries(tenant, "tx1", []*kv.Event{
+		{Op: kv.InsertEvent, Table: []byte("c1"), Key: []byte("k1"), Data: []byte("d1")},
+		{Op: kv.InsertEvent, Table: []byte("c2"), Key: []byte("k1"), Data: []byte("d1")},
+		{Op: kv.InsertEvent, Table: []byte("internal"), Key: []byte("k1"), Data: []byte("d1")},
+		{Op: kv.DeleteRangeEvent, Table: []byte("c1")},
+		{Op: kv.DeleteEvent, Table: []byte("c1"), Key: []byte("k1"), OldData: []byte("d1")},
+	})
+
+	// the document changed twice is queued once, only the key of the document is queued
+	require.Len(t, entries, 2)
+	require.Equal(t, "tx1", entries[0].TxId)
+	require.Equal(t, "ns1", entries[0].Namespace)
+	require.Equal(t, "db1", entries[0].Db)
+	require.Equal(t, "c1", entries[0].Collection)
+	require.Equal(t, []byte("c1"), entries[0].Table)
+	require.Equal(t, []byte("k1"), entries[0].Key)
+	require.Equal(t, "c2", entries[1].Collection)
+}
+
+func TestNewSearchIndexChange(t *testing.T) {
+	factory, err := schema.Build("c1", []byte(`{
+	"title": "c1",
+	"properties": {
+		"id": { "type": "integer" },
+		"name": { "type": "string" }
+	},
+	"primary_key": ["id"]
+}`))
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("c1", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "c1")
+
+	table := []byte("t1")
+	entry := &encoding.SearchQueueEntry{
+		Namespace:  "ns1",
+		Db:         "db1",
+		Collection: "c1",
+		Table:      table,
+		Key:        subspace.FromBytes(table).Pack(tuple.Tuple{"pkey", int64(1)}),
+	}
+
+	t.Run("upsert", func(t *testing.T) {
+		data, err := internal.Encode(internal.NewTableDataWithTS(internal.NewTimestamp(), nil, []byte(`{"id":1,"name":"latest"}`)))
+		require.NoError(t, err)
+
+		change, err := newSearchIndexChange(entry, coll, data)
+		require.NoError(t, err)
+		require.False(t, change.delete)
+		require.Equal(t, "c1", change.collection)
+		require.Len(t, change.docs, 1)
+
+		var doc map[string]any
+		require.NoError(t, jsoniter.Unmarshal(change.docs[0], &doc))
+		require.Equal(t, "1", doc[searchID])
+		require.Equal(t, "latest", doc["name"])
+	})
+	t.Run("deleted", func(t *testing.T) {
+		// the document is not in the collection anymore
+		change, err := newSearchIndexChange(entry, coll, nil)
+		require.NoError(t, err)
+		require.True(t, change.delete)
+		require.Equal(t, []string{"1"}, change.ids)
+	})
+}
+
+func TestSearchQueueDrainerIndex(t *testing.T) {
+	cfg := config.DefaultConfig.Search.Indexing
+	cfg.MaxAttempts = 2
+	cfg.MinBackoff = time.Millisecond
+	cfg.MaxBackoff = 2 * time.Millisecond
+
+	unavailable := search.NewSearchError(http.StatusServiceUnavailable, search.ErrCodeUnhandled, "unavailable")
+	rejected := search.NewSearchError(http.StatusBadRequest, search.ErrCodeIndexingDocuments, "rejected")
+
+	batch := func() *searchIndexBatch {
+		return &searchIndexBatch{searchCollection: "c1", docs: [][]byte{[]byte(`{"id":"1"}`), []byte(`{"id":"2"}`)}}
+	}
+
+	t.Run("retried_until_available", func(t *testing.T) {
+		store := &testIndexingStore{failures: []error{unavailable, unavailable, unavailable}}
+		d := &searchQueueDrainer{cfg: cfg, searchStore: store}
+
+		require.NoError(t, d.index(context.Background(), batch()))
+		require.Equal(t, []string{"{\"id\":\"1\"}\n{\"id\":\"2\"}"}, store.indexed)
+	})
+
+	t.Run("rejected_documents_skipped", func(t *testing.T) {
+		store := &testIndexingStore{failures: []error{rejected, rejected, rejected}}
+		d := &searchQueueDrainer{cfg: cfg, searchStore: store}
+
+		require.NoError(t, d.index(context.Background(), batch()))
+		require.Equal(t, []string{`{"id":"2"}`}, store.indexed)
+	})
+
+	t.Run("unavailable_while_skipping", func(t *testing.T) {
+		store := &testIndexingStore{failures: []error{rejected, rejected, unavailable}}
+		d := &searchQueueDrainer{cfg: cfg, searchStore: store}
+
+		require.Equal(t, unavailable, d.index(context.Background(), batch()))
+	})
+
+	t.Run("deleted_documents_not_found", func(t *testing.T) {
+		store := &testIndexingStore{failures: []error{search.ErrNotFound}}
+		d := &searchQueueDrainer{cfg: cfg, searchStore: store}
+
+		require.NoError(t, d.index(context.Background(), &searchIndexBatch{searchCollection: "c1", delete: true, ids: []string{"1", "2"}}))
+		require.Equal(t, []string{"2"}, store.deleted)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		store := &testIndexingStore{failures: []error{unavailable, unavailable, unavailable}}
+		d := &searchQueueDrainer{cfg: cfg, searchStore: store}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Equal(t, context.Canceled, d.index(ctx, batch()))
+	})
+}
@@ -16,6 +16,7 @@ package v1
 
 import (
 	"context"
+	"math"
 
 	jsoniter "github.com/json-iterator/go"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -93,6 +94,8 @@ func (p *page) readRow(row *Row) bool {
 		if row.Data.RawData, p.err = jsoniter.Marshal(doc); p.err != nil {
 			return false
 		}
+		row.Highlights = p.hits.GetHighlights(p.idx - 1)
+		row.Score = p.hits.GetScore(p.idx - 1)
 
 		return true
 	}
@@ -183,10 +186,13 @@ func (p *pageReader) buildFacets(facets *[]tsApi.FacetCounts) {
 	if facets == nil {
 		return
 	}
-	// count of values to include in response
+	// count of values to include in response, all the buckets are included for the range and interval facets
 	facetSizeRequested := map[string]int{}
 	for _, f := range p.query.Facets.Fields {
 		facetSizeRequested[f.Name] = f.Size
+		if f.IsBucketed() {
+			facetSizeRequested[f.Name] = math.MaxInt
+		}
 	}
 
 	for _, f := range *facets {
@@ -234,6 +240,14 @@ func (p *pageReader) buildStats(stats tsApi.FacetCounts) *api.FacetStats {
 	return stat
 }
 
+// searchReader is the RowReader used by the search API, on top of rows it also returns the facets and the total
+// number of hits.
+type searchReader interface {
+	RowReader
+	getFacets() map[string]*api.SearchFacet
+	getTotalFound() int64
+}
+
 // SearchRowReader is responsible for iterating on the search results. It uses pageReader internally to read page
 // and then iterate on documents inside hits.
 type SearchRowReader struct {
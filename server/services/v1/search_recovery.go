@@ -0,0 +1,185 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/store/search"
+	ulog "github.com/tigrisdata/tigris/util/log"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+// searchTarget is a collection along with what is needed to recreate its search collection.
+type searchTarget struct {
+	namespace  string
+	db         string
+	collection *schema.DefaultCollection
+	synonyms   []*encoding.Synonym
+}
+
+// searchRecovery recreates the search collections that are missing from the search store, which is the case for all
+// of them after the embedded store is restarted, as it keeps them in memory, or after the search store lost its data.
+// It also adds the reserved fields to the search collections created before these fields were indexed.
+type searchRecovery struct {
+	tenantMgr   *metadata.TenantManager
+	searchStore search.Store
+	reindexer   *searchReindexer
+}
+
+func newSearchRecovery(u *apiService) *searchRecovery {
+	return &searchRecovery{
+		tenantMgr:   u.tenantMgr,
+		searchStore: u.searchStore,
+		reindexer:   newSearchReindexer(u.kvStore, u.searchStore, u.tenantMgr, u.txMgr),
+	}
+}
+
+// start creates an empty search collection for every collection that has none, so that the writes and the searches
+// don't fail, and then backfills them from the rows stored in FDB in the background.
+func (r *searchRecovery) start() {
+	targets := r.targets()
+	addReservedSearchFields(context.Background(), r.searchStore, targets)
+
+	missing := createMissingSearchCollections(context.Background(), r.searchStore, targets)
+	if len(missing) == 0 {
+		return
+	}
+
+	log.Info().Int("collections", len(missing)).Msg("backfilling the recreated search collections")
+	go r.backfill(context.Background(), missing)
+}
+
+// targets returns the collections of all the tenants loaded in the cache.
+func (r *searchRecovery) targets() []*searchTarget {
+	var targets []*searchTarget
+	for _, tenant := range r.tenantMgr.ListTenants() {
+		for _, dbName := range tenant.ListDatabases(context.TODO(), nil) {
+			db, err := tenant.GetDatabase(context.TODO(), nil, dbName)
+			if ulog.E(err) || db == nil {
+				continue
+			}
+
+			for _, coll := range db.ListCollection() {
+				targets = append(targets, &searchTarget{
+					namespace:  tenant.GetNamespace().Name(),
+					db:         dbName,
+					collection: coll,
+					synonyms:   db.GetSynonyms(coll.Name),
+				})
+			}
+		}
+	}
+
+	return targets
+}
+
+// backfill reindexes the recreated search collections one after the other, a collection that fails is left to be
+// reindexed through the admin API.
+func (r *searchRecovery) backfill(ctx context.Context, missing []*searchTarget) {
+	for _, t := range missing {
+		err := r.reindexer.Reindex(ctx, t.namespace, t.db, t.collection.Name, func(*api.ReindexCollectionResponse) error {
+			return nil
+		})
+		ulog.E(err)
+	}
+}
+
+// createMissingSearchCollections creates the search collections of the targets that don't have one, behind an alias
+// like the collections that are created through the API. It returns the targets whose search collection was created.
+func createMissingSearchCollections(ctx context.Context, searchStore search.Store, targets []*searchTarget) []*searchTarget {
+	var missing []*searchTarget
+	for _, t := range targets {
+		name := t.collection.SearchCollectionName()
+		_, err := searchStore.DescribeCollection(ctx, name)
+		if err == nil {
+			continue
+		}
+		if err != search.ErrNotFound {
+			log.Error().Err(err).Str("search_collection", name).Msg("describing search collection failed")
+			continue
+		}
+
+		if err = createSearchCollection(ctx, searchStore, t); err != nil {
+			log.Error().Err(err).Str("search_collection", name).Msg("recreating search collection failed")
+			continue
+		}
+		missing = append(missing, t)
+	}
+
+	return missing
+}
+
+// addReservedSearchFields adds the reserved fields of the search schema of the targets that their search collection
+// doesn't have, like the creation time that orders the hits of a search cursor. The search store indexes them from the
+// documents it already has, as they were always part of the documents.
+func addReservedSearchFields(ctx context.Context, searchStore search.Store, targets []*searchTarget) {
+	for _, t := range targets {
+		name := t.collection.SearchCollectionName()
+		existing, err := searchStore.DescribeCollection(ctx, name)
+		if err != nil {
+			if err != search.ErrNotFound {
+				log.Error().Err(err).Str("search_collection", name).Msg("describing search collection failed")
+			}
+			continue
+		}
+
+		var fields []tsApi.Field
+		for _, f := range t.collection.Search.Fields {
+			if schema.IsReservedField(f.Name) && !hasSearchField(existing.Fields, f.Name) {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err = searchStore.UpdateCollection(ctx, name, &tsApi.CollectionUpdateSchema{Fields: fields}); err != nil {
+			log.Error().Err(err).Str("search_collection", name).Msg("adding reserved search fields failed")
+		}
+	}
+}
+
+func hasSearchField(fields []tsApi.Field, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func createSearchCollection(ctx context.Context, searchStore search.Store, t *searchTarget) error {
+	searchColl := *t.collection.Search
+	searchColl.Name = fmt.Sprintf("%s-r%d", t.collection.SearchCollectionName(), time.Now().UnixNano())
+	if err := searchStore.CreateCollection(ctx, &searchColl); err != nil {
+		return err
+	}
+
+	for _, synonym := range t.synonyms {
+		if err := searchStore.UpsertSynonym(ctx, searchColl.Name, synonym.Id, metadata.ToSearchSynonym(synonym)); err != nil {
+			return err
+		}
+	}
+
+	return searchStore.SwapAlias(ctx, t.collection.SearchCollectionName(), searchColl.Name)
+}
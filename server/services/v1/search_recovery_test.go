@@ -0,0 +1,78 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/store/search"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+func testSearchTarget(name string) *searchTarget {
+	return &searchTarget{
+		namespace: "ns1",
+		db:        "db1",
+		collection: &schema.DefaultCollection{
+			Name: name,
+			Search: &tsApi.CollectionSchema{
+				Name:   "ns1-db1-" + name,
+				Fields: []tsApi.Field{{Name: "id", Type: "string"}},
+			},
+		},
+		synonyms: []*encoding.Synonym{{Collection: name, Id: "s1", Synonyms: []string{"a", "b"}}},
+	}
+}
+
+func TestCreateMissingSearchCollections(t *testing.T) {
+	ctx := context.Background()
+	store := search.NewEmbeddedStore()
+
+	existing := testSearchTarget("c1")
+	require.NoError(t, store.CreateCollection(ctx, existing.collection.Search))
+
+	missing := createMissingSearchCollections(ctx, store, []*searchTarget{existing, testSearchTarget("c2")})
+	require.Len(t, missing, 1)
+	require.Equal(t, "c2", missing[0].collection.Name)
+
+	// the recreated collection is reachable through its alias, so the writes and the searches don't fail
+	coll, err := store.DescribeCollection(ctx, "ns1-db1-c2")
+	require.NoError(t, err)
+	require.Contains(t, coll.Name, "ns1-db1-c2-r")
+	require.Equal(t, existing.collection.Search.Fields, coll.Fields)
+
+	// nothing is missing anymore once it is recreated
+	require.Empty(t, createMissingSearchCollections(ctx, store, []*searchTarget{existing, testSearchTarget("c2")}))
+}
+
+func TestAddReservedSearchFields(t *testing.T) {
+	ctx := context.Background()
+	store := search.NewEmbeddedStore()
+
+	createdAt := tsApi.Field{Name: schema.ReservedFields[schema.CreatedAt], Type: "int64"}
+	target := testSearchTarget("c1")
+	require.NoError(t, store.CreateCollection(ctx, target.collection.Search))
+	target.collection.Search.Fields = append(target.collection.Search.Fields, createdAt, tsApi.Field{Name: "price", Type: "int64"})
+
+	// only the reserved fields are added, the other fields are left to the schema updates and the reindexing
+	addReservedSearchFields(ctx, store, []*searchTarget{target, testSearchTarget("c2")})
+	coll, err := store.DescribeCollection(ctx, "ns1-db1-c1")
+	require.NoError(t, err)
+	require.Equal(t, []tsApi.Field{{Name: "id", Type: "string"}, createdAt}, coll.Fields)
+}
@@ -0,0 +1,449 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/store/search"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	reindexPhaseIndexing   = "indexing"
+	reindexPhaseCatchingUp = "catching_up"
+	reindexPhaseSwapped    = "swapped"
+	reindexPhaseDone       = "done"
+
+	// reindexMaxCatchUpPasses bounds the passes made before the swap on a collection that is written faster than it
+	// is scanned, the pass made after the swap catches up with whatever is left.
+	reindexMaxCatchUpPasses = 5
+	// reindexTimestampMargin is subtracted from the start of the previous pass when looking for the rows written since.
+	// The timestamp of a row is taken when the write is received, so a row committed during the previous pass can be
+	// older than its start by the length of a transaction and the clock difference between the servers.
+	reindexTimestampMargin = 10 * time.Second
+)
+
+// rowScanner reads all the rows of a collection, a batch at a time.
+type rowScanner interface {
+	scan(ctx context.Context, fn func(rows []kv.KeyValue) error) error
+}
+
+// collectionScanner reads the rows of a collection in the primary key order. Each batch is read in its own
+// transaction, so the scan isn't a snapshot of the collection but it isn't limited by the transaction duration either.
+type collectionScanner struct {
+	kvStore   kv.KeyValueStore
+	table     []byte
+	pk        *schema.Index
+	encoder   metadata.Encoder
+	batchSize int
+}
+
+func (s *collectionScanner) scan(ctx context.Context, fn func(rows []kv.KeyValue) error) error {
+	lKey := kv.BuildKey(s.encoder.EncodeIndexName(s.pk))
+	rKey := kv.BuildKey(encoding.UInt32ToByte(s.pk.Id + 1))
+
+	var last []byte
+	for {
+		rows, err := s.read(ctx, lKey, rKey, last)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err = fn(rows); err != nil {
+			return err
+		}
+		if len(rows) < s.batchSize {
+			return nil
+		}
+
+		// the next batch starts from the last row read, which is then skipped
+		lKey = rows[len(rows)-1].Key
+		last = rows[len(rows)-1].FDBKey
+	}
+}
+
+func (s *collectionScanner) read(ctx context.Context, lKey kv.Key, rKey kv.Key, skip []byte) ([]kv.KeyValue, error) {
+	tx, err := s.kvStore.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	it, err := tx.ReadRange(ctx, s.table, lKey, rKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []kv.KeyValue
+	for len(rows) < s.batchSize {
+		var row kv.KeyValue
+		if !it.Next(&row) {
+			break
+		}
+		if skip != nil && bytes.Equal(row.FDBKey, skip) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, it.Err()
+}
+
+// searchReindexer rebuilds the search index of a collection from the rows stored in FDB.
+type searchReindexer struct {
+	kvStore     kv.KeyValueStore
+	txMgr       *transaction.Manager
+	tenantMgr   *metadata.TenantManager
+	encoder     metadata.Encoder
+	searchStore search.Store
+	batchSize   int
+}
+
+func newSearchReindexer(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) *searchReindexer {
+	return &searchReindexer{
+		kvStore:     kvStore,
+		txMgr:       txMgr,
+		tenantMgr:   tenantMgr,
+		encoder:     metadata.NewEncoder(tenantMgr),
+		searchStore: searchStore,
+		batchSize:   config.DefaultConfig.Search.Indexing.ReindexBatchSize,
+	}
+}
+
+// Reindex indexes all the rows of the collection in a new search collection, which then replaces the search collection
+// that is used by the searches. The collection stays writable, the rows written while it is reindexed are indexed by
+// scanning the collection again for the rows written since the previous scan. The progress is passed to report after
+// every batch.
+func (r *searchReindexer) Reindex(ctx context.Context, namespace string, dbName string, collName string, report func(*api.ReindexCollectionResponse) error) error {
+	tenant, db, coll, err := resolveCollection(ctx, r.tenantMgr, r.txMgr, namespace, dbName, collName)
+	if err != nil {
+		return err
+	}
+
+	table, err := r.encoder.EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		return err
+	}
+
+	job := &reindexJob{
+		searchStore: r.searchStore,
+		scanner: &collectionScanner{
+			kvStore:   r.kvStore,
+			table:     table,
+			pk:        coll.Indexes.PrimaryKey,
+			encoder:   r.encoder,
+			batchSize: r.batchSize,
+		},
+		table:      table,
+		collection: coll.WithSearchIndex(coll.SearchCollectionName(), schema.SearchIndexVersion),
+		alias:      coll.SearchCollectionName(),
+		shadow:     fmt.Sprintf("%s-r%d", coll.SearchCollectionName(), time.Now().UnixNano()),
+		synonyms:   db.GetSynonyms(collName),
+		batchSize:  r.batchSize,
+		report:     report,
+		ids:        make(map[string]struct{}),
+		beforeSwap: func(ctx context.Context) error {
+			// the shadow collection has the search schema of the collection when the reindex started
+			_, _, current, err := resolveCollection(ctx, r.tenantMgr, r.txMgr, namespace, dbName, collName)
+			if err != nil {
+				return err
+			}
+			if current.Id != coll.Id || current.SchVer != coll.SchVer {
+				return api.Errorf(api.Code_ABORTED, "collection '%s' was changed while it was reindexed", collName)
+			}
+			return nil
+		},
+	}
+
+	if coll.SearchIndexVersion == schema.SearchIndexV0 {
+		// the search collection was created before the aliases and has the name the alias would have, so the new search
+		// collection gets an alias with another name, which the collection switches to once the alias is created
+		job.legacy = coll.SearchCollectionName()
+		job.alias = legacySearchAlias(coll.SearchCollectionName())
+	}
+	if job.alias != coll.SearchCollectionName() || coll.SearchIndexVersion != schema.SearchIndexVersion {
+		job.afterSwap = func(ctx context.Context) error {
+			return r.putSearchIndex(ctx, tenant, dbName, &encoding.SearchIndex{
+				Collection: collName,
+				Alias:      job.alias,
+				Version:    schema.SearchIndexVersion,
+			})
+		}
+	}
+
+	log.Info().Str("ns", namespace).Str("db", dbName).Str("collection", collName).Str("search_collection", job.shadow).Msg("reindexing collection")
+	if err = job.run(ctx); err != nil {
+		log.Error().Err(err).Str("ns", namespace).Str("db", dbName).Str("collection", collName).Msg("reindexing collection failed")
+		return err
+	}
+	log.Info().Str("ns", namespace).Str("db", dbName).Str("collection", collName).Int64("indexed", job.indexed).Int64("deleted", job.deleted).Msg("reindexed collection")
+
+	return nil
+}
+
+// putSearchIndex records the search collection of the collection in its own transaction, as the metadata version can't
+// be bumped by a transaction that read it.
+func (r *searchReindexer) putSearchIndex(ctx context.Context, tenant *metadata.Tenant, dbName string, index *encoding.SearchIndex) error {
+	tx, err := r.txMgr.StartTx(ctx)
+	if err != nil {
+		return err
+	}
+	if err = tenant.PutSearchIndex(ctx, tx, dbName, index); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// legacySearchAlias returns the alias of a collection whose search collection was created before the aliases.
+func legacySearchAlias(searchCollection string) string {
+	return searchCollection + "-alias"
+}
+
+// resolveCollection returns the collection of the namespace, the schemas of the namespace are reloaded first if they
+// changed since they were loaded.
+func resolveCollection(ctx context.Context, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, namespace string, dbName string, collName string) (*metadata.Tenant, *metadata.Database, *schema.DefaultCollection, error) {
+	tenant, err := tenantMgr.GetTenant(ctx, namespace, txMgr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if tenant == nil {
+		return nil, nil, nil, api.Errorf(api.Code_NOT_FOUND, "namespace doesn't exist '%s'", namespace)
+	}
+
+	tx, err := txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = tenant.ReloadUsingTxVersion(ctx, tx, tx.GetTxCtx().GetId()); err != nil {
+		return nil, nil, nil, err
+	}
+
+	db, err := tenant.GetDatabase(ctx, tx, dbName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if db == nil {
+		return nil, nil, nil, api.Errorf(api.Code_NOT_FOUND, "database doesn't exist '%s'", dbName)
+	}
+
+	coll := db.GetCollection(collName)
+	if coll == nil {
+		return nil, nil, nil, api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist '%s'", collName)
+	}
+
+	return tenant, db, coll, nil
+}
+
+// reindexJob is a single reindex of a collection. It keeps the ids of all the indexed rows to find the rows deleted
+// between the scans.
+type reindexJob struct {
+	searchStore search.Store
+	scanner     rowScanner
+	table       []byte
+	collection  *schema.DefaultCollection
+	alias       string
+	shadow      string
+	synonyms    []*encoding.Synonym
+	batchSize   int
+	report      func(*api.ReindexCollectionResponse) error
+	beforeSwap  func(ctx context.Context) error
+	// afterSwap, if set, switches the collection to the alias once it points to the new search collection.
+	afterSwap func(ctx context.Context) error
+	// legacy is the search collection created before the aliases, it is dropped once the collection switched to the
+	// alias.
+	legacy string
+
+	ids     map[string]struct{}
+	indexed int64
+	deleted int64
+}
+
+func (j *reindexJob) run(ctx context.Context) (err error) {
+	shadow := *j.collection.Search
+	shadow.Name = j.shadow
+	if err = j.searchStore.CreateCollection(ctx, &shadow); err != nil {
+		return err
+	}
+
+	swapped := false
+	defer func() {
+		if !swapped {
+			// the request may have been canceled, which is why the context of the request is not used
+			ulog.E(j.searchStore.DropCollection(context.Background(), j.shadow))
+		}
+	}()
+
+	// the synonyms are kept by the search collection, so they are set on the new one before it replaces the previous
+	for _, synonym := range j.synonyms {
+		if err = j.searchStore.UpsertSynonym(ctx, j.shadow, synonym.Id, metadata.ToSearchSynonym(synonym)); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	if _, err = j.pass(ctx, reindexPhaseIndexing, time.Time{}); err != nil {
+		return err
+	}
+
+	for i := 0; i < reindexMaxCatchUpPasses; i++ {
+		since := start.Add(-reindexTimestampMargin)
+		start = time.Now()
+
+		changed, err := j.pass(ctx, reindexPhaseCatchingUp, since)
+		if err != nil {
+			return err
+		}
+		if changed < int64(j.batchSize) {
+			break
+		}
+	}
+
+	if err = j.beforeSwap(ctx); err != nil {
+		return err
+	}
+	if err = j.searchStore.SwapAlias(ctx, j.alias, j.shadow); err != nil {
+		return err
+	}
+	swapped = true
+	if j.afterSwap != nil {
+		if err = j.afterSwap(ctx); err != nil {
+			return err
+		}
+	}
+	if err = j.progress(reindexPhaseSwapped); err != nil {
+		return err
+	}
+
+	// the rows written to the previous search collection during the last pass
+	if _, err = j.pass(ctx, reindexPhaseCatchingUp, start.Add(-reindexTimestampMargin)); err != nil {
+		return err
+	}
+
+	if len(j.legacy) > 0 {
+		if err = j.searchStore.DropCollection(ctx, j.legacy); err != nil && err != search.ErrNotFound {
+			return err
+		}
+	}
+
+	return j.progress(reindexPhaseDone)
+}
+
+// pass scans the collection and indexes the rows that are not indexed yet or are written after since, then deletes the
+// documents of the rows that are not found anymore. It returns the number of the documents changed.
+func (j *reindexJob) pass(ctx context.Context, phase string, since time.Time) (int64, error) {
+	var changed int64
+	seen := make(map[string]struct{}, len(j.ids))
+	err := j.scanner.scan(ctx, func(rows []kv.KeyValue) error {
+		var docs [][]byte
+		for _, row := range rows {
+			id, err := CreateSearchKey(j.table, row.FDBKey)
+			if err != nil {
+				return err
+			}
+			seen[id] = struct{}{}
+
+			if _, ok := j.ids[id]; ok && !writtenSince(row.Data, since) {
+				continue
+			}
+
+			doc, err := PackSearchFields(row.Data, j.collection, id)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+			j.ids[id] = struct{}{}
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		if err := j.searchStore.IndexDocuments(ctx, j.shadow, bytes.NewReader(bytes.Join(docs, []byte("\n"))), search.IndexDocumentsOptions{
+			Action:    searchUpsert,
+			BatchSize: len(docs),
+		}); err != nil {
+			return err
+		}
+		j.indexed += int64(len(docs))
+		changed += int64(len(docs))
+
+		return j.progress(phase)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for id := range j.ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		if err = j.searchStore.DeleteDocuments(ctx, j.shadow, id); err != nil && err != search.ErrNotFound {
+			return 0, err
+		}
+		delete(j.ids, id)
+		deleted++
+	}
+	if deleted > 0 {
+		j.deleted += deleted
+		changed += deleted
+		if err = j.progress(phase); err != nil {
+			return 0, err
+		}
+	}
+
+	return changed, nil
+}
+
+func (j *reindexJob) progress(phase string) error {
+	return j.report(&api.ReindexCollectionResponse{
+		Phase:            phase,
+		SearchCollection: j.shadow,
+		Indexed:          j.indexed,
+		Deleted:          j.deleted,
+	})
+}
+
+// writtenSince returns true if the row is created or updated at or after since, the rows without a timestamp are
+// always considered written.
+func writtenSince(data *internal.TableData, since time.Time) bool {
+	ts := data.CreatedAt
+	if data.UpdatedAt != nil {
+		ts = data.UpdatedAt
+	}
+	if ts == nil {
+		return true
+	}
+
+	return !time.Unix(0, ts.UnixNano()).Before(since)
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/store/kv"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+type testReindexStore struct {
+	testIndexingStore
+
+	created  []string
+	dropped  []string
+	swapped  []string
+	synonyms []string
+}
+
+func (s *testReindexStore) UpsertSynonym(_ context.Context, table string, id string, _ *tsApi.SearchSynonymSchema) error {
+	s.synonyms = append(s.synonyms, table+":"+id)
+	return nil
+}
+
+func (s *testReindexStore) CreateCollection(_ context.Context, schema *tsApi.CollectionSchema) error {
+	s.created = append(s.created, schema.Name)
+	return nil
+}
+
+func (s *testReindexStore) DropCollection(_ context.Context, table string) error {
+	s.dropped = append(s.dropped, table)
+	return nil
+}
+
+func (s *testReindexStore) SwapAlias(_ context.Context, alias string, collection string) error {
+	s.swapped = append(s.swapped, alias+"->"+collection)
+	return nil
+}
+
+// testRowScanner returns the rows in batches, beforeScan can change the rows before each scan.
+type testRowScanner struct {
+	rows       []kv.KeyValue
+	batchSize  int
+	scans      int
+	beforeScan func(scan int, s *testRowScanner)
+}
+
+func (s *testRowScanner) scan(_ context.Context, fn func(rows []kv.KeyValue) error) error {
+	if s.beforeScan != nil {
+		s.beforeScan(s.scans, s)
+	}
+	s.scans++
+
+	for i := 0; i < len(s.rows); i += s.batchSize {
+		end := i + s.batchSize
+		if end > len(s.rows) {
+			end = len(s.rows)
+		}
+		if err := fn(s.rows[i:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testReindexRow(table []byte, id string, written time.Time) kv.KeyValue {
+	ts := internal.CreateNewTimestamp(written.UnixNano())
+	return kv.KeyValue{
+		FDBKey: subspace.FromBytes(table).Pack(tuple.Tuple{[]byte{0, 0, 0, 1}, id}),
+		Data:   internal.NewTableDataWithTS(ts, nil, []byte(fmt.Sprintf(`{"id":"%s"}`, id))),
+	}
+}
+
+func testReindexJob(store *testReindexStore, scanner *testRowScanner, phases *[]string) *reindexJob {
+	return &reindexJob{
+		searchStore: store,
+		scanner:     scanner,
+		table:       []byte("t1"),
+		collection: &schema.DefaultCollection{
+			Name:   "c1",
+			Search: &tsApi.CollectionSchema{Name: "ns1-db1-c1"},
+		},
+		alias:     "ns1-db1-c1",
+		shadow:    "ns1-db1-c1-r1",
+		batchSize: scanner.batchSize,
+		report: func(resp *api.ReindexCollectionResponse) error {
+			*phases = append(*phases, fmt.Sprintf("%s:%d:%d", resp.GetPhase(), resp.GetIndexed(), resp.GetDeleted()))
+			return nil
+		},
+		ids:        make(map[string]struct{}),
+		beforeSwap: func(context.Context) error { return nil },
+	}
+}
+
+func TestReindexJob(t *testing.T) {
+	table := []byte("t1")
+	old := time.Now().Add(-time.Hour)
+
+	t.Run("indexes all the rows and swaps the alias", func(t *testing.T) {
+		store := &testReindexStore{}
+		scanner := &testRowScanner{
+			rows:      []kv.KeyValue{testReindexRow(table, "1", old), testReindexRow(table, "2", old), testReindexRow(table, "3", old)},
+			batchSize: 2,
+		}
+		var phases []string
+		job := testReindexJob(store, scanner, &phases)
+
+		require.NoError(t, job.run(context.Background()))
+		require.Equal(t, []string{"ns1-db1-c1-r1"}, store.created)
+		require.Equal(t, []string{"ns1-db1-c1->ns1-db1-c1-r1"}, store.swapped)
+		require.Empty(t, store.dropped)
+		require.Len(t, store.indexed, 2)
+		require.Contains(t, store.indexed[0], `"id":"1"`)
+		require.Contains(t, store.indexed[1], `"id":"3"`)
+		require.Equal(t, []string{"indexing:2:0", "indexing:3:0", "swapped:3:0", "done:3:0"}, phases)
+	})
+	t.Run("catches up with the rows written during the scan", func(t *testing.T) {
+		store := &testReindexStore{}
+		scanner := &testRowScanner{
+			rows:      []kv.KeyValue{testReindexRow(table, "1", old), testReindexRow(table, "2", old)},
+			batchSize: 10,
+			beforeScan: func(scan int, s *testRowScanner) {
+				if scan == 1 {
+					// "1" is updated, "2" is deleted and "3" is inserted after the first scan
+					s.rows = []kv.KeyValue{testReindexRow(table, "1", time.Now()), testReindexRow(table, "3", old)}
+				}
+			},
+		}
+		var phases []string
+		job := testReindexJob(store, scanner, &phases)
+
+		require.NoError(t, job.run(context.Background()))
+		require.Equal(t, []string{"2"}, store.deleted)
+		require.Equal(t, []string{"indexing:2:0", "catching_up:4:0", "catching_up:4:1", "swapped:4:1", "catching_up:5:1", "done:5:1"}, phases)
+		require.Len(t, job.ids, 2)
+	})
+	t.Run("sets the synonyms on the new search collection", func(t *testing.T) {
+		store := &testReindexStore{}
+		scanner := &testRowScanner{
+			rows:      []kv.KeyValue{testReindexRow(table, "1", old)},
+			batchSize: 10,
+		}
+		var phases []string
+		job := testReindexJob(store, scanner, &phases)
+		job.synonyms = []*encoding.Synonym{
+			{Id: "tee", Collection: "c1", Synonyms: []string{"tee", "t-shirt"}},
+		}
+
+		require.NoError(t, job.run(context.Background()))
+		require.Equal(t, []string{"ns1-db1-c1-r1:tee"}, store.synonyms)
+	})
+	t.Run("moves a legacy search collection to an alias", func(t *testing.T) {
+		store := &testReindexStore{}
+		scanner := &testRowScanner{
+			rows:      []kv.KeyValue{testReindexRow(table, "1", old)},
+			batchSize: 10,
+		}
+		var phases []string
+		job := testReindexJob(store, scanner, &phases)
+		job.legacy = "ns1-db1-c1"
+		job.alias = legacySearchAlias("ns1-db1-c1")
+
+		var switched []string
+		job.afterSwap = func(context.Context) error {
+			// the legacy search collection is only dropped once the collection is switched to the alias
+			switched = append(switched, store.swapped...)
+			require.Empty(t, store.dropped)
+			return nil
+		}
+
+		require.NoError(t, job.run(context.Background()))
+		require.Equal(t, []string{"ns1-db1-c1-alias->ns1-db1-c1-r1"}, switched)
+		require.Equal(t, []string{"ns1-db1-c1"}, store.dropped)
+		require.Equal(t, []string{"indexing:1:0", "swapped:1:0", "done:1:0"}, phases)
+	})
+	t.Run("keeps the legacy search collection if the switch fails", func(t *testing.T) {
+		store := &testReindexStore{}
+		scanner := &testRowScanner{
+			rows:      []kv.KeyValue{testReindexRow(table, "1", old)},
+			batchSize: 10,
+		}
+		var phases []string
+		job := testReindexJob(store, scanner, &phases)
+		job.legacy = "ns1-db1-c1"
+		job.alias = legacySearchAlias("ns1-db1-c1")
+		job.afterSwap = func(context.Context) error {
+			return api.Errorf(api.Code_ABORTED, "conflict")
+		}
+
+		require.Error(t, job.run(context.Background()))
+		require.Empty(t, store.dropped)
+	})
+	t.Run("drops the new search collection on failure", func(t *testing.T) {
+		store := &testReindexStore{}
+		scanner := &testRowScanner{
+			rows:      []kv.KeyValue{testReindexRow(table, "1", old)},
+			batchSize: 10,
+		}
+		var phases []string
+		job := testReindexJob(store, scanner, &phases)
+		job.beforeSwap = func(context.Context) error {
+			return api.Errorf(api.Code_ABORTED, "collection '%s' was changed while it was reindexed", "c1")
+		}
+
+		require.Equal(t, api.Errorf(api.Code_ABORTED, "collection '%s' was changed while it was reindexed", "c1"), job.run(context.Background()))
+		require.Empty(t, store.swapped)
+		require.Equal(t, []string{"ns1-db1-c1-r1"}, store.dropped)
+	})
+}
+
+func TestWrittenSince(t *testing.T) {
+	now := time.Now()
+	created := internal.CreateNewTimestamp(now.Add(-time.Minute).UnixNano())
+
+	require.True(t, writtenSince(internal.NewTableDataWithTS(created, nil, nil), time.Time{}))
+	require.False(t, writtenSince(internal.NewTableDataWithTS(created, nil, nil), now))
+	require.True(t, writtenSince(internal.NewTableDataWithTS(created, internal.CreateNewTimestamp(now.UnixNano()), nil), now))
+	require.True(t, writtenSince(&internal.TableData{}, now))
+}
@@ -14,7 +14,13 @@
 
 package v1
 
-import tsApi "github.com/typesense/typesense-go/typesense/api"
+import (
+	"fmt"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
 
 type HitsResponse struct {
 	Hits *[]tsApi.SearchResultHit
@@ -46,3 +52,67 @@ func (h *HitsResponse) GetDocument(idx int) (*map[string]interface{}, bool) {
 func (h *HitsResponse) HasMoreHits(idx int) bool {
 	return idx < len(*h.Hits)
 }
+
+// GetScore returns the text match score of the hit, it is zero when there is no text query.
+func (h *HitsResponse) GetScore(idx int) int64 {
+	if idx >= len(*h.Hits) || (*h.Hits)[idx].TextMatch == nil {
+		return 0
+	}
+	return *(*h.Hits)[idx].TextMatch
+}
+
+// GetHighlights returns the snippets of the fields that matched the query for the hit. The array fields have a
+// snippet per matching element.
+func (h *HitsResponse) GetHighlights(idx int) []*api.SearchHighlight {
+	if idx >= len(*h.Hits) || (*h.Hits)[idx].Highlights == nil {
+		return nil
+	}
+
+	var highlights []*api.SearchHighlight
+	for _, hl := range *(*h.Hits)[idx].Highlights {
+		if hl.Field == nil {
+			continue
+		}
+		field := *hl.Field
+		if field == schema.ReservedFields[schema.IdToSearchKey] {
+			field = "id"
+		}
+
+		var tokens []interface{}
+		if hl.MatchedTokens != nil {
+			tokens = *hl.MatchedTokens
+		}
+
+		if hl.Snippet != nil {
+			highlights = append(highlights, &api.SearchHighlight{Field: field, Snippet: *hl.Snippet, MatchedTokens: matchedTokens(tokens)})
+		}
+		if hl.Snippets != nil {
+			for i, snippet := range *hl.Snippets {
+				// the matched tokens of the array fields are nested per snippet
+				var snippetTokens []string
+				if i < len(tokens) {
+					snippetTokens = matchedTokens([]interface{}{tokens[i]})
+				}
+				highlights = append(highlights, &api.SearchHighlight{Field: field, Snippet: snippet, MatchedTokens: snippetTokens})
+			}
+		}
+	}
+
+	return highlights
+}
+
+// matchedTokens flattens the matched tokens of a snippet.
+func matchedTokens(tokens []interface{}) []string {
+	var flattened []string
+	for _, t := range tokens {
+		switch v := t.(type) {
+		case string:
+			flattened = append(flattened, v)
+		case []interface{}:
+			flattened = append(flattened, matchedTokens(v)...)
+		default:
+			flattened = append(flattened, fmt.Sprint(v))
+		}
+	}
+	return flattened
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+func TestHitsResponseHighlights(t *testing.T) {
+	name, id, tags := "name", schema.ReservedFields[schema.IdToSearchKey], "tags"
+	snippet, idSnippet := "<mark>running</mark> shoes", "<mark>r1</mark>"
+
+	hits := NewHits()
+	hits.Append(tsApi.SearchResultHit{
+		Highlights: &[]tsApi.SearchHighlight{
+			{Field: &name, Snippet: &snippet, MatchedTokens: &[]interface{}{"running"}},
+			{Field: &id, Snippet: &idSnippet, MatchedTokens: &[]interface{}{"r1"}},
+			{
+				Field:         &tags,
+				Snippets:      &[]string{"<mark>run</mark>", "<mark>running</mark> club"},
+				MatchedTokens: &[]interface{}{[]interface{}{"run"}, []interface{}{"running"}},
+			},
+		},
+	})
+	hits.Append(tsApi.SearchResultHit{})
+
+	require.Equal(t, []*api.SearchHighlight{
+		{Field: "name", Snippet: snippet, MatchedTokens: []string{"running"}},
+		{Field: "id", Snippet: idSnippet, MatchedTokens: []string{"r1"}},
+		{Field: "tags", Snippet: "<mark>run</mark>", MatchedTokens: []string{"run"}},
+		{Field: "tags", Snippet: "<mark>running</mark> club", MatchedTokens: []string{"running"}},
+	}, hits.GetHighlights(0))
+	require.Nil(t, hits.GetHighlights(1))
+	require.Nil(t, hits.GetHighlights(2))
+}
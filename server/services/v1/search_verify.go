@@ -0,0 +1,251 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/store/search"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+// verifyMaxReportedIds is the most ids returned for each kind of inconsistency, the counts are always complete.
+const verifyMaxReportedIds = 100
+
+// searchVerifier compares the rows of a collection with the documents of its search collection.
+type searchVerifier struct {
+	kvStore     kv.KeyValueStore
+	txMgr       *transaction.Manager
+	tenantMgr   *metadata.TenantManager
+	encoder     metadata.Encoder
+	searchStore search.Store
+	batchSize   int
+}
+
+func newSearchVerifier(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) *searchVerifier {
+	return &searchVerifier{
+		kvStore:     kvStore,
+		txMgr:       txMgr,
+		tenantMgr:   tenantMgr,
+		encoder:     metadata.NewEncoder(tenantMgr),
+		searchStore: searchStore,
+		batchSize:   config.DefaultConfig.Search.Indexing.ReindexBatchSize,
+	}
+}
+
+// Verify reports the rows of the collection that are missing in the search collection or whose documents are older
+// than the rows, and the documents that have no row anymore. With repair, the rows are indexed again and the orphaned
+// documents are deleted.
+func (v *searchVerifier) Verify(ctx context.Context, namespace string, dbName string, collName string, repair bool) (*api.VerifyCollectionResponse, error) {
+	tenant, db, coll, err := resolveCollection(ctx, v.tenantMgr, v.txMgr, namespace, dbName, collName)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := v.encoder.EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &verifyJob{
+		searchStore: v.searchStore,
+		scanner: &collectionScanner{
+			kvStore:   v.kvStore,
+			table:     table,
+			pk:        coll.Indexes.PrimaryKey,
+			encoder:   v.encoder,
+			batchSize: v.batchSize,
+		},
+		table:      table,
+		collection: coll,
+		repair:     repair,
+		resp:       &api.VerifyCollectionResponse{},
+	}
+	if err = job.run(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("ns", namespace).Str("db", dbName).Str("collection", collName).
+		Int64("checked", job.resp.Checked).
+		Int64("missing", job.resp.Missing).
+		Int64("stale", job.resp.Stale).
+		Int64("orphaned", job.resp.Orphaned).
+		Int64("repaired", job.resp.Repaired).
+		Msg("verified search collection")
+
+	return job.resp, nil
+}
+
+type verifyJob struct {
+	searchStore search.Store
+	scanner     rowScanner
+	table       []byte
+	collection  *schema.DefaultCollection
+	repair      bool
+	resp        *api.VerifyCollectionResponse
+}
+
+// searchDocumentTimestamps is the part of a search document that tells which version of the row it is.
+type searchDocumentTimestamps struct {
+	Id        string `json:"id"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// run loads the timestamps of all the documents of the search collection and then scans the collection. The rows and
+// the documents written since the verification started, give or take the time a write takes to be indexed, are skipped
+// as they can't be compared reliably.
+func (j *verifyJob) run(ctx context.Context) error {
+	since := time.Now().Add(-reindexTimestampMargin)
+
+	documents, err := j.documentTimestamps(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = j.scanner.scan(ctx, func(rows []kv.KeyValue) error {
+		var docs [][]byte
+		for _, row := range rows {
+			id, err := CreateSearchKey(j.table, row.FDBKey)
+			if err != nil {
+				return err
+			}
+
+			indexed, found := documents[id]
+			delete(documents, id)
+			if writtenSince(row.Data, since) {
+				j.resp.Skipped++
+				continue
+			}
+			j.resp.Checked++
+
+			switch {
+			case !found:
+				j.resp.Missing++
+				j.resp.MissingIds = appendReportedId(j.resp.MissingIds, id)
+			case indexed != rowTimestamp(row.Data):
+				j.resp.Stale++
+				j.resp.StaleIds = appendReportedId(j.resp.StaleIds, id)
+			default:
+				continue
+			}
+
+			if j.repair {
+				doc, err := PackSearchFields(row.Data, j.collection, id)
+				if err != nil {
+					return err
+				}
+				docs = append(docs, doc)
+			}
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		if err := j.searchStore.IndexDocuments(ctx, j.collection.SearchCollectionName(), bytes.NewReader(bytes.Join(docs, []byte("\n"))), search.IndexDocumentsOptions{
+			Action:    searchUpsert,
+			BatchSize: len(docs),
+		}); err != nil {
+			return err
+		}
+		j.resp.Repaired += int64(len(docs))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the documents left have no row
+	for id, indexed := range documents {
+		if !time.Unix(0, indexed).Before(since) {
+			j.resp.Skipped++
+			continue
+		}
+
+		j.resp.Orphaned++
+		j.resp.OrphanedIds = appendReportedId(j.resp.OrphanedIds, id)
+		if j.repair {
+			if err = j.searchStore.DeleteDocuments(ctx, j.collection.SearchCollectionName(), id); err != nil && err != search.ErrNotFound {
+				return err
+			}
+			j.resp.Repaired++
+		}
+	}
+
+	return nil
+}
+
+// documentTimestamps returns the time each document of the search collection was written, keyed by the id.
+func (j *verifyJob) documentTimestamps(ctx context.Context) (map[string]int64, error) {
+	documents := make(map[string]int64)
+
+	reader, err := j.searchStore.ExportDocuments(ctx, j.collection.SearchCollectionName())
+	if err == search.ErrNotFound {
+		return documents, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { ulog.E(reader.Close()) }()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var doc searchDocumentTimestamps
+		if err = jsoniter.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return nil, err
+		}
+		documents[doc.Id] = doc.CreatedAt
+		if doc.UpdatedAt != 0 {
+			documents[doc.Id] = doc.UpdatedAt
+		}
+	}
+
+	return documents, scanner.Err()
+}
+
+// rowTimestamp returns the time the row was written in the form it is stored in the search documents.
+func rowTimestamp(data *internal.TableData) int64 {
+	if data.UpdatedAt != nil {
+		return data.UpdatedAt.UnixNano()
+	}
+	if data.CreatedAt != nil {
+		return data.CreatedAt.UnixNano()
+	}
+	return 0
+}
+
+func appendReportedId(ids []string, id string) []string {
+	if len(ids) >= verifyMaxReportedIds {
+		return ids
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/kv"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+type testVerifyStore struct {
+	testIndexingStore
+
+	exported []string
+}
+
+func (s *testVerifyStore) ExportDocuments(context.Context, string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(strings.Join(s.exported, "\n"))), nil
+}
+
+func testSearchDocument(id string, written time.Time) string {
+	return fmt.Sprintf(`{"id":"%s","created_at":%d}`, id, written.UnixNano())
+}
+
+func TestVerifyJob(t *testing.T) {
+	table := []byte("t1")
+	old := time.Now().Add(-time.Hour)
+	older := old.Add(-time.Minute)
+	recent := time.Now()
+
+	newJob := func(store *testVerifyStore, repair bool) *verifyJob {
+		return &verifyJob{
+			searchStore: store,
+			scanner: &testRowScanner{
+				rows: []kv.KeyValue{
+					testReindexRow(table, "consistent", old),
+					testReindexRow(table, "missing", old),
+					testReindexRow(table, "stale", old),
+					testReindexRow(table, "recent", recent),
+				},
+				batchSize: 2,
+			},
+			table: table,
+			collection: &schema.DefaultCollection{
+				Name:   "c1",
+				Search: &tsApi.CollectionSchema{Name: "ns1-db1-c1"},
+			},
+			repair: repair,
+			resp:   &api.VerifyCollectionResponse{},
+		}
+	}
+	exported := []string{
+		testSearchDocument("consistent", old),
+		testSearchDocument("stale", older),
+		testSearchDocument("orphaned", old),
+		testSearchDocument("orphaned_recent", recent),
+	}
+
+	t.Run("reports the inconsistencies", func(t *testing.T) {
+		store := &testVerifyStore{exported: exported}
+		job := newJob(store, false)
+
+		require.NoError(t, job.run(context.Background()))
+		require.Equal(t, &api.VerifyCollectionResponse{
+			Checked:     3,
+			Missing:     1,
+			Stale:       1,
+			Orphaned:    1,
+			Skipped:     2,
+			MissingIds:  []string{"missing"},
+			StaleIds:    []string{"stale"},
+			OrphanedIds: []string{"orphaned"},
+		}, job.resp)
+		require.Empty(t, store.indexed)
+		require.Empty(t, store.deleted)
+	})
+	t.Run("repairs the inconsistencies", func(t *testing.T) {
+		store := &testVerifyStore{exported: exported}
+		job := newJob(store, true)
+
+		require.NoError(t, job.run(context.Background()))
+		require.Equal(t, int64(3), job.resp.Repaired)
+		require.Len(t, store.indexed, 2)
+		require.Contains(t, store.indexed[0], `"id":"missing"`)
+		require.Contains(t, store.indexed[1], `"id":"stale"`)
+		require.Equal(t, []string{"orphaned"}, store.deleted)
+	})
+}
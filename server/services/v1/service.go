@@ -40,6 +40,6 @@ func GetRegisteredServices(kvStore kv.KeyValueStore, searchStore search.Store, t
 
 	v1Services = append(v1Services, newApiService(kvStore, searchStore, tenantMgr, txMgr))
 	v1Services = append(v1Services, newHealthService())
-	v1Services = append(v1Services, newAdminService(tenantMgr, txMgr))
+	v1Services = append(v1Services, newAdminService(kvStore, searchStore, tenantMgr, txMgr))
 	return v1Services
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/tigrisdata/tigris/server/cdc"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
 	middleware "github.com/tigrisdata/tigris/server/midddleware"
 	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/transaction"
@@ -50,12 +51,16 @@ type SessionManager struct {
 
 func NewSessionManager(txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, versionH *metadata.VersionHandler, cdc *cdc.Manager, searchStore search.Store, encoder metadata.Encoder) *SessionManager {
 	var txListeners []TxListener
+	if config.DefaultConfig.Trigger.Enabled {
+		// the triggers run first so that their writes are published and indexed along with the rest of the transaction
+		txListeners = append(txListeners, NewTriggerRunner(NewBaseQueryRunner(encoder, cdc, txMgr, searchStore)))
+	}
 	if config.DefaultConfig.Cdc.Enabled {
 		txListeners = append(txListeners, cdc)
 	}
 	if config.DefaultConfig.Search.WriteEnabled {
 		// just for testing so that we can disable it if needed
-		txListeners = append(txListeners, NewSearchIndexer(searchStore, encoder))
+		txListeners = append(txListeners, NewSearchIndexer(searchStore, encoder, txMgr, tenantMgr.GetSearchQueueStore()))
 	}
 
 	return &SessionManager{
@@ -71,7 +76,7 @@ func NewSessionManager(txMgr *transaction.Manager, tenantMgr *metadata.TenantMan
 // Create returns the QuerySession after creating all the necessary elements that a query execution needs.
 // It first creates or get a tenant, read the metadata version and based on that reload the tenant cache and then finally
 // create a transaction which will be used to execute all the query in this session.
-func (sessMgr *SessionManager) Create(ctx context.Context, reloadVerOutside bool, track bool) (*QuerySession, error) {
+func (sessMgr *SessionManager) Create(ctx context.Context, reloadVerOutside bool, track bool, opts transaction.TxOptions) (*QuerySession, error) {
 	namespaceForThisSession, err := request.GetNamespace(ctx)
 	if err != nil {
 		return nil, err
@@ -98,20 +103,29 @@ func (sessMgr *SessionManager) Create(ctx context.Context, reloadVerOutside bool
 		}
 	}
 
-	tx, err := sessMgr.txMgr.StartTx(ctx)
+	tx, err := sessMgr.txMgr.StartTxWithOptions(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 	txCtx := tx.GetTxCtx()
 
+	reloadTx := tx
+	if opts.ReadVersion > 0 {
+		// the tenant caches the latest metadata, a transaction reading an earlier version can't be used to reload it
+		if reloadTx, err = sessMgr.txMgr.StartTx(ctx); err != nil {
+			return nil, err
+		}
+		defer func() { _ = reloadTx.Rollback(ctx) }()
+	}
+
 	if reloadVerOutside {
 		// use version calculated outside
-		if err = tenant.ReloadUsingOutsideVersion(ctx, tx, version, txCtx.Id); ulog.E(err) {
+		if err = tenant.ReloadUsingOutsideVersion(ctx, reloadTx, version, txCtx.Id); ulog.E(err) {
 			return nil, err
 		}
 	} else {
 		// safe to read version in a transaction
-		if err = tenant.ReloadUsingTxVersion(ctx, tx, txCtx.Id); ulog.E(err) {
+		if err = tenant.ReloadUsingTxVersion(ctx, reloadTx, txCtx.Id); ulog.E(err) {
 			return nil, err
 		}
 	}
@@ -128,29 +142,67 @@ func (sessMgr *SessionManager) Create(ctx context.Context, reloadVerOutside bool
 		txListeners: sessMgr.txListeners,
 	}
 	if track {
+		q.created = time.Now()
+		q.lastUsed = q.created
 		sessMgr.tracker.add(txCtx.Id, q)
 	}
 
 	return q, nil
 }
 
-func (sessMgr *SessionManager) Get(id string) *QuerySession {
-	return sessMgr.tracker.get(id)
+// Get returns the session of an explicit transaction, or ErrSessionExpired if the session was rolled back by the sweep.
+func (sessMgr *SessionManager) Get(id string) (*QuerySession, error) {
+	return sessMgr.tracker.acquire(id)
 }
 
 func (sessMgr *SessionManager) Remove(id string) {
 	sessMgr.tracker.remove(id)
 }
 
+// startSweeper rolls back the explicit transactions that expired every sweep interval, so that the transactions
+// abandoned by their clients don't stay open forever.
+func (sessMgr *SessionManager) startSweeper() {
+	cfg := config.DefaultConfig.Transaction
+	if cfg.SweepInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.SweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			sessMgr.sweep(time.Now(), cfg)
+		}
+	}()
+}
+
+func (sessMgr *SessionManager) sweep(now time.Time, cfg config.TransactionConfig) {
+	for _, expired := range sessMgr.tracker.sweep(now, cfg) {
+		log.Info().Str("tx", expired.session.txCtx.GetId()).Str("reason", expired.reason).Msg("rolling back expired transaction")
+		if err := expired.session.Rollback(); err != nil {
+			log.Err(err).Str("tx", expired.session.txCtx.GetId()).Msg("rolling back expired transaction failed")
+		}
+		metrics.IncExpiredSessions(expired.reason)
+	}
+
+	metrics.UpdateActiveSessions(sessMgr.tracker.count())
+}
+
 // Execute is responsible to execute a query. In a way this method is managing the lifecycle of a query. For implicit
 // transaction everything is done in this method. For explicit transaction, a session may already exist, so it only
 // needs to run without calling Commit/Rollback.
 func (sessMgr *SessionManager) Execute(ctx context.Context, req *ReqOptions) (*Response, error) {
 	if req.txCtx != nil {
-		session := sessMgr.tracker.get(req.txCtx.Id)
+		session, err := sessMgr.tracker.acquire(req.txCtx.Id)
+		if err != nil {
+			return nil, err
+		}
 		if session == nil {
 			return nil, transaction.ErrSessionIsGone
 		}
+		defer sessMgr.tracker.release(session)
+
 		resp, ctx, err := session.Run(req.queryRunner)
 		session.ctx = ctx
 		return resp, err
@@ -169,7 +221,7 @@ func (sessMgr *SessionManager) executeWithRetry(ctx context.Context, req *ReqOpt
 	for {
 		var session *QuerySession
 		// implicit sessions doesn't need tracking
-		if session, err = sessMgr.Create(ctx, req.metadataChange, false); err != nil {
+		if session, err = sessMgr.Create(ctx, req.metadataChange, false, transaction.TxOptions{}); err != nil {
 			return nil, err
 		}
 
@@ -213,6 +265,11 @@ type QuerySession struct {
 	tenant      *metadata.Tenant
 	version     metadata.Version
 	txListeners []TxListener
+
+	// created, lastUsed and inUse are only set for the tracked sessions, they are guarded by the tracker.
+	created  time.Time
+	lastUsed time.Time
+	inUse    int
 }
 
 func (s *QuerySession) Run(runner QueryRunner) (*Response, context.Context, error) {
@@ -263,16 +320,32 @@ func (s *QuerySession) Commit(versionMgr *metadata.VersionHandler, incVersion bo
 	return err
 }
 
-// sessionTracker is used to track sessions
+const (
+	expiredIdle     = "idle"
+	expiredLifetime = "lifetime"
+
+	// expiredSessionRetention is how long the expired sessions are remembered to return ErrSessionExpired.
+	expiredSessionRetention = 10 * time.Minute
+)
+
+// sessionTracker is used to track sessions. The sessions rolled back by the sweep are remembered for a while, so that
+// the requests of their clients fail with ErrSessionExpired instead of not finding the session.
 type sessionTracker struct {
 	sync.RWMutex
 
 	sessions map[string]*QuerySession
+	expired  map[string]time.Time
+}
+
+type expiredSession struct {
+	session *QuerySession
+	reason  string
 }
 
 func newSessionTracker() *sessionTracker {
 	return &sessionTracker{
 		sessions: make(map[string]*QuerySession),
+		expired:  make(map[string]time.Time),
 	}
 }
 
@@ -296,3 +369,70 @@ func (tracker *sessionTracker) add(id string, session *QuerySession) {
 
 	tracker.sessions[id] = session
 }
+
+// acquire returns the session and marks it in use until it is released, the sweep doesn't expire a session in use.
+func (tracker *sessionTracker) acquire(id string) (*QuerySession, error) {
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	session, ok := tracker.sessions[id]
+	if !ok {
+		if _, expired := tracker.expired[id]; expired {
+			return nil, transaction.ErrSessionExpired
+		}
+		return nil, nil
+	}
+
+	session.inUse++
+	return session, nil
+}
+
+func (tracker *sessionTracker) release(session *QuerySession) {
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	session.inUse--
+	session.lastUsed = time.Now()
+}
+
+// sweep removes the sessions that are not in use and that are idle or open for too long, and returns them.
+func (tracker *sessionTracker) sweep(now time.Time, cfg config.TransactionConfig) []expiredSession {
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	for id, expiredAt := range tracker.expired {
+		if now.Sub(expiredAt) > expiredSessionRetention {
+			delete(tracker.expired, id)
+		}
+	}
+
+	var expired []expiredSession
+	for id, session := range tracker.sessions {
+		if session.inUse > 0 {
+			continue
+		}
+
+		var reason string
+		switch {
+		case cfg.MaxLifetime > 0 && now.Sub(session.created) >= cfg.MaxLifetime:
+			reason = expiredLifetime
+		case cfg.IdleTimeout > 0 && now.Sub(session.lastUsed) >= cfg.IdleTimeout:
+			reason = expiredIdle
+		default:
+			continue
+		}
+
+		delete(tracker.sessions, id)
+		tracker.expired[id] = now
+		expired = append(expired, expiredSession{session: session, reason: reason})
+	}
+
+	return expired
+}
+
+func (tracker *sessionTracker) count() int {
+	tracker.RLock()
+	defer tracker.RUnlock()
+
+	return len(tracker.sessions)
+}
@@ -16,8 +16,11 @@ package v1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/transaction"
 )
 
 func TestSessionTracker(t *testing.T) {
@@ -33,3 +36,58 @@ func TestSessionTracker(t *testing.T) {
 	s.add("abc", sess)
 	require.Equal(t, sess, s.get("abc"))
 }
+
+func TestSessionTrackerSweep(t *testing.T) {
+	cfg := config.TransactionConfig{IdleTimeout: 10 * time.Second, MaxLifetime: time.Minute}
+	now := time.Now()
+
+	newTracker := func() *sessionTracker {
+		s := newSessionTracker()
+		s.add("idle", &QuerySession{created: now.Add(-20 * time.Second), lastUsed: now.Add(-15 * time.Second)})
+		s.add("old", &QuerySession{created: now.Add(-2 * time.Minute), lastUsed: now})
+		s.add("active", &QuerySession{created: now.Add(-20 * time.Second), lastUsed: now.Add(-time.Second)})
+		return s
+	}
+
+	t.Run("expires idle and old sessions", func(t *testing.T) {
+		s := newTracker()
+		idle, old := s.get("idle"), s.get("old")
+		expired := s.sweep(now, cfg)
+
+		reasons := map[*QuerySession]string{}
+		for _, e := range expired {
+			reasons[e.session] = e.reason
+		}
+		require.Equal(t, map[*QuerySession]string{idle: expiredIdle, old: expiredLifetime}, reasons)
+		require.Equal(t, 1, s.count())
+
+		_, err := s.acquire("idle")
+		require.Equal(t, transaction.ErrSessionExpired, err)
+		session, err := s.acquire("unknown")
+		require.NoError(t, err)
+		require.Nil(t, session)
+	})
+	t.Run("skips the sessions in use", func(t *testing.T) {
+		s := newTracker()
+		session, err := s.acquire("idle")
+		require.NoError(t, err)
+		require.NotNil(t, session)
+
+		require.Len(t, s.sweep(now, cfg), 1)
+		require.Equal(t, session, s.get("idle"))
+
+		s.release(session)
+		require.Empty(t, s.sweep(now.Add(time.Second), cfg))
+		s.sweep(time.Now().Add(cfg.IdleTimeout), cfg)
+		require.Nil(t, s.get("idle"))
+	})
+	t.Run("forgets the expired sessions after a while", func(t *testing.T) {
+		s := newTracker()
+		require.Len(t, s.sweep(now, cfg), 2)
+		s.sweep(now.Add(expiredSessionRetention+time.Second), cfg)
+
+		session, err := s.acquire("idle")
+		require.NoError(t, err)
+		require.Nil(t, session)
+	})
+}
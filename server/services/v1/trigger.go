@@ -0,0 +1,332 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/update"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+var triggerOps = map[string]struct{}{
+	kv.InsertEvent:  {},
+	kv.ReplaceEvent: {},
+	kv.UpdateEvent:  {},
+	kv.DeleteEvent:  {},
+}
+
+type skipTriggersCtxKey struct{}
+
+// skipTriggers marks the writes of the transaction as internal, so that they don't fire the triggers.
+func skipTriggers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTriggersCtxKey{}, true)
+}
+
+// TriggerRunner runs the triggers of the collections written in the transaction. It is a TxListener so that the writes
+// of the triggers are part of the same transaction, the writes of a trigger can in turn fire the triggers of the target
+// collection up to the configured depth.
+type TriggerRunner struct {
+	*BaseQueryRunner
+}
+
+func NewTriggerRunner(base *BaseQueryRunner) *TriggerRunner {
+	return &TriggerRunner{
+		BaseQueryRunner: base,
+	}
+}
+
+func (runner *TriggerRunner) OnPreCommit(ctx context.Context, tenant *metadata.Tenant, tx transaction.Tx, listener kv.EventListener) error {
+	if ctx.Value(skipTriggersCtxKey{}) != nil {
+		return nil
+	}
+
+	// the events appended by the triggers are at one more depth than the event firing them, the events of the user
+	// writes are at depth zero.
+	depth := make(map[int]int)
+	for i := 0; i < len(listener.GetEvents()); i++ {
+		event := listener.GetEvents()[i]
+		if _, ok := triggerOps[event.Op]; !ok {
+			continue
+		}
+
+		_, dbName, collName, ok := runner.encoder.DecodeTableName(event.Table)
+		if !ok {
+			continue
+		}
+
+		db, err := runner.GetDatabase(ctx, tx, tenant, dbName)
+		if err != nil {
+			return err
+		}
+
+		triggers := db.GetTriggers(collName)
+		if len(triggers) == 0 {
+			continue
+		}
+
+		doc, err := triggerDocument(event)
+		if err != nil {
+			return err
+		}
+
+		written := len(listener.GetEvents())
+		for _, trigger := range triggers {
+			if depth[i] >= config.DefaultConfig.Trigger.MaxDepth {
+				return api.Errorf(api.Code_FAILED_PRECONDITION, "trigger '%s' exceeded the maximum depth of %d", trigger.Name, config.DefaultConfig.Trigger.MaxDepth)
+			}
+
+			if err = runner.fire(ctx, tx, tenant, db, trigger, event.Op, doc); err != nil {
+				if err == kv.ErrConflictingTransaction {
+					return err
+				}
+
+				log.Err(err).Str("db", dbName).Str("collection", collName).Str("trigger", trigger.Name).Msg("trigger failed")
+				return triggerError(trigger, err)
+			}
+		}
+
+		for j := written; j < len(listener.GetEvents()); j++ {
+			depth[j] = depth[i] + 1
+		}
+	}
+
+	return nil
+}
+
+func (runner *TriggerRunner) OnPostCommit(context.Context, *metadata.Tenant, kv.EventListener) error {
+	return nil
+}
+
+func (runner *TriggerRunner) OnRollback(context.Context, *metadata.Tenant, kv.EventListener) {}
+
+// fire writes the target document of the trigger if the changed document passes the filter of the trigger. The target
+// document is updated if it exists, otherwise it is inserted if the trigger is an upsert.
+func (runner *TriggerRunner) fire(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant, db *metadata.Database, trigger *encoding.Trigger, op string, doc []byte) error {
+	eventF, err := newEventFilter(&api.EventsRequest{
+		Collection: trigger.Collection,
+		Ops:        trigger.Ops,
+		Filter:     trigger.Filter,
+	}, db.GetCollection(trigger.Collection))
+	if err != nil {
+		return err
+	}
+	if !eventF.matchesEvent(trigger.Collection, op) {
+		return nil
+	}
+	if matches, err := eventF.matchesData(doc); err != nil || !matches {
+		return err
+	}
+
+	target, err := runner.GetCollections(db, trigger.Target)
+	if err != nil {
+		return err
+	}
+
+	key, err := bindFields(trigger.Key, doc)
+	if err != nil {
+		return err
+	}
+	fields, err := bindFields(trigger.Update, doc)
+	if err != nil {
+		return err
+	}
+
+	factory, err := update.BuildFieldOperators(fields)
+	if err != nil {
+		return err
+	}
+	for _, fieldOperators := range factory.FieldOperators {
+		v, err := fieldOperators.DeserializeDoc()
+		if err != nil {
+			return err
+		}
+		if err = target.Validate(v); err != nil {
+			return err
+		}
+	}
+
+	iKeys, err := runner.buildKeysUsingFilter(tenant, db, target, key)
+	if err != nil {
+		return err
+	}
+
+	ts := internal.NewTimestamp()
+	for _, iKey := range iKeys {
+		modified, err := tx.Update(ctx, iKey, func(existing *internal.TableData) (*internal.TableData, error) {
+			existingDoc, er := target.UpgradeDocument(existing.Ver, existing.RawData)
+			if er != nil {
+				return nil, er
+			}
+
+			merged, er := factory.MergeAndGet(existingDoc)
+			if er != nil {
+				return nil, er
+			}
+
+			tableData := internal.NewTableDataWithTS(existing.CreatedAt, ts, merged)
+			tableData.Ver = int32(target.SchVer)
+			return tableData, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if modified == 0 && trigger.Upsert {
+			// the new document is the key with the update applied to it
+			newDoc, err := factory.MergeAndGet(key)
+			if err != nil {
+				return err
+			}
+			if _, _, err = runner.insertOrReplace(ctx, tx, tenant, db, target, [][]byte{newDoc}, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// triggerDocument returns the document of the event, it is the document before the change for the deletes.
+func triggerDocument(event *kv.Event) ([]byte, error) {
+	data := event.Data
+	if event.Op == kv.DeleteEvent {
+		data = event.OldData
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	td, err := internal.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return td.RawData, nil
+}
+
+// bindFields replaces the string values of the template that start with "$" with the fields of the document. The nested
+// fields are referenced by the dot separated path i.e. "$address.city". A string starting with "$$" is kept as a literal
+// string starting with "$".
+func bindFields(template []byte, doc []byte) ([]byte, error) {
+	var decodedDoc map[string]interface{}
+	if len(doc) > 0 {
+		dec := jsoniter.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+		if err := dec.Decode(&decodedDoc); err != nil {
+			return nil, err
+		}
+	}
+
+	var decodedTemplate interface{}
+	dec := jsoniter.NewDecoder(bytes.NewReader(template))
+	dec.UseNumber()
+	if err := dec.Decode(&decodedTemplate); err != nil {
+		return nil, err
+	}
+
+	bound, err := bindValue(decodedTemplate, decodedDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsoniter.Marshal(bound)
+}
+
+func bindValue(value interface{}, doc map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			bound, err := bindValue(nested, doc)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = bound
+		}
+		return v, nil
+	case []interface{}:
+		for i, nested := range v {
+			bound, err := bindValue(nested, doc)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = bound
+		}
+		return v, nil
+	case string:
+		if strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+		if !strings.HasPrefix(v, "$") {
+			return v, nil
+		}
+
+		return documentField(doc, v[1:])
+	}
+
+	return value, nil
+}
+
+func documentField(doc map[string]interface{}, path string) (interface{}, error) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, schema.ObjFlattenDelimiter) {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "field '%s' doesn't exist in the document", path)
+		}
+		if current, ok = obj[part]; !ok {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "field '%s' doesn't exist in the document", path)
+		}
+	}
+
+	return current, nil
+}
+
+// triggerError names the trigger in the error, so that the failure of the transaction can be traced back to it.
+func triggerError(trigger *encoding.Trigger, err error) error {
+	if e, ok := err.(*api.TigrisError); ok {
+		return api.Errorf(e.Code, "trigger '%s' failed: %s", trigger.Name, e.Message)
+	}
+
+	return api.Errorf(api.Code_INTERNAL, "trigger '%s' failed: %s", trigger.Name, err.Error())
+}
+
+// validateTriggerKey checks that the key of the trigger has all the primary key fields of the target collection, as the
+// trigger writes a single document.
+func validateTriggerKey(key []byte, target *schema.DefaultCollection) error {
+	var fields map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(key, &fields); err != nil {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "invalid trigger key '%s'", key)
+	}
+
+	for _, f := range target.Indexes.PrimaryKey.Fields {
+		if _, ok := fields[f.FieldName]; !ok {
+			return api.Errorf(api.Code_INVALID_ARGUMENT, "trigger key is missing the primary key field '%s' of the target collection", f.FieldName)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestBindFields(t *testing.T) {
+	doc := []byte(`{"id": 1, "customer": "c1", "total": 10.5, "address": {"city": "SF"}, "tags": ["a"]}`)
+
+	for _, c := range []struct {
+		template []byte
+		bound    []byte
+	}{
+		{[]byte(`{"customer": "$customer"}`), []byte(`{"customer":"c1"}`)},
+		{[]byte(`{"$set": {"last_order": "$id", "city": "$address.city"}, "$increment": {"orders": 1, "spent": "$total"}}`), []byte(`{"$increment":{"orders":1,"spent":10.5},"$set":{"city":"SF","last_order":1}}`)},
+		{[]byte(`{"$set": {"tags": "$tags", "label": "$$customer", "status": "paid"}}`), []byte(`{"$set":{"label":"$customer","status":"paid","tags":["a"]}}`)},
+	} {
+		bound, err := bindFields(c.template, doc)
+		require.NoError(t, err)
+		require.JSONEq(t, string(c.bound), string(bound))
+	}
+
+	_, err := bindFields([]byte(`{"customer": "$unknown"}`), doc)
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "field 'unknown' doesn't exist in the document"), err)
+
+	_, err = bindFields([]byte(`{"customer": "$customer.name"}`), doc)
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "field 'customer.name' doesn't exist in the document"), err)
+
+	// deletes without the pre-image don't have the document
+	_, err = bindFields([]byte(`{"customer": "$customer"}`), nil)
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "field 'customer' doesn't exist in the document"), err)
+}
+
+func TestTriggerDocument(t *testing.T) {
+	encode := func(doc string) []byte {
+		data, err := internal.Encode(internal.NewTableData([]byte(doc)))
+		require.NoError(t, err)
+		return data
+	}
+
+	doc, err := triggerDocument(&kv.Event{Op: kv.InsertEvent, Data: encode(`{"id":1}`)})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":1}`), doc)
+
+	doc, err = triggerDocument(&kv.Event{Op: kv.DeleteEvent, OldData: encode(`{"id":2}`)})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":2}`), doc)
+
+	doc, err = triggerDocument(&kv.Event{Op: kv.DeleteEvent})
+	require.NoError(t, err)
+	require.Nil(t, doc)
+}
+
+func TestValidateTriggerKey(t *testing.T) {
+	factory, err := schema.Build("order_stats", []byte(`{"title": "order_stats", "properties": {"customer": {"type": "string"}, "region": {"type": "string"}, "orders": {"type": "integer"}}, "primary_key": ["customer", "region"]}`))
+	require.NoError(t, err)
+	target := schema.NewDefaultCollection("order_stats", 1, 1, factory.Fields, factory.Indexes, factory.Schema, "order_stats")
+
+	require.NoError(t, validateTriggerKey([]byte(`{"customer": "$customer", "region": "us"}`), target))
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "trigger key is missing the primary key field 'region' of the target collection"),
+		validateTriggerKey([]byte(`{"customer": "$customer"}`), target))
+	require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid trigger key '[1]'"), validateTriggerKey([]byte(`[1]`), target))
+}
+
+func TestTriggerError(t *testing.T) {
+	trigger := &encoding.Trigger{Name: "order_stats"}
+
+	require.Equal(t, api.Errorf(api.Code_NOT_FOUND, "trigger 'order_stats' failed: collection doesn't exist 'stats'"),
+		triggerError(trigger, api.Errorf(api.Code_NOT_FOUND, "collection doesn't exist 'stats'")))
+	require.Equal(t, api.Errorf(api.Code_INTERNAL, "trigger 'order_stats' failed: boom"),
+		triggerError(trigger, fmt.Errorf("boom")))
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+const (
+	// vectorScanBatchSize is the number of rows read in a transaction by the scan of a vector query.
+	vectorScanBatchSize = 500
+	// vectorScanMaxRows bounds the rows scanned by a vector query, the queries on larger collections are rejected.
+	vectorScanMaxRows = 100000
+)
+
+type vectorHit struct {
+	row      Row
+	distance float64
+}
+
+// vectorHits is a max-heap on distance so that the farthest of the closest k hits is always at the top.
+type vectorHits []vectorHit
+
+func (h vectorHits) Len() int            { return len(h) }
+func (h vectorHits) Less(i, j int) bool  { return h[i].distance > h[j].distance }
+func (h vectorHits) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *vectorHits) Push(x interface{}) { *h = append(*h, x.(vectorHit)) }
+func (h *vectorHits) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// VectorRowReader answers a vector query exactly by scanning the rows of the collection. It is used when the search
+// store can't answer vector queries. Documents that don't pass the filter of the query or don't have the vector field
+// are skipped, the remaining are ordered by their distance from the query vector and only the closest k are returned.
+type VectorRowReader struct {
+	idx  int
+	end  int
+	hits vectorHits
+}
+
+// NewVectorRowReader scans the rows to build the closest k hits, the scan fails once it read more than maxRows rows. If
+// pageNo is set then only the rows of that page are returned.
+func NewVectorRowReader(ctx context.Context, scanner rowScanner, query *qsearch.Query, pageNo int32, maxRows int) (*VectorRowReader, error) {
+	var hits = &vectorHits{}
+	var scanned int
+	err := scanner.scan(ctx, func(rows []kv.KeyValue) error {
+		if scanned += len(rows); scanned > maxRows {
+			return api.Errorf(api.Code_RESOURCE_EXHAUSTED, "vector search scans the collection and is limited to collections of %d documents", maxRows)
+		}
+
+		for _, row := range rows {
+			var doc map[string]interface{}
+			if err := jsoniter.Unmarshal(row.Data.RawData, &doc); err != nil {
+				return err
+			}
+
+			if query.WrappedF != nil && !query.WrappedF.Filter.MatchesDoc(doc) {
+				continue
+			}
+
+			vector, err := schema.NewVector(getNestedValue(doc, query.Vector.Field))
+			if err != nil || len(vector) != len(query.Vector.Vector) {
+				continue
+			}
+
+			hit := vectorHit{
+				row:      Row{Key: row.FDBKey, Data: row.Data},
+				distance: query.Vector.Distance(vector),
+			}
+			if hits.Len() < query.Vector.K {
+				heap.Push(hits, hit)
+			} else if hit.distance < (*hits)[0].distance {
+				(*hits)[0] = hit
+				heap.Fix(hits, 0)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(*hits, func(i, j int) bool {
+		return (*hits)[i].distance < (*hits)[j].distance
+	})
+
+	v := &VectorRowReader{
+		hits: *hits,
+		end:  hits.Len(),
+	}
+	if pageNo > 0 {
+		v.idx = int(pageNo-1) * query.PageSize
+		if v.idx+query.PageSize < v.end {
+			v.end = v.idx + query.PageSize
+		}
+	}
+
+	return v, nil
+}
+
+func (v *VectorRowReader) Next(_ context.Context, row *Row) bool {
+	if v.idx >= v.end {
+		return false
+	}
+
+	*row = v.hits[v.idx].row
+	v.idx++
+	return true
+}
+
+func (v *VectorRowReader) Err() error { return nil }
+
+func (v *VectorRowReader) getFacets() map[string]*api.SearchFacet { return nil }
+
+func (v *VectorRowReader) getTotalFound() int64 { return int64(len(v.hits)) }
+
+func getNestedValue(doc map[string]interface{}, name string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(name, schema.ObjFlattenDelimiter) {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+
+	return value
+}
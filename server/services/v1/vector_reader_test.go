@@ -0,0 +1,91 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestVectorRowReader(t *testing.T) {
+	var rows []kv.KeyValue
+	for i, doc := range []string{
+		`{"id": 0, "category": "a", "emb": [1, 0]}`,
+		`{"id": 1, "category": "b", "emb": [0, 1]}`,
+		`{"id": 2, "category": "a", "emb": [0.9, 0.1]}`,
+		`{"id": 3, "category": "a"}`,
+		`{"id": 4, "category": "a", "emb": [-1, 0]}`,
+	} {
+		rows = append(rows, kv.KeyValue{FDBKey: []byte{byte(i)}, Data: internal.NewTableData([]byte(doc))})
+	}
+	scanner := func() *testRowScanner {
+		return &testRowScanner{rows: rows, batchSize: 2}
+	}
+
+	readIds := func(reader *VectorRowReader) []byte {
+		var ids []byte
+		var row Row
+		for reader.Next(context.TODO(), &row) {
+			ids = append(ids, row.Key[0])
+		}
+		return ids
+	}
+
+	t.Run("cosine", func(t *testing.T) {
+		query := qsearch.NewBuilder().
+			Vector(&qsearch.VectorQuery{Field: "emb", Vector: []float64{1, 0}, K: 3, Metric: qsearch.MetricCosine}).
+			PageSize(10).
+			Build()
+		reader, err := NewVectorRowReader(context.TODO(), scanner(), query, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), reader.getTotalFound())
+		require.Equal(t, []byte{0, 2, 1}, readIds(reader))
+	})
+	t.Run("l2_with_filter", func(t *testing.T) {
+		wrappedF, err := filter.NewFactory([]*schema.QueryableField{
+			{FieldName: "category", DataType: schema.StringType},
+		}).WrappedFilter([]byte(`{"category": "a"}`))
+		require.NoError(t, err)
+
+		query := qsearch.NewBuilder().
+			Vector(&qsearch.VectorQuery{Field: "emb", Vector: []float64{0, 1}, K: 10, Metric: qsearch.MetricL2}).
+			Filter(wrappedF).
+			PageSize(2).
+			Build()
+		reader, err := NewVectorRowReader(context.TODO(), scanner(), query, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, []byte{2, 0, 4}, readIds(reader))
+
+		reader, err = NewVectorRowReader(context.TODO(), scanner(), query, 2, 10)
+		require.NoError(t, err)
+		require.Equal(t, []byte{4}, readIds(reader))
+	})
+	t.Run("bounded_scan", func(t *testing.T) {
+		query := qsearch.NewBuilder().
+			Vector(&qsearch.VectorQuery{Field: "emb", Vector: []float64{1, 0}, K: 3, Metric: qsearch.MetricCosine}).
+			PageSize(10).
+			Build()
+		_, err := NewVectorRowReader(context.TODO(), scanner(), query, 0, 4)
+		require.Equal(t, api.Errorf(api.Code_RESOURCE_EXHAUSTED, "vector search scans the collection and is limited to collections of 4 documents"), err)
+	})
+}
@@ -0,0 +1,603 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+const (
+	webhookNameHeader      = "X-Tigris-Webhook"
+	webhookTimestampHeader = "X-Tigris-Timestamp"
+	webhookSignatureHeader = "X-Tigris-Signature"
+
+	// webhookMaxWorkerBackoff bounds the time a failing worker waits before it is started again.
+	webhookMaxWorkerBackoff = 10 * time.Minute
+)
+
+// deadLetterSchema is the schema of the collection the undelivered batches of a webhook are stored in. The payload is
+// the request body that is posted to the webhook.
+func deadLetterSchema(collection string) []byte {
+	return []byte(fmt.Sprintf(`{
+	"title": "%s",
+	"properties": {
+		"webhook": { "type": "string" },
+		"url": { "type": "string" },
+		"error": { "type": "string" },
+		"attempts": { "type": "integer" },
+		"failed_at": { "type": "string", "format": "date-time" },
+		"payload": { "type": "string" }
+	}
+}`, collection))
+}
+
+type webhookPayload struct {
+	Webhook string             `json:"webhook"`
+	Db      string             `json:"db"`
+	Events  []*api.StreamEvent `json:"events"`
+}
+
+type deadLetter struct {
+	Webhook  string    `json:"webhook"`
+	Url      string    `json:"url"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+	Payload  string    `json:"payload"`
+}
+
+// signWebhookPayload returns the signature of the request body that is sent in the signature header, it is the hex
+// encoded HMAC-SHA256 of the timestamp header and the body joined by a dot, using the secret of the webhook.
+func signWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookDispatcher delivers the change events of the databases to the webhooks registered on them. Every webhook is
+// delivered by a single worker, the server running the worker holds a lease on the webhook in the webhook subspace so
+// that only one server is delivering the events of a webhook at a time. The resume token of the last delivered
+// transaction is checkpointed after every batch, a new worker resumes from the checkpoint which means a batch may be
+// delivered more than once.
+type webhookDispatcher struct {
+	sync.Mutex
+
+	id        string
+	cfg       config.WebhookConfig
+	kvStore   kv.KeyValueStore
+	txMgr     *transaction.Manager
+	tenantMgr *metadata.TenantManager
+	encoder   metadata.Encoder
+	cdcMgr    *cdc.Manager
+	client    *http.Client
+	// insert is used to store the undelivered batches in the dead letter collection of the webhook.
+	insert  func(ctx context.Context, r *api.InsertRequest) (*api.InsertResponse, error)
+	workers map[string]*webhookWorker
+	// failures has the webhooks whose worker failed, their worker is not started again before the retry time.
+	failures map[string]*webhookFailure
+}
+
+type webhookWorker struct {
+	key    string
+	hook   *encoding.Webhook
+	cancel context.CancelFunc
+}
+
+type webhookFailure struct {
+	count   int
+	retryAt time.Time
+}
+
+func newWebhookDispatcher(s *apiService) *webhookDispatcher {
+	return &webhookDispatcher{
+		id:        uuid.New().String(),
+		cfg:       config.DefaultConfig.Cdc.Webhook,
+		kvStore:   s.kvStore,
+		txMgr:     s.txMgr,
+		tenantMgr: s.tenantMgr,
+		encoder:   s.encoder,
+		cdcMgr:    s.cdcMgr,
+		client:    newWebhookClient(config.DefaultConfig.Cdc.Webhook),
+		insert:    s.Insert,
+		workers:   make(map[string]*webhookWorker),
+		failures:  make(map[string]*webhookFailure),
+	}
+}
+
+// newWebhookClient returns the client used to post to the webhooks. It refuses to connect to the loopback, link-local
+// and private addresses, unless the host of the webhook is allowed by the configuration, so that the webhooks can't be
+// used to reach the internal services. The address is checked once resolved, so a public name that resolves to an
+// internal address is refused as well.
+func newWebhookClient(cfg config.WebhookConfig) *http.Client {
+	guarded := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+				return fmt.Errorf("webhook address '%s' is not allowed", host)
+			}
+			return nil
+		},
+	}
+	unguarded := &net.Dialer{Timeout: cfg.Timeout}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && isAllowedWebhookHost(cfg, host) {
+			return unguarded.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+	// the proxy would be the one connecting to the webhook, bypassing the check of the address
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
+
+// validateWebhookURL rejects the webhook urls that point to a loopback, link-local or private address, unless the host
+// is allowed by the configuration. Only the literal addresses and the local host names are rejected here, the names
+// resolving to these addresses are refused by the client when the webhook is posted to.
+func validateWebhookURL(cfg config.WebhookConfig, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "invalid webhook url '%s'", rawURL)
+	}
+
+	host := u.Hostname()
+	if isAllowedWebhookHost(cfg, host) {
+		return nil
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if ip := net.ParseIP(host); (ip != nil && isInternalIP(ip)) || name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return api.Errorf(api.Code_INVALID_ARGUMENT, "webhook url '%s' points to an internal address", rawURL)
+	}
+
+	return nil
+}
+
+func isAllowedWebhookHost(cfg config.WebhookConfig, host string) bool {
+	for _, allowed := range cfg.AllowedHosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+func (d *webhookDispatcher) start() {
+	go func() {
+		ticker := time.NewTicker(d.cfg.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.refresh(context.Background()); err != nil {
+				log.Err(err).Msg("refreshing webhooks failed")
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// refresh starts a worker for every webhook this server holds the lease of and stops the workers of the webhooks that
+// are deleted or whose lease is lost.
+func (d *webhookDispatcher) refresh(ctx context.Context) error {
+	tx, err := d.txMgr.StartTx(ctx)
+	if err != nil {
+		return err
+	}
+	hooks, err := d.tenantMgr.GetWebhookStore().ListAll(ctx, tx)
+	_ = tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]struct{})
+	for _, hook := range hooks {
+		key := webhookWorkerKey(hook)
+
+		acquired, err := d.acquireLease(ctx, hook)
+		if err != nil {
+			log.Err(err).Str("webhook", hook.Name).Msg("acquiring webhook lease failed")
+			continue
+		}
+		if !acquired {
+			continue
+		}
+
+		active[key] = struct{}{}
+		d.startWorker(key, hook)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	for key, w := range d.workers {
+		if _, ok := active[key]; !ok {
+			w.cancel()
+			delete(d.workers, key)
+		}
+	}
+
+	return nil
+}
+
+// webhookWorkerKey identifies the worker of the webhook, a webhook that is deleted and created again with the same
+// name gets a new worker.
+func webhookWorkerKey(hook *encoding.Webhook) string {
+	var createdAt int64
+	if hook.CreatedAt != nil {
+		createdAt = hook.CreatedAt.UnixNano()
+	}
+	return fmt.Sprintf("%d/%d/%s/%d", hook.NamespaceId, hook.DbId, hook.Name, createdAt)
+}
+
+func (d *webhookDispatcher) acquireLease(ctx context.Context, hook *encoding.Webhook) (bool, error) {
+	tx, err := d.txMgr.StartTx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	acquired, err := d.tenantMgr.GetWebhookStore().AcquireLease(ctx, tx, hook.NamespaceId, hook.DbId, hook.Name, d.id, time.Now(), d.cfg.LeaseTimeout)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return false, err
+	}
+
+	return acquired, tx.Commit(ctx)
+}
+
+func (d *webhookDispatcher) startWorker(key string, hook *encoding.Webhook) {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.workers[key]; ok {
+		return
+	}
+	if f, ok := d.failures[key]; ok && time.Now().Before(f.retryAt) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &webhookWorker{
+		key:    key,
+		hook:   hook,
+		cancel: cancel,
+	}
+	d.workers[key] = w
+
+	go func() {
+		err := runWebhookWorker(ctx, w, d.run)
+		if err != nil {
+			log.Err(err).Str("webhook", hook.Name).Msg("delivering webhook failed")
+		}
+
+		// the worker is started again by a later refresh, from the last checkpoint
+		d.Lock()
+		if d.workers[key] == w {
+			delete(d.workers, key)
+		}
+		if err != nil {
+			d.failed(key)
+		}
+		d.Unlock()
+		cancel()
+	}()
+}
+
+// runWebhookWorker runs the worker, a panic while delivering the events of the webhook is returned as the error of the
+// worker so that a single webhook can't stop the server.
+func runWebhookWorker(ctx context.Context, w *webhookWorker, run func(context.Context, *webhookWorker) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = api.Errorf(api.Code_INTERNAL, "webhook worker failed: %v", r)
+		}
+	}()
+
+	return run(ctx, w)
+}
+
+// failed delays the next start of the worker of the webhook, the delay doubles with every failure in a row starting
+// from the refresh interval. It must be called with the lock held.
+func (d *webhookDispatcher) failed(key string) {
+	f, ok := d.failures[key]
+	if !ok {
+		f = &webhookFailure{}
+		d.failures[key] = f
+	}
+	f.count++
+
+	backoff := d.cfg.RefreshInterval
+	for i := 1; i < f.count && backoff < webhookMaxWorkerBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxWorkerBackoff {
+		backoff = webhookMaxWorkerBackoff
+	}
+	f.retryAt = time.Now().Add(backoff)
+}
+
+// delivered clears the failures of the webhook once a batch of its events is delivered.
+func (d *webhookDispatcher) delivered(key string) {
+	d.Lock()
+	defer d.Unlock()
+
+	delete(d.failures, key)
+}
+
+// run streams the change events of the database of the webhook and delivers them in batches. A batch is posted once it
+// is full or once the batch wait has passed since the first transaction of the batch.
+func (d *webhookDispatcher) run(ctx context.Context, w *webhookWorker) error {
+	namespace, db, ok := d.tenantMgr.GetDatabaseNameFromId(w.hook.NamespaceId, w.hook.DbId)
+	if !ok {
+		return api.Errorf(api.Code_NOT_FOUND, "database of the webhook doesn't exist")
+	}
+
+	tenant, err := d.tenantMgr.GetTenant(ctx, namespace, d.txMgr)
+	if err != nil {
+		return err
+	}
+
+	eventF, err := newEventFilter(&api.EventsRequest{
+		Db:         db,
+		Collection: w.hook.Collection,
+		Ops:        w.hook.Ops,
+		Filter:     w.hook.Filter,
+	}, tenant.GetCollection(db, w.hook.Collection))
+	if err != nil {
+		return err
+	}
+
+	streamer, err := d.newStreamer(ctx, w, db)
+	if err != nil {
+		return err
+	}
+	defer streamer.Close()
+
+	ctx = request.SetNamespace(ctx, namespace)
+
+	var pending []*api.StreamEvent
+	var lastToken []byte
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-flush:
+			if err = d.flush(ctx, w, db, pending, lastToken); err != nil {
+				return err
+			}
+			pending, flush = nil, nil
+		case tx, ok := <-streamer.Txs:
+			if !ok {
+				return streamer.Err()
+			}
+
+			events, err := streamEvents(d.encoder, tenant, db, eventF, &tx)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				// the failures of the webhook are not delivered to the webhook itself
+				if len(w.hook.DeadLetterCollection) == 0 || event.Collection != w.hook.DeadLetterCollection {
+					pending = append(pending, event)
+				}
+			}
+			lastToken = tx.ResumeToken
+
+			if len(pending) >= d.cfg.BatchSize {
+				if err = d.flush(ctx, w, db, pending, lastToken); err != nil {
+					return err
+				}
+				pending, flush = nil, nil
+			} else if flush == nil {
+				// the checkpoint is moved forward even if none of the events are for the webhook
+				flush = time.After(d.cfg.BatchWait)
+			}
+		}
+	}
+}
+
+// newStreamer starts the stream from the checkpoint of the webhook, or from the end of the change log if nothing is
+// delivered yet. If the checkpoint is already trimmed from the change log, the stream starts from the oldest
+// transaction that is still in the change log.
+func (d *webhookDispatcher) newStreamer(ctx context.Context, w *webhookWorker, db string) (*cdc.Streamer, error) {
+	tx, err := d.txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := d.tenantMgr.GetWebhookStore().GetCheckpoint(ctx, tx, w.hook.NamespaceId, w.hook.DbId, w.hook.Name)
+	_ = tx.Rollback(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher := d.cdcMgr.GetPublisher(db)
+	streamer, err := publisher.NewStreamer(d.kvStore, cdc.StreamerOptions{ResumeToken: token})
+	if e, ok := err.(*api.TigrisError); ok && e.Code == api.Code_OUT_OF_RANGE {
+		log.Warn().Str("webhook", w.hook.Name).Msg("webhook checkpoint is trimmed, resuming from the oldest event")
+		return publisher.NewStreamer(d.kvStore, cdc.StreamerOptions{FromBeginning: true})
+	}
+
+	return streamer, err
+}
+
+// flush delivers the pending events in batches and then checkpoints the resume token of the last transaction.
+func (d *webhookDispatcher) flush(ctx context.Context, w *webhookWorker, db string, pending []*api.StreamEvent, token []byte) error {
+	for len(pending) > 0 {
+		batch := pending
+		if len(batch) > d.cfg.BatchSize {
+			batch = pending[:d.cfg.BatchSize]
+		}
+		pending = pending[len(batch):]
+
+		if err := d.deliver(ctx, w.hook, db, batch); err != nil {
+			return err
+		}
+	}
+
+	if len(token) == 0 {
+		return nil
+	}
+
+	tx, err := d.txMgr.StartTx(ctx)
+	if err != nil {
+		return err
+	}
+	if err = d.tenantMgr.GetWebhookStore().PutCheckpoint(ctx, tx, w.hook.NamespaceId, w.hook.DbId, w.hook.Name, token); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	d.delivered(w.key)
+	return nil
+}
+
+// deliver posts the batch to the webhook, the failed attempts are retried with an exponential backoff. Once all the
+// attempts fail, the batch is stored in the dead letter collection of the webhook.
+func (d *webhookDispatcher) deliver(ctx context.Context, hook *encoding.Webhook, db string, events []*api.StreamEvent) error {
+	body, err := jsoniter.Marshal(&webhookPayload{
+		Webhook: hook.Name,
+		Db:      db,
+		Events:  events,
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := d.cfg.MinBackoff
+	attempts := 0
+	for {
+		attempts++
+		if err = d.post(ctx, hook, body); err == nil {
+			metrics.IncWebhookDelivered(db, hook.Name, int64(len(events)))
+			return nil
+		}
+		if attempts >= d.cfg.MaxAttempts {
+			break
+		}
+
+		metrics.IncWebhookFailedAttempts(db, hook.Name)
+		log.Debug().Err(err).Str("webhook", hook.Name).Int("attempts", attempts).Msg("posting to webhook failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+
+	metrics.IncWebhookDeadLettered(db, hook.Name, int64(len(events)))
+	return d.deadLetter(ctx, hook, db, body, attempts, err)
+}
+
+func (d *webhookDispatcher) post(ctx context.Context, hook *encoding.Webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookNameHeader, hook.Name)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	if len(hook.Secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(hook.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// deadLetter stores the undelivered batch in the dead letter collection of the webhook. Without a dead letter
+// collection the batch is dropped.
+func (d *webhookDispatcher) deadLetter(ctx context.Context, hook *encoding.Webhook, db string, body []byte, attempts int, cause error) error {
+	if len(hook.DeadLetterCollection) == 0 {
+		log.Error().Err(cause).Str("webhook", hook.Name).Msg("dropping undelivered webhook events")
+		return nil
+	}
+
+	doc, err := jsoniter.Marshal(&deadLetter{
+		Webhook:  hook.Name,
+		Url:      hook.Url,
+		Error:    cause.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now().UTC(),
+		Payload:  string(body),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.insert(ctx, &api.InsertRequest{
+		Db:         db,
+		Collection: hook.DeadLetterCollection,
+		Documents:  [][]byte{doc},
+	})
+	return err
+}
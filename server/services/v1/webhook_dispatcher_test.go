@@ -0,0 +1,220 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata/encoding"
+)
+
+func testWebhookDispatcher(inserted *[]*api.InsertRequest) *webhookDispatcher {
+	cfg := config.DefaultConfig.Cdc.Webhook
+	cfg.MaxAttempts = 3
+	cfg.MinBackoff = time.Millisecond
+	cfg.MaxBackoff = 2 * time.Millisecond
+
+	return &webhookDispatcher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Second},
+		insert: func(_ context.Context, r *api.InsertRequest) (*api.InsertResponse, error) {
+			*inserted = append(*inserted, r)
+			return &api.InsertResponse{}, nil
+		},
+	}
+}
+
+func TestWebhookDispatcherDeliver(t *testing.T) {
+	events := []*api.StreamEvent{{
+		TxId:       []byte{0x01},
+		Collection: "orders",
+		Op:         "insert",
+		Data:       []byte(`{"id":1}`),
+	}}
+
+	t.Run("signed_payload", func(t *testing.T) {
+		var received []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received, _ = io.ReadAll(r.Body)
+			require.Equal(t, "orders_hook", r.Header.Get(webhookNameHeader))
+			require.Equal(t, signWebhookPayload("s1", r.Header.Get(webhookTimestampHeader), received), r.Header.Get(webhookSignatureHeader))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		var inserted []*api.InsertRequest
+		d := testWebhookDispatcher(&inserted)
+		hook := &encoding.Webhook{Name: "orders_hook", Url: server.URL, Secret: "s1"}
+		require.NoError(t, d.deliver(context.Background(), hook, "db1", events))
+		require.JSONEq(t, `{"webhook":"orders_hook","db":"db1","events":[{"tx_id":"AQ==","collection":"orders","op":"insert","data":{"id":1},"last":false}]}`, string(received))
+		require.Empty(t, inserted)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		var inserted []*api.InsertRequest
+		d := testWebhookDispatcher(&inserted)
+		hook := &encoding.Webhook{Name: "orders_hook", Url: server.URL, DeadLetterCollection: "orders_dlq"}
+		require.NoError(t, d.deliver(context.Background(), hook, "db1", events))
+		require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+		require.Empty(t, inserted)
+	})
+
+	t.Run("dead_letter", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		var inserted []*api.InsertRequest
+		d := testWebhookDispatcher(&inserted)
+		hook := &encoding.Webhook{Name: "orders_hook", Url: server.URL, DeadLetterCollection: "orders_dlq"}
+		require.NoError(t, d.deliver(context.Background(), hook, "db1", events))
+		require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+		require.Len(t, inserted, 1)
+		require.Equal(t, "db1", inserted[0].Db)
+		require.Equal(t, "orders_dlq", inserted[0].Collection)
+
+		var doc deadLetter
+		require.NoError(t, jsoniter.Unmarshal(inserted[0].Documents[0], &doc))
+		require.Equal(t, "orders_hook", doc.Webhook)
+		require.Equal(t, 3, doc.Attempts)
+		require.Equal(t, "webhook responded with status 500", doc.Error)
+		require.Contains(t, doc.Payload, `"collection":"orders"`)
+
+		// without a dead letter collection the batch is dropped
+		inserted = nil
+		hook.DeadLetterCollection = ""
+		require.NoError(t, d.deliver(context.Background(), hook, "db1", events))
+		require.Empty(t, inserted)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		var inserted []*api.InsertRequest
+		d := testWebhookDispatcher(&inserted)
+		d.cfg.MinBackoff = time.Minute
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		hook := &encoding.Webhook{Name: "orders_hook", Url: server.URL, DeadLetterCollection: "orders_dlq"}
+		require.Equal(t, context.Canceled, d.deliver(ctx, hook, "db1", events))
+		require.Empty(t, inserted)
+	})
+}
+
+func TestWebhookWorkerFailures(t *testing.T) {
+	t.Run("recovered", func(t *testing.T) {
+		err := runWebhookWorker(context.Background(), &webhookWorker{}, func(context.Context, *webhookWorker) error {
+			var doc map[string]interface{}
+			_ = doc["status"].(string)
+			return nil
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "webhook worker failed")
+	})
+	t.Run("backoff", func(t *testing.T) {
+		d := testWebhookDispatcher(nil)
+		d.cfg.RefreshInterval = time.Minute
+		d.workers = make(map[string]*webhookWorker)
+		d.failures = make(map[string]*webhookFailure)
+
+		d.failed("k1")
+		require.WithinDuration(t, time.Now().Add(time.Minute), d.failures["k1"].retryAt, time.Second)
+		d.failed("k1")
+		require.WithinDuration(t, time.Now().Add(2*time.Minute), d.failures["k1"].retryAt, time.Second)
+		for i := 0; i < 10; i++ {
+			d.failed("k1")
+		}
+		require.WithinDuration(t, time.Now().Add(webhookMaxWorkerBackoff), d.failures["k1"].retryAt, time.Second)
+
+		// the worker of the failing webhook is not started before the retry time
+		d.startWorker("k1", &encoding.Webhook{Name: "orders_hook"})
+		require.Empty(t, d.workers)
+
+		d.delivered("k1")
+		require.Empty(t, d.failures)
+	})
+}
+
+func TestDeadLetterSchema(t *testing.T) {
+	factory, err := schema.Build("orders_dlq", deadLetterSchema("orders_dlq"))
+	require.NoError(t, err)
+	require.Equal(t, schema.AutoPrimaryKeyF, factory.Indexes.PrimaryKey.Fields[0].FieldName)
+	require.True(t, factory.Indexes.PrimaryKey.Fields[0].IsAutoGenerated())
+}
+
+func TestWebhookInternalAddresses(t *testing.T) {
+	cfg := config.WebhookConfig{Timeout: time.Second}
+
+	for _, u := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://api.localhost/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		require.Error(t, validateWebhookURL(cfg, u), u)
+	}
+	for _, u := range []string{"https://example.com/hook", "http://8.8.8.8/hook"} {
+		require.NoError(t, validateWebhookURL(cfg, u), u)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newWebhookClient(cfg).Post(server.URL, "application/json", nil)
+	require.Error(t, err)
+
+	cfg.AllowedHosts = []string{"127.0.0.1"}
+	require.NoError(t, validateWebhookURL(cfg, server.URL))
+	resp, err := newWebhookClient(cfg).Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+}
@@ -33,6 +33,12 @@ var (
 
 	// ErrSessionIsGone is returned when the session is gone but getting used
 	ErrSessionIsGone = api.Errorf(api.Code_INTERNAL, "session is gone")
+
+	// ErrSessionExpired is returned when the session was rolled back because it was idle or open for too long
+	ErrSessionExpired = api.Errorf(api.Code_ABORTED, "transaction has expired")
+
+	// ErrReadOnlyTransaction is returned when a read only transaction is used to write
+	ErrReadOnlyTransaction = api.Errorf(api.Code_FAILED_PRECONDITION, "transaction is read only")
 )
 
 // Tx interface exposes a method to execute and then other method to end the transaction. When Tx is returned at that
@@ -45,9 +51,12 @@ type Tx interface {
 	Update(ctx context.Context, key keys.Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error)
 	Delete(ctx context.Context, key keys.Key) error
 	Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
+	ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key) (kv.Iterator, error)
 	Get(ctx context.Context, key []byte) ([]byte, error)
 	Commit(ctx context.Context) error
 	Rollback(ctx context.Context) error
+	ReadOnly() bool
+	GetReadVersion(ctx context.Context) (int64, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	SetVersionstampedKey(ctx context.Context, key []byte, value []byte) error
 }
@@ -83,14 +92,28 @@ func NewManager(kvStore kv.KeyValueStore) *Manager {
 	}
 }
 
+// TxOptions are the options of a transaction. A read only transaction rejects the writes and reads a snapshot, so
+// its reads never abort the concurrent writers. ReadVersion pins the version a read only transaction reads, this way
+// the transactions of the same client see the same snapshot.
+type TxOptions struct {
+	ReadOnly    bool
+	ReadVersion int64
+}
+
 // StartTx always starts a new session and tracks the session based on the input parameter.
 func (m *Manager) StartTx(ctx context.Context) (Tx, error) {
+	return m.StartTxWithOptions(ctx, TxOptions{})
+}
+
+// StartTxWithOptions starts a new session like StartTx with the transaction options.
+func (m *Manager) StartTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
 	session, err := newTxSession(m.kvStore)
 	if err != nil {
 		return nil, api.Errorf(api.Code_INTERNAL, "issue creating a session %v", err)
 	}
+	session.readOnly = opts.ReadOnly
 
-	if err = session.start(ctx); err != nil {
+	if err = session.start(ctx, kv.TxOptions{Snapshot: opts.ReadOnly, ReadVersion: opts.ReadVersion}); err != nil {
 		return nil, err
 	}
 
@@ -111,11 +134,12 @@ const (
 type TxSession struct {
 	sync.RWMutex
 
-	context *SessionCtx
-	kvStore kv.KeyValueStore
-	kTx     kv.Tx
-	state   sessionState
-	txCtx   *api.TransactionCtx
+	context  *SessionCtx
+	kvStore  kv.KeyValueStore
+	kTx      kv.Tx
+	state    sessionState
+	txCtx    *api.TransactionCtx
+	readOnly bool
 }
 
 func newTxSession(kv kv.KeyValueStore) (*TxSession, error) {
@@ -134,7 +158,7 @@ func (s *TxSession) GetTxCtx() *api.TransactionCtx {
 	return s.txCtx
 }
 
-func (s *TxSession) start(ctx context.Context) error {
+func (s *TxSession) start(ctx context.Context, opts kv.TxOptions) error {
 	s.Lock()
 	defer s.Unlock()
 
@@ -143,7 +167,7 @@ func (s *TxSession) start(ctx context.Context) error {
 	}
 
 	var err error
-	if s.kTx, err = s.kvStore.BeginTx(ctx); err != nil {
+	if s.kTx, err = s.kvStore.BeginTxWithOptions(ctx, opts); err != nil {
 		return err
 	}
 	s.state = sessionActive
@@ -162,11 +186,23 @@ func (s *TxSession) validateSession() error {
 	return nil
 }
 
+// validateWrite validates the session like validateSession and rejects the writes of the read only sessions.
+func (s *TxSession) validateWrite() error {
+	if err := s.validateSession(); err != nil {
+		return err
+	}
+	if s.readOnly {
+		return ErrReadOnlyTransaction
+	}
+
+	return nil
+}
+
 func (s *TxSession) Insert(ctx context.Context, key keys.Key, data *internal.TableData) error {
 	s.Lock()
 	defer s.Unlock()
 
-	if err := s.validateSession(); err != nil {
+	if err := s.validateWrite(); err != nil {
 		return err
 	}
 
@@ -177,7 +213,7 @@ func (s *TxSession) Replace(ctx context.Context, key keys.Key, data *internal.Ta
 	s.Lock()
 	defer s.Unlock()
 
-	if err := s.validateSession(); err != nil {
+	if err := s.validateWrite(); err != nil {
 		return err
 	}
 
@@ -188,7 +224,7 @@ func (s *TxSession) Update(ctx context.Context, key keys.Key, apply func(*intern
 	s.Lock()
 	defer s.Unlock()
 
-	if err := s.validateSession(); err != nil {
+	if err := s.validateWrite(); err != nil {
 		return -1, err
 	}
 
@@ -199,7 +235,7 @@ func (s *TxSession) Delete(ctx context.Context, key keys.Key) error {
 	s.Lock()
 	defer s.Unlock()
 
-	if err := s.validateSession(); err != nil {
+	if err := s.validateWrite(); err != nil {
 		return err
 	}
 
@@ -217,6 +253,18 @@ func (s *TxSession) Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
 	return s.kTx.Read(ctx, key.Table(), kv.BuildKey(key.IndexParts()...))
 }
 
+// ReadRange reads the rows of the table of lKey from lKey, inclusive, to rKey, exclusive.
+func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key) (kv.Iterator, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return nil, err
+	}
+
+	return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...))
+}
+
 func (s *TxSession) SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error {
 	s.Lock()
 	defer s.Unlock()
@@ -224,6 +272,9 @@ func (s *TxSession) SetVersionstampedValue(ctx context.Context, key []byte, valu
 	if err := s.validateSession(); err != nil {
 		return nil
 	}
+	if s.readOnly {
+		return ErrReadOnlyTransaction
+	}
 
 	return s.kTx.SetVersionstampedValue(ctx, key, value)
 }
@@ -235,6 +286,9 @@ func (s *TxSession) SetVersionstampedKey(ctx context.Context, key []byte, value
 	if err := s.validateSession(); err != nil {
 		return nil
 	}
+	if s.readOnly {
+		return ErrReadOnlyTransaction
+	}
 
 	return s.kTx.SetVersionstampedKey(ctx, key, value)
 }
@@ -274,6 +328,21 @@ func (s *TxSession) Rollback(ctx context.Context) error {
 	return err
 }
 
+func (s *TxSession) ReadOnly() bool {
+	return s.readOnly
+}
+
+func (s *TxSession) GetReadVersion(ctx context.Context) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return 0, err
+	}
+
+	return s.kTx.GetReadVersion(ctx)
+}
+
 func (s *TxSession) Context() *SessionCtx {
 	return s.context
 }
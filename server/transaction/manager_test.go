@@ -15,9 +15,13 @@
 package transaction
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/store/kv"
 )
 
 func TestManager(t *testing.T) {
@@ -26,3 +30,33 @@ func TestManager(t *testing.T) {
 		require.NotNil(t, m)
 	})
 }
+
+func TestReadOnlyTx(t *testing.T) {
+	ctx := context.TODO()
+	m := NewManager(&kv.NoopKVStore{})
+	key := keys.NewKey([]byte("t1"), 1)
+	data := internal.NewTableData([]byte(`{"a":1}`))
+
+	t.Run("rejects_writes", func(t *testing.T) {
+		tx, err := m.StartTxWithOptions(ctx, TxOptions{ReadOnly: true})
+		require.NoError(t, err)
+		require.True(t, tx.ReadOnly())
+
+		_, err = tx.Read(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, ErrReadOnlyTransaction, tx.Insert(ctx, key, data))
+		require.Equal(t, ErrReadOnlyTransaction, tx.Replace(ctx, key, data))
+		require.Equal(t, ErrReadOnlyTransaction, tx.Delete(ctx, key))
+		_, err = tx.Update(ctx, key, func(d *internal.TableData) (*internal.TableData, error) { return d, nil })
+		require.Equal(t, ErrReadOnlyTransaction, err)
+		require.Equal(t, ErrReadOnlyTransaction, tx.SetVersionstampedKey(ctx, []byte("k"), []byte("v")))
+		require.NoError(t, tx.Commit(ctx))
+	})
+	t.Run("allows_writes", func(t *testing.T) {
+		tx, err := m.StartTx(ctx)
+		require.NoError(t, err)
+		require.False(t, tx.ReadOnly())
+		require.NoError(t, tx.Insert(ctx, key, data))
+		require.NoError(t, tx.Rollback(ctx))
+	})
+}
@@ -32,6 +32,10 @@ import (
 const (
 	maxTxSizeBytes = 10000000
 
+	// maxExpandedDeleteRange is the maximum number of keys for which a range delete is expanded into delete events of
+	// the individual keys. Larger ranges are buffered as a single range delete event.
+	maxExpandedDeleteRange = 1000
+
 	fdbAPIVersion = 710
 )
 
@@ -47,9 +51,10 @@ type fbatch struct {
 }
 
 type ftx struct {
-	d   *fdbkv
-	tx  *fdb.Transaction
-	err error
+	d        *fdbkv
+	tx       *fdb.Transaction
+	err      error
+	snapshot bool
 }
 
 type fdbIterator struct {
@@ -346,6 +351,10 @@ func (b *fbatch) IsRetriable() bool {
 }
 
 func (d *fdbkv) BeginTx(ctx context.Context) (baseTx, error) {
+	return d.BeginTxWithOptions(ctx, TxOptions{})
+}
+
+func (d *fdbkv) BeginTxWithOptions(ctx context.Context, opts TxOptions) (baseTx, error) {
 	tx, err := d.db.CreateTransaction()
 	if ulog.E(err) {
 		return nil, err
@@ -354,9 +363,12 @@ func (d *fdbkv) BeginTx(ctx context.Context) (baseTx, error) {
 	if err := setTxTimeout(&tx, getCtxTimeout(ctx)); err != nil {
 		return nil, err
 	}
+	if opts.ReadVersion > 0 {
+		tx.SetReadVersion(opts.ReadVersion)
+	}
 
-	log.Debug().Msg("create transaction")
-	return &ftx{d: d, tx: &tx}, nil
+	log.Debug().Bool("snapshot", opts.Snapshot).Int64("read_version", opts.ReadVersion).Msg("create transaction")
+	return &ftx{d: d, tx: &tx, snapshot: opts.Snapshot}, nil
 }
 
 func (t *ftx) Insert(ctx context.Context, table []byte, key Key, data []byte) error {
@@ -374,7 +386,7 @@ func (t *ftx) Insert(ctx context.Context, table []byte, key Key, data []byte) er
 	}
 
 	t.tx.Set(k, data)
-	listener.OnSet(InsertEvent, table, k, data)
+	listener.OnSet(InsertEvent, table, k, data, nil)
 
 	log.Debug().Str("table", string(table)).Interface("key", key).Msg("Insert")
 
@@ -385,8 +397,16 @@ func (t *ftx) Replace(ctx context.Context, table []byte, key Key, data []byte) e
 	listener := GetEventListener(ctx)
 	k := getFDBKey(table, key)
 
+	var oldData []byte
+	if listener.PreImageEnabled(table) {
+		var err error
+		if oldData, err = t.tx.Get(k).Get(); err != nil {
+			return err
+		}
+	}
+
 	t.tx.Set(k, data)
-	listener.OnSet(ReplaceEvent, table, k, data)
+	listener.OnSet(ReplaceEvent, table, k, data, oldData)
 
 	log.Debug().Str("table", string(table)).Interface("key", key).Msg("tx Replace")
 
@@ -400,8 +420,19 @@ func (t *ftx) Delete(ctx context.Context, table []byte, key Key) error {
 		return err
 	}
 
+	if listener.PreImageEnabled(table) {
+		it := t.tx.GetRange(kr, fdb.RangeOptions{}).Iterator()
+		for it.Advance() {
+			kv, err := it.Get()
+			if ulog.E(err) {
+				return err
+			}
+			listener.OnDelete(table, kv.Key, kv.Value)
+		}
+	} else {
+		listener.OnClearRange(DeleteEvent, table, kr.Begin.FDBKey(), kr.End.FDBKey())
+	}
 	t.tx.ClearRange(kr)
-	listener.OnClearRange(DeleteEvent, table, kr.Begin.FDBKey(), kr.End.FDBKey())
 
 	log.Debug().Str("table", string(table)).Interface("key", key).Msg("tx delete")
 
@@ -413,14 +444,46 @@ func (t *ftx) DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key)
 	lk := getFDBKey(table, lKey)
 	rk := getFDBKey(table, rKey)
 
-	t.tx.ClearRange(fdb.KeyRange{Begin: lk, End: rk})
-	listener.OnClearRange(DeleteRangeEvent, table, lk, rk)
+	kr := fdb.KeyRange{Begin: lk, End: rk}
+	if listener.RangeExpansionEnabled() {
+		if err := t.expandDeleteRange(listener, table, kr); err != nil {
+			return err
+		}
+	} else {
+		listener.OnClearRange(DeleteRangeEvent, table, lk, rk)
+	}
+	t.tx.ClearRange(kr)
 
 	log.Debug().Str("table", string(table)).Interface("lKey", lKey).Interface("rKey", rKey).Msg("tx delete range")
 
 	return nil
 }
 
+// expandDeleteRange buffers a delete event for every key in the range if the range is small enough, otherwise a
+// single range delete event is buffered.
+func (t *ftx) expandDeleteRange(listener EventListener, table []byte, kr fdb.KeyRange) error {
+	kvs, err := t.tx.GetRange(kr, fdb.RangeOptions{Limit: maxExpandedDeleteRange + 1}).GetSliceWithError()
+	if ulog.E(err) {
+		return err
+	}
+
+	if len(kvs) > maxExpandedDeleteRange {
+		listener.OnClearRange(DeleteRangeEvent, table, kr.Begin.FDBKey(), kr.End.FDBKey())
+		return nil
+	}
+
+	preImage := listener.PreImageEnabled(table)
+	for _, kv := range kvs {
+		var oldData []byte
+		if preImage {
+			oldData = kv.Value
+		}
+		listener.OnDelete(table, kv.Key, oldData)
+	}
+
+	return nil
+}
+
 func (t *ftx) Update(ctx context.Context, table []byte, key Key, apply func([]byte) ([]byte, error)) (int32, error) {
 	listener := GetEventListener(ctx)
 	k, err := fdb.PrefixRange(getFDBKey(table, key))
@@ -442,8 +505,13 @@ func (t *ftx) Update(ctx context.Context, table []byte, key Key, apply func([]by
 			return -1, err
 		}
 
+		var oldData []byte
+		if listener.PreImageEnabled(table) {
+			oldData = kv.Value
+		}
+
 		t.tx.Set(kv.Key, v)
-		listener.OnSet(UpdateEvent, table, kv.Key, v)
+		listener.OnSet(UpdateEvent, table, kv.Key, v, oldData)
 
 		modifiedCount++
 	}
@@ -472,8 +540,13 @@ func (t *ftx) UpdateRange(ctx context.Context, table []byte, lKey Key, rKey Key,
 			return -1, err
 		}
 
+		var oldData []byte
+		if listener.PreImageEnabled(table) {
+			oldData = kv.Value
+		}
+
 		t.tx.Set(kv.Key, v)
-		listener.OnSet(UpdateRangeEvent, table, kv.Key, v)
+		listener.OnSet(UpdateRangeEvent, table, kv.Key, v, oldData)
 
 		modifiedCount++
 	}
@@ -489,7 +562,7 @@ func (t *ftx) Read(_ context.Context, table []byte, key Key) (baseIterator, erro
 		return nil, err
 	}
 
-	r := t.tx.GetRange(k, fdb.RangeOptions{})
+	r := t.reader().GetRange(k, fdb.RangeOptions{})
 
 	return &fdbIterator{it: r.Iterator(), subspace: subspace.FromBytes(table)}, nil
 }
@@ -498,7 +571,7 @@ func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key) (ba
 	lk := getFDBKey(table, lKey)
 	rk := getFDBKey(table, rKey)
 
-	r := t.tx.GetRange(fdb.KeyRange{Begin: lk, End: rk}, fdb.RangeOptions{})
+	r := t.reader().GetRange(fdb.KeyRange{Begin: lk, End: rk}, fdb.RangeOptions{})
 
 	log.Debug().Str("table", string(table)).Interface("lKey", lKey).Interface("rKey", rKey).Msg("tx read range")
 
@@ -518,7 +591,21 @@ func (t *ftx) SetVersionstampedKey(ctx context.Context, key []byte, value []byte
 }
 
 func (t *ftx) Get(_ context.Context, key []byte) ([]byte, error) {
-	return t.tx.Get(fdb.Key(key)).Get()
+	return t.reader().Get(fdb.Key(key)).Get()
+}
+
+// reader returns the snapshot of the transaction for the snapshot transactions, the snapshot reads don't add read
+// conflict ranges so that the transaction never conflicts with the concurrent writers.
+func (t *ftx) reader() fdb.ReadTransaction {
+	if t.snapshot {
+		return t.tx.Snapshot()
+	}
+	return t.tx
+}
+
+// GetReadVersion returns the version of the database the transaction reads.
+func (t *ftx) GetReadVersion(_ context.Context) (int64, error) {
+	return t.tx.GetReadVersion().Get()
 }
 
 func (t *ftx) Commit(ctx context.Context) error {
@@ -52,11 +52,21 @@ type Tx interface {
 	Commit(context.Context) error
 	Rollback(context.Context) error
 	IsRetriable() bool
+	GetReadVersion(context.Context) (int64, error)
+}
+
+// TxOptions are the options of a transaction. Snapshot transactions read without adding read conflict ranges, they
+// see a consistent snapshot but don't protect their reads against concurrent writers. ReadVersion pins the version of
+// the database read by the transaction, FoundationDB only keeps the versions of the last few seconds.
+type TxOptions struct {
+	Snapshot    bool
+	ReadVersion int64
 }
 
 type KeyValueStore interface {
 	KV
 	BeginTx(ctx context.Context) (Tx, error)
+	BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error)
 	CreateTable(ctx context.Context, name []byte) error
 	DropTable(ctx context.Context, name []byte) error
 	GetInternalDatabase() (interface{}, error) // TODO: CDC remove workaround
@@ -312,7 +322,11 @@ func (m *KeyValueStoreImplWithMetrics) UpdateRange(ctx context.Context, table []
 }
 
 func (k *KeyValueStoreImpl) BeginTx(ctx context.Context) (Tx, error) {
-	btx, err := k.fdbkv.BeginTx(ctx)
+	return k.BeginTxWithOptions(ctx, TxOptions{})
+}
+
+func (k *KeyValueStoreImpl) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	btx, err := k.fdbkv.BeginTxWithOptions(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -323,11 +337,15 @@ func (k *KeyValueStoreImpl) BeginTx(ctx context.Context) (Tx, error) {
 }
 
 func (m *KeyValueStoreImplWithMetrics) BeginTx(ctx context.Context) (Tx, error) {
+	return m.BeginTxWithOptions(ctx, TxOptions{})
+}
+
+func (m *KeyValueStoreImplWithMetrics) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
 	// This needs to be a special case in order to have the tx metrics as well
 	var btx Tx
 	var err error
 	m.measure(ctx, "BeginTx", func() error {
-		btx, err = m.kv.BeginTx(ctx)
+		btx, err = m.kv.BeginTxWithOptions(ctx, opts)
 		return err
 	})
 	return &TxImplWithMetrics{
@@ -417,6 +435,14 @@ func (m *TxImplWithMetrics) IsRetriable() bool {
 	return m.tx.IsRetriable()
 }
 
+func (m *TxImplWithMetrics) GetReadVersion(ctx context.Context) (version int64, err error) {
+	m.measure(ctx, "GetReadVersion", func() error {
+		version, err = m.tx.GetReadVersion(ctx)
+		return err
+	})
+	return
+}
+
 func (tx *TxImpl) Insert(ctx context.Context, table []byte, key Key, data *internal.TableData) error {
 	enc, err := internal.Encode(data)
 	if err != nil {
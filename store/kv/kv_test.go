@@ -616,6 +616,49 @@ func testSetVersionstampedValue(t *testing.T, kv baseKVStore) {
 	require.NoError(t, tx.Commit(ctx))
 }
 
+func testSnapshotTx(t *testing.T, kv *fdbkv) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	table := []byte("t1")
+	require.NoError(t, kv.DropTable(ctx, table))
+	require.NoError(t, kv.CreateTable(ctx, table))
+	require.NoError(t, kv.Insert(ctx, table, BuildKey("p1"), []byte("value1")))
+
+	readValue := func(tx baseTx) []byte {
+		it, err := tx.Read(ctx, table, BuildKey("p1"))
+		require.NoError(t, err)
+		var v baseKeyValue
+		require.True(t, it.Next(&v))
+		require.NoError(t, it.Err())
+		return v.Value
+	}
+
+	tx, err := kv.BeginTxWithOptions(ctx, TxOptions{})
+	require.NoError(t, err)
+	snapshotTx, err := kv.BeginTxWithOptions(ctx, TxOptions{Snapshot: true})
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), readValue(tx))
+	require.Equal(t, []byte("value1"), readValue(snapshotTx))
+	version, err := snapshotTx.(*ftx).GetReadVersion(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, kv.Replace(ctx, table, BuildKey("p1"), []byte("value2")))
+
+	// the snapshot reads don't conflict with the concurrent write
+	require.NoError(t, tx.Replace(ctx, table, BuildKey("p2"), []byte("value1")))
+	require.Equal(t, ErrConflictingTransaction, tx.Commit(ctx))
+	require.NoError(t, snapshotTx.Replace(ctx, table, BuildKey("p2"), []byte("value1")))
+	require.NoError(t, snapshotTx.Commit(ctx))
+
+	pinnedTx, err := kv.BeginTxWithOptions(ctx, TxOptions{Snapshot: true, ReadVersion: version})
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), readValue(pinnedTx))
+	require.NoError(t, pinnedTx.Rollback(ctx))
+
+	require.NoError(t, kv.DropTable(ctx, table))
+}
+
 func testMeasureLow() {
 	ctx := context.Background()
 
@@ -674,6 +717,9 @@ func TestKVFDB(t *testing.T) {
 	t.Run("TestSetVersionstampedValue", func(t *testing.T) {
 		testSetVersionstampedValue(t, kv)
 	})
+	t.Run("TestSnapshotTx", func(t *testing.T) {
+		testSnapshotTx(t, kv)
+	})
 	t.Run("TestMeasureCounters", func(t *testing.T) {
 		metrics.InitializeMetrics()
 		testMeasureLow()
@@ -25,8 +25,24 @@ const (
 	UpdateRangeEvent = "updateRange"
 	DeleteEvent      = "delete"
 	DeleteRangeEvent = "deleteRange"
+
+	// the events of the DDL operations, they are not tied to a table and carry the description of the change
+	CreateDatabaseEvent   = "createDatabase"
+	DropDatabaseEvent     = "dropDatabase"
+	CreateCollectionEvent = "createCollection"
+	UpdateCollectionEvent = "updateCollection"
+	DropCollectionEvent   = "dropCollection"
 )
 
+// IsDDLEvent returns true if the operation of the event is a DDL operation.
+func IsDDLEvent(op string) bool {
+	switch op {
+	case CreateDatabaseEvent, DropDatabaseEvent, CreateCollectionEvent, UpdateCollectionEvent, DropCollectionEvent:
+		return true
+	}
+	return false
+}
+
 type EventListenerCtxKey struct{}
 
 // EventListener is listener to buffer all the changes in a transaction. It is attached by server layer in the context,
@@ -34,10 +50,28 @@ type EventListenerCtxKey struct{}
 // i.e. EventListener has no knowledge whether the transaction was committed or rolled back. The lifecycle of this
 // listener is managed by QuerySession in server package.
 type EventListener interface {
-	// OnSet buffers insert/replace/update events
-	OnSet(op string, table []byte, key []byte, data []byte)
+	// OnSet buffers insert/replace/update events, oldData is the value of the key before the change and is only set
+	// if the pre-image is enabled for the table.
+	OnSet(op string, table []byte, key []byte, data []byte, oldData []byte)
+	// OnDelete buffers the delete event of a single key, oldData is only set if the pre-image is enabled for the table.
+	OnDelete(table []byte, key []byte, oldData []byte)
 	// OnClearRange buffers delete events
 	OnClearRange(op string, table []byte, lKey []byte, rKey []byte)
+	// OnDDL buffers the event of a DDL operation, data is the description of the change.
+	OnDDL(op string, data []byte)
+	// EnablePreImage enables capturing the value of the keys before they are replaced, updated or deleted for the
+	// events of this table.
+	EnablePreImage(table []byte)
+	// PreImageEnabled returns true if the events of the table need to carry the value before the change.
+	PreImageEnabled(table []byte) bool
+	// Buffering returns false if the listener drops the events, in which case there is no need to read anything
+	// extra for the events.
+	Buffering() bool
+	// EnableRangeExpansion makes the range deletes buffer a delete event for every deleted key, which costs an extra
+	// read of the range. It is only needed if the events are published or passed to the triggers.
+	EnableRangeExpansion()
+	// RangeExpansionEnabled returns true if the range deletes need to buffer a delete event for every deleted key.
+	RangeExpansionEnabled() bool
 	// GetEvents is used to access buffered events. These events may be shared by different participants callers are
 	// strongly discourage to modify the event and if needed copy it to some other buffer. Once transaction completes
 	// session may discard all the buffered events.
@@ -51,19 +85,33 @@ type Event struct {
 	LKey  []byte `json:",omitempty"`
 	RKey  []byte `json:",omitempty"`
 	Data  []byte `json:",omitempty"`
-	Last  bool
+	// OldData is the value of the key before the change, it is only set if the pre-image is enabled for the table.
+	OldData []byte `json:",omitempty"`
+	Last    bool
 }
 
 type DefaultListener struct {
 	Events []*Event
+
+	preImageTables map[string]struct{}
+	expandRanges   bool
 }
 
-func (l *DefaultListener) OnSet(op string, table []byte, key []byte, data []byte) {
+func (l *DefaultListener) OnSet(op string, table []byte, key []byte, data []byte, oldData []byte) {
 	l.Events = append(l.Events, &Event{
-		Op:    op,
-		Table: table,
-		Key:   key,
-		Data:  data,
+		Op:      op,
+		Table:   table,
+		Key:     key,
+		Data:    data,
+		OldData: oldData,
+	})
+}
+func (l *DefaultListener) OnDelete(table []byte, key []byte, oldData []byte) {
+	l.Events = append(l.Events, &Event{
+		Op:      DeleteEvent,
+		Table:   table,
+		Key:     key,
+		OldData: oldData,
 	})
 }
 func (l *DefaultListener) OnClearRange(op string, table []byte, lKey []byte, rKey []byte) {
@@ -75,15 +123,47 @@ func (l *DefaultListener) OnClearRange(op string, table []byte, lKey []byte, rKe
 		RKey:  rKey,
 	})
 }
+func (l *DefaultListener) OnDDL(op string, data []byte) {
+	l.Events = append(l.Events, &Event{
+		Op:   op,
+		Data: data,
+	})
+}
+func (l *DefaultListener) EnablePreImage(table []byte) {
+	if l.preImageTables == nil {
+		l.preImageTables = make(map[string]struct{})
+	}
+	l.preImageTables[string(table)] = struct{}{}
+}
+func (l *DefaultListener) PreImageEnabled(table []byte) bool {
+	_, ok := l.preImageTables[string(table)]
+	return ok
+}
+func (l *DefaultListener) Buffering() bool {
+	return true
+}
+func (l *DefaultListener) EnableRangeExpansion() {
+	l.expandRanges = true
+}
+func (l *DefaultListener) RangeExpansionEnabled() bool {
+	return l.expandRanges
+}
 func (l *DefaultListener) GetEvents() []*Event {
 	return l.Events
 }
 
 type NoopEventListener struct{}
 
-func (l *NoopEventListener) OnSet(op string, table []byte, key []byte, data []byte)         {}
-func (l *NoopEventListener) OnClearRange(op string, table []byte, lKey []byte, rKey []byte) {}
-func (l *NoopEventListener) GetEvents() []*Event                                            { return nil }
+func (l *NoopEventListener) OnSet(op string, table []byte, key []byte, data []byte, oldData []byte) {}
+func (l *NoopEventListener) OnDelete(table []byte, key []byte, oldData []byte)                      {}
+func (l *NoopEventListener) OnClearRange(op string, table []byte, lKey []byte, rKey []byte)         {}
+func (l *NoopEventListener) OnDDL(op string, data []byte)                                           {}
+func (l *NoopEventListener) EnablePreImage(table []byte)                                            {}
+func (l *NoopEventListener) PreImageEnabled(table []byte) bool                                      { return false }
+func (l *NoopEventListener) Buffering() bool                                                        { return false }
+func (l *NoopEventListener) EnableRangeExpansion()                                                  {}
+func (l *NoopEventListener) RangeExpansionEnabled() bool                                            { return false }
+func (l *NoopEventListener) GetEvents() []*Event                                                    { return nil }
 
 func WrapEventListenerCtx(ctx context.Context) context.Context {
 	return context.WithValue(ctx, EventListenerCtxKey{}, &DefaultListener{})
@@ -14,9 +14,10 @@ type NoopTx struct {
 	*NoopKV
 }
 
-func (n *NoopTx) Commit(context.Context) error   { return nil }
-func (n *NoopTx) Rollback(context.Context) error { return nil }
-func (n *NoopTx) IsRetriable() bool              { return false }
+func (n *NoopTx) Commit(context.Context) error                  { return nil }
+func (n *NoopTx) Rollback(context.Context) error                { return nil }
+func (n *NoopTx) IsRetriable() bool                             { return false }
+func (n *NoopTx) GetReadVersion(context.Context) (int64, error) { return 0, nil }
 
 // NoopKVStore is a noop store, useful if we need to profile/debug only compute and not with the storage. This can be
 // initialized in main.go instead of using default kvStore.
@@ -28,6 +29,9 @@ func (n *NoopKVStore) BeginTx(ctx context.Context) (Tx, error)            { retu
 func (n *NoopKVStore) CreateTable(ctx context.Context, name []byte) error { return nil }
 func (n *NoopKVStore) DropTable(ctx context.Context, name []byte) error   { return nil }
 func (n *NoopKVStore) GetInternalDatabase() (interface{}, error)          { return nil, nil }
+func (n *NoopKVStore) BeginTxWithOptions(ctx context.Context, opts TxOptions) (Tx, error) {
+	return &NoopTx{}, nil
+}
 
 type NoopKV struct{}
 
@@ -0,0 +1,290 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+const (
+	embeddedActionCreate = "create"
+	embeddedActionUpsert = "upsert"
+	embeddedActionUpdate = "update"
+)
+
+// embeddedStore is a Store that keeps the search collections in memory and answers the searches in process, it
+// behaves like the Typesense store for the features of the query so that it can replace it in the single binary
+// deployments and in the tests. The collections don't survive a restart, the server recreates them when it starts and
+// backfills them from the rows stored in FDB.
+type embeddedStore struct {
+	sync.RWMutex
+
+	collections map[string]*embeddedCollection
+	aliases     map[string]string
+}
+
+func NewEmbeddedStore() Store {
+	return &embeddedStore{
+		collections: make(map[string]*embeddedCollection),
+		aliases:     make(map[string]string),
+	}
+}
+
+// resolve returns the collection the name points to, the name can be an alias.
+func (s *embeddedStore) resolve(name string) (*embeddedCollection, error) {
+	if target, ok := s.aliases[name]; ok {
+		name = target
+	}
+
+	c, ok := s.collections[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *embeddedStore) CreateCollection(_ context.Context, schema *tsApi.CollectionSchema) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.collections[schema.Name]; ok {
+		return ErrDuplicateEntity
+	}
+	if _, ok := s.aliases[schema.Name]; ok {
+		return ErrDuplicateEntity
+	}
+
+	s.collections[schema.Name] = newEmbeddedCollection(schema)
+	return nil
+}
+
+func (s *embeddedStore) UpdateCollection(_ context.Context, name string, schema *tsApi.CollectionUpdateSchema) error {
+	s.Lock()
+	defer s.Unlock()
+
+	c, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	c.updateFields(schema.Fields)
+	return nil
+}
+
+func (s *embeddedStore) DropCollection(_ context.Context, table string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if target, ok := s.aliases[table]; ok {
+		delete(s.aliases, table)
+		table = target
+	}
+	if _, ok := s.collections[table]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.collections, table)
+	return nil
+}
+
+func (s *embeddedStore) DescribeCollection(_ context.Context, table string) (*tsApi.CollectionResponse, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := c.schema
+	schema.Fields = append([]tsApi.Field(nil), c.schema.Fields...)
+	return &tsApi.CollectionResponse{CollectionSchema: schema, NumDocuments: int64(len(c.docs))}, nil
+}
+
+func (s *embeddedStore) IndexDocuments(_ context.Context, table string, documents io.Reader, options IndexDocumentsOptions) error {
+	s.Lock()
+	defer s.Unlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return err
+	}
+
+	action := options.Action
+	if len(action) == 0 {
+		action = embeddedActionCreate
+	}
+
+	scanner := bufio.NewScanner(documents)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var doc map[string]any
+		if err = jsoniter.Unmarshal(line, &doc); err != nil {
+			return NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "bad JSON: %s", err.Error())
+		}
+		id, ok := doc[embeddedDocumentId].(string)
+		if !ok || len(id) == 0 {
+			return NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "document is missing a string id")
+		}
+
+		existing := c.docs[id]
+		switch action {
+		case embeddedActionCreate:
+			if existing != nil {
+				return NewSearchError(http.StatusConflict, ErrCodeIndexingDocuments, "a document with id %s already exists", id)
+			}
+		case embeddedActionUpdate:
+			if existing == nil {
+				return NewSearchError(http.StatusNotFound, ErrCodeIndexingDocuments, "could not find a document with id: %s", id)
+			}
+			merged := existing.copyFields()
+			for k, v := range doc {
+				merged[k] = v
+			}
+			doc = merged
+		case embeddedActionUpsert:
+		default:
+			return NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "unsupported action '%s'", action)
+		}
+
+		if err = c.put(id, doc); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *embeddedStore) DeleteDocuments(_ context.Context, table string, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.docs[key]; !ok {
+		return ErrNotFound
+	}
+
+	c.remove(key)
+	return nil
+}
+
+func (s *embeddedStore) ExportDocuments(_ context.Context, table string) (io.ReadCloser, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(c.docs))
+	for id := range c.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	for i, id := range ids {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(c.docs[id].raw)
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func (s *embeddedStore) Search(_ context.Context, table string, query *qsearch.Query, pageNo int) ([]tsApi.SearchResult, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.search(query, pageNo)
+	if err != nil {
+		return nil, err
+	}
+	return []tsApi.SearchResult{result}, nil
+}
+
+func (s *embeddedStore) SwapAlias(_ context.Context, alias string, collection string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.collections[collection]; !ok {
+		return ErrNotFound
+	}
+
+	previous, isAlias := s.aliases[alias]
+	if !isAlias {
+		// the collection is replaced by the alias
+		previous = alias
+	}
+	if previous != collection {
+		delete(s.collections, previous)
+	}
+
+	s.aliases[alias] = collection
+	return nil
+}
+
+func (s *embeddedStore) UpsertSynonym(_ context.Context, table string, id string, synonym *tsApi.SearchSynonymSchema) error {
+	s.Lock()
+	defer s.Unlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return err
+	}
+
+	c.synonyms[id] = *synonym
+	return nil
+}
+
+func (s *embeddedStore) DeleteSynonym(_ context.Context, table string, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	c, err := s.resolve(table)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.synonyms[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(c.synonyms, id)
+	return nil
+}
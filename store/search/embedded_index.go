@@ -0,0 +1,637 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+const (
+	embeddedDocumentId = "id"
+	embeddedMatchAll   = "*"
+	// embeddedDefaultPerPage is the page size used by the searches that don't set one, the same as Typesense.
+	embeddedDefaultPerPage = 10
+)
+
+type embeddedDocument struct {
+	// seq orders the documents by the time they were last written, the most recent first when the scores are equal
+	seq    uint64
+	raw    []byte
+	fields map[string]any
+}
+
+// copyFields returns a copy of the fields that can be changed without changing the document.
+func (d *embeddedDocument) copyFields() map[string]any {
+	var fields map[string]any
+	_ = jsoniter.Unmarshal(d.raw, &fields)
+	return fields
+}
+
+// embeddedCollection is a search collection of the embedded store. The string fields of the documents are tokenized
+// into an inverted index that maps every token of a field to the documents it appears in, with its occurrences.
+type embeddedCollection struct {
+	schema   tsApi.CollectionSchema
+	docs     map[string]*embeddedDocument
+	seq      uint64
+	postings map[string]map[string]map[string]int
+	synonyms map[string]tsApi.SearchSynonymSchema
+}
+
+func newEmbeddedCollection(schema *tsApi.CollectionSchema) *embeddedCollection {
+	c := &embeddedCollection{
+		schema:   *schema,
+		docs:     make(map[string]*embeddedDocument),
+		postings: make(map[string]map[string]map[string]int),
+		synonyms: make(map[string]tsApi.SearchSynonymSchema),
+	}
+	c.schema.Fields = append([]tsApi.Field(nil), schema.Fields...)
+	return c
+}
+
+func (c *embeddedCollection) field(name string) (tsApi.Field, bool) {
+	for _, f := range c.schema.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return tsApi.Field{}, false
+}
+
+// textFields returns the fields that are tokenized, in the order of the schema.
+func (c *embeddedCollection) textFields() []string {
+	var fields []string
+	for _, f := range c.schema.Fields {
+		if isEmbeddedTextField(f) {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+func isEmbeddedTextField(f tsApi.Field) bool {
+	return (f.Type == "string" || f.Type == "string[]") && (f.Index == nil || *f.Index)
+}
+
+// updateFields adds the new fields and drops the fields marked to be dropped, the documents are indexed again for the
+// text fields that are added.
+func (c *embeddedCollection) updateFields(fields []tsApi.Field) {
+	for _, f := range fields {
+		for i, existing := range c.schema.Fields {
+			if existing.Name == f.Name {
+				c.schema.Fields = append(c.schema.Fields[:i], c.schema.Fields[i+1:]...)
+				delete(c.postings, f.Name)
+				break
+			}
+		}
+		if f.Drop != nil && *f.Drop {
+			continue
+		}
+
+		c.schema.Fields = append(c.schema.Fields, f)
+		if isEmbeddedTextField(f) {
+			for id, doc := range c.docs {
+				c.indexField(f.Name, id, doc.fields[f.Name])
+			}
+		}
+	}
+}
+
+func (c *embeddedCollection) put(id string, fields map[string]any) error {
+	// the keys are sorted so that the exported documents are stable
+	raw, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(fields)
+	if err != nil {
+		return NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "%s", err.Error())
+	}
+
+	c.remove(id)
+	c.seq++
+	c.docs[id] = &embeddedDocument{seq: c.seq, raw: raw, fields: fields}
+	for _, name := range c.textFields() {
+		c.indexField(name, id, fields[name])
+	}
+	return nil
+}
+
+func (c *embeddedCollection) remove(id string) {
+	doc, ok := c.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, name := range c.textFields() {
+		for _, token := range embeddedTokens(doc.fields[name]) {
+			if ids, ok := c.postings[name][token]; ok {
+				delete(ids, id)
+				if len(ids) == 0 {
+					delete(c.postings[name], token)
+				}
+			}
+		}
+	}
+	delete(c.docs, id)
+}
+
+func (c *embeddedCollection) indexField(name string, id string, v any) {
+	tokens := embeddedTokens(v)
+	if len(tokens) == 0 {
+		return
+	}
+
+	if c.postings[name] == nil {
+		c.postings[name] = make(map[string]map[string]int)
+	}
+	for _, token := range tokens {
+		if c.postings[name][token] == nil {
+			c.postings[name][token] = make(map[string]int)
+		}
+		c.postings[name][token][id]++
+	}
+}
+
+// embeddedTokens splits the string value, or the strings of an array value, into lower case words.
+func embeddedTokens(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return tokenize(t)
+	case []any:
+		var tokens []string
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				tokens = append(tokens, tokenize(s)...)
+			}
+		}
+		return tokens
+	}
+	return nil
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+type embeddedMatch struct {
+	id    string
+	doc   *embeddedDocument
+	score int64
+	// matched are the words of the document that matched the tokens of the query, per field
+	matched map[string]map[string]struct{}
+}
+
+func (m *embeddedMatch) addMatched(field string, word string) {
+	if m.matched[field] == nil {
+		m.matched[field] = make(map[string]struct{})
+	}
+	m.matched[field][word] = struct{}{}
+}
+
+// search returns a page of the documents that have all the tokens of the query in the search fields and pass the
+// filter, along with the facets of all these documents. The documents are ordered by the sort order of the query and
+// then by their score, the documents with more occurrences of the tokens, in the fields with a higher weight and with
+// fewer typos, are returned first.
+func (c *embeddedCollection) search(query *qsearch.Query, pageNo int) (tsApi.SearchResult, error) {
+	fields := query.Fields
+	if len(fields) == 0 {
+		fields = c.textFields()
+	}
+	for _, name := range fields {
+		if f, ok := c.field(name); !ok || !isEmbeddedTextField(f) {
+			return tsApi.SearchResult{}, NewSearchError(http.StatusBadRequest, ErrCodeInvalid, "could not find a text field named '%s' in the collection", name)
+		}
+	}
+
+	matches := c.match(query, fields)
+
+	facets, err := c.facets(query, matches)
+	if err != nil {
+		return tsApi.SearchResult{}, err
+	}
+
+	if err = c.sort(query.SortOrder, matches); err != nil {
+		return tsApi.SearchResult{}, err
+	}
+
+	perPage := query.PageSize
+	if perPage <= 0 {
+		perPage = embeddedDefaultPerPage
+	}
+	if pageNo <= 0 {
+		pageNo = 1
+	}
+
+	hits := make([]tsApi.SearchResultHit, 0, perPage)
+	for i := (pageNo - 1) * perPage; i < len(matches) && i < pageNo*perPage; i++ {
+		doc := matches[i].doc.copyFields()
+		score := matches[i].score
+		hit := tsApi.SearchResultHit{
+			Document:  &doc,
+			TextMatch: &score,
+		}
+		if highlights := embeddedHighlights(fields, matches[i]); len(highlights) > 0 {
+			hit.Highlights = &highlights
+		}
+		hits = append(hits, hit)
+	}
+
+	found, outOf := len(matches), len(c.docs)
+	result := tsApi.SearchResult{
+		Found:       &found,
+		OutOf:       &outOf,
+		Page:        &pageNo,
+		Hits:        &hits,
+		FacetCounts: &facets,
+	}
+	return result, nil
+}
+
+func (c *embeddedCollection) match(query *qsearch.Query, fields []string) []*embeddedMatch {
+	var tokens []string
+	if q := query.ToSearchQ(); q != embeddedMatchAll {
+		tokens = tokenize(q)
+	}
+
+	var matches map[string]*embeddedMatch
+	if len(tokens) == 0 {
+		matches = make(map[string]*embeddedMatch, len(c.docs))
+		for id, doc := range c.docs {
+			matches[id] = &embeddedMatch{id: id, doc: doc}
+		}
+	} else {
+		weights := embeddedFieldWeights(query, fields)
+		options := newEmbeddedMatchOptions(query)
+		// a document matching the query with more than one of the synonyms keeps its best score
+		for _, alternative := range c.expandSynonyms(tokens) {
+			for id, m := range c.matchTokens(alternative, fields, weights, options) {
+				existing, ok := matches[id]
+				if !ok {
+					if matches == nil {
+						matches = make(map[string]*embeddedMatch)
+					}
+					matches[id] = m
+					continue
+				}
+				if m.score > existing.score {
+					existing.score = m.score
+				}
+				for name, words := range m.matched {
+					for word := range words {
+						existing.addMatched(name, word)
+					}
+				}
+			}
+		}
+	}
+
+	var wrapped filter.Filter
+	if query.WrappedF != nil {
+		wrapped = query.WrappedF.Filter
+	}
+
+	filtered := make([]*embeddedMatch, 0, len(matches))
+	for _, m := range matches {
+		if wrapped != nil && !embeddedMatchesFilter(wrapped, m.doc.fields) {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
+
+// matchTokens returns the documents that have all the tokens in the fields, along with their score.
+func (c *embeddedCollection) matchTokens(tokens []string, fields []string, weights map[string]int, options embeddedMatchOptions) map[string]*embeddedMatch {
+	var matches map[string]*embeddedMatch
+	for i, token := range tokens {
+		tokenMatches := make(map[string]*embeddedMatch)
+		for _, name := range fields {
+			for word, cost := range c.candidates(name, token, i == len(tokens)-1, options) {
+				for id, occurrences := range c.postings[name][word] {
+					m, ok := tokenMatches[id]
+					if !ok {
+						m = &embeddedMatch{id: id, doc: c.docs[id], matched: make(map[string]map[string]struct{})}
+						tokenMatches[id] = m
+					}
+					m.score += int64(occurrences * weights[name] * (embeddedMaxCost + 1 - cost))
+					m.addMatched(name, word)
+				}
+			}
+		}
+
+		if i == 0 {
+			matches = tokenMatches
+			continue
+		}
+		for id, m := range matches {
+			t, ok := tokenMatches[id]
+			if !ok {
+				delete(matches, id)
+				continue
+			}
+			m.score += t.score
+			for name, words := range t.matched {
+				for word := range words {
+					m.addMatched(name, word)
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// expandSynonyms returns the tokens of the query followed by the tokens of the query where one of the synonyms is
+// replaced by another synonym of its set. With a root, only the root is replaced by the synonyms. Like Typesense, the
+// synonyms are not expanded recursively.
+func (c *embeddedCollection) expandSynonyms(tokens []string) [][]string {
+	alternatives := [][]string{tokens}
+	seen := map[string]struct{}{strings.Join(tokens, " "): {}}
+
+	ids := make([]string, 0, len(c.synonyms))
+	for id := range c.synonyms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		synonym := c.synonyms[id]
+		phrases := make([][]string, 0, len(synonym.Synonyms))
+		for _, s := range synonym.Synonyms {
+			if t := tokenize(s); len(t) > 0 {
+				phrases = append(phrases, t)
+			}
+		}
+
+		from := phrases
+		if synonym.Root != nil && len(*synonym.Root) > 0 {
+			from = [][]string{tokenize(*synonym.Root)}
+		}
+
+		for _, f := range from {
+			for pos := 0; pos+len(f) <= len(tokens) && len(f) > 0; pos++ {
+				if strings.Join(tokens[pos:pos+len(f)], " ") != strings.Join(f, " ") {
+					continue
+				}
+				for _, to := range phrases {
+					alternative := append(append(append([]string(nil), tokens[:pos]...), to...), tokens[pos+len(f):]...)
+					key := strings.Join(alternative, " ")
+					if _, ok := seen[key]; ok {
+						continue
+					}
+					seen[key] = struct{}{}
+					alternatives = append(alternatives, alternative)
+				}
+			}
+		}
+	}
+	return alternatives
+}
+
+// embeddedMatchesFilter evaluates the filter on the document like the search backend does, unlike MatchesDoc of the
+// filters a document without the field of a condition doesn't pass the condition.
+func embeddedMatchesFilter(f filter.Filter, doc map[string]any) bool {
+	switch t := f.(type) {
+	case filter.LogicalFilter:
+		if t.Type() == filter.OrOP {
+			for _, nested := range t.GetFilters() {
+				if embeddedMatchesFilter(nested, doc) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, nested := range t.GetFilters() {
+			if !embeddedMatchesFilter(nested, doc) {
+				return false
+			}
+		}
+		return true
+	case *filter.Selector:
+		val := embeddedValue(t.Field.DataType, doc[t.Field.Name()])
+		if val == nil {
+			return false
+		}
+		return t.Matcher.Matches(val)
+	case *filter.GeoSelector:
+		point, err := schema.NewGeoPointFromSearch(doc[t.Field.Name()])
+		if err != nil {
+			return false
+		}
+		return t.Matcher.Matches(point)
+	default:
+		return f.MatchesDoc(doc)
+	}
+}
+
+// embeddedValue converts a field of a search document to the value the conditions on the field are compared with.
+func embeddedValue(dataType schema.FieldType, v any) value.Value {
+	var raw string
+	switch t := v.(type) {
+	case string:
+		raw = t
+	case float64:
+		if dataType == schema.Int32Type || dataType == schema.Int64Type {
+			raw = strconv.FormatInt(int64(t), 10)
+		} else {
+			raw = strconv.FormatFloat(t, 'f', -1, 64)
+		}
+	case bool:
+		raw = strconv.FormatBool(t)
+	default:
+		return nil
+	}
+
+	val, err := value.NewValue(dataType, []byte(raw))
+	if err != nil {
+		return nil
+	}
+	return val
+}
+
+// facets counts the values of the facet fields in the matched documents, the most frequent values first. The range and
+// the interval facets count the documents in every range or interval instead, and the numeric facets also have the
+// stats of the values.
+func (c *embeddedCollection) facets(query *qsearch.Query, matches []*embeddedMatch) ([]tsApi.FacetCounts, error) {
+	var facets []tsApi.FacetCounts
+	size := query.ToSearchFacetSize()
+	for _, ff := range query.Facets.Fields {
+		f, ok := c.field(ff.Name)
+		if !ok || f.Facet == nil || !*f.Facet {
+			return nil, NewSearchError(http.StatusBadRequest, ErrCodeInvalid, "could not find a facet field named '%s' in the collection", ff.Name)
+		}
+
+		name := ff.Name
+		facet := tsApi.FacetCounts{FieldName: &name}
+		if isEmbeddedNumericField(f) {
+			facet.Stats = embeddedFacetStats(ff.Name, matches)
+		}
+
+		if len(ff.Ranges) > 0 {
+			counts := make([]int, len(ff.Ranges))
+			for _, m := range matches {
+				for i, r := range ff.Ranges {
+					for _, v := range embeddedNumbers(m.doc.fields[ff.Name]) {
+						if r.Contains(v) {
+							counts[i]++
+							break
+						}
+					}
+				}
+			}
+			setFacetBuckets(&facet, facetRangeLabels(ff), counts)
+			facets = append(facets, facet)
+			continue
+		}
+		if len(ff.Interval) > 0 {
+			counts := make(map[string]int)
+			for _, m := range matches {
+				if v, ok := m.doc.fields[ff.Name].(string); ok {
+					if bucket, ok := ff.IntervalBucket(v); ok {
+						counts[bucket]++
+					}
+				}
+			}
+			setFacetIntervals(&facet, counts)
+			facets = append(facets, facet)
+			continue
+		}
+
+		counts := make(map[string]int)
+		for _, m := range matches {
+			for _, v := range embeddedFacetValues(m.doc.fields[ff.Name]) {
+				counts[v]++
+			}
+		}
+
+		values := make([]string, 0, len(counts))
+		for v := range counts {
+			values = append(values, v)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+		if len(values) > size {
+			values = values[:size]
+		}
+
+		valueCounts := make([]int, len(values))
+		for i, v := range values {
+			valueCounts[i] = counts[v]
+		}
+		setFacetBuckets(&facet, values, valueCounts)
+		facets = append(facets, facet)
+	}
+
+	return facets, nil
+}
+
+func isEmbeddedNumericField(f tsApi.Field) bool {
+	switch f.Type {
+	case "int32", "int64", "float", "int32[]", "int64[]", "float[]":
+		return true
+	}
+	return false
+}
+
+// embeddedFacetStats returns the stats of the numeric values of the field in the matched documents, the stats are
+// integers in the search results so the fractions are dropped.
+func embeddedFacetStats(field string, matches []*embeddedMatch) *struct {
+	Avg         *float32 `json:"avg,omitempty"`
+	Max         *int     `json:"max,omitempty"`
+	Min         *int     `json:"min,omitempty"`
+	Sum         *int     `json:"sum,omitempty"`
+	TotalValues *int     `json:"total_values,omitempty"`
+} {
+	var count int
+	var min, max, sum float64
+	distinct := make(map[float64]struct{})
+	for _, m := range matches {
+		for _, v := range embeddedNumbers(m.doc.fields[field]) {
+			if count == 0 || v < min {
+				min = v
+			}
+			if count == 0 || v > max {
+				max = v
+			}
+			sum += v
+			count++
+			distinct[v] = struct{}{}
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	avg := float32(sum / float64(count))
+	minInt, maxInt, sumInt, total := int(min), int(max), int(sum), len(distinct)
+	return &struct {
+		Avg         *float32 `json:"avg,omitempty"`
+		Max         *int     `json:"max,omitempty"`
+		Min         *int     `json:"min,omitempty"`
+		Sum         *int     `json:"sum,omitempty"`
+		TotalValues *int     `json:"total_values,omitempty"`
+	}{Avg: &avg, Max: &maxInt, Min: &minInt, Sum: &sumInt, TotalValues: &total}
+}
+
+// embeddedNumbers returns the number value, or the numbers of an array value.
+func embeddedNumbers(v any) []float64 {
+	switch t := v.(type) {
+	case float64:
+		return []float64{t}
+	case []any:
+		var numbers []float64
+		for _, e := range t {
+			if f, ok := e.(float64); ok {
+				numbers = append(numbers, f)
+			}
+		}
+		return numbers
+	}
+	return nil
+}
+
+// embeddedFacetValues returns the values of the field as the search backend reports them in the facets.
+func embeddedFacetValues(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case float64:
+		if t == float64(int64(t)) {
+			return []string{strconv.FormatInt(int64(t), 10)}
+		}
+		return []string{strconv.FormatFloat(t, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(t)}
+	case []any:
+		var values []string
+		for _, e := range t {
+			values = append(values, embeddedFacetValues(e)...)
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+const (
+	// the defaults of the typo tolerance and of the prefix matching are the same as Typesense
+	embeddedDefaultNumTypos          = 2
+	embeddedDefaultMinLengthOneTypo  = 4
+	embeddedDefaultMinLengthTwoTypos = 7
+
+	// embeddedPrefixCost is the cost of a word that the last token of the query is a prefix of, it ranks like a typo.
+	embeddedPrefixCost = 1
+	// embeddedMaxCost is the highest cost of a match, an exact match has no cost and a match with two typos has the
+	// highest cost.
+	embeddedMaxCost = 2
+
+	embeddedHighlightStartTag = "<mark>"
+	embeddedHighlightEndTag   = "</mark>"
+)
+
+type embeddedMatchOptions struct {
+	prefix            bool
+	numTypos          int
+	minLengthOneTypo  int
+	minLengthTwoTypos int
+}
+
+func newEmbeddedMatchOptions(query *qsearch.Query) embeddedMatchOptions {
+	options := embeddedMatchOptions{
+		prefix:            true,
+		numTypos:          embeddedDefaultNumTypos,
+		minLengthOneTypo:  embeddedDefaultMinLengthOneTypo,
+		minLengthTwoTypos: embeddedDefaultMinLengthTwoTypos,
+	}
+	if query.Prefix != nil {
+		options.prefix = *query.Prefix
+	}
+	if query.Typos != nil {
+		if query.Typos.NumTypos != nil {
+			options.numTypos = *query.Typos.NumTypos
+		}
+		if query.Typos.MinLengthOneTypo > 0 {
+			options.minLengthOneTypo = query.Typos.MinLengthOneTypo
+		}
+		if query.Typos.MinLengthTwoTypos > 0 {
+			options.minLengthTwoTypos = query.Typos.MinLengthTwoTypos
+		}
+	}
+	return options
+}
+
+// typos returns the number of typos the token can have, the short tokens can't have typos.
+func (o embeddedMatchOptions) typos(token string) int {
+	typos, length := 0, utf8.RuneCountInString(token)
+	if length >= o.minLengthTwoTypos {
+		typos = 2
+	} else if length >= o.minLengthOneTypo {
+		typos = 1
+	}
+
+	if typos > o.numTypos {
+		return o.numTypos
+	}
+	return typos
+}
+
+// embeddedFieldWeights returns the weight of the search fields. Without boosts the fields that come first have a
+// higher weight, with boosts the fields have the weight of their boost or the default weight.
+func embeddedFieldWeights(query *qsearch.Query, fields []string) map[string]int {
+	weights := make(map[string]int, len(fields))
+	for i, name := range fields {
+		switch {
+		case len(query.Boost) == 0:
+			weights[name] = len(fields) - i
+		case query.Boost[name] > 0:
+			weights[name] = query.Boost[name]
+		default:
+			weights[name] = qsearch.DefaultFieldWeight
+		}
+	}
+	return weights
+}
+
+// candidates returns the words of the field that match the token of the query along with the cost of the match. The
+// word equal to the token matches with no cost, the last token of the query also matches the words it is a prefix of
+// and the tokens match the words that are within the allowed number of typos.
+func (c *embeddedCollection) candidates(field string, token string, last bool, options embeddedMatchOptions) map[string]int {
+	candidates := make(map[string]int)
+	if _, ok := c.postings[field][token]; ok {
+		candidates[token] = 0
+	}
+
+	prefix := last && options.prefix
+	typos := options.typos(token)
+	if !prefix && typos == 0 {
+		return candidates
+	}
+
+	for word := range c.postings[field] {
+		if word == token {
+			continue
+		}
+		if prefix && strings.HasPrefix(word, token) {
+			candidates[word] = embeddedPrefixCost
+			continue
+		}
+		if d := editDistance(token, word, typos); d <= typos {
+			candidates[word] = d
+		}
+	}
+	return candidates
+}
+
+// editDistance returns the Levenshtein distance between the two words, or max+1 when it is more than max.
+func editDistance(a string, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	prev, curr := make([]int, len(rb)+1), make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+			rowMin = minInt(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return minInt(prev[len(rb)], max+1)
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// sort orders the matches by the sort order and then by their score, the most recently written documents come first
+// when the rest is equal. The documents without a value for a sort field come last.
+func (c *embeddedCollection) sort(ordering qsearch.Ordering, matches []*embeddedMatch) error {
+	for _, sf := range ordering {
+		if sf.Name == qsearch.TextMatchSortField {
+			continue
+		}
+
+		f, ok := c.field(sf.Name)
+		if !ok {
+			return NewSearchError(http.StatusBadRequest, ErrCodeInvalid, "could not find a field named `%s` in the schema for sorting", sf.Name)
+		}
+		switch {
+		case f.Type == "geopoint" && sf.GeoOrigin != nil:
+		case (f.Type == "int32" || f.Type == "int64" || f.Type == "float") && sf.GeoOrigin == nil:
+		default:
+			return NewSearchError(http.StatusBadRequest, ErrCodeInvalid, "cannot sort on field `%s` of type %s", sf.Name, f.Type)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, sf := range ordering {
+			if cmp := compareSortValues(sf, matches[i], matches[j]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].doc.seq > matches[j].doc.seq
+	})
+	return nil
+}
+
+// compareSortValues returns a negative number when the first match comes before the second for the sort field and a
+// positive number when it comes after.
+func compareSortValues(sf qsearch.SortField, a *embeddedMatch, b *embeddedMatch) int {
+	va, okA := sortValue(sf, a)
+	vb, okB := sortValue(sf, b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return 1
+	case !okB:
+		return -1
+	}
+
+	cmp := 0
+	if va < vb {
+		cmp = -1
+	} else if va > vb {
+		cmp = 1
+	}
+	if !sf.Ascending {
+		cmp = -cmp
+	}
+	return cmp
+}
+
+func sortValue(sf qsearch.SortField, m *embeddedMatch) (float64, bool) {
+	if sf.Name == qsearch.TextMatchSortField {
+		return float64(m.score), true
+	}
+
+	v, ok := m.doc.fields[sf.Name]
+	if !ok {
+		return 0, false
+	}
+	if sf.GeoOrigin != nil {
+		point, err := schema.NewGeoPointFromSearch(v)
+		if err != nil {
+			return 0, false
+		}
+		return filter.HaversineKm(*sf.GeoOrigin, point), true
+	}
+
+	f, ok := v.(float64)
+	return f, ok
+}
+
+// embeddedHighlights returns the highlights of the fields that matched the query, in the order of the search fields.
+// The snippet is the whole value of the field with the matched words marked.
+func embeddedHighlights(fields []string, m *embeddedMatch) []tsApi.SearchHighlight {
+	var highlights []tsApi.SearchHighlight
+	for _, name := range fields {
+		words, ok := m.matched[name]
+		if !ok {
+			continue
+		}
+
+		field := name
+		switch v := m.doc.fields[name].(type) {
+		case string:
+			snippet, tokens := highlight(v, words)
+			matched := make([]interface{}, 0, len(tokens))
+			for _, t := range tokens {
+				matched = append(matched, t)
+			}
+			highlights = append(highlights, tsApi.SearchHighlight{Field: &field, Snippet: &snippet, MatchedTokens: &matched})
+		case []any:
+			var snippets []string
+			var indices []int
+			var matched []interface{}
+			for i, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					continue
+				}
+				snippet, tokens := highlight(s, words)
+				if len(tokens) == 0 {
+					continue
+				}
+				snippets = append(snippets, snippet)
+				indices = append(indices, i)
+				matched = append(matched, tokens)
+			}
+			highlights = append(highlights, tsApi.SearchHighlight{Field: &field, Snippets: &snippets, Indices: &indices, MatchedTokens: &matched})
+		}
+	}
+	return highlights
+}
+
+// highlight marks the words of the text that are in the matched words and returns them as they appear in the text.
+func highlight(text string, words map[string]struct{}) (string, []string) {
+	var sb strings.Builder
+	var tokens []string
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := text[start:end]
+		if _, ok := words[strings.ToLower(word)]; ok {
+			sb.WriteString(embeddedHighlightStartTag)
+			sb.WriteString(word)
+			sb.WriteString(embeddedHighlightEndTag)
+			tokens = append(tokens, word)
+		} else {
+			sb.WriteString(word)
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		sb.WriteRune(r)
+	}
+	flush(len(text))
+
+	return sb.String(), tokens
+}
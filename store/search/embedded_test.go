@@ -0,0 +1,346 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+func testEmbeddedStore(t *testing.T) Store {
+	facet := true
+	s := NewEmbeddedStore()
+	require.NoError(t, s.CreateCollection(context.Background(), &tsApi.CollectionSchema{
+		Name: "products",
+		Fields: []tsApi.Field{
+			{Name: "name", Type: "string"},
+			{Name: "description", Type: "string"},
+			{Name: "brand", Type: "string", Facet: &facet},
+			{Name: "price", Type: "float", Facet: &facet},
+			{Name: "stock", Type: "int64", Facet: &facet},
+			{Name: "released", Type: "string", Facet: &facet},
+		},
+	}))
+	require.NoError(t, s.IndexDocuments(context.Background(), "products", strings.NewReader(strings.Join([]string{
+		`{"id":"1","name":"Running shoes","description":"light shoes for running","brand":"acme","price":80.5,"stock":10,"released":"2022-10-03T10:00:00Z"}`,
+		`{"id":"2","name":"Trail shoes","description":"shoes for the trail","brand":"acme","price":120,"stock":0,"released":"2022-10-09T23:30:00-02:00"}`,
+		`{"id":"3","name":"Running shirt","description":"a shirt","brand":"other","price":25,"stock":10,"released":"2022-11-01T00:00:00Z"}`,
+		`{"id":"4","name":"Socks","description":"running socks","brand":"other"}`,
+	}, "\n")), IndexDocumentsOptions{Action: embeddedActionCreate}))
+	return s
+}
+
+func testEmbeddedFilter(t *testing.T, f string) *filter.WrappedFilter {
+	wrapped, err := filter.NewFactory([]*schema.QueryableField{
+		schema.NewQueryableField("brand", schema.StringType),
+		schema.NewQueryableField("price", schema.DoubleType),
+		schema.NewQueryableField("stock", schema.Int64Type),
+	}).WrappedFilter([]byte(f))
+	require.NoError(t, err)
+	return wrapped
+}
+
+func testEmbeddedSearch(t *testing.T, s Store, query *qsearch.Query, pageNo int) ([]string, tsApi.SearchResult) {
+	result, err := s.Search(context.Background(), "products", query, pageNo)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	var ids []string
+	for _, h := range *result[0].Hits {
+		ids = append(ids, (*h.Document)["id"].(string))
+	}
+	return ids, result[0]
+}
+
+func TestEmbeddedStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("search", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		ids, result := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").Build(), 1)
+		// "name" comes before "description" so the matches in the name rank higher
+		require.Equal(t, []string{"1", "3", "4"}, ids)
+		require.Equal(t, 3, *result.Found)
+
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("RUNNING shoes").Build(), 1)
+		require.Equal(t, []string{"1"}, ids)
+
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").SearchFields([]string{"description"}).Build(), 1)
+		require.Equal(t, []string{"4", "1"}, ids)
+
+		// without a query the most recently written documents come first
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Build(), 1)
+		require.Equal(t, []string{"4", "3", "2", "1"}, ids)
+
+		_, err := s.Search(ctx, "products", qsearch.NewBuilder().Query("x").SearchFields([]string{"price"}).Build(), 1)
+		require.Error(t, err)
+		_, err = s.Search(ctx, "unknown", qsearch.NewBuilder().Build(), 1)
+		require.Equal(t, ErrNotFound, err)
+	})
+	t.Run("filter", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		ids, _ := testEmbeddedSearch(t, s, qsearch.NewBuilder().Filter(testEmbeddedFilter(t, `{"brand":"acme"}`)).Build(), 1)
+		require.Equal(t, []string{"2", "1"}, ids)
+
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Filter(testEmbeddedFilter(t, `{"price":{"$lt":100}}`)).Build(), 1)
+		require.Equal(t, []string{"3", "1"}, ids)
+
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shoes").Filter(testEmbeddedFilter(t, `{"$or":[{"stock":0},{"price":{"$gte":100}}]}`)).Build(), 1)
+		require.Equal(t, []string{"2"}, ids)
+
+		// a document without the field doesn't pass the condition
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Filter(testEmbeddedFilter(t, `{"stock":{"$gte":0}}`)).Build(), 1)
+		require.Equal(t, []string{"3", "2", "1"}, ids)
+	})
+	t.Run("facets", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		_, result := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").Facets(qsearch.Facets{Fields: []qsearch.FacetField{{Name: "brand", Size: 10}, {Name: "stock", Size: 1}}}).Build(), 1)
+		require.Len(t, *result.FacetCounts, 2)
+
+		brand := (*result.FacetCounts)[0]
+		require.Equal(t, "brand", *brand.FieldName)
+		require.Len(t, *brand.Counts, 2)
+		require.Equal(t, "other", *(*brand.Counts)[0].Value)
+		require.Equal(t, 2, *(*brand.Counts)[0].Count)
+		require.Equal(t, "acme", *(*brand.Counts)[1].Value)
+		require.Equal(t, 1, *(*brand.Counts)[1].Count)
+
+		stock := (*result.FacetCounts)[1]
+		require.Len(t, *stock.Counts, 1)
+		require.Equal(t, "10", *(*stock.Counts)[0].Value)
+		require.Equal(t, 2, *(*stock.Counts)[0].Count)
+
+		require.Equal(t, 10, *stock.Stats.Max)
+		require.Equal(t, 10, *stock.Stats.Min)
+		require.Equal(t, 1, *stock.Stats.TotalValues)
+		require.Nil(t, brand.Stats)
+
+		_, err := s.Search(ctx, "products", qsearch.NewBuilder().Facets(qsearch.Facets{Fields: []qsearch.FacetField{{Name: "name"}}}).Build(), 1)
+		require.Error(t, err)
+	})
+	t.Run("range and interval facets", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		facets, err := qsearch.UnmarshalFacet([]byte(`{"price":{"ranges":[{"to":50},{"from":50,"to":100,"label":"medium"},{"from":100}]},"released":{"interval":"week"}}`))
+		require.NoError(t, err)
+
+		_, result := testEmbeddedSearch(t, s, qsearch.NewBuilder().Facets(facets).Build(), 1)
+		require.Len(t, *result.FacetCounts, 2)
+
+		price := (*result.FacetCounts)[0]
+		var buckets []string
+		var counts []int
+		for _, c := range *price.Counts {
+			buckets, counts = append(buckets, *c.Value), append(counts, *c.Count)
+		}
+		require.Equal(t, []string{"*-50", "medium", "100-*"}, buckets)
+		require.Equal(t, []int{1, 1, 1}, counts)
+		require.Equal(t, 225, *price.Stats.Sum)
+
+		released := (*result.FacetCounts)[1]
+		buckets, counts = nil, nil
+		for _, c := range *released.Counts {
+			buckets, counts = append(buckets, *c.Value), append(counts, *c.Count)
+		}
+		// the second release is on Monday in UTC
+		require.Equal(t, []string{"2022-10-03T00:00:00Z", "2022-10-10T00:00:00Z", "2022-10-31T00:00:00Z"}, buckets)
+		require.Equal(t, []int{1, 1, 1}, counts)
+
+		// all the intervals are counted, whatever the facet size
+		facets, err = qsearch.UnmarshalFacet([]byte(`{"brand":{"size":1},"released":{"interval":"month"}}`))
+		require.NoError(t, err)
+		_, result = testEmbeddedSearch(t, s, qsearch.NewBuilder().Facets(facets).Build(), 1)
+		require.Len(t, *(*result.FacetCounts)[0].Counts, 1)
+		require.Len(t, *(*result.FacetCounts)[1].Counts, 2)
+	})
+	t.Run("sort", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		ordering, err := qsearch.UnmarshalSort([]byte(`[{"price":"$asc"}]`))
+		require.NoError(t, err)
+		// the document without a price comes last
+		ids, _ := testEmbeddedSearch(t, s, qsearch.NewBuilder().SortOrder(ordering).Build(), 1)
+		require.Equal(t, []string{"3", "1", "2", "4"}, ids)
+
+		ordering, err = qsearch.UnmarshalSort([]byte(`[{"stock":"$desc"},{"_text_match":"$desc"}]`))
+		require.NoError(t, err)
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").SortOrder(ordering).Build(), 1)
+		require.Equal(t, []string{"1", "3", "4"}, ids)
+
+		ordering, err = qsearch.UnmarshalSort([]byte(`[{"_text_match":"$asc"}]`))
+		require.NoError(t, err)
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").SortOrder(ordering).Build(), 1)
+		require.Equal(t, []string{"4", "3", "1"}, ids)
+
+		ordering, err = qsearch.UnmarshalSort([]byte(`[{"name":"$asc"}]`))
+		require.NoError(t, err)
+		_, err = s.Search(ctx, "products", qsearch.NewBuilder().SortOrder(ordering).Build(), 1)
+		require.Error(t, err)
+	})
+	t.Run("relevance", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		// the boost of the description makes its matches rank higher than the matches in the name
+		ids, _ := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").SearchFields([]string{"name", "description"}).Boost(qsearch.Boost{"description": 5}).Build(), 1)
+		require.Equal(t, []string{"1", "4", "3"}, ids)
+
+		// the last token is a prefix
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("runn").Build(), 1)
+		require.Equal(t, []string{"1", "3", "4"}, ids)
+		noPrefix := false
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("runn").Prefix(&noPrefix).Build(), 1)
+		require.Empty(t, ids)
+
+		// the exact matches rank higher than the matches with typos
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shoes").SearchFields([]string{"name"}).Build(), 1)
+		require.Equal(t, []string{"2", "1"}, ids)
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shies").SearchFields([]string{"name"}).Build(), 1)
+		require.Equal(t, []string{"2", "1"}, ids)
+		noTypos, oneTypo := 0, 1
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shies").SearchFields([]string{"name"}).TypoTolerance(&qsearch.TypoTolerance{NumTypos: &noTypos}).Build(), 1)
+		require.Empty(t, ids)
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shies").SearchFields([]string{"name"}).TypoTolerance(&qsearch.TypoTolerance{NumTypos: &oneTypo, MinLengthOneTypo: 6}).Build(), 1)
+		require.Empty(t, ids)
+		// the default number of typos is kept when only the minimum lengths are set
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shies").SearchFields([]string{"name"}).TypoTolerance(&qsearch.TypoTolerance{MinLengthOneTypo: 4}).Build(), 1)
+		require.Equal(t, []string{"2", "1"}, ids)
+	})
+	t.Run("highlights", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		_, result := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("runing sho").Build(), 1)
+		require.Len(t, *result.Hits, 1)
+
+		highlights := *(*result.Hits)[0].Highlights
+		require.Len(t, highlights, 2)
+		require.Equal(t, "name", *highlights[0].Field)
+		require.Equal(t, "<mark>Running</mark> <mark>shoes</mark>", *highlights[0].Snippet)
+		require.Equal(t, []interface{}{"Running", "shoes"}, *highlights[0].MatchedTokens)
+		require.Equal(t, "description", *highlights[1].Field)
+		require.Equal(t, "light <mark>shoes</mark> for <mark>running</mark>", *highlights[1].Snippet)
+
+		_, result = testEmbeddedSearch(t, s, qsearch.NewBuilder().Build(), 1)
+		require.Nil(t, (*result.Hits)[0].Highlights)
+	})
+	t.Run("pagination", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		query := qsearch.NewBuilder().PageSize(3).Build()
+		ids, result := testEmbeddedSearch(t, s, query, 1)
+		require.Equal(t, []string{"4", "3", "2"}, ids)
+		require.Equal(t, 4, *result.Found)
+
+		ids, _ = testEmbeddedSearch(t, s, query, 2)
+		require.Equal(t, []string{"1"}, ids)
+
+		ids, _ = testEmbeddedSearch(t, s, query, 3)
+		require.Empty(t, ids)
+	})
+	t.Run("index and delete", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		err := s.IndexDocuments(ctx, "products", strings.NewReader(`{"id":"1","name":"duplicate"}`), IndexDocumentsOptions{Action: embeddedActionCreate})
+		require.Equal(t, NewSearchError(409, ErrCodeIndexingDocuments, "a document with id 1 already exists"), err)
+
+		require.NoError(t, s.IndexDocuments(ctx, "products", strings.NewReader(`{"id":"1","name":"Walking shoes"}`), IndexDocumentsOptions{Action: embeddedActionUpdate}))
+		ids, result := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("walking").Build(), 1)
+		require.Equal(t, []string{"1"}, ids)
+		// the fields that are not updated are kept
+		require.Equal(t, "acme", (*(*result.Hits)[0].Document)["brand"])
+
+		// the previous tokens are removed from the index
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").SearchFields([]string{"name"}).Build(), 1)
+		require.Equal(t, []string{"3"}, ids)
+
+		require.NoError(t, s.DeleteDocuments(ctx, "products", "3"))
+		require.Equal(t, ErrNotFound, s.DeleteDocuments(ctx, "products", "3"))
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").SearchFields([]string{"name"}).Build(), 1)
+		require.Empty(t, ids)
+	})
+	t.Run("arrays", func(t *testing.T) {
+		s := NewEmbeddedStore()
+		require.NoError(t, s.CreateCollection(ctx, &tsApi.CollectionSchema{Name: "posts", Fields: []tsApi.Field{{Name: "author.tags", Type: "string[]"}}}))
+		require.NoError(t, s.IndexDocuments(ctx, "posts", strings.NewReader(strings.Join([]string{
+			`{"id":"1","author.tags":["go","databases"]}`,
+			`{"id":"2","author.tags":["rust"]}`,
+		}, "\n")), IndexDocumentsOptions{Action: embeddedActionCreate}))
+
+		result, err := s.Search(ctx, "posts", qsearch.NewBuilder().Query("databases").Build(), 1)
+		require.NoError(t, err)
+		require.Len(t, *result[0].Hits, 1)
+
+		highlights := *(*result[0].Hits)[0].Highlights
+		require.Equal(t, []string{"<mark>databases</mark>"}, *highlights[0].Snippets)
+		require.Equal(t, []int{1}, *highlights[0].Indices)
+	})
+	t.Run("synonyms", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		require.NoError(t, s.UpsertSynonym(ctx, "products", "sneakers", &tsApi.SearchSynonymSchema{Synonyms: []string{"sneakers", "shoes"}}))
+		ids, _ := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running sneakers").Build(), 1)
+		require.Equal(t, []string{"1"}, ids)
+
+		root := "tee"
+		require.NoError(t, s.UpsertSynonym(ctx, "products", "tee", &tsApi.SearchSynonymSchema{Root: &root, Synonyms: []string{"t-shirt", "shirt"}}))
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("tee").Build(), 1)
+		require.Equal(t, []string{"3"}, ids)
+		// the root is not matched by its synonyms
+		require.NoError(t, s.IndexDocuments(ctx, "products", strings.NewReader(`{"id":"5","name":"Golf tee"}`), IndexDocumentsOptions{Action: embeddedActionCreate}))
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("shirt").Build(), 1)
+		require.Equal(t, []string{"3"}, ids)
+
+		require.NoError(t, s.DeleteSynonym(ctx, "products", "sneakers"))
+		ids, _ = testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running sneakers").Build(), 1)
+		require.Empty(t, ids)
+		require.Equal(t, ErrNotFound, s.DeleteSynonym(ctx, "products", "sneakers"))
+	})
+	t.Run("stop words", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		ids, _ := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("the running shirt").StopWords([]string{"the"}).Build(), 1)
+		require.Equal(t, []string{"3"}, ids)
+	})
+	t.Run("alias", func(t *testing.T) {
+		s := testEmbeddedStore(t)
+
+		require.NoError(t, s.CreateCollection(ctx, &tsApi.CollectionSchema{Name: "products-r1", Fields: []tsApi.Field{{Name: "name", Type: "string"}}}))
+		require.NoError(t, s.IndexDocuments(ctx, "products-r1", strings.NewReader(`{"id":"9","name":"Running shoes"}`), IndexDocumentsOptions{Action: embeddedActionUpsert}))
+		require.NoError(t, s.SwapAlias(ctx, "products", "products-r1"))
+
+		ids, _ := testEmbeddedSearch(t, s, qsearch.NewBuilder().Query("running").Build(), 1)
+		require.Equal(t, []string{"9"}, ids)
+
+		exported, err := s.ExportDocuments(ctx, "products")
+		require.NoError(t, err)
+		docs, err := io.ReadAll(exported)
+		require.NoError(t, err)
+		require.Equal(t, `{"id":"9","name":"Running shoes"}`, string(docs))
+
+		require.NoError(t, s.DropCollection(ctx, "products"))
+		_, err = s.Search(ctx, "products-r1", qsearch.NewBuilder().Build(), 1)
+		require.Equal(t, ErrNotFound, err)
+	})
+}
@@ -52,3 +52,14 @@ func IsSearchError(err error) bool {
 	_, ok := err.(*Error)
 	return ok
 }
+
+// IsRetryable returns false for the errors that are caused by the request itself, like a document that is rejected by
+// the search store, as retrying them can't succeed.
+func IsRetryable(err error) bool {
+	se, ok := err.(Error)
+	if !ok {
+		return true
+	}
+
+	return se.httpCode >= http.StatusInternalServerError || se.httpCode == http.StatusTooManyRequests || se.httpCode == http.StatusRequestTimeout
+}
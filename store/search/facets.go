@@ -0,0 +1,79 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"sort"
+
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+// facetCount is the type of the counts of a facet in the search results.
+type facetCount = struct {
+	Count       *int    `json:"count,omitempty"`
+	Highlighted *string `json:"highlighted,omitempty"`
+	Value       *string `json:"value,omitempty"`
+}
+
+// facetOf returns the facet of the field in the result, it is added when the result doesn't have it.
+func facetOf(result *tsApi.SearchResult, field string) *tsApi.FacetCounts {
+	if result.FacetCounts == nil {
+		result.FacetCounts = &[]tsApi.FacetCounts{}
+	}
+	for i := range *result.FacetCounts {
+		if f := &(*result.FacetCounts)[i]; f.FieldName != nil && *f.FieldName == field {
+			return f
+		}
+	}
+
+	name := field
+	*result.FacetCounts = append(*result.FacetCounts, tsApi.FacetCounts{FieldName: &name})
+	return &(*result.FacetCounts)[len(*result.FacetCounts)-1]
+}
+
+// setFacetBuckets replaces the counts of the facet with the counts of the buckets, in the order of the buckets.
+func setFacetBuckets(facet *tsApi.FacetCounts, buckets []string, counts []int) {
+	bucketCounts := make([]facetCount, 0, len(buckets))
+	for i := range buckets {
+		count, value := counts[i], buckets[i]
+		bucketCounts = append(bucketCounts, facetCount{Count: &count, Highlighted: &value, Value: &value})
+	}
+	facet.Counts = &bucketCounts
+}
+
+// setFacetIntervals replaces the counts of the facet with the counts of the intervals, in chronological order.
+func setFacetIntervals(facet *tsApi.FacetCounts, counts map[string]int) {
+	buckets := make([]string, 0, len(counts))
+	for b := range counts {
+		buckets = append(buckets, b)
+	}
+	// RFC 3339 in UTC sorts in chronological order
+	sort.Strings(buckets)
+
+	bucketCounts := make([]int, len(buckets))
+	for i, b := range buckets {
+		bucketCounts[i] = counts[b]
+	}
+	setFacetBuckets(facet, buckets, bucketCounts)
+}
+
+func facetRangeLabels(ff qsearch.FacetField) []string {
+	labels := make([]string, len(ff.Ranges))
+	for i, r := range ff.Ranges {
+		labels[i] = r.Label
+	}
+	return labels
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	qsearch "github.com/tigrisdata/tigris/query/search"
@@ -30,12 +31,35 @@ type Store interface {
 	CreateCollection(ctx context.Context, schema *tsApi.CollectionSchema) error
 	UpdateCollection(ctx context.Context, name string, schema *tsApi.CollectionUpdateSchema) error
 	DropCollection(ctx context.Context, table string) error
+	// DescribeCollection returns the schema of the collection the name points to, ErrNotFound if there is none.
+	DescribeCollection(ctx context.Context, table string) (*tsApi.CollectionResponse, error)
 	IndexDocuments(ctx context.Context, table string, documents io.Reader, options IndexDocumentsOptions) error
 	DeleteDocuments(ctx context.Context, table string, key string) error
 	Search(ctx context.Context, table string, query *qsearch.Query, pageNo int) ([]tsApi.SearchResult, error)
+	// ExportDocuments returns all the documents of the collection, one JSON document per line.
+	ExportDocuments(ctx context.Context, table string) (io.ReadCloser, error)
+	// SwapAlias points the alias to the collection and drops the collection the alias pointed to before.
+	SwapAlias(ctx context.Context, alias string, collection string) error
+	// UpsertSynonym creates or replaces the synonym set of the collection that has the id.
+	UpsertSynonym(ctx context.Context, table string, id string, synonym *tsApi.SearchSynonymSchema) error
+	DeleteSynonym(ctx context.Context, table string, id string) error
 }
 
+const (
+	BackendTypesense = "typesense"
+	BackendEmbedded  = "embedded"
+)
+
 func NewStore(config *config.SearchConfig) (Store, error) {
+	switch config.Backend {
+	case BackendEmbedded:
+		log.Info().Msg("initialized embedded search store")
+		return NewEmbeddedStore(), nil
+	case BackendTypesense, "":
+	default:
+		return nil, fmt.Errorf("unknown search backend '%s'", config.Backend)
+	}
+
 	client := typesense.NewClient(
 		typesense.WithServer(fmt.Sprintf("http://%s:%d", config.Host, config.Port)),
 		typesense.WithAPIKey(config.AuthKey))
@@ -46,15 +70,11 @@ func NewStore(config *config.SearchConfig) (Store, error) {
 }
 
 func NewStoreWithMetrics(config *config.SearchConfig) (Store, error) {
-	client := typesense.NewClient(
-		typesense.WithServer(fmt.Sprintf("http://%s:%d", config.Host, config.Port)),
-		typesense.WithAPIKey(config.AuthKey))
-	log.Info().Str("host", config.Host).Int16("port", config.Port).Msg("initialized search store")
-	return &storeImplWithMetrics{
-		&storeImpl{
-			client: client,
-		},
-	}, nil
+	s, err := NewStore(config)
+	if err != nil {
+		return nil, err
+	}
+	return &storeImplWithMetrics{s}, nil
 }
 
 type NoopStore struct{}
@@ -64,6 +84,9 @@ func (n *NoopStore) UpdateCollection(context.Context, string, *tsApi.CollectionU
 	return nil
 }
 func (n *NoopStore) DropCollection(context.Context, string) error { return nil }
+func (n *NoopStore) DescribeCollection(_ context.Context, table string) (*tsApi.CollectionResponse, error) {
+	return &tsApi.CollectionResponse{CollectionSchema: tsApi.CollectionSchema{Name: table}}, nil
+}
 func (n *NoopStore) IndexDocuments(context.Context, string, io.Reader, IndexDocumentsOptions) error {
 	return nil
 }
@@ -71,3 +94,11 @@ func (n *NoopStore) DeleteDocuments(context.Context, string, string) error { ret
 func (n *NoopStore) Search(context.Context, string, *qsearch.Query, int) ([]tsApi.SearchResult, error) {
 	return nil, nil
 }
+func (n *NoopStore) ExportDocuments(context.Context, string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+func (n *NoopStore) SwapAlias(context.Context, string, string) error { return nil }
+func (n *NoopStore) UpsertSynonym(context.Context, string, string, *tsApi.SearchSynonymSchema) error {
+	return nil
+}
+func (n *NoopStore) DeleteSynonym(context.Context, string, string) error { return nil }
@@ -15,11 +15,13 @@
 package search
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	jsoniter "github.com/json-iterator/go"
 	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metrics"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -27,9 +29,15 @@ import (
 	tsApi "github.com/typesense/typesense-go/typesense/api"
 	"github.com/uber-go/tally"
 	"io"
+	"math"
 	"net/http"
+	"time"
 )
 
+// maxIntervalFacetValues is the most values of a datetime field counted by a single facet to count the hits in the
+// intervals of an interval facet.
+const maxIntervalFacetValues = 10000
+
 type storeImpl struct {
 	client *typesense.Client
 }
@@ -62,6 +70,14 @@ func (m *storeImplWithMetrics) DropCollection(ctx context.Context, table string)
 	return
 }
 
+func (m *storeImplWithMetrics) DescribeCollection(ctx context.Context, table string) (collection *tsApi.CollectionResponse, err error) {
+	m.measure(ctx, "DescribeCollection", func() error {
+		collection, err = m.s.DescribeCollection(ctx, table)
+		return err
+	})
+	return
+}
+
 func (m *storeImplWithMetrics) IndexDocuments(ctx context.Context, table string, documents io.Reader, options IndexDocumentsOptions) (err error) {
 	m.measure(ctx, "IndexDocuments", func() error {
 		err = m.s.IndexDocuments(ctx, table, documents, options)
@@ -86,6 +102,38 @@ func (m *storeImplWithMetrics) Search(ctx context.Context, table string, query *
 	return
 }
 
+func (m *storeImplWithMetrics) ExportDocuments(ctx context.Context, table string) (documents io.ReadCloser, err error) {
+	m.measure(ctx, "ExportDocuments", func() error {
+		documents, err = m.s.ExportDocuments(ctx, table)
+		return err
+	})
+	return
+}
+
+func (m *storeImplWithMetrics) SwapAlias(ctx context.Context, alias string, collection string) (err error) {
+	m.measure(ctx, "SwapAlias", func() error {
+		err = m.s.SwapAlias(ctx, alias, collection)
+		return err
+	})
+	return
+}
+
+func (m *storeImplWithMetrics) UpsertSynonym(ctx context.Context, table string, id string, synonym *tsApi.SearchSynonymSchema) (err error) {
+	m.measure(ctx, "UpsertSynonym", func() error {
+		err = m.s.UpsertSynonym(ctx, table, id, synonym)
+		return err
+	})
+	return
+}
+
+func (m *storeImplWithMetrics) DeleteSynonym(ctx context.Context, table string, id string) (err error) {
+	m.measure(ctx, "DeleteSynonym", func() error {
+		err = m.s.DeleteSynonym(ctx, table, id)
+		return err
+	})
+	return
+}
+
 func (m *storeImplWithMetrics) measure(ctx context.Context, name string, f func() error) {
 	// Low level measurement wrapper that is called by the measure functions on the appropriate receiver
 	tags := metrics.GetSearchTags(ctx, name)
@@ -140,7 +188,7 @@ func (s *storeImpl) IndexDocuments(_ context.Context, table string, reader io.Re
 		BatchSize: &options.BatchSize,
 	})
 	if err != nil {
-		return err
+		return s.convertToInternalError(err)
 	}
 	defer func() { ulog.E(closer.Close()) }()
 
@@ -151,27 +199,36 @@ func (s *storeImpl) IndexDocuments(_ context.Context, table string, reader io.Re
 		Success  bool
 	}
 	if closer != nil {
-		var r resp
-		res, err := io.ReadAll(closer)
-		if err != nil {
-			return err
-		}
-		if err = jsoniter.Unmarshal(res, &r); err != nil {
-			return err
-		}
-		if len(r.Error) > 0 {
-			if err = fmt.Errorf(r.Error); err != nil {
+		// the response has a line for every document of the batch, the first failed document is returned
+		scanner := bufio.NewScanner(closer)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			var r resp
+			if err = jsoniter.Unmarshal(scanner.Bytes(), &r); err != nil {
 				return err
 			}
+			if len(r.Error) > 0 {
+				code := r.Code
+				if code == 0 {
+					code = http.StatusBadRequest
+				}
+				return NewSearchError(code, ErrCodeIndexingDocuments, "%s", r.Error)
+			}
 		}
+		return scanner.Err()
 	}
 
 	return nil
 }
 
 func (s *storeImpl) getBaseSearchParam(query *qsearch.Query, pageNo int) tsApi.MultiSearchParameters {
+	q := query.ToSearchQ()
 	var baseParam = tsApi.MultiSearchParameters{
-		Q:       &query.Q,
+		Q:       &q,
 		Page:    &pageNo,
 		PerPage: &query.PageSize,
 	}
@@ -184,6 +241,24 @@ func (s *storeImpl) getBaseSearchParam(query *qsearch.Query, pageNo int) tsApi.M
 			baseParam.MaxFacetValues = &size
 		}
 	}
+	if sortBy := query.ToSearchSort(); len(sortBy) > 0 {
+		baseParam.SortBy = &sortBy
+	}
+	if weights := query.ToSearchFieldWeights(); len(weights) > 0 {
+		baseParam.QueryByWeights = &weights
+	}
+	if prefix := query.ToSearchPrefix(); len(prefix) > 0 {
+		baseParam.Prefix = &prefix
+	}
+	if query.Typos != nil {
+		baseParam.NumTypos = query.Typos.NumTypos
+		if query.Typos.MinLengthOneTypo > 0 {
+			baseParam.MinLen1typo = &query.Typos.MinLengthOneTypo
+		}
+		if query.Typos.MinLengthTwoTypos > 0 {
+			baseParam.MinLen2typo = &query.Typos.MinLengthTwoTypos
+		}
+	}
 
 	return baseParam
 }
@@ -215,20 +290,367 @@ func (s *storeImpl) Search(_ context.Context, table string, query *qsearch.Query
 		return nil, s.convertToInternalError(err)
 	}
 
+	if err = s.countFacetRanges(params, query, res.Results); err != nil {
+		return nil, err
+	}
+	if err = s.countFacetIntervals(params, query, res.Results); err != nil {
+		return nil, err
+	}
+
 	return res.Results, nil
 }
 
+// countFacetRanges counts the hits in the ranges of the range facets. The search backend only counts the values, so
+// the hits of every range are counted by a search with the filter of the range added to the filter of the search.
+func (s *storeImpl) countFacetRanges(params []tsApi.MultiSearchCollectionParameters, query *qsearch.Query, results []tsApi.SearchResult) error {
+	var rangeParams []tsApi.MultiSearchCollectionParameters
+	for _, p := range params {
+		for _, ff := range query.Facets.Fields {
+			for _, r := range ff.Ranges {
+				rangeParams = append(rangeParams, facetCountParam(p, r.ToSearchFilter(ff.Name), 0))
+			}
+		}
+	}
+	if len(rangeParams) == 0 {
+		return nil
+	}
+
+	res, err := s.client.MultiSearch.Perform(&tsApi.MultiSearchParams{}, tsApi.MultiSearchSearchesParameter{
+		Searches: rangeParams,
+	})
+	if err != nil {
+		return s.convertToInternalError(err)
+	}
+
+	next := 0
+	for i := range results {
+		for _, ff := range query.Facets.Fields {
+			if len(ff.Ranges) == 0 {
+				continue
+			}
+
+			counts := make([]int, len(ff.Ranges))
+			for j := range ff.Ranges {
+				if next < len(res.Results) && res.Results[next].Found != nil {
+					counts[j] = *res.Results[next].Found
+				}
+				next++
+			}
+			setFacetBuckets(facetOf(&results[i], ff.Name), facetRangeLabels(ff), counts)
+		}
+	}
+	return nil
+}
+
+// intervalFacet is an interval facet of a search whose intervals are counted by a search per interval.
+type intervalFacet struct {
+	result int
+	param  tsApi.MultiSearchCollectionParameters
+	field  qsearch.FacetField
+}
+
+// countFacetIntervals counts the hits in the intervals of the interval facets. The values of the field are counted by
+// a single facet and added up by the interval they fall in. The fields that have more values than a facet returns are
+// counted by a search per interval instead.
+func (s *storeImpl) countFacetIntervals(params []tsApi.MultiSearchCollectionParameters, query *qsearch.Query, results []tsApi.SearchResult) error {
+	var intervals []qsearch.FacetField
+	for _, ff := range query.Facets.Fields {
+		if len(ff.Interval) > 0 {
+			intervals = append(intervals, ff)
+		}
+	}
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	var valueParams []tsApi.MultiSearchCollectionParameters
+	for _, p := range params {
+		for _, ff := range intervals {
+			valueParams = append(valueParams, facetValuesParam(p, ff.Name, maxIntervalFacetValues))
+		}
+	}
+
+	values, err := s.client.MultiSearch.Perform(&tsApi.MultiSearchParams{}, tsApi.MultiSearchSearchesParameter{
+		Searches: valueParams,
+	})
+	if err != nil {
+		return s.convertToInternalError(err)
+	}
+
+	var ranged []intervalFacet
+	next := 0
+	for i := range results {
+		for _, ff := range intervals {
+			var counts map[string]int
+			complete := false
+			if next < len(values.Results) {
+				counts, complete = facetIntervalCounts(ff, values.Results[next], maxIntervalFacetValues)
+			}
+			if complete {
+				setFacetIntervals(facetOf(&results[i], ff.Name), counts)
+			} else {
+				ranged = append(ranged, intervalFacet{result: i, param: params[i], field: ff})
+			}
+			next++
+		}
+	}
+
+	return s.countFacetIntervalsByRange(ranged, results)
+}
+
+// countFacetIntervalsByRange counts the hits in the intervals of the facets by a search per interval. The datetime
+// values are strings in the search backend, so the first and the last value of the hits are looked up by sorting on
+// the unix time of the field, and then every interval in between is counted by a search with a range filter on the
+// unix time.
+func (s *storeImpl) countFacetIntervalsByRange(facets []intervalFacet, results []tsApi.SearchResult) error {
+	if len(facets) == 0 {
+		return nil
+	}
+
+	var boundParams []tsApi.MultiSearchCollectionParameters
+	for _, f := range facets {
+		key := schema.ToSearchDateTimeKey(f.field.Name)
+		for _, order := range []string{"asc", "desc"} {
+			param := facetCountParam(f.param, fmt.Sprintf("%s:>=%d", key, int64(math.MinInt64)), 1)
+			sortBy := key + ":" + order
+			param.SortBy, param.IncludeFields = &sortBy, &key
+			boundParams = append(boundParams, param)
+		}
+	}
+
+	bounds, err := s.client.MultiSearch.Perform(&tsApi.MultiSearchParams{}, tsApi.MultiSearchSearchesParameter{
+		Searches: boundParams,
+	})
+	if err != nil {
+		return s.convertToInternalError(err)
+	}
+
+	// the starts of the intervals of every facet, in the order of the facets
+	starts := make([][]time.Time, len(facets))
+	var countParams []tsApi.MultiSearchCollectionParameters
+	for i, f := range facets {
+		key := schema.ToSearchDateTimeKey(f.field.Name)
+		first, firstOk := facetIntervalBound(bounds.Results, 2*i, key)
+		last, lastOk := facetIntervalBound(bounds.Results, 2*i+1, key)
+		if firstOk && lastOk {
+			if starts[i], err = f.field.Intervals(first, last); err != nil {
+				return err
+			}
+		}
+		for _, start := range starts[i] {
+			countParams = append(countParams, facetCountParam(f.param, f.field.IntervalFilter(key, start), 0))
+		}
+	}
+
+	var counts []tsApi.SearchResult
+	if len(countParams) > 0 {
+		res, err := s.client.MultiSearch.Perform(&tsApi.MultiSearchParams{}, tsApi.MultiSearchSearchesParameter{
+			Searches: countParams,
+		})
+		if err != nil {
+			return s.convertToInternalError(err)
+		}
+		counts = res.Results
+	}
+
+	next := 0
+	for i, f := range facets {
+		bucketCounts := make(map[string]int)
+		for _, start := range starts[i] {
+			if next < len(counts) && counts[next].Found != nil && *counts[next].Found > 0 {
+				bucketCounts[start.Format(time.RFC3339)] = *counts[next].Found
+			}
+			next++
+		}
+		setFacetIntervals(facetOf(&results[f.result], f.field.Name), bucketCounts)
+	}
+	return nil
+}
+
+// facetValuesParam returns the parameters of the search that counts the values of the field in the hits of the search
+// p, at most size of them.
+func facetValuesParam(p tsApi.MultiSearchCollectionParameters, field string, size int) tsApi.MultiSearchCollectionParameters {
+	var firstPage, perPage = 1, 0
+
+	param := p.MultiSearchParameters
+	param.Page, param.PerPage = &firstPage, &perPage
+	param.FacetBy, param.MaxFacetValues, param.SortBy = &field, &size, nil
+	return tsApi.MultiSearchCollectionParameters{
+		Collection:            p.Collection,
+		MultiSearchParameters: param,
+	}
+}
+
+// facetIntervalCounts adds up the counts of the values of the facet by the interval they fall in. It returns false if
+// the facet has as many values as were asked for, as the values that are not returned can't be counted then.
+func facetIntervalCounts(ff qsearch.FacetField, result tsApi.SearchResult, size int) (map[string]int, bool) {
+	counts := make(map[string]int)
+	if result.FacetCounts == nil {
+		return counts, true
+	}
+
+	for _, facet := range *result.FacetCounts {
+		if facet.FieldName == nil || *facet.FieldName != ff.Name || facet.Counts == nil {
+			continue
+		}
+		if len(*facet.Counts) >= size {
+			return nil, false
+		}
+
+		for _, c := range *facet.Counts {
+			if c.Value == nil || c.Count == nil {
+				continue
+			}
+			if bucket, ok := ff.IntervalBucket(*c.Value); ok {
+				counts[bucket] += *c.Count
+			}
+		}
+	}
+	return counts, true
+}
+
+// facetCountParam returns the parameters of the search that counts the hits of the search p that also match filterBy.
+func facetCountParam(p tsApi.MultiSearchCollectionParameters, filterBy string, perPage int) tsApi.MultiSearchCollectionParameters {
+	var firstPage = 1
+	if p.FilterBy != nil {
+		filterBy = *p.FilterBy + "&&" + filterBy
+	}
+
+	param := p.MultiSearchParameters
+	param.FilterBy = &filterBy
+	param.Page, param.PerPage = &firstPage, &perPage
+	param.FacetBy, param.MaxFacetValues, param.SortBy = nil, nil, nil
+	return tsApi.MultiSearchCollectionParameters{
+		Collection:            p.Collection,
+		MultiSearchParameters: param,
+	}
+}
+
+// facetIntervalBound returns the time in the field of the only hit of the i-th result, false if it has no hit.
+func facetIntervalBound(results []tsApi.SearchResult, i int, key string) (time.Time, bool) {
+	if i >= len(results) || results[i].Hits == nil || len(*results[i].Hits) == 0 {
+		return time.Time{}, false
+	}
+
+	doc := (*results[i].Hits)[0].Document
+	if doc == nil {
+		return time.Time{}, false
+	}
+	millis, ok := (*doc)[key].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(millis)), true
+}
+
 func (s *storeImpl) CreateCollection(_ context.Context, schema *tsApi.CollectionSchema) error {
 	_, err := s.client.Collections().Create(schema)
 	return s.convertToInternalError(err)
 }
 
 func (s *storeImpl) UpdateCollection(_ context.Context, name string, schema *tsApi.CollectionUpdateSchema) error {
-	_, err := s.client.Collection(name).Update(schema)
+	target, _, err := s.resolveAlias(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Collection(target).Update(schema)
 	return s.convertToInternalError(err)
 }
 
+// DropCollection drops the collection, if the name is an alias of a reindexed collection then both the alias and the
+// collection it points to are dropped.
 func (s *storeImpl) DropCollection(_ context.Context, table string) error {
-	_, err := s.client.Collection(table).Delete()
+	target, isAlias, err := s.resolveAlias(table)
+	if err != nil {
+		return err
+	}
+	if isAlias {
+		if _, err = s.client.Alias(table).Delete(); err != nil {
+			return s.convertToInternalError(err)
+		}
+	}
+
+	_, err = s.client.Collection(target).Delete()
 	return s.convertToInternalError(err)
 }
+
+func (s *storeImpl) DescribeCollection(_ context.Context, table string) (*tsApi.CollectionResponse, error) {
+	target, _, err := s.resolveAlias(table)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.client.Collection(target).Retrieve()
+	if err != nil {
+		return nil, s.convertToInternalError(err)
+	}
+	return collection, nil
+}
+
+func (s *storeImpl) ExportDocuments(_ context.Context, table string) (io.ReadCloser, error) {
+	documents, err := s.client.Collection(table).Documents().Export()
+	if err != nil {
+		return nil, s.convertToInternalError(err)
+	}
+	return documents, nil
+}
+
+// SwapAlias moves the alias to the collection in a single step, so the searches and the writes through the alias never
+// fail. The alias can't have the name of an existing collection, the collections created before the aliases get an alias
+// with another name when they are reindexed.
+func (s *storeImpl) SwapAlias(_ context.Context, alias string, collection string) error {
+	previous, isAlias, err := s.resolveAlias(alias)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.client.Aliases().Upsert(alias, &tsApi.CollectionAliasSchema{CollectionName: collection}); err != nil {
+		return s.convertToInternalError(err)
+	}
+
+	if isAlias && previous != collection {
+		if _, err = s.client.Collection(previous).Delete(); err != nil {
+			if err = s.convertToInternalError(err); err != ErrNotFound {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpsertSynonym sets the synonyms on the collection the name points to, the name can be the alias of a reindexed
+// collection.
+func (s *storeImpl) UpsertSynonym(_ context.Context, table string, id string, synonym *tsApi.SearchSynonymSchema) error {
+	target, _, err := s.resolveAlias(table)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Collection(target).Synonyms().Upsert(id, synonym)
+	return s.convertToInternalError(err)
+}
+
+func (s *storeImpl) DeleteSynonym(_ context.Context, table string, id string) error {
+	target, _, err := s.resolveAlias(table)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Collection(target).Synonym(id).Delete()
+	return s.convertToInternalError(err)
+}
+
+// resolveAlias returns the collection the name points to, which is the name itself unless it is an alias.
+func (s *storeImpl) resolveAlias(name string) (string, bool, error) {
+	alias, err := s.client.Alias(name).Retrieve()
+	if err != nil {
+		if err = s.convertToInternalError(err); err == ErrNotFound {
+			return name, false, nil
+		}
+		return "", false, err
+	}
+
+	return alias.CollectionName, true, nil
+}
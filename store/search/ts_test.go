@@ -0,0 +1,76 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+func TestBaseSearchParamTypos(t *testing.T) {
+	s := &storeImpl{}
+
+	param := s.getBaseSearchParam(qsearch.NewBuilder().Query("shoes").Build(), 1)
+	require.Nil(t, param.NumTypos)
+	require.Nil(t, param.MinLen1typo)
+
+	// the number of typos is left to the search backend unless it is set
+	param = s.getBaseSearchParam(qsearch.NewBuilder().Query("shoes").TypoTolerance(&qsearch.TypoTolerance{MinLengthOneTypo: 4}).Build(), 1)
+	require.Nil(t, param.NumTypos)
+	require.Equal(t, 4, *param.MinLen1typo)
+
+	numTypos := 0
+	param = s.getBaseSearchParam(qsearch.NewBuilder().Query("shoes").TypoTolerance(&qsearch.TypoTolerance{NumTypos: &numTypos}).Build(), 1)
+	require.Equal(t, 0, *param.NumTypos)
+}
+
+func TestFacetIntervalCounts(t *testing.T) {
+	ff := qsearch.FacetField{Name: "created", Interval: qsearch.FacetIntervalDay}
+	facet := func(field string, values map[string]int) tsApi.SearchResult {
+		var counts []facetCount
+		for v, c := range values {
+			value, count := v, c
+			counts = append(counts, facetCount{Value: &value, Count: &count})
+		}
+		return tsApi.SearchResult{FacetCounts: &[]tsApi.FacetCounts{{FieldName: &field, Counts: &counts}}}
+	}
+
+	t.Run("added_up_by_interval", func(t *testing.T) {
+		counts, ok := facetIntervalCounts(ff, facet("created", map[string]int{
+			"2022-10-16T10:00:00Z":      2,
+			"2022-10-16T23:30:00Z":      1,
+			"2022-10-17T01:00:00+02:00": 3,
+			"2022-10-17T08:00:00Z":      4,
+			"yesterday":                 5,
+		}), 10)
+		require.True(t, ok)
+		require.Equal(t, map[string]int{"2022-10-16T00:00:00Z": 6, "2022-10-17T00:00:00Z": 4}, counts)
+	})
+	t.Run("no_hits", func(t *testing.T) {
+		counts, ok := facetIntervalCounts(ff, tsApi.SearchResult{}, 10)
+		require.True(t, ok)
+		require.Empty(t, counts)
+	})
+	t.Run("too_many_values", func(t *testing.T) {
+		_, ok := facetIntervalCounts(ff, facet("created", map[string]int{
+			"2022-10-16T10:00:00Z": 1,
+			"2022-10-16T11:00:00Z": 1,
+		}), 2)
+		require.False(t, ok)
+	})
+}